@@ -2,8 +2,10 @@ package main
 
 import (
 	"log"
+	"os"
 	"strings"
 
+	"github.com/pwnderpants/homenet/internal/cli"
 	"github.com/pwnderpants/homenet/internal/config"
 	"github.com/pwnderpants/homenet/internal/logger"
 	"github.com/pwnderpants/homenet/internal/server"
@@ -38,6 +40,15 @@ func main() {
 		log.Println("Log level set to INFO (default)")
 	}
 
+	// Run a subcommand instead of the server if one was given
+	if len(os.Args) > 1 {
+		if err := cli.Run(cfg, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	// Start the server with configured port
 	if err := server.StartServer(cfg.Server.Port); err != nil {
 		log.Fatal(err)
@@ -2,4 +2,10 @@ module github.com/pwnderpants/homenet
 
 go 1.24.3
 
-require github.com/mattn/go-sqlite3 v1.14.28
+require (
+	github.com/mattn/go-sqlite3 v1.14.28
+	golang.org/x/crypto v0.38.0
+	golang.org/x/term v0.32.0
+)
+
+require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,237 @@
+// Package auth hashes passwords and manages the session cookies, API tokens
+// and request context that identify a signed-in user
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// MinPasswordLength is the shortest password an account may have
+const MinPasswordLength = 8
+
+// SessionCookie is the name of the cookie holding a browser's session token
+const SessionCookie = "homenet_session"
+
+// CSRF tokens are sent by HTMX in CSRFHeader and by plain forms in CSRFField.
+// Browsers without a session get a random secret in CSRFCookie to derive theirs from.
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+	CSRFCookie = "homenet_csrf"
+)
+
+// APITokenPrefix starts every API token, so a leaked one is easy to recognize
+const APITokenPrefix = "hn_"
+
+// tokenBytes is how much randomness a session or API token carries
+const tokenBytes = 32
+
+// dummyHash is compared against when a username is unknown, so failed sign-ins
+// take as long whether or not the account exists
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("homenet-dummy-password"), bcrypt.DefaultCost)
+
+	return hash
+})
+
+// HashPassword returns the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password is the user's; user may be nil
+func CheckPassword(user *database.User, password string) bool {
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// NewToken returns a random token for a session cookie
+func NewToken() (string, error) {
+	token := make([]byte, tokenBytes)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// NewAPIToken returns a random secret for an API token
+func NewAPIToken() (string, error) {
+	token, err := NewToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	return APITokenPrefix + token, nil
+}
+
+// HashToken returns the hash a token is stored under, so a leaked database
+// does not hand out working sessions
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// SetSessionCookie hands a browser its session token
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie removes a browser's session token
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SessionToken returns the session token a request carries, or "" without one
+func SessionToken(r *http.Request) string {
+	cookie, err := r.Cookie(SessionCookie)
+
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// CSRFToken derives the CSRF token of a session token or CSRF cookie secret.
+// It is a MAC rather than the secret itself, so pages never expose the cookie.
+func CSRFToken(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("homenet-csrf"))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidCSRFToken reports whether token is the CSRF token of secret
+func ValidCSRFToken(secret, token string) bool {
+	return secret != "" && hmac.Equal([]byte(CSRFToken(secret)), []byte(token))
+}
+
+// SetCSRFCookie hands a browser without a session the secret its CSRF token is derived from
+func SetCSRFCookie(w http.ResponseWriter, r *http.Request, secret string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    secret,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// CSRFSecret returns the secret in a request's CSRF cookie, or "" without one
+func CSRFSecret(r *http.Request) string {
+	cookie, err := r.Cookie(CSRFCookie)
+
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// BearerToken returns the token in a request's Authorization header, or "" without one
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// userKey is the context key holding the signed-in user
+type userKey struct{}
+
+// WithUser returns a context carrying the signed-in user
+func WithUser(ctx context.Context, user *database.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the signed-in user, or nil when nobody is signed in
+// or accounts are not set up
+func UserFromContext(ctx context.Context) *database.User {
+	user, _ := ctx.Value(userKey{}).(*database.User)
+
+	return user
+}
+
+// apiTokenKey is the context key holding the API token a request was made with
+type apiTokenKey struct{}
+
+// WithAPIToken returns a context carrying the API token that authenticated a request
+func WithAPIToken(ctx context.Context, token *database.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenKey{}, token)
+}
+
+// APITokenFromContext returns the API token a request was made with, or nil
+// for browser sessions
+func APITokenFromContext(ctx context.Context) *database.APIToken {
+	token, _ := ctx.Value(apiTokenKey{}).(*database.APIToken)
+
+	return token
+}
+
+// csrfTokenKey is the context key holding the CSRF token pages embed
+type csrfTokenKey struct{}
+
+// WithCSRFToken returns a context carrying the request's CSRF token
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey{}, token)
+}
+
+// CSRFTokenFromContext returns the CSRF token pages rendered for a request embed
+func CSRFTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey{}).(string)
+
+	return token
+}
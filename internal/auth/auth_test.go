@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidCSRFToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		want   bool
+	}{
+		{"matching", "secret", CSRFToken("secret"), true},
+		{"other secret", "secret", CSRFToken("other"), false},
+		{"the secret itself", "secret", "secret", false},
+		{"empty token", "secret", "", false},
+		{"no secret", "", CSRFToken(""), false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidCSRFToken(tt.secret, tt.token); got != tt.want {
+			t.Errorf("%s: ValidCSRFToken = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Bearer hn_abc", "hn_abc"},
+		{"bearer  hn_abc ", "hn_abc"},
+		{"Basic c2FtOnB3", ""},
+		{"hn_abc", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", tt.header)
+
+		if got := BearerToken(r); got != tt.want {
+			t.Errorf("BearerToken(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
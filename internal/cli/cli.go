@@ -0,0 +1,621 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/config"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/transfer"
+)
+
+// Run dispatches a homenet subcommand such as "migrate status"
+func Run(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return usage()
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "backup":
+		return runBackup(cfg, args[1:])
+	case "restore":
+		return runRestore(cfg, args[1:])
+	case "export":
+		return runExport(cfg, args[1:])
+	case "import":
+		return runImport(cfg, args[1:])
+	case "import-from":
+		return runImportFrom(cfg, args[1:])
+	case "import-imdb-dataset":
+		return runImportIMDbDataset(cfg, args[1:])
+	case "user":
+		return runUser(cfg, args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+
+		return nil
+	default:
+		return usage()
+	}
+}
+
+// printUsage writes the list of supported subcommands to stdout
+func printUsage() {
+	fmt.Println("Usage: homenet [command]")
+	fmt.Println()
+	fmt.Println("Run without a command to start the web server.")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  migrate status        Show applied and pending schema migrations")
+	fmt.Println("  migrate pending       List pending schema migrations")
+	fmt.Println("  migrate up            Apply all pending schema migrations")
+	fmt.Println("  migrate down <ver>    Roll back to the given schema version")
+	fmt.Println("  backup                Take a snapshot of the database now")
+	fmt.Println("  backup list           List snapshots in the backup directory")
+	fmt.Println("  restore <snapshot>    Validate a snapshot and restore it (stop the server first)")
+	fmt.Println("  export <board> <fmt>  Write movies or tvshows as csv or json to stdout")
+	fmt.Println("  import <board> <file> [upsert|skip]")
+	fmt.Println("                        Import a .csv or .json file (default: skip titles already on the board)")
+	fmt.Println("  import-from <source> <file> [--dry-run]")
+	fmt.Println("                        Import a letterboxd-watchlist, letterboxd-watched, imdb or trakt export")
+	fmt.Println("  import-imdb-dataset <file>")
+	fmt.Println("                        Load IMDb's title.basics.tsv.gz for offline autocomplete")
+	fmt.Println("  user add <name> <role>")
+	fmt.Println("                        Create an admin, editor or viewer account (password read from stdin)")
+	fmt.Println("  user list             List accounts and their roles")
+	fmt.Println("  help                  Show this help")
+}
+
+// usage prints the help text and returns an error for unknown invocations
+func usage() error {
+	printUsage()
+
+	return fmt.Errorf("unknown or missing command")
+}
+
+// runMigrate handles the migrate subcommands
+func runMigrate(cfg *config.Config, args []string) error {
+	store, err := database.OpenDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	action := "status"
+
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "status":
+		statuses, err := store.GetMigrationStatus()
+
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+
+			if status.Applied {
+				state = "applied " + status.AppliedAt
+			}
+
+			fmt.Printf("%4d  %-40s %s\n", status.Version, status.Name, state)
+		}
+
+		version, err := store.SchemaVersion()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nSchema version %d (latest known: %d)\n", version, database.LatestSchemaVersion())
+
+		return nil
+	case "pending":
+		pending, err := store.GetPendingMigrations()
+
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			fmt.Println("No pending migrations")
+
+			return nil
+		}
+
+		for _, m := range pending {
+			fmt.Printf("%4d  %s\n", m.Version, m.Name)
+		}
+
+		return nil
+	case "up":
+		return store.MigrateUp()
+	case "down":
+		if len(args) < 2 {
+			return fmt.Errorf("migrate down requires a target version")
+		}
+
+		target, err := strconv.Atoi(args[1])
+
+		if err != nil || target < 0 {
+			return fmt.Errorf("invalid target version: %s", args[1])
+		}
+
+		return store.MigrateDown(target)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action: %s\n", action)
+
+		return usage()
+	}
+}
+
+// runBackup takes a snapshot now, or lists snapshots with "backup list"
+func runBackup(cfg *config.Config, args []string) error {
+	dir := cfg.Database.BackupDir
+
+	if len(args) > 0 && args[0] == "list" {
+		snapshots, err := database.ListSnapshots(dir, cfg.Database.DBName)
+
+		if err != nil {
+			return err
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Printf("No snapshots in %s\n", dir)
+
+			return nil
+		}
+
+		for _, snapshot := range snapshots {
+			fmt.Printf("%-40s %10d bytes  %s\n", snapshot.Name(), snapshot.Size, snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+
+		return nil
+	}
+
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "unknown backup action: %s\n", args[0])
+
+		return usage()
+	}
+
+	store, err := database.OpenDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	path, err := store.Backup(context.Background(), dir)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backed up to %s\n", path)
+
+	return nil
+}
+
+// runRestore validates a snapshot, backs up the current database and swaps
+// the snapshot in. A bare file name is looked up in the backup directory.
+func runRestore(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("restore requires a snapshot file")
+	}
+
+	snapshot := args[0]
+
+	if _, err := os.Stat(snapshot); os.IsNotExist(err) && !filepath.IsAbs(snapshot) {
+		snapshot = filepath.Join(cfg.Database.BackupDir, snapshot)
+	}
+
+	version, err := database.ValidateSnapshot(snapshot)
+
+	if err != nil {
+		return fmt.Errorf("refusing to restore %s: %w", snapshot, err)
+	}
+
+	fmt.Printf("Snapshot %s is valid (schema version %d)\n", snapshot, version)
+
+	// Keep the database being replaced, so a restore can itself be undone
+	if _, err := os.Stat(filepath.Join(cfg.Database.DataDir, cfg.Database.DBName+".db")); err == nil {
+		store, err := database.OpenDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+		if err != nil {
+			return err
+		}
+
+		path, err := store.Backup(context.Background(), cfg.Database.BackupDir)
+		store.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to back up the current database before restoring: %w", err)
+		}
+
+		fmt.Printf("Current database saved to %s\n", path)
+	}
+
+	if err := database.RestoreSnapshot(cfg.Database.DataDir, cfg.Database.DBName, snapshot); err != nil {
+		return err
+	}
+
+	fmt.Println("Restore complete; pending migrations will run when the server starts")
+
+	return nil
+}
+
+// boardItemTypes maps the board names accepted by export and import to item types
+var boardItemTypes = map[string]string{
+	"movies":  database.ItemTypeMovie,
+	"tvshows": database.ItemTypeTVShow,
+}
+
+// cliContext attributes changes made from the command line to the local user
+func cliContext() context.Context {
+	actor := "cli"
+
+	if current, err := user.Current(); err == nil {
+		actor = "cli:" + current.Username
+	}
+
+	return database.WithActor(context.Background(), actor)
+}
+
+// runExport writes a board to stdout as CSV or JSON
+func runExport(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("export requires a board (movies or tvshows) and a format (csv or json)")
+	}
+
+	itemType, ok := boardItemTypes[args[0]]
+
+	if !ok {
+		return fmt.Errorf("unknown board: %s", args[0])
+	}
+
+	format := args[1]
+
+	if !transfer.ValidFormat(format) {
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	if itemType == database.ItemTypeMovie {
+		movies, err := transfer.CollectMovies(ctx, store)
+
+		if err != nil {
+			return err
+		}
+
+		return transfer.ExportMovies(os.Stdout, format, movies)
+	}
+
+	tvShows, err := transfer.CollectTVShows(ctx, store)
+
+	if err != nil {
+		return err
+	}
+
+	return transfer.ExportTVShows(os.Stdout, format, tvShows)
+}
+
+// runImport imports a CSV or JSON file into a board and prints a report
+func runImport(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("import requires a board (movies or tvshows) and a file")
+	}
+
+	itemType, ok := boardItemTypes[args[0]]
+
+	if !ok {
+		return fmt.Errorf("unknown board: %s", args[0])
+	}
+
+	format := transfer.FormatFromName(args[1])
+
+	if format == "" {
+		return fmt.Errorf("import files must end in .csv or .json")
+	}
+
+	mode := transfer.ModeSkip
+
+	if len(args) > 2 {
+		mode = args[2]
+	}
+
+	if !transfer.ValidMode(mode) {
+		return fmt.Errorf("unknown import mode: %s", mode)
+	}
+
+	file, err := os.Open(args[1])
+
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+
+	defer file.Close()
+
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	var report *transfer.Report
+
+	if itemType == database.ItemTypeMovie {
+		report, err = transfer.ImportMovies(cliContext(), store, store, file, format, mode)
+	} else {
+		report, err = transfer.ImportTVShows(cliContext(), store, store, file, format, mode)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d added, %d updated, %d skipped, %d conflicts, %d failed\n", report.Added, report.Updated, report.Skipped, len(report.Conflicts), len(report.Errors))
+
+	for _, conflict := range report.Conflicts {
+		fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", conflict.Row, conflict.Title, conflict.Message)
+	}
+
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", rowErr.Row, rowErr.Title, rowErr.Message)
+	}
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d rows failed to import", len(report.Errors))
+	}
+
+	return nil
+}
+
+// runImportFrom imports a Letterboxd, IMDb or Trakt export and prints what
+// was, or with --dry-run would be, added, merged and skipped
+func runImportFrom(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("import-from requires a source and a file")
+	}
+
+	source := args[0]
+
+	if !transfer.ValidSource(source) {
+		return fmt.Errorf("unknown import source: %s", source)
+	}
+
+	dryRun := len(args) > 2 && args[2] == "--dry-run"
+
+	file, err := os.Open(args[1])
+
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+
+	defer file.Close()
+
+	entries, err := transfer.ParseSource(source, file)
+
+	if err != nil {
+		return err
+	}
+
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	ctx := cliContext()
+	plan, err := transfer.BuildPlan(ctx, store, store, source, "", entries)
+
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, item := range plan.Items {
+			fmt.Printf("row %d\t%s\t%s\t%s\n", item.Row, item.Action, item.Label(), strings.Join(item.Details, "; "))
+		}
+
+		fmt.Printf("%d to add, %d to merge, %d to skip\n", plan.Count(transfer.ActionAdd), plan.Count(transfer.ActionMerge), plan.Count(transfer.ActionSkip))
+		fmt.Println("Dry run: nothing was saved")
+
+		return nil
+	}
+
+	report := transfer.Apply(ctx, store, store, plan)
+
+	for _, item := range plan.Items {
+		if item.Failure != "" {
+			fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", item.Row, item.Label(), item.Failure)
+
+			continue
+		}
+
+		fmt.Printf("row %d\t%s\t%s\t%s\n", item.Row, item.Action, item.Label(), strings.Join(item.Details, "; "))
+	}
+
+	fmt.Printf("%d added, %d merged, %d skipped, %d failed\n", report.Added, report.Updated, report.Skipped, len(report.Errors))
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d entries failed to import", len(report.Errors))
+	}
+
+	return nil
+}
+
+// runImportIMDbDataset replaces the local IMDb title dataset with the movies
+// and series in a title.basics.tsv.gz download
+func runImportIMDbDataset(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("import-imdb-dataset requires a title.basics.tsv.gz file")
+	}
+
+	file, err := os.Open(args[0])
+
+	if err != nil {
+		return fmt.Errorf("failed to open dataset: %w", err)
+	}
+
+	defer file.Close()
+
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	count, err := store.ReplaceIMDbTitles(cliContext(), transfer.ReadIMDbDataset(file))
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d IMDb titles imported\n", count)
+
+	return nil
+}
+
+// runUser handles the user subcommands that manage sign-in accounts
+func runUser(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return usage()
+	}
+
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			return fmt.Errorf("user add requires a username and a role (admin, editor or viewer)")
+		}
+
+		username, role := args[1], args[2]
+
+		if !database.ValidRole(role) {
+			return fmt.Errorf("unknown role %q: use admin, editor or viewer", role)
+		}
+
+		password, err := readNewPassword()
+
+		if err != nil {
+			return err
+		}
+
+		hash, err := auth.HashPassword(password)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := store.AddUser(cliContext(), database.User{Username: username, PasswordHash: hash, Role: role}); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added %s %s\n", role, username)
+
+		return nil
+	case "list":
+		users, err := store.ListUsers(cliContext())
+
+		if err != nil {
+			return err
+		}
+
+		if len(users) == 0 {
+			fmt.Println("No users; sign-in is off until one is added")
+
+			return nil
+		}
+
+		for _, user := range users {
+			fmt.Printf("%-24s %-8s %s\n", user.Username, user.Role, user.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "unknown user action: %s\n", args[0])
+
+		return usage()
+	}
+}
+
+// readNewPassword prompts twice for a password on a terminal, or reads the
+// first line of stdin when it is piped so accounts can be scripted
+func readNewPassword() (string, error) {
+	stdin := int(os.Stdin.Fd())
+
+	if !term.IsTerminal(stdin) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if string(password) != string(repeated) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	return string(password), nil
+}
@@ -7,6 +7,13 @@ import (
 	"path/filepath"
 )
 
+// Default metadata provider endpoints; point them at a local stub server for testing
+const (
+	DefaultTMDbBaseURL      = "https://api.themoviedb.org/3"
+	DefaultTMDbImageBaseURL = "https://image.tmdb.org/t/p/w500"
+	DefaultOMDbBaseURL      = "https://www.omdbapi.com"
+)
+
 // Config represents the application configuration
 type Config struct {
 	Ollama struct {
@@ -17,16 +24,40 @@ type Config struct {
 		Level string `json:"level"`
 	} `json:"logging"`
 	Server struct {
-		Host string `json:"host"`
-		Port string `json:"port"`
+		Host           string   `json:"host"`
+		Port           string   `json:"port"`
+		TrustedProxies []string `json:"trusted_proxies"` // addresses or CIDR ranges allowed to name the user in Remote-User
 	} `json:"server"`
 	Database struct {
-		DataDir string `json:"data_dir"`
-		DBName  string `json:"db_name"`
+		DataDir             string `json:"data_dir"`
+		DBName              string `json:"db_name"`
+		BackupDir           string `json:"backup_dir"`
+		BackupIntervalHours int    `json:"backup_interval_hours"` // negative disables scheduled backups
+		BackupRetention     int    `json:"backup_retention"`      // snapshots kept; negative keeps them all
+		MediaDir            string `json:"media_dir"`             // cached posters and thumbnails
 	} `json:"database"`
 	Static struct {
 		Dir string `json:"dir"`
 	} `json:"static"`
+	Trash struct {
+		RetentionDays int `json:"retention_days"` // negative keeps trashed items forever
+	} `json:"trash"`
+	Auth struct {
+		SessionHours int `json:"session_hours"` // how long a sign-in lasts
+	} `json:"auth"`
+	Metadata struct {
+		Provider            string `json:"provider"`              // "tmdb", "omdb" or empty to turn lookups off
+		EnrichIntervalHours int    `json:"enrich_interval_hours"` // negative disables background enrichment
+		TMDb                struct {
+			BaseURL      string `json:"base_url"`
+			ImageBaseURL string `json:"image_base_url"`
+			APIKey       string `json:"api_key"`
+		} `json:"tmdb"`
+		OMDb struct {
+			BaseURL string `json:"base_url"`
+			APIKey  string `json:"api_key"`
+		} `json:"omdb"`
+	} `json:"metadata"`
 	Fortune struct {
 		Command     string `json:"command"`
 		Args        string `json:"args"`
@@ -117,10 +148,26 @@ func createDefaultConfig(configPath string) error {
 	}
 	defaultConfig.Database.DataDir = filepath.Join(homeDir, ".local", "share", "homenet", "data")
 	defaultConfig.Database.DBName = "homenet"
+	defaultConfig.Database.BackupDir = filepath.Join(defaultConfig.Database.DataDir, "backups")
+	defaultConfig.Database.BackupIntervalHours = 24
+	defaultConfig.Database.BackupRetention = 7
+	defaultConfig.Database.MediaDir = filepath.Join(defaultConfig.Database.DataDir, "media")
 
 	// Set default static files configuration
 	defaultConfig.Static.Dir = "web/static"
 
+	// Set default trash retention
+	defaultConfig.Trash.RetentionDays = 30
+
+	// Set default sign-in lifetime
+	defaultConfig.Auth.SessionHours = 720
+
+	// Set default metadata provider URLs; lookups stay off until a provider and key are set
+	defaultConfig.Metadata.EnrichIntervalHours = 24
+	defaultConfig.Metadata.TMDb.BaseURL = DefaultTMDbBaseURL
+	defaultConfig.Metadata.TMDb.ImageBaseURL = DefaultTMDbImageBaseURL
+	defaultConfig.Metadata.OMDb.BaseURL = DefaultOMDbBaseURL
+
 	// Set default fortune command configuration
 	defaultConfig.Fortune.Command = "/usr/games/fortune"
 	defaultConfig.Fortune.Args = "-s"
@@ -227,10 +274,50 @@ func setDefaults(config *Config) {
 		config.Database.DBName = "movies"
 	}
 
+	if config.Database.BackupDir == "" {
+		config.Database.BackupDir = filepath.Join(config.Database.DataDir, "backups")
+	}
+
+	if config.Database.MediaDir == "" {
+		config.Database.MediaDir = filepath.Join(config.Database.DataDir, "media")
+	}
+
+	if config.Database.BackupIntervalHours == 0 {
+		config.Database.BackupIntervalHours = 24
+	}
+
+	if config.Database.BackupRetention == 0 {
+		config.Database.BackupRetention = 7
+	}
+
 	if config.Static.Dir == "" {
 		config.Static.Dir = "web/static"
 	}
 
+	if config.Trash.RetentionDays == 0 {
+		config.Trash.RetentionDays = 30
+	}
+
+	if config.Auth.SessionHours <= 0 {
+		config.Auth.SessionHours = 720
+	}
+
+	if config.Metadata.EnrichIntervalHours == 0 {
+		config.Metadata.EnrichIntervalHours = 24
+	}
+
+	if config.Metadata.TMDb.BaseURL == "" {
+		config.Metadata.TMDb.BaseURL = DefaultTMDbBaseURL
+	}
+
+	if config.Metadata.TMDb.ImageBaseURL == "" {
+		config.Metadata.TMDb.ImageBaseURL = DefaultTMDbImageBaseURL
+	}
+
+	if config.Metadata.OMDb.BaseURL == "" {
+		config.Metadata.OMDb.BaseURL = DefaultOMDbBaseURL
+	}
+
 	if config.Fortune.Command == "" {
 		config.Fortune.Command = "/usr/games/fortune"
 	}
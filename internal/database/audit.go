@@ -0,0 +1,270 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Audit actions recorded for movies and TV shows
+const (
+	AuditAdd     = "add"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	AuditMerge   = "merge"
+)
+
+// SystemActor is recorded when a change has no request behind it, such as the trash purger
+const SystemActor = "system"
+
+// actorKey is the context key carrying the actor for audit entries
+type actorKey struct{}
+
+// WithActor returns a context whose store changes are attributed to actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return SystemActor
+}
+
+// AuditEntry is one recorded change to a movie or TV show. Before and After
+// hold JSON objects with only the fields that changed.
+type AuditEntry struct {
+	ID        int
+	ItemType  string
+	ItemID    int
+	Action    string
+	Actor     string
+	CreatedAt time.Time
+	Before    string
+	After     string
+}
+
+// auditActionLabels are the history view's wording for each action
+var auditActionLabels = map[string]string{
+	AuditAdd:     "Added",
+	AuditUpdate:  "Edited",
+	AuditDelete:  "Moved to trash",
+	AuditRestore: "Restored from trash",
+	AuditPurge:   "Deleted permanently",
+	AuditMerge:   "Merged duplicate",
+}
+
+// Label returns a readable description of the entry's action
+func (e AuditEntry) Label() string {
+	if label, ok := auditActionLabels[e.Action]; ok {
+		return label
+	}
+
+	return e.Action
+}
+
+// FieldChange is one field of an audit entry's before/after diff
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Changes decodes the before/after diff into a sorted list of fields
+func (e AuditEntry) Changes() []FieldChange {
+	before := decodeAuditJSON(e.Before)
+	after := decodeAuditJSON(e.After)
+
+	fields := make(map[string]bool)
+
+	for field := range before {
+		fields[field] = true
+	}
+
+	for field := range after {
+		fields[field] = true
+	}
+
+	var changes []FieldChange
+
+	for field := range fields {
+		changes = append(changes, FieldChange{Field: field, Before: formatAuditValue(before[field]), After: formatAuditValue(after[field])})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+// auditIgnoredFields are derived or tracked elsewhere and left out of diffs
+var auditIgnoredFields = []string{"id", "watched", "progress"}
+
+// auditSnapshot converts an item into a field map for diffing; nil stays nil
+func auditSnapshot(item interface{}) (map[string]interface{}, error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(item)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+
+	return fields, nil
+}
+
+// auditDiff returns JSON objects holding only the fields that differ between before and after
+func auditDiff(before, after interface{}) (string, string, error) {
+	beforeFields, err := auditSnapshot(before)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	afterFields, err := auditSnapshot(after)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	for field, value := range beforeFields {
+		if other, ok := afterFields[field]; ok && fmt.Sprint(other) == fmt.Sprint(value) {
+			delete(beforeFields, field)
+			delete(afterFields, field)
+		}
+	}
+
+	return encodeAuditJSON(beforeFields), encodeAuditJSON(afterFields), nil
+}
+
+// encodeAuditJSON marshals a field map, returning "" for nil maps
+func encodeAuditJSON(fields map[string]interface{}) string {
+	if fields == nil {
+		return ""
+	}
+
+	data, _ := json.Marshal(fields)
+
+	return string(data)
+}
+
+// decodeAuditJSON parses a stored diff, treating empty or invalid JSON as no fields
+func decodeAuditJSON(data string) map[string]interface{} {
+	var fields map[string]interface{}
+
+	if data != "" {
+		json.Unmarshal([]byte(data), &fields)
+	}
+
+	return fields
+}
+
+// formatAuditValue renders a decoded JSON value for the history view
+func formatAuditValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+
+		for _, item := range v {
+			values = append(values, formatAuditValue(item))
+		}
+
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		if service, ok := v["service"]; ok {
+			return formatAvailabilityValue(service, v["from"], v["until"])
+		}
+
+		return fmt.Sprint(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatAvailabilityValue renders a decoded availability window as "Service (from – until)"
+func formatAvailabilityValue(service, from, until interface{}) string {
+	window := Availability{Service: formatAuditValue(service), From: formatAuditValue(from), Until: formatAuditValue(until)}
+
+	if window.From == "" && window.Until == "" {
+		return window.Service
+	}
+
+	return fmt.Sprintf("%s (%s – %s)", window.Service, window.From, window.Until)
+}
+
+// recordAudit writes an audit entry for a change, attributed to the context's actor
+func recordAudit(ctx context.Context, q queryer, itemType string, itemID int, action string, before, after interface{}) error {
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode audit diff: %w", err)
+	}
+
+	if action == AuditUpdate && beforeJSON == "{}" && afterJSON == "{}" {
+		return nil
+	}
+
+	_, err = q.ExecContext(ctx, `
+	INSERT INTO audit_log (item_type, item_id, action, actor, created_at, before_json, after_json)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`, itemType, itemID, action, ActorFromContext(ctx), time.Now().UTC(), beforeJSON, afterJSON)
+
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetItemHistory returns the audit timeline of a movie or TV show, oldest first
+func (s *SQLiteStore) GetItemHistory(ctx context.Context, itemType string, itemID int) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT id, item_type, item_id, action, actor, created_at, before_json, after_json
+	FROM audit_log
+	WHERE item_type = ? AND item_id = ?
+	ORDER BY created_at, id`, itemType, itemID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	defer rows.Close()
+
+	var entries []AuditEntry
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		if err := rows.Scan(&entry.ID, &entry.ItemType, &entry.ItemID, &entry.Action, &entry.Actor, &entry.CreatedAt, &entry.Before, &entry.After); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AvailabilityDateLayout is the format of availability window dates
+const AvailabilityDateLayout = "2006-01-02"
+
+// LeavingSoonDays is how close a window's end must be for cards to warn that a title is leaving
+const LeavingSoonDays = 14
+
+// ErrInvalidAvailability is returned when an availability window has bad dates
+var ErrInvalidAvailability = errors.New("invalid availability window")
+
+// Availability is a window during which a title streams on a service.
+// From and Until are YYYY-MM-DD dates and are empty when the window is open-ended.
+type Availability struct {
+	Service string `json:"service"`
+	From    string `json:"from"`
+	Until   string `json:"until"`
+}
+
+// AvailabilityList is every window recorded for a movie or TV show
+type AvailabilityList []Availability
+
+// availabilityToday returns today's date in AvailabilityDateLayout, in local time
+func availabilityToday() string {
+	return time.Now().Format(AvailabilityDateLayout)
+}
+
+// Current reports whether the window includes today
+func (a Availability) Current() bool {
+	today := availabilityToday()
+
+	return (a.From == "" || a.From <= today) && (a.Until == "" || a.Until >= today)
+}
+
+// LeavingSoon reports whether a current window ends within LeavingSoonDays
+func (a Availability) LeavingSoon() bool {
+	if a.Until == "" || !a.Current() {
+		return false
+	}
+
+	return a.Until <= time.Now().AddDate(0, 0, LeavingSoonDays).Format(AvailabilityDateLayout)
+}
+
+// UntilLabel formats the window's end date for display
+func (a Availability) UntilLabel() string {
+	until, err := time.Parse(AvailabilityDateLayout, a.Until)
+
+	if err != nil {
+		return a.Until
+	}
+
+	return until.Format("Jan 2")
+}
+
+// Current returns the windows that include today
+func (l AvailabilityList) Current() AvailabilityList {
+	var current AvailabilityList
+
+	for _, window := range l {
+		if window.Current() {
+			current = append(current, window)
+		}
+	}
+
+	return current
+}
+
+// Available reports whether any window includes today
+func (l AvailabilityList) Available() bool {
+	return len(l.Current()) > 0
+}
+
+// services joins the service names of every window, for search
+func (l AvailabilityList) services() string {
+	names := make([]string, 0, len(l))
+
+	for _, window := range l {
+		names = append(names, window.Service)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// String encodes the windows as "service|from|until" entries separated by ";",
+// the format used by exports and the edit form
+func (l AvailabilityList) String() string {
+	entries := make([]string, 0, len(l))
+
+	for _, window := range l {
+		entries = append(entries, window.Service+"|"+window.From+"|"+window.Until)
+	}
+
+	return strings.Join(entries, ";")
+}
+
+// ParseAvailability decodes windows encoded by AvailabilityList.String; the
+// dates may be left off, so "Netflix" alone is an open-ended window
+func ParseAvailability(text string) (AvailabilityList, error) {
+	var windows AvailabilityList
+
+	for _, entry := range strings.Split(text, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, "|")
+
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("%w: %q has more than service, from and until", ErrInvalidAvailability, entry)
+		}
+
+		fields = append(fields, "", "")
+		windows = append(windows, Availability{Service: fields[0], From: fields[1], Until: fields[2]})
+	}
+
+	return normalizeAvailability(windows)
+}
+
+// currentAvailabilitySQL matches availability rows whose window includes today
+const currentAvailabilitySQL = "(available_from IS NULL OR available_from <= date('now', 'localtime')) AND (available_until IS NULL OR available_until >= date('now', 'localtime'))"
+
+// normalizeAvailability trims services, drops rows without one, validates the
+// dates and sorts the windows by service and start date
+func normalizeAvailability(windows AvailabilityList) (AvailabilityList, error) {
+	var normalized AvailabilityList
+
+	for _, window := range windows {
+		window.Service = strings.TrimSpace(window.Service)
+		window.From = strings.TrimSpace(window.From)
+		window.Until = strings.TrimSpace(window.Until)
+
+		if window.Service == "" {
+			continue
+		}
+
+		for _, date := range []string{window.From, window.Until} {
+			if _, err := time.Parse(AvailabilityDateLayout, date); date != "" && err != nil {
+				return nil, fmt.Errorf("%w: %q is not a YYYY-MM-DD date", ErrInvalidAvailability, date)
+			}
+		}
+
+		if window.From != "" && window.Until != "" && window.From > window.Until {
+			return nil, fmt.Errorf("%w: %s ends before it starts", ErrInvalidAvailability, window.Service)
+		}
+
+		normalized = append(normalized, window)
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		a, b := normalized[i], normalized[j]
+
+		if !strings.EqualFold(a.Service, b.Service) {
+			return strings.ToLower(a.Service) < strings.ToLower(b.Service)
+		}
+
+		return a.From < b.From
+	})
+
+	return normalized, nil
+}
+
+// parseAvailabilityList decodes the availability list selected with movieColumns
+// and tvShowColumns: windows separated by char(30), fields by char(31)
+func parseAvailabilityList(list string) AvailabilityList {
+	var windows AvailabilityList
+
+	for _, row := range strings.Split(list, "\x1e") {
+		fields := strings.Split(row, "\x1f")
+
+		if len(fields) != 3 {
+			continue
+		}
+
+		windows = append(windows, Availability{Service: fields[0], From: fields[1], Until: fields[2]})
+	}
+
+	normalized, _ := normalizeAvailability(windows)
+
+	return normalized
+}
+
+// nullableDate stores an empty window date as NULL
+func nullableDate(date string) interface{} {
+	if date == "" {
+		return nil
+	}
+
+	return date
+}
+
+// setAvailability replaces the availability windows of a movie or TV show
+func setAvailability(ctx context.Context, q queryer, itemType string, itemID int, windows AvailabilityList) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM availability WHERE item_type = ? AND item_id = ?", itemType, itemID); err != nil {
+		return fmt.Errorf("failed to clear availability: %w", err)
+	}
+
+	for _, window := range windows {
+		_, err := q.ExecContext(ctx, `
+		INSERT INTO availability (item_type, item_id, service, available_from, available_until)
+		VALUES (?, ?, ?, ?, ?)`, itemType, itemID, window.Service, nullableDate(window.From), nullableDate(window.Until))
+
+		if err != nil {
+			return fmt.Errorf("failed to save availability: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// day returns the date offset days from today in AvailabilityDateLayout
+func day(offset int) string {
+	return time.Now().AddDate(0, 0, offset).Format(AvailabilityDateLayout)
+}
+
+func TestParseAvailability(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    AvailabilityList
+		wantErr bool
+	}{
+		{text: "", want: nil},
+		{text: "Netflix", want: AvailabilityList{{Service: "Netflix"}}},
+		{text: " Hulu |2025-01-01| ; Netflix||2025-02-01", want: AvailabilityList{{Service: "Hulu", From: "2025-01-01"}, {Service: "Netflix", Until: "2025-02-01"}}},
+		{text: "netflix|2025-03-01|;Netflix|2025-01-01|", want: AvailabilityList{{Service: "Netflix", From: "2025-01-01"}, {Service: "netflix", From: "2025-03-01"}}},
+		{text: "|2025-01-01|;;", want: nil},
+		{text: "Netflix|a|b|c", wantErr: true},
+		{text: "Netflix|01/02/2025|", wantErr: true},
+		{text: "Netflix|2025-02-01|2025-01-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAvailability(tt.text)
+
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAvailability) {
+				t.Errorf("ParseAvailability(%q) error = %v, want ErrInvalidAvailability", tt.text, err)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAvailability(%q) = %+v, %v; want %+v", tt.text, got, err, tt.want)
+		}
+
+		if again, err := ParseAvailability(got.String()); err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("ParseAvailability(%q.String()) = %+v, %v; want %+v", tt.text, again, err, got)
+		}
+	}
+}
+
+func TestAvailabilityWindows(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      Availability
+		current     bool
+		leavingSoon bool
+	}{
+		{"open-ended", Availability{Service: "Netflix"}, true, false},
+		{"started", Availability{Service: "Netflix", From: day(-3)}, true, false},
+		{"not started", Availability{Service: "Netflix", From: day(3)}, false, false},
+		{"ends today", Availability{Service: "Netflix", Until: day(0)}, true, true},
+		{"ends soon", Availability{Service: "Netflix", Until: day(LeavingSoonDays)}, true, true},
+		{"ends later", Availability{Service: "Netflix", Until: day(LeavingSoonDays + 1)}, true, false},
+		{"ended", Availability{Service: "Netflix", Until: day(-1)}, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.window.Current(); got != tt.current {
+			t.Errorf("%s: Current() = %v, want %v", tt.name, got, tt.current)
+		}
+
+		if got := tt.window.LeavingSoon(); got != tt.leavingSoon {
+			t.Errorf("%s: LeavingSoon() = %v, want %v", tt.name, got, tt.leavingSoon)
+		}
+	}
+}
+
+func TestAvailabilityFilter(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		streaming := mustAddMovie(t, store, Movie{Title: "Streaming", Availability: AvailabilityList{{Service: "Netflix"}}})
+		ended := mustAddMovie(t, store, Movie{Title: "Ended", Availability: AvailabilityList{{Service: "Netflix", Until: day(-1)}}})
+		upcoming := mustAddMovie(t, store, Movie{Title: "Upcoming", Availability: AvailabilityList{{Service: "Hulu", From: day(2)}}})
+		nowhere := mustAddMovie(t, store, Movie{Title: "Nowhere"})
+
+		tests := []struct {
+			name  string
+			query BoardQuery
+			want  []int
+		}{
+			{"now", BoardQuery{Availability: AvailableNow, Sort: SortTitle}, []int{streaming}},
+			{"none", BoardQuery{Availability: AvailableNone, Sort: SortTitle}, []int{ended, nowhere, upcoming}},
+			{"service any time", BoardQuery{Service: "Netflix", Sort: SortTitle}, []int{ended, streaming}},
+			{"service now", BoardQuery{Service: "Netflix", Availability: AvailableNow, Sort: SortTitle}, []int{streaming}},
+			{"service later", BoardQuery{Service: "Hulu", Availability: AvailableNow}, nil},
+		}
+
+		for _, tt := range tests {
+			page, err := store.ListMovies(ctx, tt.query)
+
+			if err != nil {
+				t.Fatalf("%s: ListMovies: %v", tt.name, err)
+			}
+
+			if got := movieIDs(page.Movies); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: ListMovies = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+// movieIDs lists the IDs of movies in order, nil for none
+func movieIDs(movies []Movie) []int {
+	var ids []int
+
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+
+	return ids
+}
+
+func TestAvailabilityMigration(t *testing.T) {
+	store := openTestDB(t)
+
+	if err := store.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	if err := store.MigrateDown(7); err != nil {
+		t.Fatalf("MigrateDown(7): %v", err)
+	}
+
+	seed := `
+	INSERT INTO movies (id, title, year, notes, imdb_link, streaming, available_now) VALUES
+		(1, 'Streaming', 2001, '', '', 'Netflix', 1),
+		(2, 'Left Hulu', 2002, '', '', ' Hulu ', 0),
+		(3, 'Never Streamed', 2003, '', '', '', 0);
+	INSERT INTO tv_shows (id, title, year, notes, imdb_link, streaming) VALUES (4, 'Show', 2004, '', '', 'Max');`
+
+	if _, err := store.db.Exec(seed); err != nil {
+		t.Fatalf("seed version 7: %v", err)
+	}
+
+	if err := store.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	ctx := context.Background()
+
+	tests := []struct {
+		id        int
+		get       func(id int) (AvailabilityList, error)
+		want      AvailabilityList
+		available bool
+	}{
+		{1, movieAvailability(store), AvailabilityList{{Service: "Netflix"}}, true},
+		{2, movieAvailability(store), AvailabilityList{{Service: "Hulu", Until: day(-1)}}, false},
+		{3, movieAvailability(store), nil, false},
+		{4, func(id int) (AvailabilityList, error) {
+			tvShow, err := store.GetTVShow(ctx, id)
+
+			if err != nil || tvShow == nil {
+				return nil, err
+			}
+
+			return tvShow.Availability, nil
+		}, AvailabilityList{{Service: "Max"}}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.get(tt.id)
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("item %d availability = %+v, %v; want %+v", tt.id, got, err, tt.want)
+		}
+
+		if got.Available() != tt.available {
+			t.Errorf("item %d Available() = %v, want %v", tt.id, got.Available(), tt.available)
+		}
+	}
+}
+
+// movieAvailability returns a getter for a movie's windows
+func movieAvailability(store *SQLiteStore) func(id int) (AvailabilityList, error) {
+	return func(id int) (AvailabilityList, error) {
+		movie, err := store.GetMovie(context.Background(), id)
+
+		if err != nil || movie == nil {
+			return nil, err
+		}
+
+		return movie.Availability, nil
+	}
+}
@@ -0,0 +1,377 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// backupTimeLayout is the timestamp embedded in snapshot file names
+const backupTimeLayout = "20060102-150405"
+
+// backupPagesPerStep is how many pages each backup step copies before
+// releasing the source database to other connections
+const backupPagesPerStep = 256
+
+// backupStepPause is the pause between backup steps that lets writers through
+const backupStepPause = 10 * time.Millisecond
+
+// Snapshot is a backup file of the database
+type Snapshot struct {
+	Path      string
+	Size      int64
+	CreatedAt time.Time // from the file name
+	seq       int       // the -n suffix of a second snapshot within the same second
+}
+
+// Name returns the snapshot's file name
+func (s Snapshot) Name() string {
+	return filepath.Base(s.Path)
+}
+
+// CheckBackupDir rejects a backup directory that is the database's own
+// directory, where pruning would share a folder with the live database
+func CheckBackupDir(dataDir, backupDir string) error {
+	if sameDir(dataDir, backupDir) {
+		return fmt.Errorf("backup directory %s is the database directory; snapshots need a directory of their own", backupDir)
+	}
+
+	return nil
+}
+
+// sameDir reports whether two paths name the same directory, following
+// symlinks where the directories exist
+func sameDir(a, b string) bool {
+	resolve := func(path string) string {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			path = real
+		}
+
+		return filepath.Clean(path)
+	}
+
+	return resolve(a) == resolve(b)
+}
+
+// Backup copies the live database into a new timestamped snapshot in dir
+// using SQLite's online backup API and returns the snapshot's path
+func (s *SQLiteStore) Backup(ctx context.Context, dir string) (string, error) {
+	if err := CheckBackupDir(filepath.Dir(s.path), dir); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(s.path), ".db") + "-" + time.Now().Format(backupTimeLayout)
+	path := filepath.Join(dir, name+".db")
+
+	// Never overwrite a snapshot taken within the same second
+	for i := 2; fileExists(path); i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.db", name, i))
+	}
+
+	tmpPath := path + ".tmp"
+
+	logger.Info("Backing up database to %s", path)
+
+	conn, err := s.db.Conn(ctx)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		src, ok := driverConn.(*sqlite3.SQLiteConn)
+
+		if !ok {
+			return fmt.Errorf("unexpected database driver connection %T", driverConn)
+		}
+
+		return copyDatabase(ctx, src, tmpPath)
+	})
+
+	if err != nil {
+		os.Remove(tmpPath)
+
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+
+		return "", fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	return path, nil
+}
+
+// copyDatabase runs the backup API from src into a new database file at
+// dest, a few pages at a time so the server keeps serving writes
+func copyDatabase(ctx context.Context, src *sqlite3.SQLiteConn, dest string) error {
+	driverConn, err := (&sqlite3.SQLiteDriver{}).Open(dest)
+
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	destConn := driverConn.(*sqlite3.SQLiteConn)
+
+	defer destConn.Close()
+
+	backup, err := destConn.Backup("main", src, "main")
+
+	if err != nil {
+		return fmt.Errorf("failed to start backup: %w", err)
+	}
+
+	for {
+		done, err := backup.Step(backupPagesPerStep)
+
+		if err != nil {
+			backup.Close()
+
+			return fmt.Errorf("failed to copy pages: %w", err)
+		}
+
+		if done {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			backup.Close()
+
+			return ctx.Err()
+		case <-time.After(backupStepPause):
+		}
+	}
+
+	return backup.Finish()
+}
+
+// snapshotPattern matches the names Backup gives snapshots of a database:
+// <name>-YYYYMMDD-HHMMSS.db, with -n before .db for later ones in the same second
+func snapshotPattern(dbName string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(dbName) + `-(\d{8}-\d{6})(?:-(\d+))?\.db$`)
+}
+
+// ListSnapshots returns the snapshots of the database dbName in dir, newest
+// first. Files not named like a snapshot of it are left out, so they are
+// never pruned.
+func ListSnapshots(dir, dbName string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	pattern := snapshotPattern(dbName)
+
+	var snapshots []Snapshot
+
+	for _, entry := range entries {
+		match := pattern.FindStringSubmatch(entry.Name())
+
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		createdAt, err := time.ParseInLocation(backupTimeLayout, match[1], time.Local)
+
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			continue
+		}
+
+		seq, _ := strconv.Atoi(match[2])
+		snapshots = append(snapshots, Snapshot{Path: filepath.Join(dir, entry.Name()), Size: info.Size(), CreatedAt: createdAt, seq: seq})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+		}
+
+		return snapshots[i].seq > snapshots[j].seq
+	})
+
+	return snapshots, nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots of dbName in dir
+// and returns how many were removed; a negative keep removes nothing
+func PruneSnapshots(dir, dbName string, keep int) (int, error) {
+	if keep < 0 {
+		return 0, nil
+	}
+
+	snapshots, err := ListSnapshots(dir, dbName)
+
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for i := keep; i < len(snapshots); i++ {
+		if err := os.Remove(snapshots[i].Path); err != nil {
+			return removed, fmt.Errorf("failed to remove snapshot %s: %w", snapshots[i].Name(), err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// ValidateSnapshot checks that a snapshot is an intact homenet database this
+// build can migrate, and returns its schema version
+func ValidateSnapshot(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+
+	defer db.Close()
+
+	var result string
+
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("snapshot is not a readable SQLite database: %w", err)
+	}
+
+	if result != "ok" {
+		return 0, fmt.Errorf("snapshot failed the integrity check: %s", result)
+	}
+
+	for _, table := range []string{"schema_migrations", "movies", "tv_shows"} {
+		var name string
+
+		err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+
+		if err != nil {
+			return 0, fmt.Errorf("snapshot is missing the %s table", table)
+		}
+	}
+
+	version, err := schemaVersion(db)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if version > LatestSchemaVersion() {
+		return 0, fmt.Errorf("snapshot schema version %d is newer than the latest known version %d", version, LatestSchemaVersion())
+	}
+
+	return version, nil
+}
+
+// RestoreSnapshot validates a snapshot and swaps it in as the database file.
+// The server must not be running; pending migrations run on its next start.
+func RestoreSnapshot(dataDir, dbName, snapshot string) error {
+	version, err := ValidateSnapshot(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(dataDir, dbName+".db")
+	tmpPath := dbPath + ".restore"
+
+	logger.Info("Restoring %s (schema version %d) to %s", snapshot, version, dbPath)
+
+	if err := copyFile(snapshot, tmpPath); err != nil {
+		os.Remove(tmpPath)
+
+		return fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+
+	// Journal files left by the old database must not be replayed into the restored one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(tmpPath)
+
+			return fmt.Errorf("failed to remove %s: %w", dbPath+suffix, err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+
+		return fmt.Errorf("failed to swap in snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+// copyFile copies src to dest and syncs it to disk
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(dest)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	if err := out.Sync(); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	return out.Close()
+}
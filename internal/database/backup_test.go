@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// snapshotNames returns the file names of snapshots in order
+func snapshotNames(snapshots []Snapshot) []string {
+	var names []string
+
+	for _, snapshot := range snapshots {
+		names = append(names, snapshot.Name())
+	}
+
+	return names
+}
+
+// writeFile creates a file with content or fails the test
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestBackupListAndPrune(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	store, err := InitDB(dataDir, "homenet")
+
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	defer store.Close()
+
+	if _, err := store.Backup(ctx, dataDir); err == nil {
+		t.Error("Backup into the database's own directory succeeded")
+	}
+
+	if err := CheckBackupDir(dataDir, filepath.Join(dataDir, "backups")); err != nil {
+		t.Errorf("CheckBackupDir(a subdirectory) = %v, want nil", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "backups")
+
+	var taken []string
+
+	for i := 0; i < 3; i++ {
+		path, err := store.Backup(ctx, dir)
+
+		if err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+
+		taken = append(taken, filepath.Base(path))
+	}
+
+	// An old snapshot, and files that only look like snapshots
+	writeFile(t, filepath.Join(dir, "homenet-20200101-000000.db"), "")
+
+	foreign := []string{"notes.db", "other-20200101-000000.db", "homenet-2020.db", "homenet-20200101-000000.db.tmp", "homenet-20200101-000000-x.db"}
+
+	for _, name := range foreign {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	snapshots, err := ListSnapshots(dir, "homenet")
+
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+
+	// Newest first, whether the three backups fell within one second or not
+	want := []string{taken[2], taken[1], taken[0], "homenet-20200101-000000.db"}
+
+	if got := snapshotNames(snapshots); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSnapshots = %q, want %q", got, want)
+	}
+
+	if removed, err := PruneSnapshots(dir, "homenet", -1); err != nil || removed != 0 {
+		t.Errorf("PruneSnapshots(-1) = %d, %v; want nothing removed", removed, err)
+	}
+
+	if removed, err := PruneSnapshots(dir, "homenet", 2); err != nil || removed != 2 {
+		t.Errorf("PruneSnapshots(2) = %d, %v; want 2 removed", removed, err)
+	}
+
+	if snapshots, err := ListSnapshots(dir, "homenet"); err != nil || !reflect.DeepEqual(snapshotNames(snapshots), want[:2]) {
+		t.Errorf("ListSnapshots after pruning = %q, %v; want %q", snapshotNames(snapshots), err, want[:2])
+	}
+
+	for _, name := range foreign {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("pruning removed %s", name)
+		}
+	}
+
+	if snapshots, err := ListSnapshots(filepath.Join(dir, "missing"), "homenet"); err != nil || snapshots != nil {
+		t.Errorf("ListSnapshots of a missing directory = %v, %v; want none", snapshots, err)
+	}
+}
+
+// backupOf takes a snapshot of a new database holding one movie and returns
+// its path
+func backupOf(t *testing.T, title string) string {
+	t.Helper()
+
+	store, err := InitDB(t.TempDir(), "homenet")
+
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	defer store.Close()
+
+	mustAddMovie(t, store, Movie{Title: title})
+
+	path, err := store.Backup(context.Background(), t.TempDir())
+
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	return path
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	valid := backupOf(t, "Heat")
+
+	corrupt := filepath.Join(dir, "corrupt.db")
+	writeFile(t, corrupt, "not a database")
+
+	foreign := filepath.Join(dir, "foreign.db")
+	db, err := sql.Open("sqlite3", foreign)
+
+	if err != nil {
+		t.Fatalf("open %s: %v", foreign, err)
+	}
+
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatalf("create foreign table: %v", err)
+	}
+
+	db.Close()
+
+	newer := filepath.Join(dir, "newer.db")
+
+	if err := copyFile(valid, newer); err != nil {
+		t.Fatalf("copy snapshot: %v", err)
+	}
+
+	db, err = sql.Open("sqlite3", newer)
+
+	if err != nil {
+		t.Fatalf("open %s: %v", newer, err)
+	}
+
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, 'from the future')", LatestSchemaVersion()+1); err != nil {
+		t.Fatalf("add a newer migration: %v", err)
+	}
+
+	db.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    int
+		wantErr bool
+	}{
+		{"valid", valid, LatestSchemaVersion(), false},
+		{"corrupt", corrupt, 0, true},
+		{"foreign database", foreign, 0, true},
+		{"newer schema", newer, 0, true},
+		{"missing", filepath.Join(dir, "missing.db"), 0, true},
+	}
+
+	for _, tt := range tests {
+		version, err := ValidateSnapshot(tt.path)
+
+		if version != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateSnapshot = %d, %v; want %d, error %v", tt.name, version, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	snapshot := backupOf(t, "From the snapshot")
+	dataDir := t.TempDir()
+	dbPath := filepath.Join(dataDir, "homenet.db")
+
+	store, err := InitDB(dataDir, "homenet")
+
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	mustAddMovie(t, store, Movie{Title: "Live"})
+	store.Close()
+
+	// Journal files the old database left behind, which must not be replayed
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		writeFile(t, dbPath+suffix, "stale")
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.db")
+	writeFile(t, corrupt, "not a database")
+
+	if err := RestoreSnapshot(dataDir, "homenet", corrupt); err == nil {
+		t.Fatal("RestoreSnapshot of a corrupt file succeeded")
+	}
+
+	if !fileExists(dbPath + "-wal") {
+		t.Error("a refused restore removed the live database's files")
+	}
+
+	if err := RestoreSnapshot(dataDir, "homenet", snapshot); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal", ".restore"} {
+		if fileExists(dbPath + suffix) {
+			t.Errorf("%s is still there after the restore", dbPath+suffix)
+		}
+	}
+
+	if !fileExists(snapshot) {
+		t.Error("the restore moved the snapshot instead of copying it")
+	}
+
+	store, err = InitDB(dataDir, "homenet")
+
+	if err != nil {
+		t.Fatalf("InitDB after the restore: %v", err)
+	}
+
+	defer store.Close()
+
+	movies, err := store.GetAllMovies(ctx)
+
+	if err != nil || len(movies) != 1 || movies[0].Title != "From the snapshot" {
+		t.Errorf("movies after the restore = %+v, %v; want only the snapshot's", movies, err)
+	}
+}
@@ -1,48 +1,192 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pwnderpants/homenet/internal/logger"
 )
 
+// ErrItemNotFound is returned when a change names a movie or TV show that does
+// not exist or is in the trash
+var ErrItemNotFound = errors.New("item not found")
+
 type Movie struct {
-	ID           int
-	Title        string
-	Year         int
-	Genre        string
-	Streaming    string
-	Notes        string
-	IMDBLink     string
-	AvailableNow bool
+	ID           int              `json:"id"`
+	Title        string           `json:"title"`
+	Year         int              `json:"year"`
+	Runtime      int              `json:"runtime"` // minutes, 0 when unknown
+	Tags         []string         `json:"tags"`
+	Availability AvailabilityList `json:"availability"`
+	Notes        string           `json:"notes"`
+	IMDBLink     string           `json:"imdb_link"`
+	Watched      bool             `json:"watched"`
+	Details
 }
 
 type TVShow struct {
-	ID           int
-	Title        string
-	Year         int
-	Genre        string
-	Streaming    string
-	Notes        string
-	IMDBLink     string
-	ActiveSeason bool
+	ID           int              `json:"id"`
+	Title        string           `json:"title"`
+	Year         int              `json:"year"`
+	Tags         []string         `json:"tags"`
+	Availability AvailabilityList `json:"availability"`
+	Notes        string           `json:"notes"`
+	IMDBLink     string           `json:"imdb_link"`
+	ActiveSeason bool             `json:"active_season"`
+	Watched      bool             `json:"watched"`
+	Details
+	Progress EpisodeProgress `json:"progress"`
+}
+
+// movieColumns is the column list every movie query selects, in scanMovie
+// order; tags and availability windows each come back as one encoded list
+const movieColumns = "movies.id, movies.title, movies.year, " +
+	"COALESCE((SELECT group_concat(tags.name, ',') FROM movie_tags j JOIN tags ON tags.id = j.tag_id WHERE j.movie_id = movies.id), ''), " +
+	"COALESCE((SELECT group_concat(a.service || char(31) || COALESCE(a.available_from, '') || char(31) || COALESCE(a.available_until, ''), char(30)) FROM availability a WHERE a.item_type = 'movie' AND a.item_id = movies.id), ''), " +
+	"movies.notes, movies.imdb_link, movies.watched, movies.runtime, movies.poster_url, movies.director, movies.cast_members"
+
+// tvShowColumns is the column list every TV show query selects, in scanTVShow
+// order; tags and availability windows each come back as one encoded list and
+// the trailing subqueries describe the next episode for the progress badge
+const tvShowColumns = "tv_shows.id, tv_shows.title, tv_shows.year, " +
+	"COALESCE((SELECT group_concat(tags.name, ',') FROM tv_show_tags j JOIN tags ON tags.id = j.tag_id WHERE j.tv_show_id = tv_shows.id), ''), " +
+	"COALESCE((SELECT group_concat(a.service || char(31) || COALESCE(a.available_from, '') || char(31) || COALESCE(a.available_until, ''), char(30)) FROM availability a WHERE a.item_type = 'tvshow' AND a.item_id = tv_shows.id), ''), " +
+	"tv_shows.notes, tv_shows.imdb_link, tv_shows.active_season, tv_shows.watched, tv_shows.poster_url, tv_shows.director, tv_shows.cast_members, " +
+	"COALESCE((SELECT s.number FROM tv_episodes e JOIN tv_seasons s ON s.id = e.season_id WHERE e.id = tv_shows.next_episode_id), 0), " +
+	"COALESCE((SELECT e.number FROM tv_episodes e WHERE e.id = tv_shows.next_episode_id), 0), " +
+	"(SELECT COUNT(*) FROM tv_episodes e WHERE e.season_id = (SELECT season_id FROM tv_episodes WHERE id = tv_shows.next_episode_id)), " +
+	"(SELECT COUNT(*) FROM tv_episodes e JOIN tv_seasons s ON s.id = e.season_id WHERE s.tv_show_id = tv_shows.id), " +
+	"(SELECT COUNT(*) FROM tv_episodes e JOIN tv_seasons s ON s.id = e.season_id WHERE s.tv_show_id = tv_shows.id AND e.watched = 1)"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-var db *sql.DB
+// scanMovie reads one row selected with movieColumns
+func scanMovie(row rowScanner) (Movie, error) {
+	var movie Movie
+	var tagList, availabilityList, cast string
+	var watchedInt int
+
+	err := row.Scan(&movie.ID, &movie.Title, &movie.Year, &tagList, &availabilityList, &movie.Notes, &movie.IMDBLink, &watchedInt, &movie.Runtime,
+		&movie.PosterURL, &movie.Director, &cast)
+
+	movie.Tags = normalizeTags([]string{tagList})
+	movie.Availability = parseAvailabilityList(availabilityList)
+	movie.Watched = watchedInt == 1
+	movie.Cast = splitCast(cast)
+
+	return movie, err
+}
+
+// scanMovies reads every row selected with movieColumns
+func scanMovies(rows *sql.Rows) ([]Movie, error) {
+	defer rows.Close()
+
+	var movies []Movie
+
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
+// scanTVShow reads one row selected with tvShowColumns
+func scanTVShow(row rowScanner) (TVShow, error) {
+	var tvShow TVShow
+	var tagList, availabilityList, cast string
+	var activeSeasonInt, watchedInt int
+
+	progress := &tvShow.Progress
 
-// InitDB initializes the database connection and creates tables if they don't exist
-func InitDB(dataDir, dbName string) error {
+	err := row.Scan(&tvShow.ID, &tvShow.Title, &tvShow.Year, &tagList, &availabilityList, &tvShow.Notes, &tvShow.IMDBLink, &activeSeasonInt, &watchedInt,
+		&tvShow.PosterURL, &tvShow.Director, &cast, &progress.Season, &progress.Episode, &progress.SeasonEpisodes, &progress.TotalEpisodes, &progress.WatchedEpisodes)
+
+	tvShow.Tags = normalizeTags([]string{tagList})
+	tvShow.Availability = parseAvailabilityList(availabilityList)
+	tvShow.ActiveSeason = activeSeasonInt == 1
+	tvShow.Watched = watchedInt == 1
+	tvShow.Cast = splitCast(cast)
+
+	return tvShow, err
+}
+
+// scanTVShows reads every row selected with tvShowColumns
+func scanTVShows(rows *sql.Rows) ([]TVShow, error) {
+	defer rows.Close()
+
+	var tvShows []TVShow
+
+	for rows.Next() {
+		tvShow, err := scanTVShow(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tv show: %w", err)
+		}
+
+		tvShows = append(tvShows, tvShow)
+	}
+
+	return tvShows, rows.Err()
+}
+
+// SQLiteStore implements Store on top of a SQLite database file
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// InitDB opens the database and applies any pending migrations
+func InitDB(dataDir, dbName string) (*SQLiteStore, error) {
 	logger.Info("Initializing database...")
 
+	store, err := OpenDB(dataDir, dbName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.MigrateUp(); err != nil {
+		logger.ErrorWithErr("Failed to migrate database", err)
+		store.Close()
+
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	version, err := store.SchemaVersion()
+
+	if err != nil {
+		store.Close()
+
+		return nil, err
+	}
+
+	logger.Info("Database initialized successfully at schema version %d", version)
+
+	return store, nil
+}
+
+// OpenDB opens the database connection without applying migrations
+func OpenDB(dataDir, dbName string) (*SQLiteStore, error) {
 	// Create data directory
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		logger.ErrorWithErr("Failed to create data directory", err)
 
-		return fmt.Errorf("failed to create data directory: %w", err)
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
 	// Database file path
@@ -50,134 +194,127 @@ func InitDB(dataDir, dbName string) error {
 
 	logger.Info("Database path: %s", dbPath)
 
-	// Open database
-	var err error
-
-	db, err = sql.Open("sqlite3", dbPath)
+	// Open database. WAL lets the board keep reading while a backup, import or
+	// background job writes, and the busy timeout makes a connection wait for
+	// another's write lock instead of failing with "database is locked".
+	// Foreign keys are off by default in SQLite and must be turned on for the
+	// ON DELETE CASCADE clauses on sessions, API tokens and votes to apply.
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to open database", err)
 
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Create movies table if it doesn't exist
-	createMoviesTableSQL := `
-	CREATE TABLE IF NOT EXISTS movies (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		year INTEGER,
-		genre TEXT,
-		streaming TEXT,
-		notes TEXT,
-		imdb_link TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		available_now INTEGER DEFAULT 0
-	);`
-
-	// Create tv_shows table if it doesn't exist
-	createTVShowsTableSQL := `
-	CREATE TABLE IF NOT EXISTS tv_shows (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		year INTEGER,
-		genre TEXT,
-		streaming TEXT,
-		notes TEXT,
-		imdb_link TEXT,
-		active_season INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Add streaming column if it doesn't exist (for existing databases)
-	addStreamingColumnSQL := `ALTER TABLE movies ADD COLUMN streaming TEXT;`
-	addTVShowsStreamingColumnSQL := `ALTER TABLE tv_shows ADD COLUMN streaming TEXT;`
-	addActiveSeasonColumnSQL := `ALTER TABLE tv_shows ADD COLUMN active_season INTEGER DEFAULT 0;`
-
-	db.Exec(addStreamingColumnSQL)        // Ignore error if column already exists
-	db.Exec(addTVShowsStreamingColumnSQL) // Ignore error if column already exists
-	db.Exec(addActiveSeasonColumnSQL)     // Ignore error if column already exists
-
-	_, err = db.Exec(createMoviesTableSQL)
-
-	if err != nil {
-		logger.ErrorWithErr("Failed to create movies table", err)
+	if err := requireFTS5(db); err != nil {
+		db.Close()
 
-		return fmt.Errorf("failed to create movies table: %w", err)
+		return nil, err
 	}
 
-	_, err = db.Exec(createTVShowsTableSQL)
+	return &SQLiteStore{db: db, path: dbPath}, nil
+}
 
-	if err != nil {
-		logger.ErrorWithErr("Failed to create tv_shows table", err)
+// ErrNoFTS5 is returned when the SQLite driver was built without full-text search
+var ErrNoFTS5 = errors.New("SQLite was built without FTS5; build homenet with -tags sqlite_fts5 (make build does)")
 
-		return fmt.Errorf("failed to create tv_shows table: %w", err)
-	}
+// requireFTS5 checks that the driver can create the search tables before any
+// migration needs them, so a build without the tag fails with a clear error
+// rather than "no such module: fts5" halfway through migrating
+func requireFTS5(db *sql.DB) error {
+	var enabled bool
 
-	logger.Info("Database initialized successfully")
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		logger.ErrorWithErr("Failed to open database", err)
 
-	return nil
-}
+		return fmt.Errorf("failed to open database: %w", err)
+	}
 
-// CloseDB closes the database connection
-func CloseDB() error {
-	if db != nil {
-		return db.Close()
+	if !enabled {
+		return ErrNoFTS5
 	}
 
 	return nil
 }
 
-// GetAllMovies retrieves all movies from the database
-func GetAllMovies() ([]Movie, error) {
-	rows, err := db.Query("SELECT id, title, year, genre, streaming, notes, imdb_link, available_now FROM movies ORDER BY available_now DESC, year DESC, created_at DESC")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to query movies: %w", err)
+// Close closes the database connection
+func (s *SQLiteStore) Close() error {
+	if s.db != nil {
+		return s.db.Close()
 	}
 
-	defer rows.Close()
+	return nil
+}
 
-	var movies []Movie
+// GetAllMovies retrieves all movies on the watchlist in the board's default order
+func (s *SQLiteStore) GetAllMovies(ctx context.Context) ([]Movie, error) {
+	page, err := s.ListMovies(ctx, BoardQuery{Desc: true})
 
-	for rows.Next() {
-		var movie Movie
-		var availableNowInt int
+	return page.Movies, err
+}
 
-		err := rows.Scan(&movie.ID, &movie.Title, &movie.Year, &movie.Genre, &movie.Streaming, &movie.Notes, &movie.IMDBLink, &availableNowInt)
+// GetMovie retrieves a single movie by ID, returning nil if it does not exist
+func (s *SQLiteStore) GetMovie(ctx context.Context, id int) (*Movie, error) {
+	movie, err := scanMovie(s.db.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ? AND deleted_at IS NULL", id))
 
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan movie: %w", err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
 		}
 
-		movie.AvailableNow = availableNowInt == 1
-		movies = append(movies, movie)
+		return nil, fmt.Errorf("failed to get movie: %w", err)
 	}
 
-	return movies, nil
+	return &movie, nil
 }
 
 // AddMovie adds a new movie to the database and returns the ID
-func AddMovie(movie Movie) (int, error) {
+func (s *SQLiteStore) AddMovie(ctx context.Context, movie Movie) (int, error) {
 	logger.Info("Adding movie: %s (%d)", movie.Title, movie.Year)
 
-	query := `
-	INSERT INTO movies (title, year, genre, streaming, notes, imdb_link, available_now)
-	VALUES (?, ?, ?, ?, ?, ?, ?)`
+	var id int64
 
-	result, err := db.Exec(query, movie.Title, movie.Year, movie.Genre, movie.Streaming, movie.Notes, movie.IMDBLink, boolToInt(movie.AvailableNow))
+	movie, err := normalizeMovie(movie)
 
 	if err != nil {
-		logger.ErrorWithErr("Failed to insert movie", err)
-
-		return 0, fmt.Errorf("failed to insert movie: %w", err)
+		return 0, err
 	}
 
-	id, err := result.LastInsertId()
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+		INSERT INTO movies (title, year, runtime, notes, imdb_link, poster_url, director, cast_members)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+		result, err := tx.ExecContext(ctx, query, movie.Title, movie.Year, movie.Runtime, movie.Notes, movie.IMDBLink, movie.PosterURL, movie.Director, joinCast(movie.Cast))
+
+		if err != nil {
+			return fmt.Errorf("failed to insert movie: %w", err)
+		}
+
+		id, err = result.LastInsertId()
+
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+
+		movie.ID = int(id)
+
+		if err := setItemTags(ctx, tx, ItemTypeMovie, movie.ID, movie.Tags); err != nil {
+			return err
+		}
+
+		if err := setAvailability(ctx, tx, ItemTypeMovie, movie.ID, movie.Availability); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, ItemTypeMovie, movie.ID, AuditAdd, nil, movie)
+	})
 
 	if err != nil {
-		logger.ErrorWithErr("Failed to get last insert id", err)
-		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+		logger.ErrorWithErr("Failed to add movie", err)
+
+		return 0, err
 	}
 
 	logger.Info("Movie added successfully with ID: %d", id)
@@ -185,29 +322,45 @@ func AddMovie(movie Movie) (int, error) {
 	return int(id), nil
 }
 
-// DeleteMovie deletes a movie by ID
-func DeleteMovie(id int) error {
-	logger.Info("Deleting movie with ID: %d", id)
+// DeleteMovie moves a movie to the trash; a missing or already trashed movie
+// is ErrItemNotFound
+func (s *SQLiteStore) DeleteMovie(ctx context.Context, id int) error {
+	logger.Info("Moving movie with ID %d to the trash", id)
 
-	query := "DELETE FROM movies WHERE id = ?"
-	_, err := db.Exec(query, id)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanMovie(tx.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ? AND deleted_at IS NULL", id))
+
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: movie %d", ErrItemNotFound, id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE movies SET deleted_at = ? WHERE id = ?", time.Now().UTC(), id); err != nil {
+			return fmt.Errorf("failed to delete movie: %w", err)
+		}
+
+		return recordAudit(ctx, tx, ItemTypeMovie, id, AuditDelete, before, nil)
+	})
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to delete movie", err)
 
-		return fmt.Errorf("failed to delete movie: %w", err)
+		return err
 	}
 
-	logger.Info("Movie deleted successfully")
+	logger.Info("Movie moved to the trash")
 
 	return nil
 }
 
-// GetMovieCount returns the total number of movies
-func GetMovieCount() (int, error) {
+// GetMovieCount returns the number of movies on the watchlist
+func (s *SQLiteStore) GetMovieCount(ctx context.Context) (int, error) {
 	var count int
 
-	err := db.QueryRow("SELECT COUNT(*) FROM movies").Scan(&count)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM movies WHERE watched = 0 AND deleted_at IS NULL").Scan(&count)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to get movie count: %w", err)
@@ -217,20 +370,52 @@ func GetMovieCount() (int, error) {
 }
 
 // UpdateMovie updates an existing movie in the database
-func UpdateMovie(movie Movie) error {
+func (s *SQLiteStore) UpdateMovie(ctx context.Context, movie Movie) error {
 	logger.Info("Updating movie with ID: %d, title: %s", movie.ID, movie.Title)
 
-	query := `
-	UPDATE movies 
-	SET title = ?, year = ?, genre = ?, streaming = ?, notes = ?, imdb_link = ?, available_now = ?
-	WHERE id = ?`
+	movie, err := normalizeMovie(movie)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanMovie(tx.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE id = ? AND deleted_at IS NULL", movie.ID))
+
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: movie %d", ErrItemNotFound, movie.ID)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie: %w", err)
+		}
 
-	_, err := db.Exec(query, movie.Title, movie.Year, movie.Genre, movie.Streaming, movie.Notes, movie.IMDBLink, boolToInt(movie.AvailableNow), movie.ID)
+		query := `
+		UPDATE movies
+		SET title = ?, year = ?, runtime = ?, notes = ?, imdb_link = ?, poster_url = ?, director = ?, cast_members = ?
+		WHERE id = ?`
+
+		_, err = tx.ExecContext(ctx, query, movie.Title, movie.Year, movie.Runtime, movie.Notes, movie.IMDBLink, movie.PosterURL, movie.Director, joinCast(movie.Cast), movie.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to update movie: %w", err)
+		}
+
+		if err := setItemTags(ctx, tx, ItemTypeMovie, movie.ID, movie.Tags); err != nil {
+			return err
+		}
+
+		if err := setAvailability(ctx, tx, ItemTypeMovie, movie.ID, movie.Availability); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, ItemTypeMovie, movie.ID, AuditUpdate, before, movie)
+	})
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to update movie", err)
 
-		return fmt.Errorf("failed to update movie: %w", err)
+		return err
 	}
 
 	logger.Info("Movie updated successfully")
@@ -238,76 +423,75 @@ func UpdateMovie(movie Movie) error {
 	return nil
 }
 
-// GetRandomMovie retrieves a random movie from the database
-func GetRandomMovie() (*Movie, error) {
-	query := "SELECT id, title, year, genre, streaming, notes, imdb_link FROM movies WHERE available_now = 1 ORDER BY RANDOM() LIMIT 1"
+// GetAllTVShows retrieves all TV shows on the watchlist in the board's default order
+func (s *SQLiteStore) GetAllTVShows(ctx context.Context) ([]TVShow, error) {
+	page, err := s.ListTVShows(ctx, BoardQuery{Desc: true})
 
-	var movie Movie
+	return page.TVShows, err
+}
 
-	err := db.QueryRow(query).Scan(&movie.ID, &movie.Title, &movie.Year, &movie.Genre, &movie.Streaming, &movie.Notes, &movie.IMDBLink)
+// GetTVShow retrieves a single TV show by ID, returning nil if it does not exist
+func (s *SQLiteStore) GetTVShow(ctx context.Context, id int) (*TVShow, error) {
+	tvShow, err := scanTVShow(s.db.QueryRowContext(ctx, "SELECT "+tvShowColumns+" FROM tv_shows WHERE id = ? AND deleted_at IS NULL", id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil // No movies found
+			return nil, nil
 		}
 
-		return nil, fmt.Errorf("failed to get random movie: %w", err)
+		return nil, fmt.Errorf("failed to get tv show: %w", err)
 	}
 
-	return &movie, nil
+	return &tvShow, nil
 }
 
-// GetAllTVShows retrieves all TV shows from the database
-func GetAllTVShows() ([]TVShow, error) {
-	rows, err := db.Query("SELECT id, title, year, genre, streaming, notes, imdb_link, active_season FROM tv_shows ORDER BY active_season DESC, year DESC, created_at DESC")
+// AddTVShow adds a new TV show to the database and returns the ID
+func (s *SQLiteStore) AddTVShow(ctx context.Context, tvShow TVShow) (int, error) {
+	logger.Info("Adding TV show: %s (%d)", tvShow.Title, tvShow.Year)
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to query tv shows: %w", err)
-	}
+	var id int64
 
-	defer rows.Close()
+	tvShow, err := normalizeTVShow(tvShow)
 
-	var tvShows []TVShow
+	if err != nil {
+		return 0, err
+	}
 
-	for rows.Next() {
-		var tvShow TVShow
-		var activeSeasonInt int
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+		INSERT INTO tv_shows (title, year, notes, imdb_link, active_season, poster_url, director, cast_members)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-		err := rows.Scan(&tvShow.ID, &tvShow.Title, &tvShow.Year, &tvShow.Genre, &tvShow.Streaming, &tvShow.Notes, &tvShow.IMDBLink, &activeSeasonInt)
+		result, err := tx.ExecContext(ctx, query, tvShow.Title, tvShow.Year, tvShow.Notes, tvShow.IMDBLink, boolToInt(tvShow.ActiveSeason),
+			tvShow.PosterURL, tvShow.Director, joinCast(tvShow.Cast))
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan tv show: %w", err)
+			return fmt.Errorf("failed to insert tv show: %w", err)
 		}
 
-		tvShow.ActiveSeason = activeSeasonInt == 1
-		tvShows = append(tvShows, tvShow)
-	}
+		id, err = result.LastInsertId()
 
-	return tvShows, nil
-}
-
-// AddTVShow adds a new TV show to the database and returns the ID
-func AddTVShow(tvShow TVShow) (int, error) {
-	logger.Info("Adding TV show: %s (%d)", tvShow.Title, tvShow.Year)
-
-	query := `
-	INSERT INTO tv_shows (title, year, genre, streaming, notes, imdb_link, active_season)
-	VALUES (?, ?, ?, ?, ?, ?, ?)`
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
 
-	result, err := db.Exec(query, tvShow.Title, tvShow.Year, tvShow.Genre, tvShow.Streaming, tvShow.Notes, tvShow.IMDBLink, boolToInt(tvShow.ActiveSeason))
+		tvShow.ID = int(id)
 
-	if err != nil {
-		logger.ErrorWithErr("Failed to insert tv show", err)
+		if err := setItemTags(ctx, tx, ItemTypeTVShow, tvShow.ID, tvShow.Tags); err != nil {
+			return err
+		}
 
-		return 0, fmt.Errorf("failed to insert tv show: %w", err)
-	}
+		if err := setAvailability(ctx, tx, ItemTypeTVShow, tvShow.ID, tvShow.Availability); err != nil {
+			return err
+		}
 
-	id, err := result.LastInsertId()
+		return recordAudit(ctx, tx, ItemTypeTVShow, tvShow.ID, AuditAdd, nil, tvShow)
+	})
 
 	if err != nil {
-		logger.ErrorWithErr("Failed to get last insert id", err)
+		logger.ErrorWithErr("Failed to add tv show", err)
 
-		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, err
 	}
 
 	logger.Info("TV show added successfully with ID: %d", id)
@@ -315,29 +499,45 @@ func AddTVShow(tvShow TVShow) (int, error) {
 	return int(id), nil
 }
 
-// DeleteTVShow deletes a TV show by ID
-func DeleteTVShow(id int) error {
-	logger.Info("Deleting TV show with ID: %d", id)
+// DeleteTVShow moves a TV show to the trash; a missing or already trashed TV
+// show is ErrItemNotFound
+func (s *SQLiteStore) DeleteTVShow(ctx context.Context, id int) error {
+	logger.Info("Moving TV show with ID %d to the trash", id)
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanTVShow(tx.QueryRowContext(ctx, "SELECT "+tvShowColumns+" FROM tv_shows WHERE id = ? AND deleted_at IS NULL", id))
 
-	query := "DELETE FROM tv_shows WHERE id = ?"
-	_, err := db.Exec(query, id)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: TV show %d", ErrItemNotFound, id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get tv show: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE tv_shows SET deleted_at = ? WHERE id = ?", time.Now().UTC(), id); err != nil {
+			return fmt.Errorf("failed to delete tv show: %w", err)
+		}
+
+		return recordAudit(ctx, tx, ItemTypeTVShow, id, AuditDelete, before, nil)
+	})
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to delete tv show", err)
 
-		return fmt.Errorf("failed to delete tv show: %w", err)
+		return err
 	}
 
-	logger.Info("TV show deleted successfully")
+	logger.Info("TV show moved to the trash")
 
 	return nil
 }
 
-// GetTVShowCount returns the total number of TV shows
-func GetTVShowCount() (int, error) {
+// GetTVShowCount returns the number of TV shows on the watchlist
+func (s *SQLiteStore) GetTVShowCount(ctx context.Context) (int, error) {
 	var count int
 
-	err := db.QueryRow("SELECT COUNT(*) FROM tv_shows").Scan(&count)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tv_shows WHERE watched = 0 AND deleted_at IS NULL").Scan(&count)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to get tv show count: %w", err)
@@ -347,20 +547,53 @@ func GetTVShowCount() (int, error) {
 }
 
 // UpdateTVShow updates an existing TV show in the database
-func UpdateTVShow(tvShow TVShow) error {
+func (s *SQLiteStore) UpdateTVShow(ctx context.Context, tvShow TVShow) error {
 	logger.Info("Updating TV show with ID: %d, title: %s", tvShow.ID, tvShow.Title)
 
-	query := `
-	UPDATE tv_shows 
-	SET title = ?, year = ?, genre = ?, streaming = ?, notes = ?, imdb_link = ?, active_season = ?
-	WHERE id = ?`
+	tvShow, err := normalizeTVShow(tvShow)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		before, err := scanTVShow(tx.QueryRowContext(ctx, "SELECT "+tvShowColumns+" FROM tv_shows WHERE id = ? AND deleted_at IS NULL", tvShow.ID))
 
-	_, err := db.Exec(query, tvShow.Title, tvShow.Year, tvShow.Genre, tvShow.Streaming, tvShow.Notes, tvShow.IMDBLink, boolToInt(tvShow.ActiveSeason), tvShow.ID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: TV show %d", ErrItemNotFound, tvShow.ID)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get tv show: %w", err)
+		}
+
+		query := `
+		UPDATE tv_shows
+		SET title = ?, year = ?, notes = ?, imdb_link = ?, active_season = ?, poster_url = ?, director = ?, cast_members = ?
+		WHERE id = ?`
+
+		_, err = tx.ExecContext(ctx, query, tvShow.Title, tvShow.Year, tvShow.Notes, tvShow.IMDBLink, boolToInt(tvShow.ActiveSeason),
+			tvShow.PosterURL, tvShow.Director, joinCast(tvShow.Cast), tvShow.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to update tv show: %w", err)
+		}
+
+		if err := setItemTags(ctx, tx, ItemTypeTVShow, tvShow.ID, tvShow.Tags); err != nil {
+			return err
+		}
+
+		if err := setAvailability(ctx, tx, ItemTypeTVShow, tvShow.ID, tvShow.Availability); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, tx, ItemTypeTVShow, tvShow.ID, AuditUpdate, before, tvShow)
+	})
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to update tv show", err)
 
-		return fmt.Errorf("failed to update tv show: %w", err)
+		return err
 	}
 
 	logger.Info("TV show updated successfully")
@@ -368,6 +601,23 @@ func UpdateTVShow(tvShow TVShow) error {
 	return nil
 }
 
+// withTx runs fn in a transaction, committing only if it succeeds
+func (s *SQLiteStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -0,0 +1,498 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// ErrMergeSameItem is returned when asked to merge an item into itself
+var ErrMergeSameItem = errors.New("cannot merge an item into itself")
+
+// leadingArticles are dropped from the start of titles when looking for duplicates
+var leadingArticles = map[string]bool{"the": true, "a": true, "an": true}
+
+// NormalizeTitle reduces a title to the form used to spot duplicates: lower
+// case, "&" read as "and", punctuation ignored and a leading article dropped,
+// so "The Lord of the Rings: The Two Towers" and "lord of the rings the two towers" match
+func NormalizeTitle(title string) string {
+	title = strings.ReplaceAll(strings.ToLower(title), "&", " and ")
+
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) > 1 && leadingArticles[words[0]] {
+		words = words[1:]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// duplicateKey holds what two titles are compared on
+type duplicateKey struct {
+	title  string
+	year   int
+	imdbID string
+}
+
+// movieDuplicateKey returns the comparison key of a movie
+func movieDuplicateKey(movie Movie) duplicateKey {
+	return duplicateKey{title: NormalizeTitle(movie.Title), year: movie.Year, imdbID: IMDbID(movie.IMDBLink)}
+}
+
+// tvShowDuplicateKey returns the comparison key of a TV show
+func tvShowDuplicateKey(tvShow TVShow) duplicateKey {
+	return duplicateKey{title: NormalizeTitle(tvShow.Title), year: tvShow.Year, imdbID: IMDbID(tvShow.IMDBLink)}
+}
+
+// likelyDuplicate reports whether two keys probably name the same title. Two
+// different IMDb IDs never match, so remakes that share a title stay apart;
+// otherwise titles must normalize the same and years agree when both are known.
+func likelyDuplicate(a, b duplicateKey) bool {
+	if a.imdbID != "" && b.imdbID != "" {
+		return a.imdbID == b.imdbID
+	}
+
+	return a.title != "" && a.title == b.title && (a.year == 0 || b.year == 0 || a.year == b.year)
+}
+
+// duplicateGroups returns the indexes of keys that are likely duplicates of
+// each other, in groups of two or more; matches chain, so if a matches b and
+// b matches c all three are one group
+func duplicateGroups(keys []duplicateKey) [][]int {
+	parent := make([]int, len(keys))
+
+	for i := range parent {
+		parent[i] = i
+	}
+
+	root := func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+
+		return i
+	}
+
+	for i := range keys {
+		for j := i + 1; j < len(keys); j++ {
+			if likelyDuplicate(keys[i], keys[j]) {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+
+	for i := range keys {
+		members[root(i)] = append(members[root(i)], i)
+	}
+
+	var groups [][]int
+
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+
+	return groups
+}
+
+// mergeNotes combines two sets of notes, keeping both unless one already contains the other
+func mergeNotes(keep, other string) string {
+	keep, other = strings.TrimSpace(keep), strings.TrimSpace(other)
+
+	switch {
+	case other == "" || strings.Contains(keep, other):
+		return keep
+	case keep == "" || strings.Contains(other, keep):
+		return other
+	default:
+		return keep + "\n\n" + other
+	}
+}
+
+// mergeAvailability combines two window lists, dropping exact repeats
+func mergeAvailability(keep, other AvailabilityList) AvailabilityList {
+	merged := append(AvailabilityList{}, keep...)
+
+	for _, window := range other {
+		found := false
+
+		for _, existing := range merged {
+			if strings.EqualFold(existing.Service, window.Service) && existing.From == window.From && existing.Until == window.Until {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			merged = append(merged, window)
+		}
+	}
+
+	return merged
+}
+
+// mergeMovieFields returns keep filled in from other: a missing year or IMDb
+// link is taken from other, tags and availability are combined, notes are
+// joined and the movie stays watched if either was
+func mergeMovieFields(keep, other Movie) Movie {
+	if keep.Year == 0 {
+		keep.Year = other.Year
+	}
+
+	if keep.Runtime == 0 {
+		keep.Runtime = other.Runtime
+	}
+
+	if keep.IMDBLink == "" {
+		keep.IMDBLink = other.IMDBLink
+	}
+
+	keep.Details = keep.Details.Fill(other.Details)
+	keep.Tags = append(append([]string{}, keep.Tags...), other.Tags...)
+	keep.Availability = mergeAvailability(keep.Availability, other.Availability)
+	keep.Notes = mergeNotes(keep.Notes, other.Notes)
+	keep.Watched = keep.Watched || other.Watched
+
+	return keep
+}
+
+// mergeTVShowFields is mergeMovieFields for TV shows; the active season flag is kept if either had it
+func mergeTVShowFields(keep, other TVShow) TVShow {
+	if keep.Year == 0 {
+		keep.Year = other.Year
+	}
+
+	if keep.IMDBLink == "" {
+		keep.IMDBLink = other.IMDBLink
+	}
+
+	keep.Details = keep.Details.Fill(other.Details)
+	keep.Tags = append(append([]string{}, keep.Tags...), other.Tags...)
+	keep.Availability = mergeAvailability(keep.Availability, other.Availability)
+	keep.Notes = mergeNotes(keep.Notes, other.Notes)
+	keep.ActiveSeason = keep.ActiveSeason || other.ActiveSeason
+	keep.Watched = keep.Watched || other.Watched
+
+	return keep
+}
+
+// mergedInto is the audit record left on the item a merge removes
+type mergedInto struct {
+	MergedInto int `json:"merged_into"`
+}
+
+// liveMovies returns every movie not in the trash, watched or not, by ID
+func (s *SQLiteStore) liveMovies(ctx context.Context) ([]Movie, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE deleted_at IS NULL ORDER BY id")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+
+	return scanMovies(rows)
+}
+
+// liveTVShows returns every TV show not in the trash, watched or not, by ID
+func (s *SQLiteStore) liveTVShows(ctx context.Context) ([]TVShow, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tvShowColumns+" FROM tv_shows WHERE deleted_at IS NULL ORDER BY id")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TV shows: %w", err)
+	}
+
+	return scanTVShows(rows)
+}
+
+// FindMovieDuplicates returns the movies, watched or not, that movie likely
+// duplicates; the movie itself is skipped when it has an ID
+func (s *SQLiteStore) FindMovieDuplicates(ctx context.Context, movie Movie) ([]Movie, error) {
+	movies, err := s.liveMovies(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return movieDuplicatesOf(movies, movie), nil
+}
+
+// FindTVShowDuplicates returns the TV shows, watched or not, that tvShow likely
+// duplicates; the show itself is skipped when it has an ID
+func (s *SQLiteStore) FindTVShowDuplicates(ctx context.Context, tvShow TVShow) ([]TVShow, error) {
+	tvShows, err := s.liveTVShows(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tvShowDuplicatesOf(tvShows, tvShow), nil
+}
+
+// GetDuplicateMovies returns groups of movies that are likely the same title
+func (s *SQLiteStore) GetDuplicateMovies(ctx context.Context) ([][]Movie, error) {
+	movies, err := s.liveMovies(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return groupMovies(movies), nil
+}
+
+// GetDuplicateTVShows returns groups of TV shows that are likely the same title
+func (s *SQLiteStore) GetDuplicateTVShows(ctx context.Context) ([][]TVShow, error) {
+	tvShows, err := s.liveTVShows(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return groupTVShows(tvShows), nil
+}
+
+// movieDuplicatesOf returns the movies in movies that movie likely duplicates
+func movieDuplicatesOf(movies []Movie, movie Movie) []Movie {
+	key := movieDuplicateKey(movie)
+
+	var matches []Movie
+
+	for _, candidate := range movies {
+		if candidate.ID != movie.ID && likelyDuplicate(key, movieDuplicateKey(candidate)) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches
+}
+
+// tvShowDuplicatesOf returns the TV shows in tvShows that tvShow likely duplicates
+func tvShowDuplicatesOf(tvShows []TVShow, tvShow TVShow) []TVShow {
+	key := tvShowDuplicateKey(tvShow)
+
+	var matches []TVShow
+
+	for _, candidate := range tvShows {
+		if candidate.ID != tvShow.ID && likelyDuplicate(key, tvShowDuplicateKey(candidate)) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches
+}
+
+// groupMovies splits movies into groups of likely duplicates
+func groupMovies(movies []Movie) [][]Movie {
+	keys := make([]duplicateKey, len(movies))
+
+	for i, movie := range movies {
+		keys[i] = movieDuplicateKey(movie)
+	}
+
+	var groups [][]Movie
+
+	for _, indexes := range duplicateGroups(keys) {
+		group := make([]Movie, 0, len(indexes))
+
+		for _, i := range indexes {
+			group = append(group, movies[i])
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// groupTVShows splits TV shows into groups of likely duplicates
+func groupTVShows(tvShows []TVShow) [][]TVShow {
+	keys := make([]duplicateKey, len(tvShows))
+
+	for i, tvShow := range tvShows {
+		keys[i] = tvShowDuplicateKey(tvShow)
+	}
+
+	var groups [][]TVShow
+
+	for _, indexes := range duplicateGroups(keys) {
+		group := make([]TVShow, 0, len(indexes))
+
+		for _, i := range indexes {
+			group = append(group, tvShows[i])
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// MergeMovies folds the movie mergeID into keepID: fields are combined, the
+// viewings move across and the merged movie goes to the trash
+func (s *SQLiteStore) MergeMovies(ctx context.Context, keepID, mergeID int) error {
+	if keepID == mergeID {
+		return ErrMergeSameItem
+	}
+
+	logger.Info("Merging movie %d into movie %d", mergeID, keepID)
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		query := "SELECT " + movieColumns + " FROM movies WHERE id = ? AND deleted_at IS NULL"
+		keep, err := scanMovie(tx.QueryRowContext(ctx, query, keepID))
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie %d: %w", keepID, err)
+		}
+
+		other, err := scanMovie(tx.QueryRowContext(ctx, query, mergeID))
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie %d: %w", mergeID, err)
+		}
+
+		merged, err := normalizeMovie(mergeMovieFields(keep, other))
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE movies SET year = ?, runtime = ?, notes = ?, imdb_link = ?, watched = ?, poster_url = ?, director = ?, cast_members = ? WHERE id = ?",
+			merged.Year, merged.Runtime, merged.Notes, merged.IMDBLink, boolToInt(merged.Watched), merged.PosterURL, merged.Director, joinCast(merged.Cast), keepID)
+
+		if err != nil {
+			return fmt.Errorf("failed to update movie: %w", err)
+		}
+
+		if err := setItemTags(ctx, tx, ItemTypeMovie, keepID, merged.Tags); err != nil {
+			return err
+		}
+
+		if err := setAvailability(ctx, tx, ItemTypeMovie, keepID, merged.Availability); err != nil {
+			return err
+		}
+
+		return finishMerge(ctx, tx, ItemTypeMovie, keepID, mergeID, keep, merged)
+	})
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to merge movies", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// MergeTVShows folds the TV show mergeID into keepID like MergeMovies; seasons
+// the kept show doesn't have move across with their episodes
+func (s *SQLiteStore) MergeTVShows(ctx context.Context, keepID, mergeID int) error {
+	if keepID == mergeID {
+		return ErrMergeSameItem
+	}
+
+	logger.Info("Merging TV show %d into TV show %d", mergeID, keepID)
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		query := "SELECT " + tvShowColumns + " FROM tv_shows WHERE id = ? AND deleted_at IS NULL"
+		keep, err := scanTVShow(tx.QueryRowContext(ctx, query, keepID))
+
+		if err != nil {
+			return fmt.Errorf("failed to get TV show %d: %w", keepID, err)
+		}
+
+		other, err := scanTVShow(tx.QueryRowContext(ctx, query, mergeID))
+
+		if err != nil {
+			return fmt.Errorf("failed to get TV show %d: %w", mergeID, err)
+		}
+
+		merged, err := normalizeTVShow(mergeTVShowFields(keep, other))
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE tv_shows SET year = ?, notes = ?, imdb_link = ?, active_season = ?, watched = ?, poster_url = ?, director = ?, cast_members = ? WHERE id = ?",
+			merged.Year, merged.Notes, merged.IMDBLink, boolToInt(merged.ActiveSeason), boolToInt(merged.Watched), merged.PosterURL, merged.Director, joinCast(merged.Cast), keepID)
+
+		if err != nil {
+			return fmt.Errorf("failed to update TV show: %w", err)
+		}
+
+		if err := setItemTags(ctx, tx, ItemTypeTVShow, keepID, merged.Tags); err != nil {
+			return err
+		}
+
+		if err := setAvailability(ctx, tx, ItemTypeTVShow, keepID, merged.Availability); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		UPDATE tv_seasons SET tv_show_id = ?
+		WHERE tv_show_id = ? AND number NOT IN (SELECT number FROM tv_seasons WHERE tv_show_id = ?)`, keepID, mergeID, keepID)
+
+		if err != nil {
+			return fmt.Errorf("failed to move seasons: %w", err)
+		}
+
+		for _, id := range []int{keepID, mergeID} {
+			if err := updateNextEpisode(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		return finishMerge(ctx, tx, ItemTypeTVShow, keepID, mergeID, keep, merged)
+	})
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to merge TV shows", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// finishMerge moves the merged item's viewings, and any votes the kept item
+// lacks, to the kept item, puts the merged item in the trash and records the
+// merge on both
+func finishMerge(ctx context.Context, tx *sql.Tx, itemType string, keepID, mergeID int, before, after interface{}) error {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE watch_events SET item_id = ? WHERE item_type = ? AND item_id = ?", keepID, itemType, mergeID); err != nil {
+		return fmt.Errorf("failed to move watch history: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE OR IGNORE profile_votes SET item_id = ? WHERE item_type = ? AND item_id = ?", keepID, itemType, mergeID); err != nil {
+		return fmt.Errorf("failed to move votes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = ? WHERE id = ?", time.Now().UTC(), mergeID); err != nil {
+		return fmt.Errorf("failed to trash merged %s: %w", itemType, err)
+	}
+
+	if err := recordAudit(ctx, tx, itemType, keepID, AuditMerge, before, after); err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, tx, itemType, mergeID, AuditMerge, nil, mergedInto{MergedInto: keepID})
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"The Lord of the Rings: The Two Towers": "lord of the rings the two towers",
+		"  Fast & Furious ":                     "fast and furious",
+		"A":                                     "a",
+		"An American Werewolf in London":        "american werewolf in london",
+		"Amélie":                                "amélie",
+		"WALL·E":                                "wall e",
+		"!!!":                                   "",
+	}
+
+	for title, want := range tests {
+		if got := NormalizeTitle(title); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestLikelyDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Movie
+		want bool
+	}{
+		{"same title and year", Movie{Title: "Heat", Year: 1995}, Movie{Title: "heat", Year: 1995}, true},
+		{"article and punctuation", Movie{Title: "The Thing"}, Movie{Title: "Thing!", Year: 1982}, true},
+		{"different years", Movie{Title: "The Thing", Year: 1982}, Movie{Title: "The Thing", Year: 2011}, false},
+		{"same IMDb ID, different titles", Movie{Title: "Se7en", IMDBLink: "tt0114369"}, Movie{Title: "Seven", IMDBLink: "https://www.imdb.com/title/tt0114369/"}, true},
+		{"different IMDb IDs", Movie{Title: "Heat", IMDBLink: "tt0113277"}, Movie{Title: "Heat", IMDBLink: "tt0068699"}, false},
+		{"one IMDb ID", Movie{Title: "Heat", Year: 1995, IMDBLink: "tt0113277"}, Movie{Title: "Heat", Year: 1995}, true},
+		{"empty titles", Movie{Title: "?"}, Movie{Title: "!"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := likelyDuplicate(movieDuplicateKey(tt.a), movieDuplicateKey(tt.b)); got != tt.want {
+			t.Errorf("%s: likelyDuplicate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDuplicateGroupsChain(t *testing.T) {
+	keys := []duplicateKey{
+		{title: "heat"},
+		{title: "ronin"},
+		{title: "heat", year: 1995},
+		{title: "heat", imdbID: "tt0113277"},
+		{title: "ronin", year: 1998},
+		{title: "zodiac"},
+	}
+
+	if got, want := duplicateGroups(keys), [][]int{{0, 2, 3}, {1, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("duplicateGroups = %v, want %v", got, want)
+	}
+}
+
+func TestFindAndMergeDuplicates(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		keep := mustAddMovie(t, store, Movie{Title: "The Thing", Year: 1982, Tags: []string{"Horror"}, Notes: "Carpenter"})
+		merge := mustAddMovie(t, store, Movie{
+			Title: "Thing", Runtime: 109, IMDBLink: "tt0084787", Tags: []string{"Sci-Fi"}, Notes: "rewatch",
+			Availability: AvailabilityList{{Service: "Netflix"}},
+		})
+		remake := mustAddMovie(t, store, Movie{Title: "The Thing", Year: 2011})
+
+		found, err := store.FindMovieDuplicates(ctx, Movie{Title: "the thing", Year: 1982})
+
+		if err != nil {
+			t.Fatalf("FindMovieDuplicates: %v", err)
+		}
+
+		if got := movieIDs(found); !reflect.DeepEqual(got, []int{keep, merge}) {
+			t.Errorf("FindMovieDuplicates = %v, want %v", got, []int{keep, merge})
+		}
+
+		groups, err := store.GetDuplicateMovies(ctx)
+
+		if err != nil || len(groups) != 1 || len(groups[0]) != 3 {
+			t.Fatalf("GetDuplicateMovies = %v, %v; want all three chained through the yearless title", groups, err)
+		}
+
+		if err := store.MergeMovies(ctx, keep, keep); !errors.Is(err, ErrMergeSameItem) {
+			t.Errorf("MergeMovies into itself: error = %v, want ErrMergeSameItem", err)
+		}
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: merge, Watcher: "sam"}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		if err := store.MergeMovies(ctx, keep, merge); err != nil {
+			t.Fatalf("MergeMovies: %v", err)
+		}
+
+		watched, err := store.GetWatchedMovies(ctx)
+
+		if err != nil || len(watched) != 1 || watched[0].ID != keep {
+			t.Fatalf("GetWatchedMovies = %+v, %v; want the kept movie, watched through the merge", watched, err)
+		}
+
+		merged := watched[0].Movie
+
+		if merged.Year != 1982 || merged.Runtime != 109 || IMDbID(merged.IMDBLink) != "tt0084787" || merged.Notes != "Carpenter\n\nrewatch" ||
+			len(merged.Tags) != 2 || len(merged.Availability) != 1 {
+			t.Errorf("merged movie = %+v", merged)
+		}
+
+		if movie, err := store.GetMovie(ctx, merge); err != nil || movie != nil {
+			t.Errorf("GetMovie(merged) = %v, %v; want it in the trash", movie, err)
+		}
+
+		if found, err := store.FindMovieDuplicates(ctx, Movie{Title: "The Thing", Year: 2011}); err != nil || !reflect.DeepEqual(movieIDs(found), []int{remake}) {
+			t.Errorf("FindMovieDuplicates(remake) = %v, %v; want only the remake", movieIDs(found), err)
+		}
+	})
+}
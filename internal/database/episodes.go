@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Season is one season of a TV show with its episodes in order
+type Season struct {
+	ID       int
+	TVShowID int
+	Number   int
+	Episodes []Episode
+}
+
+// Episode is one episode of a season
+type Episode struct {
+	ID        int
+	SeasonID  int
+	Number    int
+	Title     string
+	Watched   bool
+	WatchedAt time.Time
+}
+
+// EpisodeProgress describes where a household is in a TV show
+type EpisodeProgress struct {
+	Season          int `json:"season"`          // season of the next episode, 0 when caught up
+	Episode         int `json:"episode"`         // number of the next episode within its season
+	SeasonEpisodes  int `json:"season_episodes"` // episodes in the next episode's season
+	TotalEpisodes   int `json:"total_episodes"`
+	WatchedEpisodes int `json:"watched_episodes"`
+}
+
+// Tracked reports whether any episodes have been added for the show
+func (p EpisodeProgress) Tracked() bool {
+	return p.TotalEpisodes > 0
+}
+
+// Label renders the progress badge, e.g. "S03E05 of 10" or "Caught up"
+func (p EpisodeProgress) Label() string {
+	if p.Season == 0 {
+		return "Caught up"
+	}
+
+	return fmt.Sprintf("S%02dE%02d of %d", p.Season, p.Episode, p.SeasonEpisodes)
+}
+
+// ErrSeasonExists is returned when adding a season number the show already has
+var ErrSeasonExists = errors.New("season already exists")
+
+// nextEpisode returns the first unwatched episode after the furthest watched one,
+// or nil when the show is caught up; seasons and episodes must be in order
+func nextEpisode(seasons []Season) *Episode {
+	var ordered []*Episode
+
+	for i := range seasons {
+		for j := range seasons[i].Episodes {
+			ordered = append(ordered, &seasons[i].Episodes[j])
+		}
+	}
+
+	start := 0
+
+	for i, episode := range ordered {
+		if episode.Watched {
+			start = i + 1
+		}
+	}
+
+	for _, episode := range ordered[start:] {
+		if !episode.Watched {
+			return episode
+		}
+	}
+
+	return nil
+}
+
+// episodeProgress summarizes the seasons of a show for its progress badge
+func episodeProgress(seasons []Season) EpisodeProgress {
+	var progress EpisodeProgress
+
+	for _, season := range seasons {
+		for _, episode := range season.Episodes {
+			progress.TotalEpisodes++
+
+			if episode.Watched {
+				progress.WatchedEpisodes++
+			}
+		}
+	}
+
+	next := nextEpisode(seasons)
+
+	if next == nil {
+		return progress
+	}
+
+	for _, season := range seasons {
+		if season.ID == next.SeasonID {
+			progress.Season = season.Number
+			progress.Episode = next.Number
+			progress.SeasonEpisodes = len(season.Episodes)
+		}
+	}
+
+	return progress
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// loadSeasons reads the seasons and episodes of one show in viewing order
+func loadSeasons(ctx context.Context, q queryer, tvShowID int) ([]Season, error) {
+	rows, err := q.QueryContext(ctx, `
+	SELECT s.id, s.number, e.id, e.number, COALESCE(e.title, ''), e.watched, e.watched_at
+	FROM tv_seasons s
+	LEFT JOIN tv_episodes e ON e.season_id = s.id
+	WHERE s.tv_show_id = ?
+	ORDER BY s.number, e.number`, tvShowID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seasons: %w", err)
+	}
+
+	defer rows.Close()
+
+	var seasons []Season
+
+	for rows.Next() {
+		var seasonID, seasonNumber int
+		var episodeID, episodeNumber, watched sql.NullInt64
+		var title string
+		var watchedAt sql.NullTime
+
+		if err := rows.Scan(&seasonID, &seasonNumber, &episodeID, &episodeNumber, &title, &watched, &watchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan season: %w", err)
+		}
+
+		if len(seasons) == 0 || seasons[len(seasons)-1].ID != seasonID {
+			seasons = append(seasons, Season{ID: seasonID, TVShowID: tvShowID, Number: seasonNumber})
+		}
+
+		if !episodeID.Valid {
+			continue
+		}
+
+		season := &seasons[len(seasons)-1]
+		season.Episodes = append(season.Episodes, Episode{
+			ID:        int(episodeID.Int64),
+			SeasonID:  seasonID,
+			Number:    int(episodeNumber.Int64),
+			Title:     title,
+			Watched:   watched.Int64 == 1,
+			WatchedAt: watchedAt.Time,
+		})
+	}
+
+	return seasons, rows.Err()
+}
+
+// updateNextEpisode recomputes the next episode pointer of a show
+func updateNextEpisode(ctx context.Context, q queryer, tvShowID int) error {
+	seasons, err := loadSeasons(ctx, q, tvShowID)
+
+	if err != nil {
+		return err
+	}
+
+	var nextID interface{}
+
+	if next := nextEpisode(seasons); next != nil {
+		nextID = next.ID
+	}
+
+	if _, err := q.ExecContext(ctx, "UPDATE tv_shows SET next_episode_id = ? WHERE id = ?", nextID, tvShowID); err != nil {
+		return fmt.Errorf("failed to update next episode: %w", err)
+	}
+
+	return nil
+}
+
+// withTVShowTx runs fn in a transaction and refreshes the show's next episode pointer
+func (s *SQLiteStore) withTVShowTx(ctx context.Context, tvShowID int, fn func(tx *sql.Tx) error) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return updateNextEpisode(ctx, tx, tvShowID)
+	})
+}
+
+// GetSeasons returns the seasons and episodes of a TV show in viewing order
+func (s *SQLiteStore) GetSeasons(ctx context.Context, tvShowID int) ([]Season, error) {
+	return loadSeasons(ctx, s.db, tvShowID)
+}
+
+// AddSeason adds a season with the given number of episodes to a TV show
+func (s *SQLiteStore) AddSeason(ctx context.Context, tvShowID, number, episodeCount int) error {
+	logger.Info("Adding season %d with %d episodes to TV show %d", number, episodeCount, tvShowID)
+
+	return s.withTVShowTx(ctx, tvShowID, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "INSERT INTO tv_seasons (tv_show_id, number) VALUES (?, ?)", tvShowID, number)
+
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return ErrSeasonExists
+			}
+
+			return fmt.Errorf("failed to insert season: %w", err)
+		}
+
+		seasonID, err := result.LastInsertId()
+
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+
+		for episode := 1; episode <= episodeCount; episode++ {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO tv_episodes (season_id, number) VALUES (?, ?)", seasonID, episode); err != nil {
+				return fmt.Errorf("failed to insert episode: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteSeason removes a season and its episodes
+func (s *SQLiteStore) DeleteSeason(ctx context.Context, tvShowID, seasonID int) error {
+	logger.Info("Deleting season %d from TV show %d", seasonID, tvShowID)
+
+	return s.withTVShowTx(ctx, tvShowID, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM tv_seasons WHERE id = ? AND tv_show_id = ?", seasonID, tvShowID); err != nil {
+			return fmt.Errorf("failed to delete season: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SetEpisodeWatched marks a single episode watched or unwatched
+func (s *SQLiteStore) SetEpisodeWatched(ctx context.Context, tvShowID, episodeID int, watched bool) error {
+	logger.Debug("Setting episode %d of TV show %d watched=%t", episodeID, tvShowID, watched)
+
+	return s.withTVShowTx(ctx, tvShowID, func(tx *sql.Tx) error {
+		var watchedAt interface{}
+
+		if watched {
+			watchedAt = time.Now().UTC()
+		}
+
+		_, err := tx.ExecContext(ctx, `
+		UPDATE tv_episodes SET watched = ?, watched_at = ?
+		WHERE id = ? AND season_id IN (SELECT id FROM tv_seasons WHERE tv_show_id = ?)`, boolToInt(watched), watchedAt, episodeID, tvShowID)
+
+		if err != nil {
+			return fmt.Errorf("failed to update episode: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// WatchNextEpisode marks the show's next episode watched and advances the pointer
+func (s *SQLiteStore) WatchNextEpisode(ctx context.Context, tvShowID int) error {
+	logger.Info("Marking next episode of TV show %d watched", tvShowID)
+
+	return s.withTVShowTx(ctx, tvShowID, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+		UPDATE tv_episodes SET watched = 1, watched_at = ?
+		WHERE id = (SELECT next_episode_id FROM tv_shows WHERE id = ?)`, time.Now().UTC(), tvShowID)
+
+		if err != nil {
+			return fmt.Errorf("failed to mark next episode watched: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// episodeIDs returns the IDs of a show's episodes by season and episode number
+func episodeIDs(t *testing.T, store Store, tvShowID int) map[int]map[int]int {
+	t.Helper()
+
+	seasons, err := store.GetSeasons(context.Background(), tvShowID)
+
+	if err != nil {
+		t.Fatalf("GetSeasons(%d): %v", tvShowID, err)
+	}
+
+	ids := make(map[int]map[int]int)
+
+	for _, season := range seasons {
+		ids[season.Number] = make(map[int]int)
+
+		for _, episode := range season.Episodes {
+			ids[season.Number][episode.Number] = episode.ID
+		}
+	}
+
+	return ids
+}
+
+func TestEpisodeProgress(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		id := mustAddTVShow(t, store, TVShow{Title: "Lost"})
+
+		// progress returns the show's badge as the board shows it
+		progress := func() EpisodeProgress {
+			t.Helper()
+
+			tvShow, err := store.GetTVShow(ctx, id)
+
+			if err != nil || tvShow == nil {
+				t.Fatalf("GetTVShow(%d) = %v, %v", id, tvShow, err)
+			}
+
+			return tvShow.Progress
+		}
+
+		if got := progress(); got.Tracked() || got.Label() != "Caught up" {
+			t.Errorf("progress without seasons = %+v, want untracked", got)
+		}
+
+		// Seasons come back in order whatever order they were added in
+		for _, season := range []struct{ number, episodes int }{{2, 3}, {1, 2}} {
+			if err := store.AddSeason(ctx, id, season.number, season.episodes); err != nil {
+				t.Fatalf("AddSeason(%d): %v", season.number, err)
+			}
+		}
+
+		if err := store.AddSeason(ctx, id, 2, 5); !errors.Is(err, ErrSeasonExists) {
+			t.Errorf("AddSeason of a season the show has: error = %v, want ErrSeasonExists", err)
+		}
+
+		seasons, err := store.GetSeasons(ctx, id)
+
+		if err != nil || len(seasons) != 2 || seasons[0].Number != 1 || len(seasons[0].Episodes) != 2 || seasons[1].Number != 2 || len(seasons[1].Episodes) != 3 {
+			t.Fatalf("GetSeasons = %+v, %v; want seasons 1 and 2 of 2 and 3 episodes", seasons, err)
+		}
+
+		ids := episodeIDs(t, store, id)
+
+		steps := []struct {
+			name    string
+			step    func() error
+			label   string
+			watched int
+		}{
+			{"new seasons", func() error { return nil }, "S01E01 of 2", 0},
+			{"watch next", func() error { return store.WatchNextEpisode(ctx, id) }, "S01E02 of 2", 1},
+			{"watch next into season 2", func() error { return store.WatchNextEpisode(ctx, id) }, "S02E01 of 3", 2},
+			// Skipping ahead moves past the episodes left behind
+			{"watch the finale", func() error { return store.SetEpisodeWatched(ctx, id, ids[2][3], true) }, "Caught up", 3},
+			{"watch next when caught up", func() error { return store.WatchNextEpisode(ctx, id) }, "Caught up", 3},
+			{"unwatch the finale", func() error { return store.SetEpisodeWatched(ctx, id, ids[2][3], false) }, "S02E01 of 3", 2},
+			{"an episode of another show", func() error { return store.SetEpisodeWatched(ctx, id+1, ids[2][1], true) }, "S02E01 of 3", 2},
+			{"delete season 2", func() error { return store.DeleteSeason(ctx, id, seasons[1].ID) }, "Caught up", 2},
+		}
+
+		for _, tt := range steps {
+			if err := tt.step(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+
+			if got := progress(); got.Label() != tt.label || got.WatchedEpisodes != tt.watched {
+				t.Errorf("%s: progress = %q with %d watched, want %q with %d", tt.name, got.Label(), got.WatchedEpisodes, tt.label, tt.watched)
+			}
+		}
+
+		if got := progress(); got.TotalEpisodes != 2 || !got.Tracked() {
+			t.Errorf("progress after deleting season 2 = %+v, want 2 episodes tracked", got)
+		}
+
+		seasons, err = store.GetSeasons(ctx, id)
+
+		if err != nil || len(seasons) != 1 || !seasons[0].Episodes[0].Watched || seasons[0].Episodes[0].WatchedAt.IsZero() {
+			t.Errorf("GetSeasons after deleting season 2 = %+v, %v; want season 1 watched", seasons, err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// imdbIDPattern matches IMDb title identifiers such as tt0078748
+var imdbIDPattern = regexp.MustCompile(`tt\d{7,}`)
+
+// IMDbID extracts the title identifier from an IMDb link or bare ID, returning "" when there is none
+func IMDbID(link string) string {
+	return imdbIDPattern.FindString(link)
+}
+
+// IMDbLink returns the canonical IMDb link for an ID or link, or "" when there is no ID in it
+func IMDbLink(id string) string {
+	id = IMDbID(id)
+
+	if id == "" {
+		return ""
+	}
+
+	return "https://www.imdb.com/title/" + id + "/"
+}
+
+// GetMovieByIMDbID returns the movie whose IMDb link has the given ID, watched or not, or nil
+func (s *SQLiteStore) GetMovieByIMDbID(ctx context.Context, imdbID string) (*Movie, error) {
+	if imdbID == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE deleted_at IS NULL AND imdb_link LIKE ? ORDER BY id", "%"+imdbID+"%")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies by IMDb ID: %w", err)
+	}
+
+	movies, err := scanMovies(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// LIKE also matches longer IDs that start with this one
+	for _, movie := range movies {
+		if IMDbID(movie.IMDBLink) == imdbID {
+			return &movie, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetTVShowByIMDbID returns the TV show whose IMDb link has the given ID, watched or not, or nil
+func (s *SQLiteStore) GetTVShowByIMDbID(ctx context.Context, imdbID string) (*TVShow, error) {
+	if imdbID == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tvShowColumns+" FROM tv_shows WHERE deleted_at IS NULL AND imdb_link LIKE ? ORDER BY id", "%"+imdbID+"%")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tv shows by IMDb ID: %w", err)
+	}
+
+	tvShows, err := scanTVShows(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tvShow := range tvShows {
+		if IMDbID(tvShow.IMDBLink) == imdbID {
+			return &tvShow, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// importProgressInterval is how many dataset rows pass between progress log lines
+const importProgressInterval = 100000
+
+// importBatchSize is how many dataset rows each staging transaction inserts
+const importBatchSize = 10000
+
+// imdbTitlesStaging is the table an import loads into before it replaces
+// imdb_titles; it has the same columns as migration 12's table
+const imdbTitlesStaging = `CREATE TABLE imdb_titles_import (
+	id TEXT PRIMARY KEY,
+	item_type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	search_title TEXT NOT NULL,
+	year INTEGER NOT NULL DEFAULT 0,
+	end_year INTEGER NOT NULL DEFAULT 0,
+	runtime INTEGER NOT NULL DEFAULT 0,
+	genres TEXT NOT NULL DEFAULT ''
+) WITHOUT ROWID`
+
+// IMDbTitle is one movie or series from IMDb's title.basics dataset
+type IMDbTitle struct {
+	ID       string // the tt identifier
+	ItemType string // ItemTypeMovie or ItemTypeTVShow
+	Title    string
+	Year     int // start year for series
+	EndYear  int
+	Runtime  int // minutes
+	Genres   []string
+}
+
+// ReplaceIMDbTitles swaps the stored dataset for the titles read from an
+// import, returning how many were stored. Titles are loaded into a staging
+// table in batches, so the server keeps running and saving during the import,
+// and only the final swap holds a write lock. A failed import changes nothing.
+func (s *SQLiteStore) ReplaceIMDbTitles(ctx context.Context, titles iter.Seq2[IMDbTitle, error]) (int, error) {
+	// A staging table left by an interrupted import is stale
+	if _, err := s.db.ExecContext(ctx, "DROP TABLE IF EXISTS imdb_titles_import"); err != nil {
+		return 0, fmt.Errorf("failed to clear IMDb staging table: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, imdbTitlesStaging); err != nil {
+		return 0, fmt.Errorf("failed to create IMDb staging table: %w", err)
+	}
+
+	count := 0
+	err := s.stageIMDbTitles(ctx, titles)
+
+	if err == nil {
+		err = s.withTx(ctx, func(tx *sql.Tx) error {
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM imdb_titles_import").Scan(&count); err != nil {
+				return fmt.Errorf("failed to count IMDb titles: %w", err)
+			}
+
+			// Dropping the old table drops its index, freeing the name for
+			// the staging table's
+			for _, statement := range []string{
+				"DROP TABLE imdb_titles",
+				"ALTER TABLE imdb_titles_import RENAME TO imdb_titles",
+				"CREATE INDEX idx_imdb_titles_search ON imdb_titles(item_type, search_title)",
+			} {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("failed to replace IMDb titles: %w", err)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err != nil {
+		// Use a fresh context: the import's may be what was cancelled
+		if _, dropErr := s.db.ExecContext(context.Background(), "DROP TABLE IF EXISTS imdb_titles_import"); dropErr != nil {
+			logger.ErrorWithErr("Failed to drop IMDb staging table", dropErr)
+		}
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// stageIMDbTitles inserts titles into the staging table, one transaction per
+// batch; a repeated ID replaces the earlier row
+func (s *SQLiteStore) stageIMDbTitles(ctx context.Context, titles iter.Seq2[IMDbTitle, error]) error {
+	read := 0
+	next, stop := iter.Pull2(titles)
+	defer stop()
+
+	for done := false; !done; {
+		err := s.withTx(ctx, func(tx *sql.Tx) error {
+			stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO imdb_titles_import
+				(id, item_type, title, search_title, year, end_year, runtime, genres)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+
+			if err != nil {
+				return fmt.Errorf("failed to prepare IMDb title insert: %w", err)
+			}
+
+			defer stmt.Close()
+
+			for batched := 0; batched < importBatchSize; batched++ {
+				title, err, ok := next()
+
+				if !ok {
+					done = true
+
+					return nil
+				}
+
+				if err != nil {
+					return err
+				}
+
+				if _, err := stmt.ExecContext(ctx, title.ID, title.ItemType, title.Title, NormalizeTitle(title.Title),
+					title.Year, title.EndYear, title.Runtime, strings.Join(title.Genres, ",")); err != nil {
+					return fmt.Errorf("failed to insert IMDb title %s: %w", title.ID, err)
+				}
+
+				read++
+
+				if read%importProgressInterval == 0 {
+					logger.Info("Imported %d IMDb titles", read)
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SearchIMDbTitles returns up to limit titles of a type whose normalized title
+// starts with the query's, exact matches first, then shorter titles, then newer ones
+func (s *SQLiteStore) SearchIMDbTitles(ctx context.Context, itemType, query string, limit int) ([]IMDbTitle, error) {
+	prefix := NormalizeTitle(query)
+
+	if prefix == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, item_type, title, year, end_year, runtime, genres
+		FROM imdb_titles
+		WHERE item_type = ? AND search_title >= ? AND search_title < ?
+		ORDER BY search_title = ? DESC, length(search_title), year DESC, id
+		LIMIT ?`, itemType, prefix, prefix+string(utf8.MaxRune), prefix, limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search IMDb titles: %w", err)
+	}
+
+	defer rows.Close()
+
+	var titles []IMDbTitle
+
+	for rows.Next() {
+		title, err := scanIMDbTitle(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		titles = append(titles, *title)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IMDb titles: %w", err)
+	}
+
+	return titles, nil
+}
+
+// GetIMDbTitle returns the dataset's title with an IMDb ID, or nil when it has none
+func (s *SQLiteStore) GetIMDbTitle(ctx context.Context, id string) (*IMDbTitle, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, item_type, title, year, end_year, runtime, genres
+		FROM imdb_titles WHERE id = ?`, id)
+
+	title, err := scanIMDbTitle(row)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return title, err
+}
+
+// CountIMDbTitles returns how many titles the imported dataset holds; zero
+// when none has been imported
+func (s *SQLiteStore) CountIMDbTitles(ctx context.Context) (int, error) {
+	var count int
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM imdb_titles").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count IMDb titles: %w", err)
+	}
+
+	return count, nil
+}
+
+// scanIMDbTitle reads one imdb_titles row
+func scanIMDbTitle(row interface{ Scan(...any) error }) (*IMDbTitle, error) {
+	var title IMDbTitle
+	var genres string
+
+	err := row.Scan(&title.ID, &title.ItemType, &title.Title, &title.Year, &title.EndYear, &title.Runtime, &genres)
+
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan IMDb title: %w", err)
+	}
+
+	if genres != "" {
+		title.Genres = strings.Split(genres, ",")
+	}
+
+	return &title, nil
+}
+
+// ReplaceIMDbTitles swaps the stored dataset for the titles read from an import
+func (m *MemoryStore) ReplaceIMDbTitles(ctx context.Context, titles iter.Seq2[IMDbTitle, error]) (int, error) {
+	imported := make(map[string]IMDbTitle)
+
+	for title, err := range titles {
+		if err != nil {
+			return 0, err
+		}
+
+		imported[title.ID] = title
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.imdbTitles = imported
+
+	return len(imported), nil
+}
+
+// SearchIMDbTitles returns up to limit titles of a type whose normalized title
+// starts with the query's, in the same order as the SQLite store
+func (m *MemoryStore) SearchIMDbTitles(ctx context.Context, itemType, query string, limit int) ([]IMDbTitle, error) {
+	prefix := NormalizeTitle(query)
+
+	if prefix == "" {
+		return nil, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type match struct {
+		IMDbTitle
+		search string
+	}
+
+	var matches []match
+
+	for _, title := range m.imdbTitles {
+		if search := NormalizeTitle(title.Title); title.ItemType == itemType && strings.HasPrefix(search, prefix) {
+			matches = append(matches, match{title, search})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+
+		switch {
+		case (a.search == prefix) != (b.search == prefix):
+			return a.search == prefix
+		case utf8.RuneCountInString(a.search) != utf8.RuneCountInString(b.search):
+			return utf8.RuneCountInString(a.search) < utf8.RuneCountInString(b.search)
+		case a.Year != b.Year:
+			return a.Year > b.Year
+		default:
+			return a.ID < b.ID
+		}
+	})
+
+	titles := make([]IMDbTitle, 0, min(limit, len(matches)))
+
+	for _, match := range matches[:min(limit, len(matches))] {
+		titles = append(titles, match.IMDbTitle)
+	}
+
+	return titles, nil
+}
+
+// GetIMDbTitle returns the dataset's title with an IMDb ID, or nil when it has none
+func (m *MemoryStore) GetIMDbTitle(ctx context.Context, id string) (*IMDbTitle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	title, ok := m.imdbTitles[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &title, nil
+}
+
+// CountIMDbTitles returns how many titles the imported dataset holds
+func (m *MemoryStore) CountIMDbTitles(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.imdbTitles), nil
+}
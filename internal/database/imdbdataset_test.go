@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"testing"
+)
+
+// imdbTitles yields titles as a dataset read would, ending with err when it
+// is not nil
+func imdbTitles(titles []IMDbTitle, err error) iter.Seq2[IMDbTitle, error] {
+	return func(yield func(IMDbTitle, error) bool) {
+		for _, title := range titles {
+			if !yield(title, nil) {
+				return
+			}
+		}
+
+		if err != nil {
+			yield(IMDbTitle{}, err)
+		}
+	}
+}
+
+// imdbTitleIDs returns the IDs of titles in order
+func imdbTitleIDs(titles []IMDbTitle) []string {
+	var ids []string
+
+	for _, title := range titles {
+		ids = append(ids, title.ID)
+	}
+
+	return ids
+}
+
+func TestReplaceIMDbTitles(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		// Enough titles to fill more than one staging batch
+		var titles []IMDbTitle
+
+		for i := 1; i <= 2*importBatchSize+1; i++ {
+			titles = append(titles, IMDbTitle{ID: fmt.Sprintf("tt%07d", i), ItemType: ItemTypeMovie, Title: fmt.Sprintf("Title %d", i)})
+		}
+
+		heat := IMDbTitle{ID: "tt0113277", ItemType: ItemTypeMovie, Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime", "Drama"}}
+		titles = append(titles, heat)
+
+		if count, err := store.ReplaceIMDbTitles(ctx, imdbTitles(titles, nil)); err != nil || count != len(titles) {
+			t.Fatalf("ReplaceIMDbTitles = %d, %v; want %d", count, err, len(titles))
+		}
+
+		if got, err := store.GetIMDbTitle(ctx, heat.ID); err != nil || got == nil || !reflect.DeepEqual(*got, heat) {
+			t.Errorf("GetIMDbTitle(%s) = %+v, %v; want %+v", heat.ID, got, err, heat)
+		}
+
+		// A failed import leaves the previous copy in place
+		readErr := errors.New("line 3: expected 9 columns, found 2")
+
+		if _, err := store.ReplaceIMDbTitles(ctx, imdbTitles(titles[:importBatchSize+1], readErr)); !errors.Is(err, readErr) {
+			t.Errorf("ReplaceIMDbTitles with a bad line: error = %v, want %v", err, readErr)
+		}
+
+		if count, err := store.CountIMDbTitles(ctx); err != nil || count != len(titles) {
+			t.Errorf("CountIMDbTitles after a failed import = %d, %v; want %d", count, err, len(titles))
+		}
+
+		// A newer dataset replaces every title, and can be searched
+		lost := IMDbTitle{ID: "tt0411008", ItemType: ItemTypeTVShow, Title: "Lost", Year: 2004, EndYear: 2010}
+
+		if count, err := store.ReplaceIMDbTitles(ctx, imdbTitles([]IMDbTitle{lost, lost}, nil)); err != nil || count != 1 {
+			t.Fatalf("ReplaceIMDbTitles of a newer dataset = %d, %v; want its one title", count, err)
+		}
+
+		if count, err := store.CountIMDbTitles(ctx); err != nil || count != 1 {
+			t.Errorf("CountIMDbTitles after replacing = %d, %v; want 1", count, err)
+		}
+
+		if got, err := store.GetIMDbTitle(ctx, heat.ID); err != nil || got != nil {
+			t.Errorf("GetIMDbTitle(%s) after replacing = %+v, %v; want none", heat.ID, got, err)
+		}
+
+		if got, err := store.SearchIMDbTitles(ctx, ItemTypeTVShow, "lo", 10); err != nil || !reflect.DeepEqual(got, []IMDbTitle{lost}) {
+			t.Errorf("SearchIMDbTitles after replacing = %+v, %v; want Lost", got, err)
+		}
+	})
+}
+
+func TestSearchIMDbTitles(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		titles := []IMDbTitle{
+			{ID: "tt0000005", ItemType: ItemTypeMovie, Title: "Heat Wave", Year: 1990},
+			{ID: "tt0000004", ItemType: ItemTypeMovie, Title: "Heatwave", Year: 1983},
+			{ID: "tt0000003", ItemType: ItemTypeMovie, Title: "Heat", Year: 1986},
+			{ID: "tt0000002", ItemType: ItemTypeMovie, Title: "The Heat", Year: 1995},
+			{ID: "tt0000001", ItemType: ItemTypeMovie, Title: "Heat", Year: 1995},
+			{ID: "tt0000006", ItemType: ItemTypeMovie, Title: "Heatstroke", Year: 1990},
+			{ID: "tt0000007", ItemType: ItemTypeTVShow, Title: "Heat", Year: 2020},
+			{ID: "tt0000008", ItemType: ItemTypeMovie, Title: "Wheat", Year: 2000},
+		}
+
+		if _, err := store.ReplaceIMDbTitles(ctx, imdbTitles(titles, nil)); err != nil {
+			t.Fatalf("ReplaceIMDbTitles: %v", err)
+		}
+
+		tests := []struct {
+			itemType string
+			query    string
+			limit    int
+			want     []string
+		}{
+			// Exact matches newest first, with "The Heat" equal to "Heat" and
+			// the lower ID breaking the tie, then shorter titles
+			{ItemTypeMovie, "HEAT", 10, []string{"tt0000001", "tt0000002", "tt0000003", "tt0000004", "tt0000005", "tt0000006"}},
+			{ItemTypeMovie, "heat", 3, []string{"tt0000001", "tt0000002", "tt0000003"}},
+			{ItemTypeMovie, "heat w", 10, []string{"tt0000005"}},
+			{ItemTypeMovie, "the heat", 10, []string{"tt0000001", "tt0000002", "tt0000003", "tt0000004", "tt0000005", "tt0000006"}},
+			{ItemTypeTVShow, "heat", 10, []string{"tt0000007"}},
+			{ItemTypeMovie, "eat", 10, nil},
+			{ItemTypeMovie, " !", 10, nil},
+		}
+
+		for _, tt := range tests {
+			got, err := store.SearchIMDbTitles(ctx, tt.itemType, tt.query, tt.limit)
+
+			if err != nil || !reflect.DeepEqual(imdbTitleIDs(got), tt.want) {
+				t.Errorf("SearchIMDbTitles(%s, %q, %d) = %q, %v; want %q", tt.itemType, tt.query, tt.limit, imdbTitleIDs(got), err, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,1463 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements Store in memory, for tests and throwaway instances
+type MemoryStore struct {
+	mu      sync.RWMutex
+	nextID  int
+	seq     int
+	movies  map[int]memoryMovie
+	tvShows map[int]memoryTVShow
+	events  []WatchEvent
+	seasons map[int][]Season
+	audit   []AuditEntry
+	picks   []memoryPick
+
+	imdbTitles map[string]IMDbTitle
+	users      map[int]User
+	sessions   map[string]Session
+	apiTokens  map[string]APIToken // keyed by token hash
+	profiles   map[int]Profile
+	votes      map[voteKey]string
+}
+
+// memoryMovie keeps the insertion order used for the created_at tiebreak and the trash state
+type memoryMovie struct {
+	Movie
+	seq       int
+	addedAt   time.Time
+	deletedAt time.Time
+	checkedAt time.Time // last metadata lookup
+}
+
+// memoryTVShow keeps the insertion order used for the created_at tiebreak and the trash state
+type memoryTVShow struct {
+	TVShow
+	seq       int
+	addedAt   time.Time
+	deletedAt time.Time
+	checkedAt time.Time // last metadata lookup
+}
+
+// memoryPick records when an item came up in the random picker
+type memoryPick struct {
+	itemType string
+	itemID   int
+	pickedAt time.Time
+}
+
+// voteKey identifies one profile's vote on one title
+type voteKey struct {
+	profileID int
+	itemType  string
+	itemID    int
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:  1,
+		movies:  make(map[int]memoryMovie),
+		tvShows: make(map[int]memoryTVShow),
+		seasons: make(map[int][]Season),
+
+		imdbTitles: make(map[string]IMDbTitle),
+		users:      make(map[int]User),
+		sessions:   make(map[string]Session),
+		apiTokens:  make(map[string]APIToken),
+		profiles:   make(map[int]Profile),
+		votes:      make(map[voteKey]string),
+	}
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// GetAllMovies returns all watchlist movies in the same order as the SQLite store
+func (m *MemoryStore) GetAllMovies(ctx context.Context) ([]Movie, error) {
+	page, err := m.ListMovies(ctx, BoardQuery{Desc: true})
+
+	return page.Movies, err
+}
+
+// ListMovies returns one page of the movie watchlist, filtered and sorted as the SQLite store does
+func (m *MemoryStore) ListMovies(ctx context.Context, query BoardQuery) (MoviePage, error) {
+	if err := validateQuery(query); err != nil {
+		return MoviePage{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	var keys [][]interface{}
+
+	for _, entry := range m.movies {
+		if entry.Watched || !entry.deletedAt.IsZero() || !matchesQuery(query, entry.Tags, entry.Availability, entry.Year) {
+			continue
+		}
+
+		movies = append(movies, entry.Movie)
+		keys = append(keys, sortValues(query.Sort, entry.Availability.Available(), entry.Title, entry.Year, entry.seq, entry.ID))
+	}
+
+	order, cursor, err := memoryPage(keys, query)
+
+	if err != nil {
+		return MoviePage{}, err
+	}
+
+	page := MoviePage{Total: len(movies), NextCursor: cursor}
+
+	for _, i := range order {
+		page.Movies = append(page.Movies, movies[i])
+	}
+
+	return page, nil
+}
+
+// GetMovie returns a movie by ID, or nil if it does not exist
+func (m *MemoryStore) GetMovie(ctx context.Context, id int) (*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.movies[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return nil, nil
+	}
+
+	movie := entry.Movie
+
+	return &movie, nil
+}
+
+// GetMovieByIMDbID returns the lowest-ID movie whose IMDb link has the given ID, or nil
+func (m *MemoryStore) GetMovieByIMDbID(ctx context.Context, imdbID string) (*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *Movie
+
+	for _, entry := range m.movies {
+		if imdbID == "" || !entry.deletedAt.IsZero() || IMDbID(entry.IMDBLink) != imdbID {
+			continue
+		}
+
+		if found == nil || entry.ID < found.ID {
+			movie := entry.Movie
+			found = &movie
+		}
+	}
+
+	return found, nil
+}
+
+// AddMovie stores a new movie and returns its ID
+func (m *MemoryStore) AddMovie(ctx context.Context, movie Movie) (int, error) {
+	movie, err := normalizeMovie(movie)
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie.ID = m.nextID
+	m.nextID++
+	m.seq++
+	m.movies[movie.ID] = memoryMovie{Movie: movie, seq: m.seq, addedAt: time.Now()}
+	m.recordAudit(ctx, ItemTypeMovie, movie.ID, AuditAdd, nil, movie)
+
+	return movie.ID, nil
+}
+
+// UpdateMovie replaces a movie on the board, keeping its watched flag like the SQLite store
+func (m *MemoryStore) UpdateMovie(ctx context.Context, movie Movie) error {
+	movie, err := normalizeMovie(movie)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.movies[movie.ID]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return fmt.Errorf("%w: movie %d", ErrItemNotFound, movie.ID)
+	}
+
+	movie.Watched = entry.Watched
+	m.recordAudit(ctx, ItemTypeMovie, movie.ID, AuditUpdate, entry.Movie, movie)
+	entry.Movie = movie
+	m.movies[movie.ID] = entry
+
+	return nil
+}
+
+// DeleteMovie moves a movie to the trash; a missing or already trashed movie
+// is ErrItemNotFound
+func (m *MemoryStore) DeleteMovie(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.movies[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return fmt.Errorf("%w: movie %d", ErrItemNotFound, id)
+	}
+
+	entry.deletedAt = time.Now()
+	m.movies[id] = entry
+	m.recordAudit(ctx, ItemTypeMovie, id, AuditDelete, entry.Movie, nil)
+
+	return nil
+}
+
+// GetMovieCount returns the number of movies on the watchlist
+func (m *MemoryStore) GetMovieCount(ctx context.Context) (int, error) {
+	movies, err := m.GetAllMovies(ctx)
+
+	return len(movies), err
+}
+
+// PickMovie draws a random watchlist movie streaming somewhere today, weighted
+// by its time on the list as the SQLite store does, and records the pick
+func (m *MemoryStore) PickMovie(ctx context.Context, options PickOptions) (*Movie, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query := BoardQuery{Tags: options.Tags, Service: options.Service, Availability: AvailableNow}
+	now := time.Now()
+
+	var candidates []Movie
+	var weights []float64
+
+	for _, entry := range m.movies {
+		if entry.Watched || !entry.deletedAt.IsZero() || !matchesQuery(query, entry.Tags, entry.Availability, entry.Year) {
+			continue
+		}
+
+		if options.MaxRuntime > 0 && (entry.Runtime < 1 || entry.Runtime > options.MaxRuntime) {
+			continue
+		}
+
+		if excluded(options.Exclude, entry.ID) || m.pickedWithin(ItemTypeMovie, entry.ID, options.NotPickedDays, now) || m.vetoedBy(options.VetoedBy, ItemTypeMovie, entry.ID) {
+			continue
+		}
+
+		candidates = append(candidates, entry.Movie)
+		weights = append(weights, pickWeight(entry.addedAt, now))
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	movie := candidates[weightedChoice(weights)]
+	m.picks = append(m.picks, memoryPick{itemType: ItemTypeMovie, itemID: movie.ID, pickedAt: now})
+
+	return &movie, nil
+}
+
+// GetAllTVShows returns all watchlist TV shows in the same order as the SQLite store
+func (m *MemoryStore) GetAllTVShows(ctx context.Context) ([]TVShow, error) {
+	page, err := m.ListTVShows(ctx, BoardQuery{Desc: true})
+
+	return page.TVShows, err
+}
+
+// ListTVShows returns one page of the TV show watchlist, filtered and sorted as the SQLite store does
+func (m *MemoryStore) ListTVShows(ctx context.Context, query BoardQuery) (TVShowPage, error) {
+	if err := validateQuery(query); err != nil {
+		return TVShowPage{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tvShows []TVShow
+	var keys [][]interface{}
+
+	for _, entry := range m.tvShows {
+		if entry.Watched || !entry.deletedAt.IsZero() || !matchesQuery(query, entry.Tags, entry.Availability, entry.Year) {
+			continue
+		}
+
+		tvShows = append(tvShows, entry.TVShow)
+		keys = append(keys, sortValues(query.Sort, entry.ActiveSeason, entry.Title, entry.Year, entry.seq, entry.ID))
+	}
+
+	order, cursor, err := memoryPage(keys, query)
+
+	if err != nil {
+		return TVShowPage{}, err
+	}
+
+	page := TVShowPage{Total: len(tvShows), NextCursor: cursor}
+
+	for _, i := range order {
+		page.TVShows = append(page.TVShows, m.withProgress(tvShows[i]))
+	}
+
+	return page, nil
+}
+
+// GetTVShow returns a TV show by ID, or nil if it does not exist
+func (m *MemoryStore) GetTVShow(ctx context.Context, id int) (*TVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.tvShows[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return nil, nil
+	}
+
+	tvShow := m.withProgress(entry.TVShow)
+
+	return &tvShow, nil
+}
+
+// GetTVShowByIMDbID returns the lowest-ID TV show whose IMDb link has the given ID, or nil
+func (m *MemoryStore) GetTVShowByIMDbID(ctx context.Context, imdbID string) (*TVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *TVShow
+
+	for _, entry := range m.tvShows {
+		if imdbID == "" || !entry.deletedAt.IsZero() || IMDbID(entry.IMDBLink) != imdbID {
+			continue
+		}
+
+		if found == nil || entry.ID < found.ID {
+			tvShow := entry.TVShow
+			found = &tvShow
+		}
+	}
+
+	return found, nil
+}
+
+// AddTVShow stores a new TV show and returns its ID
+func (m *MemoryStore) AddTVShow(ctx context.Context, tvShow TVShow) (int, error) {
+	tvShow, err := normalizeTVShow(tvShow)
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tvShow.ID = m.nextID
+	m.nextID++
+	m.seq++
+	m.tvShows[tvShow.ID] = memoryTVShow{TVShow: tvShow, seq: m.seq, addedAt: time.Now()}
+	m.recordAudit(ctx, ItemTypeTVShow, tvShow.ID, AuditAdd, nil, tvShow)
+
+	return tvShow.ID, nil
+}
+
+// UpdateTVShow replaces a TV show on the board, keeping its watched flag like the SQLite store
+func (m *MemoryStore) UpdateTVShow(ctx context.Context, tvShow TVShow) error {
+	tvShow, err := normalizeTVShow(tvShow)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tvShows[tvShow.ID]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return fmt.Errorf("%w: TV show %d", ErrItemNotFound, tvShow.ID)
+	}
+
+	tvShow.Watched = entry.Watched
+	m.recordAudit(ctx, ItemTypeTVShow, tvShow.ID, AuditUpdate, entry.TVShow, tvShow)
+	entry.TVShow = tvShow
+	m.tvShows[tvShow.ID] = entry
+
+	return nil
+}
+
+// DeleteTVShow moves a TV show to the trash; a missing or already trashed TV
+// show is ErrItemNotFound
+func (m *MemoryStore) DeleteTVShow(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tvShows[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return fmt.Errorf("%w: TV show %d", ErrItemNotFound, id)
+	}
+
+	entry.deletedAt = time.Now()
+	m.tvShows[id] = entry
+	m.recordAudit(ctx, ItemTypeTVShow, id, AuditDelete, entry.TVShow, nil)
+
+	return nil
+}
+
+// GetTVShowCount returns the number of TV shows on the watchlist
+func (m *MemoryStore) GetTVShowCount(ctx context.Context) (int, error) {
+	tvShows, err := m.GetAllTVShows(ctx)
+
+	return len(tvShows), err
+}
+
+// PickTVShow draws a random watchlist TV show from the options' pool, weighted
+// by its time on the list as the SQLite store does, and records the pick
+func (m *MemoryStore) PickTVShow(ctx context.Context, options PickOptions) (*TVShow, error) {
+	if !ValidPool(options.Pool) {
+		return nil, fmt.Errorf("%w: unknown pool %q", ErrInvalidQuery, options.Pool)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query := BoardQuery{Tags: options.Tags, Service: options.Service, Availability: options.availability(ItemTypeTVShow)}
+	now := time.Now()
+
+	var candidates []TVShow
+	var weights []float64
+
+	for _, entry := range m.tvShows {
+		if entry.Watched || !entry.deletedAt.IsZero() || !matchesQuery(query, entry.Tags, entry.Availability, entry.Year) {
+			continue
+		}
+
+		tvShow := m.withProgress(entry.TVShow)
+		inProgress := tvShow.Progress.WatchedEpisodes > 0 && tvShow.Progress.Season != 0
+
+		switch options.Pool {
+		case PoolActive:
+			if !tvShow.ActiveSeason {
+				continue
+			}
+		case PoolInProgress:
+			if !inProgress {
+				continue
+			}
+		default:
+			if !tvShow.ActiveSeason && !inProgress {
+				continue
+			}
+		}
+
+		if excluded(options.Exclude, entry.ID) || m.pickedWithin(ItemTypeTVShow, entry.ID, options.NotPickedDays, now) || m.vetoedBy(options.VetoedBy, ItemTypeTVShow, entry.ID) {
+			continue
+		}
+
+		candidates = append(candidates, tvShow)
+		weights = append(weights, pickWeight(entry.addedAt, now))
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	tvShow := candidates[weightedChoice(weights)]
+	m.picks = append(m.picks, memoryPick{itemType: ItemTypeTVShow, itemID: tvShow.ID, pickedAt: now})
+
+	return &tvShow, nil
+}
+
+// SearchMovies returns movies where every query term prefixes a word in the
+// title, notes, tags or streaming service, with title matches first
+func (m *MemoryStore) SearchMovies(ctx context.Context, query string) ([]Movie, error) {
+	terms := searchTerms(query)
+
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	all, _ := m.GetAllMovies(ctx)
+
+	var titleHits, otherHits []Movie
+
+	for _, movie := range all {
+		if matchesAllPrefixes(terms, movie.Title) {
+			titleHits = append(titleHits, movie)
+		} else if matchesAllPrefixes(terms, movie.Title, movie.Notes, strings.Join(movie.Tags, " "), movie.Availability.services()) {
+			otherHits = append(otherHits, movie)
+		}
+	}
+
+	return append(titleHits, otherHits...), nil
+}
+
+// SearchTVShows returns TV shows matching the query, with title matches first
+func (m *MemoryStore) SearchTVShows(ctx context.Context, query string) ([]TVShow, error) {
+	terms := searchTerms(query)
+
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	all, _ := m.GetAllTVShows(ctx)
+
+	var titleHits, otherHits []TVShow
+
+	for _, tvShow := range all {
+		if matchesAllPrefixes(terms, tvShow.Title) {
+			titleHits = append(titleHits, tvShow)
+		} else if matchesAllPrefixes(terms, tvShow.Title, tvShow.Notes, strings.Join(tvShow.Tags, " "), tvShow.Availability.services()) {
+			otherHits = append(otherHits, tvShow)
+		}
+	}
+
+	return append(titleHits, otherHits...), nil
+}
+
+// addWatchEvent records a viewing; callers hold the write lock
+func (m *MemoryStore) addWatchEvent(event WatchEvent) {
+	if event.WatchedAt.IsZero() {
+		event.WatchedAt = time.Now()
+	}
+
+	event.ID = len(m.events) + 1
+	m.events = append(m.events, event)
+}
+
+// watchEventsFor returns the viewings of one item, newest first; callers hold the lock
+func (m *MemoryStore) watchEventsFor(itemType string, itemID int) []WatchEvent {
+	var events []WatchEvent
+
+	for _, event := range m.events {
+		if event.ItemType == itemType && event.ItemID == itemID {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].WatchedAt.After(events[j].WatchedAt)
+	})
+
+	return events
+}
+
+// MarkMovieWatched records a viewing and moves the movie into the watched archive
+func (m *MemoryStore) MarkMovieWatched(ctx context.Context, event WatchEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.movies[event.ItemID]
+
+	if !ok {
+		return nil
+	}
+
+	event.ItemType = ItemTypeMovie
+	m.addWatchEvent(event)
+
+	entry.Watched = true
+	m.movies[event.ItemID] = entry
+
+	return nil
+}
+
+// ReturnMovieToWatchlist moves a watched movie back onto the watchlist
+func (m *MemoryStore) ReturnMovieToWatchlist(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.movies[id]; ok {
+		entry.Watched = false
+		m.movies[id] = entry
+	}
+
+	return nil
+}
+
+// GetWatchedMovies returns the watched archive, most recently watched first
+func (m *MemoryStore) GetWatchedMovies(ctx context.Context) ([]WatchedMovie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var watched []WatchedMovie
+
+	for _, entry := range m.movies {
+		if entry.Watched && entry.deletedAt.IsZero() {
+			watched = append(watched, WatchedMovie{Movie: entry.Movie, Events: m.watchEventsFor(ItemTypeMovie, entry.ID)})
+		}
+	}
+
+	sort.Slice(watched, func(i, j int) bool {
+		return lastWatched(watched[i].Events).After(lastWatched(watched[j].Events))
+	})
+
+	return watched, nil
+}
+
+// MarkTVShowWatched records a viewing and moves the TV show into the watched archive
+func (m *MemoryStore) MarkTVShowWatched(ctx context.Context, event WatchEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tvShows[event.ItemID]
+
+	if !ok {
+		return nil
+	}
+
+	event.ItemType = ItemTypeTVShow
+	m.addWatchEvent(event)
+
+	entry.Watched = true
+	m.tvShows[event.ItemID] = entry
+
+	return nil
+}
+
+// ReturnTVShowToWatchlist moves a watched TV show back onto the watchlist
+func (m *MemoryStore) ReturnTVShowToWatchlist(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.tvShows[id]; ok {
+		entry.Watched = false
+		m.tvShows[id] = entry
+	}
+
+	return nil
+}
+
+// GetWatchedTVShows returns the watched archive, most recently watched first
+func (m *MemoryStore) GetWatchedTVShows(ctx context.Context) ([]WatchedTVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var watched []WatchedTVShow
+
+	for _, entry := range m.tvShows {
+		if entry.Watched && entry.deletedAt.IsZero() {
+			watched = append(watched, WatchedTVShow{TVShow: m.withProgress(entry.TVShow), Events: m.watchEventsFor(ItemTypeTVShow, entry.ID)})
+		}
+	}
+
+	sort.Slice(watched, func(i, j int) bool {
+		return lastWatched(watched[i].Events).After(lastWatched(watched[j].Events))
+	})
+
+	return watched, nil
+}
+
+// lastWatched returns the newest viewing time from a newest-first event list
+func lastWatched(events []WatchEvent) time.Time {
+	if len(events) == 0 {
+		return time.Time{}
+	}
+
+	return events[0].WatchedAt
+}
+
+// withProgress fills in the episode progress of a TV show; callers hold the lock
+func (m *MemoryStore) withProgress(tvShow TVShow) TVShow {
+	tvShow.Progress = episodeProgress(m.seasons[tvShow.ID])
+
+	return tvShow
+}
+
+// GetSeasons returns the seasons and episodes of a TV show in viewing order
+func (m *MemoryStore) GetSeasons(ctx context.Context, tvShowID int) ([]Season, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var seasons []Season
+
+	for _, season := range m.seasons[tvShowID] {
+		season.Episodes = append([]Episode(nil), season.Episodes...)
+		seasons = append(seasons, season)
+	}
+
+	return seasons, nil
+}
+
+// AddSeason adds a season with the given number of episodes to a TV show
+func (m *MemoryStore) AddSeason(ctx context.Context, tvShowID, number, episodeCount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, season := range m.seasons[tvShowID] {
+		if season.Number == number {
+			return ErrSeasonExists
+		}
+	}
+
+	season := Season{ID: m.nextID, TVShowID: tvShowID, Number: number}
+	m.nextID++
+
+	for episode := 1; episode <= episodeCount; episode++ {
+		season.Episodes = append(season.Episodes, Episode{ID: m.nextID, SeasonID: season.ID, Number: episode})
+		m.nextID++
+	}
+
+	seasons := append(m.seasons[tvShowID], season)
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].Number < seasons[j].Number
+	})
+
+	m.seasons[tvShowID] = seasons
+
+	return nil
+}
+
+// DeleteSeason removes a season and its episodes
+func (m *MemoryStore) DeleteSeason(ctx context.Context, tvShowID, seasonID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []Season
+
+	for _, season := range m.seasons[tvShowID] {
+		if season.ID != seasonID {
+			kept = append(kept, season)
+		}
+	}
+
+	m.seasons[tvShowID] = kept
+
+	return nil
+}
+
+// SetEpisodeWatched marks a single episode watched or unwatched
+func (m *MemoryStore) SetEpisodeWatched(ctx context.Context, tvShowID, episodeID int, watched bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setEpisodeWatched(tvShowID, episodeID, watched)
+
+	return nil
+}
+
+// WatchNextEpisode marks the show's next episode watched
+func (m *MemoryStore) WatchNextEpisode(ctx context.Context, tvShowID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if next := nextEpisode(m.seasons[tvShowID]); next != nil {
+		m.setEpisodeWatched(tvShowID, next.ID, true)
+	}
+
+	return nil
+}
+
+// setEpisodeWatched updates one episode in place; callers hold the write lock
+func (m *MemoryStore) setEpisodeWatched(tvShowID, episodeID int, watched bool) {
+	seasons := m.seasons[tvShowID]
+
+	for i := range seasons {
+		for j := range seasons[i].Episodes {
+			episode := &seasons[i].Episodes[j]
+
+			if episode.ID != episodeID {
+				continue
+			}
+
+			episode.Watched = watched
+			episode.WatchedAt = time.Time{}
+
+			if watched {
+				episode.WatchedAt = time.Now()
+			}
+		}
+	}
+}
+
+// GetTrash returns every trashed movie and TV show, most recently deleted first
+func (m *MemoryStore) GetTrash(ctx context.Context) ([]TrashItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []TrashItem
+
+	for _, entry := range m.movies {
+		if !entry.deletedAt.IsZero() {
+			items = append(items, TrashItem{ItemType: ItemTypeMovie, ID: entry.ID, Title: entry.Title, Year: entry.Year, DeletedAt: entry.deletedAt})
+		}
+	}
+
+	for _, entry := range m.tvShows {
+		if !entry.deletedAt.IsZero() {
+			items = append(items, TrashItem{ItemType: ItemTypeTVShow, ID: entry.ID, Title: entry.Title, Year: entry.Year, DeletedAt: entry.deletedAt})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// RestoreItem takes a movie or TV show back out of the trash
+func (m *MemoryStore) RestoreItem(ctx context.Context, itemType string, id int) error {
+	if _, err := trashTable(itemType); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.movies[id]; ok && itemType == ItemTypeMovie && !entry.deletedAt.IsZero() {
+		entry.deletedAt = time.Time{}
+		m.movies[id] = entry
+		m.recordAudit(ctx, itemType, id, AuditRestore, nil, nil)
+	}
+
+	if entry, ok := m.tvShows[id]; ok && itemType == ItemTypeTVShow && !entry.deletedAt.IsZero() {
+		entry.deletedAt = time.Time{}
+		m.tvShows[id] = entry
+		m.recordAudit(ctx, itemType, id, AuditRestore, nil, nil)
+	}
+
+	return nil
+}
+
+// PurgeItem permanently deletes a trashed movie or TV show and its history
+func (m *MemoryStore) PurgeItem(ctx context.Context, itemType string, id int) error {
+	if _, err := trashTable(itemType); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(ctx, itemType, id)
+
+	return nil
+}
+
+// PurgeTrashBefore permanently deletes items trashed before the cutoff
+func (m *MemoryStore) PurgeTrashBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	items, _ := m.GetTrash(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+
+	for _, item := range items {
+		if item.DeletedAt.Before(cutoff) && m.purge(ctx, item.ItemType, item.ID) {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// purge removes a trashed item and its history; callers hold the write lock
+func (m *MemoryStore) purge(ctx context.Context, itemType string, id int) bool {
+	switch itemType {
+	case ItemTypeMovie:
+		if entry, ok := m.movies[id]; !ok || entry.deletedAt.IsZero() {
+			return false
+		}
+
+		delete(m.movies, id)
+	case ItemTypeTVShow:
+		if entry, ok := m.tvShows[id]; !ok || entry.deletedAt.IsZero() {
+			return false
+		}
+
+		delete(m.tvShows, id)
+		delete(m.seasons, id)
+	}
+
+	var kept []WatchEvent
+
+	for _, event := range m.events {
+		if event.ItemType != itemType || event.ItemID != id {
+			kept = append(kept, event)
+		}
+	}
+
+	m.events = kept
+
+	for key := range m.votes {
+		if key.itemType == itemType && key.itemID == id {
+			delete(m.votes, key)
+		}
+	}
+
+	m.recordAudit(ctx, itemType, id, AuditPurge, nil, nil)
+
+	return true
+}
+
+// recordAudit appends an audit entry like the SQLite store; callers hold the write lock
+func (m *MemoryStore) recordAudit(ctx context.Context, itemType string, itemID int, action string, before, after interface{}) {
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+
+	if err != nil || (action == AuditUpdate && beforeJSON == "{}" && afterJSON == "{}") {
+		return
+	}
+
+	m.audit = append(m.audit, AuditEntry{
+		ID:        len(m.audit) + 1,
+		ItemType:  itemType,
+		ItemID:    itemID,
+		Action:    action,
+		Actor:     ActorFromContext(ctx),
+		CreatedAt: time.Now().UTC(),
+		Before:    beforeJSON,
+		After:     afterJSON,
+	})
+}
+
+// GetItemHistory returns the audit timeline of a movie or TV show, oldest first
+func (m *MemoryStore) GetItemHistory(ctx context.Context, itemType string, itemID int) ([]AuditEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []AuditEntry
+
+	for _, entry := range m.audit {
+		if entry.ItemType == itemType && entry.ItemID == itemID {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// GetTags returns every tag attached to at least one item of the given type
+func (m *MemoryStore) GetTags(ctx context.Context, itemType string) ([]string, error) {
+	if _, ok := tagJoins[itemType]; !ok {
+		return nil, fmt.Errorf("unknown item type: %s", itemType)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tags []string
+
+	switch itemType {
+	case ItemTypeMovie:
+		for _, entry := range m.movies {
+			tags = append(tags, entry.Tags...)
+		}
+	case ItemTypeTVShow:
+		for _, entry := range m.tvShows {
+			tags = append(tags, entry.Tags...)
+		}
+	}
+
+	return normalizeTags(tags), nil
+}
+
+// liveMovies returns every movie not in the trash, by ID; callers hold the lock
+func (m *MemoryStore) liveMovies() []Movie {
+	var movies []Movie
+
+	for _, entry := range m.movies {
+		if entry.deletedAt.IsZero() {
+			movies = append(movies, entry.Movie)
+		}
+	}
+
+	sort.Slice(movies, func(i, j int) bool {
+		return movies[i].ID < movies[j].ID
+	})
+
+	return movies
+}
+
+// liveTVShows returns every TV show not in the trash, by ID; callers hold the lock
+func (m *MemoryStore) liveTVShows() []TVShow {
+	var tvShows []TVShow
+
+	for _, entry := range m.tvShows {
+		if entry.deletedAt.IsZero() {
+			tvShows = append(tvShows, m.withProgress(entry.TVShow))
+		}
+	}
+
+	sort.Slice(tvShows, func(i, j int) bool {
+		return tvShows[i].ID < tvShows[j].ID
+	})
+
+	return tvShows
+}
+
+// FindMovieDuplicates returns the movies, watched or not, that movie likely duplicates
+func (m *MemoryStore) FindMovieDuplicates(ctx context.Context, movie Movie) ([]Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return movieDuplicatesOf(m.liveMovies(), movie), nil
+}
+
+// FindTVShowDuplicates returns the TV shows, watched or not, that tvShow likely duplicates
+func (m *MemoryStore) FindTVShowDuplicates(ctx context.Context, tvShow TVShow) ([]TVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return tvShowDuplicatesOf(m.liveTVShows(), tvShow), nil
+}
+
+// GetDuplicateMovies returns groups of movies that are likely the same title
+func (m *MemoryStore) GetDuplicateMovies(ctx context.Context) ([][]Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return groupMovies(m.liveMovies()), nil
+}
+
+// GetDuplicateTVShows returns groups of TV shows that are likely the same title
+func (m *MemoryStore) GetDuplicateTVShows(ctx context.Context) ([][]TVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return groupTVShows(m.liveTVShows()), nil
+}
+
+// MergeMovies folds the movie mergeID into keepID like the SQLite store
+func (m *MemoryStore) MergeMovies(ctx context.Context, keepID, mergeID int) error {
+	if keepID == mergeID {
+		return ErrMergeSameItem
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep, ok := m.movies[keepID]
+	other, found := m.movies[mergeID]
+
+	if !ok || !found || !keep.deletedAt.IsZero() || !other.deletedAt.IsZero() {
+		return fmt.Errorf("movie not found")
+	}
+
+	merged, err := normalizeMovie(mergeMovieFields(keep.Movie, other.Movie))
+
+	if err != nil {
+		return err
+	}
+
+	m.recordAudit(ctx, ItemTypeMovie, keepID, AuditMerge, keep.Movie, merged)
+	keep.Movie = merged
+	m.movies[keepID] = keep
+
+	m.finishMerge(ctx, ItemTypeMovie, keepID, mergeID)
+	other.deletedAt = time.Now()
+	m.movies[mergeID] = other
+
+	return nil
+}
+
+// MergeTVShows folds the TV show mergeID into keepID like the SQLite store
+func (m *MemoryStore) MergeTVShows(ctx context.Context, keepID, mergeID int) error {
+	if keepID == mergeID {
+		return ErrMergeSameItem
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep, ok := m.tvShows[keepID]
+	other, found := m.tvShows[mergeID]
+
+	if !ok || !found || !keep.deletedAt.IsZero() || !other.deletedAt.IsZero() {
+		return fmt.Errorf("TV show not found")
+	}
+
+	merged, err := normalizeTVShow(mergeTVShowFields(keep.TVShow, other.TVShow))
+
+	if err != nil {
+		return err
+	}
+
+	m.recordAudit(ctx, ItemTypeTVShow, keepID, AuditMerge, keep.TVShow, merged)
+	keep.TVShow = merged
+	m.tvShows[keepID] = keep
+
+	// Seasons the kept show lacks move across with their episodes
+	numbers := make(map[int]bool)
+
+	for _, season := range m.seasons[keepID] {
+		numbers[season.Number] = true
+	}
+
+	var left []Season
+
+	for _, season := range m.seasons[mergeID] {
+		if numbers[season.Number] {
+			left = append(left, season)
+
+			continue
+		}
+
+		season.TVShowID = keepID
+		m.seasons[keepID] = append(m.seasons[keepID], season)
+	}
+
+	sort.Slice(m.seasons[keepID], func(i, j int) bool {
+		return m.seasons[keepID][i].Number < m.seasons[keepID][j].Number
+	})
+
+	m.seasons[mergeID] = left
+
+	m.finishMerge(ctx, ItemTypeTVShow, keepID, mergeID)
+	other.deletedAt = time.Now()
+	m.tvShows[mergeID] = other
+
+	return nil
+}
+
+// finishMerge moves the merged item's viewings, and any votes the kept item
+// lacks, and records the merge on it; callers hold the write lock
+func (m *MemoryStore) finishMerge(ctx context.Context, itemType string, keepID, mergeID int) {
+	for i, event := range m.events {
+		if event.ItemType == itemType && event.ItemID == mergeID {
+			m.events[i].ItemID = keepID
+		}
+	}
+
+	for key, vote := range m.votes {
+		if key.itemType != itemType || key.itemID != mergeID {
+			continue
+		}
+
+		kept := voteKey{profileID: key.profileID, itemType: itemType, itemID: keepID}
+
+		if _, ok := m.votes[kept]; !ok {
+			m.votes[kept] = vote
+			delete(m.votes, key)
+		}
+	}
+
+	m.recordAudit(ctx, itemType, mergeID, AuditMerge, nil, mergedInto{MergedInto: keepID})
+}
+
+// GetMoviesMissingMetadata returns up to limit movies with a field a provider
+// could fill in that were not looked up since checkedBefore, never-checked ones first
+func (m *MemoryStore) GetMoviesMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []memoryMovie
+
+	for _, entry := range m.movies {
+		if entry.deletedAt.IsZero() && entry.MissingMetadata() && entry.checkedAt.Before(checkedBefore) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].checkedAt.Equal(entries[j].checkedAt) {
+			return entries[i].checkedAt.Before(entries[j].checkedAt)
+		}
+
+		return entries[i].ID < entries[j].ID
+	})
+
+	var movies []Movie
+
+	for _, entry := range entries[:min(limit, len(entries))] {
+		movies = append(movies, entry.Movie)
+	}
+
+	return movies, nil
+}
+
+// GetTVShowsMissingMetadata is GetMoviesMissingMetadata for TV shows
+func (m *MemoryStore) GetTVShowsMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]TVShow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []memoryTVShow
+
+	for _, entry := range m.tvShows {
+		if entry.deletedAt.IsZero() && entry.MissingMetadata() && entry.checkedAt.Before(checkedBefore) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].checkedAt.Equal(entries[j].checkedAt) {
+			return entries[i].checkedAt.Before(entries[j].checkedAt)
+		}
+
+		return entries[i].ID < entries[j].ID
+	})
+
+	var tvShows []TVShow
+
+	for _, entry := range entries[:min(limit, len(entries))] {
+		tvShows = append(tvShows, m.withProgress(entry.TVShow))
+	}
+
+	return tvShows, nil
+}
+
+// MarkMetadataChecked records that the enrichment job looked an item up
+func (m *MemoryStore) MarkMetadataChecked(ctx context.Context, itemType string, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch itemType {
+	case ItemTypeMovie:
+		if entry, ok := m.movies[id]; ok {
+			entry.checkedAt = time.Now()
+			m.movies[id] = entry
+		}
+	case ItemTypeTVShow:
+		if entry, ok := m.tvShows[id]; ok {
+			entry.checkedAt = time.Now()
+			m.tvShows[id] = entry
+		}
+	default:
+		return fmt.Errorf("unknown item type: %s", itemType)
+	}
+
+	return nil
+}
+
+// FillMovieMetadata writes a fill into a movie's empty fields and reports
+// whether any changed. A trashed or missing movie is ErrItemNotFound.
+func (m *MemoryStore) FillMovieMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.movies[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return false, fmt.Errorf("%w: movie %d", ErrItemNotFound, id)
+	}
+
+	fill = fill.normalize()
+	movie := entry.Movie
+	fill.fillCommon(&movie.Year, &movie.Tags, &movie.IMDBLink, &movie.Details)
+
+	if movie.Runtime == 0 {
+		movie.Runtime = fill.Runtime
+	}
+
+	if reflect.DeepEqual(movie, entry.Movie) {
+		return false, nil
+	}
+
+	m.recordAudit(ctx, ItemTypeMovie, id, AuditUpdate, entry.Movie, movie)
+	entry.Movie = movie
+	m.movies[id] = entry
+
+	return true, nil
+}
+
+// FillTVShowMetadata is FillMovieMetadata for TV shows, which have no runtime
+func (m *MemoryStore) FillTVShowMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tvShows[id]
+
+	if !ok || !entry.deletedAt.IsZero() {
+		return false, fmt.Errorf("%w: TV show %d", ErrItemNotFound, id)
+	}
+
+	tvShow := entry.TVShow
+	fill.normalize().fillCommon(&tvShow.Year, &tvShow.Tags, &tvShow.IMDBLink, &tvShow.Details)
+
+	if reflect.DeepEqual(tvShow, entry.TVShow) {
+		return false, nil
+	}
+
+	m.recordAudit(ctx, ItemTypeTVShow, id, AuditUpdate, entry.TVShow, tvShow)
+	entry.TVShow = tvShow
+	m.tvShows[id] = entry
+
+	return true, nil
+}
+
+// GetPosterURLs returns every distinct poster URL, including those of trashed items
+func (m *MemoryStore) GetPosterURLs(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+
+	var urls []string
+
+	for _, entry := range m.movies {
+		if entry.PosterURL != "" && !seen[entry.PosterURL] {
+			seen[entry.PosterURL] = true
+			urls = append(urls, entry.PosterURL)
+		}
+	}
+
+	for _, entry := range m.tvShows {
+		if entry.PosterURL != "" && !seen[entry.PosterURL] {
+			seen[entry.PosterURL] = true
+			urls = append(urls, entry.PosterURL)
+		}
+	}
+
+	return urls, nil
+}
+
+// matchesQuery reports whether an item passes a board query's filters, as
+// the SQLite store's WHERE clause does
+func matchesQuery(query BoardQuery, tags []string, availability AvailabilityList, year int) bool {
+	if !hasAllTags(tags, query.Tags) {
+		return false
+	}
+
+	if service := strings.TrimSpace(query.Service); service != "" {
+		windows := availability
+
+		if query.Availability == AvailableNow {
+			windows = availability.Current()
+		}
+
+		found := false
+
+		for _, window := range windows {
+			if strings.EqualFold(window.Service, service) {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	switch query.Availability {
+	case AvailableNow:
+		if !availability.Available() {
+			return false
+		}
+	case AvailableNone:
+		if availability.Available() {
+			return false
+		}
+	}
+
+	if (query.YearFrom > 0 || query.YearTo > 0) && year <= 0 {
+		return false
+	}
+
+	return (query.YearFrom == 0 || year >= query.YearFrom) && (query.YearTo == 0 || year <= query.YearTo)
+}
+
+// sortValues returns an item's sort key values in the order boardTable.sortKeys
+// lists them; insertion order stands in for created_at
+func sortValues(sort string, first bool, title string, year, seq, id int) []interface{} {
+	switch sort {
+	case SortTitle:
+		return []interface{}{strings.ToLower(title), int64(id)}
+	case SortYear:
+		return []interface{}{int64(year), int64(id)}
+	case SortAdded:
+		return []interface{}{int64(seq), int64(id)}
+	default:
+		firstValue := int64(0)
+
+		if first {
+			firstValue = 1
+		}
+
+		return []interface{}{firstValue, int64(year), int64(seq), int64(id)}
+	}
+}
+
+// compareValues orders two tuples of sort key values, each an int64 or a string
+func compareValues(a, b []interface{}) int {
+	for i := range a {
+		switch x := a[i].(type) {
+		case int64:
+			if y, ok := b[i].(int64); ok && x != y {
+				if x < y {
+					return -1
+				}
+
+				return 1
+			}
+		case string:
+			if y, ok := b[i].(string); ok && x != y {
+				return strings.Compare(x, y)
+			}
+		}
+	}
+
+	return 0
+}
+
+// memoryPage sorts items by their sort key values and returns the indexes of
+// the page after the query's cursor, with the cursor for the page after that
+func memoryPage(keys [][]interface{}, query BoardQuery) ([]int, string, error) {
+	direction := 1
+
+	if query.Desc {
+		direction = -1
+	}
+
+	var after []interface{}
+
+	if query.Cursor != "" {
+		var err error
+
+		if after, err = decodeCursor(query.Cursor, len(sortValues(query.Sort, false, "", 0, 0, 0))); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var order []int
+
+	for i := range keys {
+		if after == nil || compareValues(keys[i], after)*direction > 0 {
+			order = append(order, i)
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return compareValues(keys[order[i]], keys[order[j]])*direction < 0
+	})
+
+	if query.Limit == 0 || len(order) <= query.Limit {
+		return order, "", nil
+	}
+
+	order = order[:query.Limit]
+	cursor, err := encodeCursor(keys[order[len(order)-1]])
+
+	return order, cursor, err
+}
+
+// pickedWithin reports whether an item came up in the picker within the last days
+func (m *MemoryStore) pickedWithin(itemType string, id, days int, now time.Time) bool {
+	if days <= 0 {
+		return false
+	}
+
+	since := now.AddDate(0, 0, -days)
+
+	for _, pick := range m.picks {
+		if pick.itemType == itemType && pick.itemID == id && !pick.pickedAt.Before(since) {
+			return true
+		}
+	}
+
+	return false
+}
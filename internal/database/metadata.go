@@ -0,0 +1,336 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Details are the fields a metadata provider fills in beyond those every item has
+type Details struct {
+	PosterURL string   `json:"poster_url"`
+	Director  string   `json:"director"` // a TV show's creator
+	Cast      []string `json:"cast"`
+}
+
+// Fill returns d with its empty fields taken from other
+func (d Details) Fill(other Details) Details {
+	if d.PosterURL == "" {
+		d.PosterURL = other.PosterURL
+	}
+
+	if d.Director == "" {
+		d.Director = other.Director
+	}
+
+	if len(d.Cast) == 0 {
+		d.Cast = other.Cast
+	}
+
+	return d
+}
+
+// MetadataFill is what a provider found for an item. Each field is written
+// only where the item's own is still empty, so edits made while a lookup was
+// in flight are kept.
+type MetadataFill struct {
+	Year     int
+	Runtime  int // movies only
+	IMDBLink string
+	Tags     []string
+	Details
+}
+
+// normalize drops the values that would not pass validation, so one bad field
+// from a provider doesn't keep the others out
+func (f MetadataFill) normalize() MetadataFill {
+	if f.Year < MinYear || f.Year > MaxYear {
+		f.Year = 0
+	}
+
+	if f.Runtime < 0 || f.Runtime > MaxRuntime {
+		f.Runtime = 0
+	}
+
+	details, err := normalizeDetails(f.Details)
+
+	if err != nil {
+		details.PosterURL = ""
+	}
+
+	f.IMDBLink = IMDbLink(f.IMDBLink)
+	f.Tags = normalizeTags(f.Tags)
+	f.Details = details
+
+	return f
+}
+
+// fillCommon writes a fill into the empty fields every item has
+func (f MetadataFill) fillCommon(year *int, tags *[]string, imdbLink *string, details *Details) {
+	if *year == 0 {
+		*year = f.Year
+	}
+
+	if len(*tags) == 0 {
+		*tags = f.Tags
+	}
+
+	if *imdbLink == "" {
+		*imdbLink = f.IMDBLink
+	}
+
+	*details = details.Fill(f.Details)
+}
+
+// complete reports whether every details field is filled in
+func (d Details) complete() bool {
+	return d.PosterURL != "" && d.Director != "" && len(d.Cast) > 0
+}
+
+// PosterKey names a poster's files in the image cache: a hash of its URL, so a
+// changed URL gets new files and the old ones become orphans
+func PosterKey(posterURL string) string {
+	sum := sha256.Sum256([]byte(posterURL))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// PosterImage is the path the image cache serves the poster at in a size such
+// as w185, or "" when there is no poster
+func (d Details) PosterImage(size string) string {
+	if d.PosterURL == "" {
+		return ""
+	}
+
+	return "/media/posters/" + PosterKey(d.PosterURL) + "-" + size + ".jpg"
+}
+
+// joinCast encodes cast members for the cast_members column, one per line
+func joinCast(cast []string) string {
+	return strings.Join(cast, "\n")
+}
+
+// splitCast decodes the cast_members column
+func splitCast(cast string) []string {
+	if cast == "" {
+		return nil
+	}
+
+	return strings.Split(cast, "\n")
+}
+
+// MissingMetadata reports whether a movie lacks a field a provider could fill in
+func (m Movie) MissingMetadata() bool {
+	return m.Year == 0 || m.Runtime == 0 || m.IMDBLink == "" || len(m.Tags) == 0 || !m.Details.complete()
+}
+
+// MissingMetadata reports whether a TV show lacks a field a provider could fill in
+func (t TVShow) MissingMetadata() bool {
+	return t.Year == 0 || t.IMDBLink == "" || len(t.Tags) == 0 || !t.Details.complete()
+}
+
+// missingMetadataSQL selects the rows of a board with a field a provider could fill in
+func missingMetadataSQL(t boardTable, tagTable, tagColumn string) string {
+	return "(COALESCE(" + t.name + ".year, 0) = 0 OR COALESCE(" + t.name + ".imdb_link, '') = '' OR " + t.name + ".poster_url = '' OR " +
+		t.name + ".director = '' OR " + t.name + ".cast_members = '' OR NOT EXISTS (SELECT 1 FROM " + tagTable + " j WHERE j." + tagColumn + " = " + t.name + ".id))"
+}
+
+// GetMoviesMissingMetadata returns up to limit movies with a field a provider
+// could fill in that were not looked up since checkedBefore, never-checked ones first
+func (s *SQLiteStore) GetMoviesMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]Movie, error) {
+	query := "SELECT " + movieColumns + " FROM movies WHERE deleted_at IS NULL" +
+		" AND (" + missingMetadataSQL(movieBoard, "movie_tags", "movie_id") + " OR movies.runtime = 0)" +
+		" AND (metadata_checked_at IS NULL OR metadata_checked_at < ?)" +
+		" ORDER BY metadata_checked_at IS NOT NULL, metadata_checked_at, id LIMIT ?"
+
+	rows, err := s.db.QueryContext(ctx, query, checkedBefore.UTC(), limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies missing metadata: %w", err)
+	}
+
+	return scanMovies(rows)
+}
+
+// GetTVShowsMissingMetadata is GetMoviesMissingMetadata for TV shows
+func (s *SQLiteStore) GetTVShowsMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]TVShow, error) {
+	query := "SELECT " + tvShowColumns + " FROM tv_shows WHERE deleted_at IS NULL" +
+		" AND " + missingMetadataSQL(tvShowBoard, "tv_show_tags", "tv_show_id") +
+		" AND (metadata_checked_at IS NULL OR metadata_checked_at < ?)" +
+		" ORDER BY metadata_checked_at IS NOT NULL, metadata_checked_at, id LIMIT ?"
+
+	rows, err := s.db.QueryContext(ctx, query, checkedBefore.UTC(), limit)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query TV shows missing metadata: %w", err)
+	}
+
+	return scanTVShows(rows)
+}
+
+// MarkMetadataChecked records that the enrichment job looked an item up
+func (s *SQLiteStore) MarkMetadataChecked(ctx context.Context, itemType string, id int) error {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE "+table+" SET metadata_checked_at = ? WHERE id = ?", time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to record metadata check: %w", err)
+	}
+
+	return nil
+}
+
+// FillMovieMetadata writes a fill into a movie's empty fields in one UPDATE and
+// reports whether any changed. A trashed or missing movie is ErrItemNotFound.
+func (s *SQLiteStore) FillMovieMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error) {
+	changed := false
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		query := "SELECT " + movieColumns + " FROM movies WHERE id = ? AND deleted_at IS NULL"
+		before, err := scanMovie(tx.QueryRowContext(ctx, query, id))
+
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: movie %d", ErrItemNotFound, id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		if err := fillItemMetadata(ctx, tx, ItemTypeMovie, id, fill.normalize()); err != nil {
+			return err
+		}
+
+		after, err := scanMovie(tx.QueryRowContext(ctx, query, id))
+
+		if err != nil {
+			return fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		changed = !reflect.DeepEqual(before, after)
+
+		return recordAudit(ctx, tx, ItemTypeMovie, id, AuditUpdate, before, after)
+	})
+
+	return changed, err
+}
+
+// FillTVShowMetadata is FillMovieMetadata for TV shows, which have no runtime
+func (s *SQLiteStore) FillTVShowMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error) {
+	changed := false
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		query := "SELECT " + tvShowColumns + " FROM tv_shows WHERE id = ? AND deleted_at IS NULL"
+		before, err := scanTVShow(tx.QueryRowContext(ctx, query, id))
+
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: TV show %d", ErrItemNotFound, id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get TV show: %w", err)
+		}
+
+		fill = fill.normalize()
+		fill.Runtime = 0
+
+		if err := fillItemMetadata(ctx, tx, ItemTypeTVShow, id, fill); err != nil {
+			return err
+		}
+
+		after, err := scanTVShow(tx.QueryRowContext(ctx, query, id))
+
+		if err != nil {
+			return fmt.Errorf("failed to get TV show: %w", err)
+		}
+
+		changed = !reflect.DeepEqual(before, after)
+
+		return recordAudit(ctx, tx, ItemTypeTVShow, id, AuditUpdate, before, after)
+	})
+
+	return changed, err
+}
+
+// fillItemMetadata sets each empty column of an item's row to the fill's value
+// for it, keeping the column when the fill has none, and attaches the fill's
+// tags to an item without any
+func fillItemMetadata(ctx context.Context, tx *sql.Tx, itemType string, id int, fill MetadataFill) error {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return err
+	}
+
+	query := "UPDATE " + table + " SET" +
+		" year = COALESCE(NULLIF(year, 0), NULLIF(?, 0), year)," +
+		" imdb_link = COALESCE(NULLIF(imdb_link, ''), NULLIF(?, ''), imdb_link)," +
+		" poster_url = COALESCE(NULLIF(poster_url, ''), NULLIF(?, ''), poster_url)," +
+		" director = COALESCE(NULLIF(director, ''), NULLIF(?, ''), director)," +
+		" cast_members = COALESCE(NULLIF(cast_members, ''), NULLIF(?, ''), cast_members)"
+	args := []interface{}{fill.Year, fill.IMDBLink, fill.PosterURL, fill.Director, joinCast(fill.Cast)}
+
+	if itemType == ItemTypeMovie {
+		query += ", runtime = COALESCE(NULLIF(runtime, 0), NULLIF(?, 0), runtime)"
+		args = append(args, fill.Runtime)
+	}
+
+	if _, err := tx.ExecContext(ctx, query+" WHERE id = ?", append(args, id)...); err != nil {
+		return fmt.Errorf("failed to fill in %s metadata: %w", itemType, err)
+	}
+
+	if len(fill.Tags) == 0 {
+		return nil
+	}
+
+	join := tagJoins[itemType]
+
+	var tagged bool
+
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+join.table+" WHERE "+join.column+" = ?)", id).Scan(&tagged); err != nil {
+		return fmt.Errorf("failed to check tags: %w", err)
+	}
+
+	if tagged {
+		return nil
+	}
+
+	return setItemTags(ctx, tx, itemType, id, fill.Tags)
+}
+
+// GetPosterURLs returns every distinct poster URL, including those of trashed items
+func (s *SQLiteStore) GetPosterURLs(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT poster_url FROM movies WHERE poster_url != '' UNION SELECT poster_url FROM tv_shows WHERE poster_url != ''")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poster URLs: %w", err)
+	}
+
+	defer rows.Close()
+
+	var urls []string
+
+	for rows.Next() {
+		var url string
+
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("failed to scan poster URL: %w", err)
+		}
+
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read poster URLs: %w", err)
+	}
+
+	return urls, nil
+}
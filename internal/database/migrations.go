@@ -0,0 +1,1021 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Migration describes a single numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus pairs a migration with whether it has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// migrations lists every schema change in the order it must be applied.
+// Never edit or reorder an entry once it has shipped; add a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_movies_and_tv_shows",
+		// Databases created before migrations existed already have these
+		// tables (including the streaming and active_season columns added by
+		// the old best-effort ALTERs), so IF NOT EXISTS adopts them as-is.
+		Up: `
+		CREATE TABLE IF NOT EXISTS movies (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			year INTEGER,
+			genre TEXT,
+			streaming TEXT,
+			notes TEXT,
+			imdb_link TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			available_now INTEGER DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS tv_shows (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			year INTEGER,
+			genre TEXT,
+			streaming TEXT,
+			notes TEXT,
+			imdb_link TEXT,
+			active_season INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		Down: `
+		DROP TABLE IF EXISTS tv_shows;
+		DROP TABLE IF EXISTS movies;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_fts5_search_indexes",
+		// External-content FTS5 tables mirror the searchable columns and are
+		// kept in sync by triggers; the final inserts index existing rows.
+		Up: `
+		CREATE VIRTUAL TABLE movies_fts USING fts5(
+			title, notes, genre, streaming,
+			content='movies', content_rowid='id'
+		);
+
+		CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+			INSERT INTO movies_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+			INSERT INTO movies_fts(movies_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+			INSERT INTO movies_fts(movies_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+			INSERT INTO movies_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		CREATE VIRTUAL TABLE tv_shows_fts USING fts5(
+			title, notes, genre, streaming,
+			content='tv_shows', content_rowid='id'
+		);
+
+		CREATE TRIGGER tv_shows_fts_insert AFTER INSERT ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_delete AFTER DELETE ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(tv_shows_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_update AFTER UPDATE ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(tv_shows_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+			INSERT INTO tv_shows_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		INSERT INTO movies_fts(movies_fts) VALUES ('rebuild');
+		INSERT INTO tv_shows_fts(tv_shows_fts) VALUES ('rebuild');`,
+		Down: `
+		DROP TRIGGER IF EXISTS tv_shows_fts_update;
+		DROP TRIGGER IF EXISTS tv_shows_fts_delete;
+		DROP TRIGGER IF EXISTS tv_shows_fts_insert;
+		DROP TABLE IF EXISTS tv_shows_fts;
+		DROP TRIGGER IF EXISTS movies_fts_update;
+		DROP TRIGGER IF EXISTS movies_fts_delete;
+		DROP TRIGGER IF EXISTS movies_fts_insert;
+		DROP TABLE IF EXISTS movies_fts;`,
+	},
+	{
+		Version: 3,
+		Name:    "create_watch_events",
+		Up: `
+		CREATE TABLE watch_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			watcher TEXT NOT NULL,
+			watched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+			review TEXT
+		);
+
+		CREATE INDEX idx_watch_events_item ON watch_events(item_type, item_id);
+
+		ALTER TABLE movies ADD COLUMN watched INTEGER DEFAULT 0;
+		ALTER TABLE tv_shows ADD COLUMN watched INTEGER DEFAULT 0;`,
+		Down: `
+		ALTER TABLE tv_shows DROP COLUMN watched;
+		ALTER TABLE movies DROP COLUMN watched;
+		DROP INDEX IF EXISTS idx_watch_events_item;
+		DROP TABLE IF EXISTS watch_events;`,
+	},
+	{
+		Version: 4,
+		Name:    "create_tv_seasons_and_episodes",
+		Up: `
+		CREATE TABLE tv_seasons (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tv_show_id INTEGER NOT NULL,
+			number INTEGER NOT NULL,
+			UNIQUE (tv_show_id, number)
+		);
+
+		CREATE TABLE tv_episodes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			season_id INTEGER NOT NULL,
+			number INTEGER NOT NULL,
+			title TEXT DEFAULT '',
+			watched INTEGER DEFAULT 0,
+			watched_at DATETIME,
+			UNIQUE (season_id, number)
+		);
+
+		ALTER TABLE tv_shows ADD COLUMN next_episode_id INTEGER;
+
+		CREATE TRIGGER tv_seasons_delete AFTER DELETE ON tv_seasons BEGIN
+			DELETE FROM tv_episodes WHERE season_id = old.id;
+		END;
+
+		CREATE TRIGGER tv_shows_delete_seasons AFTER DELETE ON tv_shows BEGIN
+			DELETE FROM tv_seasons WHERE tv_show_id = old.id;
+		END;`,
+		Down: `
+		DROP TRIGGER IF EXISTS tv_shows_delete_seasons;
+		DROP TRIGGER IF EXISTS tv_seasons_delete;
+		ALTER TABLE tv_shows DROP COLUMN next_episode_id;
+		DROP TABLE IF EXISTS tv_episodes;
+		DROP TABLE IF EXISTS tv_seasons;`,
+	},
+	{
+		Version: 5,
+		Name:    "add_soft_delete",
+		Up: `
+		ALTER TABLE movies ADD COLUMN deleted_at DATETIME;
+		ALTER TABLE tv_shows ADD COLUMN deleted_at DATETIME;
+
+		CREATE INDEX idx_movies_deleted_at ON movies(deleted_at);
+		CREATE INDEX idx_tv_shows_deleted_at ON tv_shows(deleted_at);`,
+		Down: `
+		DELETE FROM movies WHERE deleted_at IS NOT NULL;
+		DELETE FROM tv_shows WHERE deleted_at IS NOT NULL;
+		DROP INDEX IF EXISTS idx_tv_shows_deleted_at;
+		DROP INDEX IF EXISTS idx_movies_deleted_at;
+		ALTER TABLE tv_shows DROP COLUMN deleted_at;
+		ALTER TABLE movies DROP COLUMN deleted_at;`,
+	},
+	{
+		Version: 6,
+		Name:    "create_audit_log",
+		Up: `
+		CREATE TABLE audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			actor TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			before_json TEXT DEFAULT '',
+			after_json TEXT DEFAULT ''
+		);
+
+		CREATE INDEX idx_audit_log_item ON audit_log(item_type, item_id);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_audit_log_item;
+		DROP TABLE IF EXISTS audit_log;`,
+	},
+	{
+		Version: 7,
+		Name:    "create_tags",
+		// Genres become many-to-many tags. Existing values are split on "+",
+		// "/" and ","; the search indexes are rebuilt to index tag names and are
+		// now regular FTS5 tables, since tags no longer live in the base table.
+		Up: `
+		CREATE TABLE tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE
+		);
+
+		CREATE TABLE movie_tags (
+			movie_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (movie_id, tag_id)
+		);
+
+		CREATE TABLE tv_show_tags (
+			tv_show_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (tv_show_id, tag_id)
+		);
+
+		CREATE INDEX idx_movie_tags_tag ON movie_tags(tag_id);
+		CREATE INDEX idx_tv_show_tags_tag ON tv_show_tags(tag_id);
+
+		CREATE TEMP TABLE genre_tags AS
+		WITH RECURSIVE split(item_type, item_id, name, rest) AS (
+			SELECT 'movie', id, '', replace(replace(genre, '+', ','), '/', ',') || ',' FROM movies WHERE trim(COALESCE(genre, '')) != ''
+			UNION ALL
+			SELECT 'tvshow', id, '', replace(replace(genre, '+', ','), '/', ',') || ',' FROM tv_shows WHERE trim(COALESCE(genre, '')) != ''
+			UNION ALL
+			SELECT item_type, item_id, trim(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1)
+			FROM split WHERE rest != ''
+		)
+		SELECT DISTINCT item_type, item_id, name FROM split WHERE name != '';
+
+		INSERT OR IGNORE INTO tags (name) SELECT name FROM genre_tags ORDER BY name;
+
+		INSERT OR IGNORE INTO movie_tags (movie_id, tag_id)
+		SELECT g.item_id, tags.id FROM genre_tags g JOIN tags ON tags.name = g.name WHERE g.item_type = 'movie';
+
+		INSERT OR IGNORE INTO tv_show_tags (tv_show_id, tag_id)
+		SELECT g.item_id, tags.id FROM genre_tags g JOIN tags ON tags.name = g.name WHERE g.item_type = 'tvshow';
+
+		DROP TABLE genre_tags;
+
+		DROP TRIGGER IF EXISTS movies_fts_update;
+		DROP TRIGGER IF EXISTS movies_fts_delete;
+		DROP TRIGGER IF EXISTS movies_fts_insert;
+		DROP TABLE IF EXISTS movies_fts;
+
+		DROP TRIGGER IF EXISTS tv_shows_fts_update;
+		DROP TRIGGER IF EXISTS tv_shows_fts_delete;
+		DROP TRIGGER IF EXISTS tv_shows_fts_insert;
+		DROP TABLE IF EXISTS tv_shows_fts;
+
+		ALTER TABLE movies DROP COLUMN genre;
+		ALTER TABLE tv_shows DROP COLUMN genre;
+
+		CREATE VIRTUAL TABLE movies_fts USING fts5(title, notes, tags, streaming);
+
+		CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+			INSERT INTO movies_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', new.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+			DELETE FROM movies_fts WHERE rowid = old.id;
+			DELETE FROM movie_tags WHERE movie_id = old.id;
+		END;
+
+		CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, notes, streaming ON movies BEGIN
+			UPDATE movies_fts SET title = new.title, notes = new.notes, streaming = new.streaming WHERE rowid = new.id;
+		END;
+
+		CREATE TRIGGER movie_tags_fts_insert AFTER INSERT ON movie_tags BEGIN
+			UPDATE movies_fts SET tags = (
+				SELECT group_concat(tags.name, ' ') FROM movie_tags j JOIN tags ON tags.id = j.tag_id WHERE j.movie_id = new.movie_id
+			) WHERE rowid = new.movie_id;
+		END;
+
+		CREATE TRIGGER movie_tags_fts_delete AFTER DELETE ON movie_tags BEGIN
+			UPDATE movies_fts SET tags = COALESCE((
+				SELECT group_concat(tags.name, ' ') FROM movie_tags j JOIN tags ON tags.id = j.tag_id WHERE j.movie_id = old.movie_id
+			), '') WHERE rowid = old.movie_id;
+		END;
+
+		INSERT INTO movies_fts(rowid, title, notes, tags, streaming)
+		SELECT id, title, notes, COALESCE((
+			SELECT group_concat(tags.name, ' ') FROM movie_tags j JOIN tags ON tags.id = j.tag_id WHERE j.movie_id = movies.id
+		), ''), streaming FROM movies;
+
+		CREATE VIRTUAL TABLE tv_shows_fts USING fts5(title, notes, tags, streaming);
+
+		CREATE TRIGGER tv_shows_fts_insert AFTER INSERT ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', new.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_delete AFTER DELETE ON tv_shows BEGIN
+			DELETE FROM tv_shows_fts WHERE rowid = old.id;
+			DELETE FROM tv_show_tags WHERE tv_show_id = old.id;
+		END;
+
+		CREATE TRIGGER tv_shows_fts_update AFTER UPDATE OF title, notes, streaming ON tv_shows BEGIN
+			UPDATE tv_shows_fts SET title = new.title, notes = new.notes, streaming = new.streaming WHERE rowid = new.id;
+		END;
+
+		CREATE TRIGGER tv_show_tags_fts_insert AFTER INSERT ON tv_show_tags BEGIN
+			UPDATE tv_shows_fts SET tags = (
+				SELECT group_concat(tags.name, ' ') FROM tv_show_tags j JOIN tags ON tags.id = j.tag_id WHERE j.tv_show_id = new.tv_show_id
+			) WHERE rowid = new.tv_show_id;
+		END;
+
+		CREATE TRIGGER tv_show_tags_fts_delete AFTER DELETE ON tv_show_tags BEGIN
+			UPDATE tv_shows_fts SET tags = COALESCE((
+				SELECT group_concat(tags.name, ' ') FROM tv_show_tags j JOIN tags ON tags.id = j.tag_id WHERE j.tv_show_id = old.tv_show_id
+			), '') WHERE rowid = old.tv_show_id;
+		END;
+
+		INSERT INTO tv_shows_fts(rowid, title, notes, tags, streaming)
+		SELECT id, title, notes, COALESCE((
+			SELECT group_concat(tags.name, ' ') FROM tv_show_tags j JOIN tags ON tags.id = j.tag_id WHERE j.tv_show_id = tv_shows.id
+		), ''), streaming FROM tv_shows;`,
+		Down: `
+		ALTER TABLE movies ADD COLUMN genre TEXT;
+		ALTER TABLE tv_shows ADD COLUMN genre TEXT;
+
+		UPDATE movies SET genre = (
+			SELECT group_concat(tags.name, ' + ') FROM movie_tags j JOIN tags ON tags.id = j.tag_id WHERE j.movie_id = movies.id
+		);
+
+		UPDATE tv_shows SET genre = (
+			SELECT group_concat(tags.name, ' + ') FROM tv_show_tags j JOIN tags ON tags.id = j.tag_id WHERE j.tv_show_id = tv_shows.id
+		);
+
+		DROP TRIGGER IF EXISTS movies_fts_update;
+		DROP TRIGGER IF EXISTS movies_fts_delete;
+		DROP TRIGGER IF EXISTS movies_fts_insert;
+		DROP TRIGGER IF EXISTS movie_tags_fts_insert;
+		DROP TRIGGER IF EXISTS movie_tags_fts_delete;
+		DROP TABLE IF EXISTS movies_fts;
+
+		DROP TRIGGER IF EXISTS tv_shows_fts_update;
+		DROP TRIGGER IF EXISTS tv_shows_fts_delete;
+		DROP TRIGGER IF EXISTS tv_shows_fts_insert;
+		DROP TRIGGER IF EXISTS tv_show_tags_fts_insert;
+		DROP TRIGGER IF EXISTS tv_show_tags_fts_delete;
+		DROP TABLE IF EXISTS tv_shows_fts;
+
+		DROP INDEX IF EXISTS idx_tv_show_tags_tag;
+		DROP INDEX IF EXISTS idx_movie_tags_tag;
+		DROP TABLE IF EXISTS tv_show_tags;
+		DROP TABLE IF EXISTS movie_tags;
+		DROP TABLE IF EXISTS tags;
+
+		CREATE VIRTUAL TABLE movies_fts USING fts5(
+			title, notes, genre, streaming,
+			content='movies', content_rowid='id'
+		);
+
+		CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+			INSERT INTO movies_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+			INSERT INTO movies_fts(movies_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_update AFTER UPDATE ON movies BEGIN
+			INSERT INTO movies_fts(movies_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+			INSERT INTO movies_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		INSERT INTO movies_fts(movies_fts) VALUES ('rebuild');
+
+		CREATE VIRTUAL TABLE tv_shows_fts USING fts5(
+			title, notes, genre, streaming,
+			content='tv_shows', content_rowid='id'
+		);
+
+		CREATE TRIGGER tv_shows_fts_insert AFTER INSERT ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_delete AFTER DELETE ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(tv_shows_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_update AFTER UPDATE ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(tv_shows_fts, rowid, title, notes, genre, streaming)
+			VALUES ('delete', old.id, old.title, old.notes, old.genre, old.streaming);
+			INSERT INTO tv_shows_fts(rowid, title, notes, genre, streaming)
+			VALUES (new.id, new.title, new.notes, new.genre, new.streaming);
+		END;
+
+		INSERT INTO tv_shows_fts(tv_shows_fts) VALUES ('rebuild');`,
+	},
+	{
+		Version: 8,
+		Name:    "create_availability",
+		// A title's single streaming service and manual available_now flag
+		// become a list of (service, from, until) windows. Each existing
+		// service becomes an open-ended window, except that a movie marked
+		// not available now gets one that ended yesterday; rolling back keeps
+		// the first current service and sets available_now from the current
+		// windows.
+		Up: `
+		CREATE TABLE availability (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			service TEXT NOT NULL,
+			available_from TEXT,
+			available_until TEXT
+		);
+
+		CREATE INDEX idx_availability_item ON availability(item_type, item_id);
+
+		INSERT INTO availability (item_type, item_id, service, available_until)
+		SELECT 'movie', id, trim(streaming), CASE WHEN available_now = 1 THEN NULL ELSE date('now', 'localtime', '-1 day') END
+		FROM movies WHERE trim(COALESCE(streaming, '')) != '';
+
+		INSERT INTO availability (item_type, item_id, service)
+		SELECT 'tvshow', id, trim(streaming) FROM tv_shows WHERE trim(COALESCE(streaming, '')) != '';
+
+		DROP TRIGGER movies_fts_insert;
+		DROP TRIGGER movies_fts_update;
+		DROP TRIGGER tv_shows_fts_insert;
+		DROP TRIGGER tv_shows_fts_update;
+
+		ALTER TABLE movies DROP COLUMN streaming;
+		ALTER TABLE movies DROP COLUMN available_now;
+		ALTER TABLE tv_shows DROP COLUMN streaming;
+
+		CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+			INSERT INTO movies_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', '');
+		END;
+
+		CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, notes ON movies BEGIN
+			UPDATE movies_fts SET title = new.title, notes = new.notes WHERE rowid = new.id;
+		END;
+
+		CREATE TRIGGER tv_shows_fts_insert AFTER INSERT ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', '');
+		END;
+
+		CREATE TRIGGER tv_shows_fts_update AFTER UPDATE OF title, notes ON tv_shows BEGIN
+			UPDATE tv_shows_fts SET title = new.title, notes = new.notes WHERE rowid = new.id;
+		END;
+
+		CREATE TRIGGER availability_movies_fts_insert AFTER INSERT ON availability WHEN new.item_type = 'movie' BEGIN
+			UPDATE movies_fts SET streaming = (
+				SELECT group_concat(service, ' ') FROM availability WHERE item_type = 'movie' AND item_id = new.item_id
+			) WHERE rowid = new.item_id;
+		END;
+
+		CREATE TRIGGER availability_movies_fts_delete AFTER DELETE ON availability WHEN old.item_type = 'movie' BEGIN
+			UPDATE movies_fts SET streaming = COALESCE((
+				SELECT group_concat(service, ' ') FROM availability WHERE item_type = 'movie' AND item_id = old.item_id
+			), '') WHERE rowid = old.item_id;
+		END;
+
+		CREATE TRIGGER movies_delete_availability AFTER DELETE ON movies BEGIN
+			DELETE FROM availability WHERE item_type = 'movie' AND item_id = old.id;
+		END;
+
+		CREATE TRIGGER availability_tv_shows_fts_insert AFTER INSERT ON availability WHEN new.item_type = 'tvshow' BEGIN
+			UPDATE tv_shows_fts SET streaming = (
+				SELECT group_concat(service, ' ') FROM availability WHERE item_type = 'tvshow' AND item_id = new.item_id
+			) WHERE rowid = new.item_id;
+		END;
+
+		CREATE TRIGGER availability_tv_shows_fts_delete AFTER DELETE ON availability WHEN old.item_type = 'tvshow' BEGIN
+			UPDATE tv_shows_fts SET streaming = COALESCE((
+				SELECT group_concat(service, ' ') FROM availability WHERE item_type = 'tvshow' AND item_id = old.item_id
+			), '') WHERE rowid = old.item_id;
+		END;
+
+		CREATE TRIGGER tv_shows_delete_availability AFTER DELETE ON tv_shows BEGIN
+			DELETE FROM availability WHERE item_type = 'tvshow' AND item_id = old.id;
+		END;`,
+		Down: `
+		DROP TRIGGER IF EXISTS tv_shows_delete_availability;
+		DROP TRIGGER IF EXISTS availability_tv_shows_fts_delete;
+		DROP TRIGGER IF EXISTS availability_tv_shows_fts_insert;
+		DROP TRIGGER IF EXISTS movies_delete_availability;
+		DROP TRIGGER IF EXISTS availability_movies_fts_delete;
+		DROP TRIGGER IF EXISTS availability_movies_fts_insert;
+		DROP TRIGGER IF EXISTS tv_shows_fts_update;
+		DROP TRIGGER IF EXISTS tv_shows_fts_insert;
+		DROP TRIGGER IF EXISTS movies_fts_update;
+		DROP TRIGGER IF EXISTS movies_fts_insert;
+
+		ALTER TABLE movies ADD COLUMN streaming TEXT;
+		ALTER TABLE movies ADD COLUMN available_now INTEGER DEFAULT 0;
+		ALTER TABLE tv_shows ADD COLUMN streaming TEXT;
+
+		UPDATE movies SET
+			streaming = (
+				SELECT service FROM availability
+				WHERE item_type = 'movie' AND item_id = movies.id
+				ORDER BY (available_from IS NULL OR available_from <= date('now', 'localtime')) AND (available_until IS NULL OR available_until >= date('now', 'localtime')) DESC, id
+				LIMIT 1
+			),
+			available_now = EXISTS (
+				SELECT 1 FROM availability
+				WHERE item_type = 'movie' AND item_id = movies.id
+				AND (available_from IS NULL OR available_from <= date('now', 'localtime')) AND (available_until IS NULL OR available_until >= date('now', 'localtime'))
+			);
+
+		UPDATE tv_shows SET streaming = (
+			SELECT service FROM availability
+			WHERE item_type = 'tvshow' AND item_id = tv_shows.id
+			ORDER BY (available_from IS NULL OR available_from <= date('now', 'localtime')) AND (available_until IS NULL OR available_until >= date('now', 'localtime')) DESC, id
+			LIMIT 1
+		);
+
+		UPDATE movies_fts SET streaming = COALESCE((SELECT streaming FROM movies WHERE movies.id = movies_fts.rowid), '');
+		UPDATE tv_shows_fts SET streaming = COALESCE((SELECT streaming FROM tv_shows WHERE tv_shows.id = tv_shows_fts.rowid), '');
+
+		CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+			INSERT INTO movies_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', new.streaming);
+		END;
+
+		CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, notes, streaming ON movies BEGIN
+			UPDATE movies_fts SET title = new.title, notes = new.notes, streaming = new.streaming WHERE rowid = new.id;
+		END;
+
+		CREATE TRIGGER tv_shows_fts_insert AFTER INSERT ON tv_shows BEGIN
+			INSERT INTO tv_shows_fts(rowid, title, notes, tags, streaming)
+			VALUES (new.id, new.title, new.notes, '', new.streaming);
+		END;
+
+		CREATE TRIGGER tv_shows_fts_update AFTER UPDATE OF title, notes, streaming ON tv_shows BEGIN
+			UPDATE tv_shows_fts SET title = new.title, notes = new.notes, streaming = new.streaming WHERE rowid = new.id;
+		END;
+
+		DROP INDEX IF EXISTS idx_availability_item;
+		DROP TABLE IF EXISTS availability;`,
+	},
+	{
+		Version: 9,
+		Name:    "allow_unrated_watch_events",
+		// Viewings imported from other services may have no rating, stored
+		// as 0. SQLite can't change a CHECK constraint in place, so the table
+		// is rebuilt; rolling back drops the unrated viewings.
+		Up: `
+		CREATE TABLE watch_events_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			watcher TEXT NOT NULL,
+			watched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			rating INTEGER NOT NULL CHECK (rating BETWEEN 0 AND 5),
+			review TEXT
+		);
+
+		INSERT INTO watch_events_new SELECT id, item_type, item_id, watcher, watched_at, rating, review FROM watch_events;
+
+		DROP INDEX IF EXISTS idx_watch_events_item;
+		DROP TABLE watch_events;
+		ALTER TABLE watch_events_new RENAME TO watch_events;
+
+		CREATE INDEX idx_watch_events_item ON watch_events(item_type, item_id);`,
+		Down: `
+		CREATE TABLE watch_events_old (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			watcher TEXT NOT NULL,
+			watched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+			review TEXT
+		);
+
+		INSERT INTO watch_events_old SELECT id, item_type, item_id, watcher, watched_at, rating, review FROM watch_events WHERE rating > 0;
+
+		DROP INDEX IF EXISTS idx_watch_events_item;
+		DROP TABLE watch_events;
+		ALTER TABLE watch_events_old RENAME TO watch_events;
+
+		CREATE INDEX idx_watch_events_item ON watch_events(item_type, item_id);`,
+	},
+	{
+		Version: 10,
+		Name:    "add_movie_runtime_and_picks",
+		// Runtime is in minutes, 0 when unknown. picks records every random
+		// pick so the pickers can skip recent ones; purging an item drops its
+		// picks the way it drops its availability.
+		Up: `
+		ALTER TABLE movies ADD COLUMN runtime INTEGER NOT NULL DEFAULT 0;
+
+		CREATE TABLE picks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_type TEXT NOT NULL CHECK (item_type IN ('movie', 'tvshow')),
+			item_id INTEGER NOT NULL,
+			picked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX idx_picks_item ON picks(item_type, item_id, picked_at);
+
+		CREATE TRIGGER movies_delete_picks AFTER DELETE ON movies BEGIN
+			DELETE FROM picks WHERE item_type = 'movie' AND item_id = old.id;
+		END;
+
+		CREATE TRIGGER tv_shows_delete_picks AFTER DELETE ON tv_shows BEGIN
+			DELETE FROM picks WHERE item_type = 'tvshow' AND item_id = old.id;
+		END;`,
+		Down: `
+		DROP TRIGGER IF EXISTS tv_shows_delete_picks;
+		DROP TRIGGER IF EXISTS movies_delete_picks;
+		DROP INDEX IF EXISTS idx_picks_item;
+		DROP TABLE IF EXISTS picks;
+
+		ALTER TABLE movies DROP COLUMN runtime;`,
+	},
+	{
+		Version: 11,
+		Name:    "add_metadata_details",
+		// Cast members are stored one per line. metadata_checked_at records
+		// the last time the enrichment job looked an item up, so titles a
+		// provider does not know are not looked up on every run.
+		Up: `
+		ALTER TABLE movies ADD COLUMN poster_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE movies ADD COLUMN director TEXT NOT NULL DEFAULT '';
+		ALTER TABLE movies ADD COLUMN cast_members TEXT NOT NULL DEFAULT '';
+		ALTER TABLE movies ADD COLUMN metadata_checked_at DATETIME;
+
+		ALTER TABLE tv_shows ADD COLUMN poster_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE tv_shows ADD COLUMN director TEXT NOT NULL DEFAULT '';
+		ALTER TABLE tv_shows ADD COLUMN cast_members TEXT NOT NULL DEFAULT '';
+		ALTER TABLE tv_shows ADD COLUMN metadata_checked_at DATETIME;`,
+		Down: `
+		ALTER TABLE tv_shows DROP COLUMN metadata_checked_at;
+		ALTER TABLE tv_shows DROP COLUMN cast_members;
+		ALTER TABLE tv_shows DROP COLUMN director;
+		ALTER TABLE tv_shows DROP COLUMN poster_url;
+
+		ALTER TABLE movies DROP COLUMN metadata_checked_at;
+		ALTER TABLE movies DROP COLUMN cast_members;
+		ALTER TABLE movies DROP COLUMN director;
+		ALTER TABLE movies DROP COLUMN poster_url;`,
+	},
+	{
+		Version: 12,
+		Name:    "create_imdb_titles",
+		// A local copy of IMDb's title.basics dataset, limited to movies and
+		// series. search_title holds NormalizeTitle(title) so autocomplete is
+		// a range scan on the index; genres are comma separated.
+		Up: `
+		CREATE TABLE imdb_titles (
+			id TEXT PRIMARY KEY,
+			item_type TEXT NOT NULL,
+			title TEXT NOT NULL,
+			search_title TEXT NOT NULL,
+			year INTEGER NOT NULL DEFAULT 0,
+			end_year INTEGER NOT NULL DEFAULT 0,
+			runtime INTEGER NOT NULL DEFAULT 0,
+			genres TEXT NOT NULL DEFAULT ''
+		) WITHOUT ROWID;
+
+		CREATE INDEX idx_imdb_titles_search ON imdb_titles(item_type, search_title);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_imdb_titles_search;
+		DROP TABLE IF EXISTS imdb_titles;`,
+	},
+	{
+		Version: 13,
+		Name:    "create_users_and_sessions",
+		// Sessions are keyed by a hash of the cookie's token, so a copy of the
+		// database cannot be used to sign in
+		Up: `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		);
+
+		CREATE INDEX idx_sessions_user ON sessions(user_id);
+		CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_sessions_expires_at;
+		DROP INDEX IF EXISTS idx_sessions_user;
+		DROP TABLE IF EXISTS sessions;
+		DROP TABLE IF EXISTS users;`,
+	},
+	{
+		Version: 14,
+		Name:    "create_api_tokens",
+		// Like sessions, tokens are stored as hashes; scopes is a comma-separated list
+		Up: `
+		CREATE TABLE api_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME
+		);
+
+		CREATE INDEX idx_api_tokens_user ON api_tokens(user_id);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_api_tokens_user;
+		DROP TABLE IF EXISTS api_tokens;`,
+	},
+	{
+		Version: 15,
+		Name:    "create_profiles_and_votes",
+		// Profiles are household members, not accounts; each has at most one
+		// vote per title
+		Up: `
+		CREATE TABLE profiles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE profile_votes (
+			profile_id INTEGER NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+			item_type TEXT NOT NULL,
+			item_id INTEGER NOT NULL,
+			vote TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (profile_id, item_type, item_id)
+		);
+
+		CREATE INDEX idx_profile_votes_item ON profile_votes(item_type, item_id);`,
+		Down: `
+		DROP INDEX IF EXISTS idx_profile_votes_item;
+		DROP TABLE IF EXISTS profile_votes;
+		DROP TABLE IF EXISTS profiles;`,
+	},
+}
+
+// LatestSchemaVersion returns the highest migration version known to this build
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureMigrationsTable creates the schema_migrations bookkeeping table
+func ensureMigrationsTable(conn *sql.DB) error {
+	createSQL := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := conn.Exec(createSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// appliedMigrations returns the applied_at timestamp of every applied version
+func appliedMigrations(conn *sql.DB) (map[int]string, error) {
+	rows, err := conn.Query("SELECT version, applied_at FROM schema_migrations ORDER BY version")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]string)
+
+	for rows.Next() {
+		var version int
+		var appliedAt string
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema migration: %w", err)
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// schemaVersion returns the highest applied migration version, or 0
+func schemaVersion(conn *sql.DB) (int, error) {
+	var version sql.NullInt64
+
+	err := conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// checkSchemaNotNewer refuses to continue when the database was migrated by a newer build
+func checkSchemaNotNewer(conn *sql.DB) error {
+	current, err := schemaVersion(conn)
+
+	if err != nil {
+		return err
+	}
+
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d; refusing to start", current, LatestSchemaVersion())
+	}
+
+	return nil
+}
+
+// migrateUp applies every pending migration, each in its own transaction
+func migrateUp(conn *sql.DB) error {
+	if err := ensureMigrationsTable(conn); err != nil {
+		return err
+	}
+
+	if err := checkSchemaNotNewer(conn); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(conn)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		logger.Info("Applying migration %d: %s", m.Version, m.Name)
+
+		if err := runMigration(conn, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name)
+
+			return err
+		}); err != nil {
+			logger.ErrorWithErr("Migration %d failed", err, m.Version)
+
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateDown rolls back applied migrations, newest first, until only versions <= target remain
+func migrateDown(conn *sql.DB, target int) error {
+	if err := ensureMigrationsTable(conn); err != nil {
+		return err
+	}
+
+	if err := checkSchemaNotNewer(conn); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(conn)
+
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+
+		if m.Version <= target {
+			break
+		}
+
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		logger.Info("Rolling back migration %d: %s", m.Version, m.Name)
+
+		if err := runMigration(conn, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+
+			return err
+		}); err != nil {
+			logger.ErrorWithErr("Rollback of migration %d failed", err, m.Version)
+
+			return fmt.Errorf("rollback of migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// runMigration executes a migration script and its bookkeeping inside one transaction
+func runMigration(conn *sql.DB, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+
+	if err := record(tx); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// migrationStatus reports every known migration and whether it has been applied
+func migrationStatus(conn *sql.DB) ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(conn); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{Migration: m, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies all pending migrations to the open database
+func (s *SQLiteStore) MigrateUp() error {
+	return migrateUp(s.db)
+}
+
+// MigrateDown rolls the open database back to the given schema version
+func (s *SQLiteStore) MigrateDown(target int) error {
+	return migrateDown(s.db, target)
+}
+
+// SchemaVersion returns the schema version of the open database
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	if err := ensureMigrationsTable(s.db); err != nil {
+		return 0, err
+	}
+
+	return schemaVersion(s.db)
+}
+
+// GetMigrationStatus lists every known migration along with its applied state
+func (s *SQLiteStore) GetMigrationStatus() ([]MigrationStatus, error) {
+	return migrationStatus(s.db)
+}
+
+// GetPendingMigrations lists migrations that have not been applied yet
+func (s *SQLiteStore) GetPendingMigrations() ([]Migration, error) {
+	statuses, err := migrationStatus(s.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+// openTestDB opens an empty database in a temporary directory without migrating it
+func openTestDB(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	store, err := OpenDB(t.TempDir(), "test")
+
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestMigrationsAreNumberedInOrder(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migrations[%d] has version %d, want %d", i, m.Version, i+1)
+		}
+
+		if m.Name == "" || strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d needs a name and both scripts", m.Version)
+		}
+	}
+
+	if LatestSchemaVersion() != len(migrations) {
+		t.Errorf("LatestSchemaVersion() = %d, want %d", LatestSchemaVersion(), len(migrations))
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	latest := LatestSchemaVersion()
+
+	tests := []struct {
+		name   string
+		target int
+	}{
+		{"everything", 0},
+		{"to the first", 1},
+		{"before availability windows", 7},
+		{"before accounts", 12},
+		{"the newest only", latest - 1},
+		{"nothing", latest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := openTestDB(t)
+
+			if err := store.MigrateUp(); err != nil {
+				t.Fatalf("MigrateUp: %v", err)
+			}
+
+			if err := store.MigrateDown(tt.target); err != nil {
+				t.Fatalf("MigrateDown(%d): %v", tt.target, err)
+			}
+
+			if version, err := store.SchemaVersion(); err != nil || version != tt.target {
+				t.Fatalf("SchemaVersion after down = %d, %v; want %d", version, err, tt.target)
+			}
+
+			pending, err := store.GetPendingMigrations()
+
+			if err != nil || len(pending) != latest-tt.target {
+				t.Fatalf("GetPendingMigrations = %d, %v; want %d", len(pending), err, latest-tt.target)
+			}
+
+			if err := store.MigrateUp(); err != nil {
+				t.Fatalf("MigrateUp again: %v", err)
+			}
+
+			if version, err := store.SchemaVersion(); err != nil || version != latest {
+				t.Errorf("SchemaVersion after up = %d, %v; want %d", version, err, latest)
+			}
+		})
+	}
+}
+
+func TestMigrateUpIsIdempotent(t *testing.T) {
+	store := openTestDB(t)
+
+	for i := 0; i < 2; i++ {
+		if err := store.MigrateUp(); err != nil {
+			t.Fatalf("MigrateUp #%d: %v", i+1, err)
+		}
+	}
+
+	statuses, err := store.GetMigrationStatus()
+
+	if err != nil {
+		t.Fatalf("GetMigrationStatus: %v", err)
+	}
+
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt == "" {
+			t.Errorf("migration %d: applied = %v at %q", status.Version, status.Applied, status.AppliedAt)
+		}
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	store := openTestDB(t)
+
+	if err := store.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	if _, err := store.db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, 'from_the_future')", LatestSchemaVersion()+1); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+
+	for name, migrate := range map[string]func() error{
+		"up":   store.MigrateUp,
+		"down": func() error { return store.MigrateDown(0) },
+	} {
+		if err := migrate(); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("migrate %s error = %v, want a newer schema error", name, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Pools the TV show picker draws from
+const (
+	PoolAny        = ""        // shows with an active season or started but not finished
+	PoolActive     = "active"  // shows with an active season
+	PoolInProgress = "started" // shows with watched episodes and a next episode
+)
+
+// PickOptions narrows a random pick. Every option left at its zero value
+// matches everything.
+type PickOptions struct {
+	Tags          []string // tags the pick must carry, such as a genre
+	Service       string   // a service the pick streams on today
+	MaxRuntime    int      // minutes; movies without a runtime are left out when set
+	NotPickedDays int      // leave out items picked within this many days
+	Exclude       []int    // IDs already offered, as on a reroll
+	VetoedBy      []int    // profiles watching tonight; titles any of them vetoed are left out
+	Pool          string   // TV shows only
+}
+
+// ValidPool reports whether pool is a known TV show picker pool
+func ValidPool(pool string) bool {
+	switch pool {
+	case PoolAny, PoolActive, PoolInProgress:
+		return true
+	}
+
+	return false
+}
+
+// tvShowPoolSQL selects the TV shows in each picker pool
+var tvShowPoolSQL = map[string]string{
+	PoolActive: "tv_shows.active_season = 1",
+	PoolInProgress: "(tv_shows.next_episode_id IS NOT NULL AND EXISTS (SELECT 1 FROM tv_episodes e JOIN tv_seasons s ON s.id = e.season_id " +
+		"WHERE s.tv_show_id = tv_shows.id AND e.watched = 1))",
+}
+
+// pickWeight is how strongly an item is favoured: one more than the days it
+// has been on the list, so titles that have waited longest come up more often
+func pickWeight(added, now time.Time) float64 {
+	days := now.Sub(added).Hours() / 24
+
+	return max(days, 0) + 1
+}
+
+// weightedChoice returns an index drawn with probability proportional to its weight
+func weightedChoice(weights []float64) int {
+	total := 0.0
+
+	for _, weight := range weights {
+		total += weight
+	}
+
+	target := rand.Float64() * total
+
+	for i, weight := range weights {
+		if target < weight {
+			return i
+		}
+
+		target -= weight
+	}
+
+	return len(weights) - 1
+}
+
+// excluded reports whether id is in ids
+func excluded(ids []int, id int) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// availability is what the picker asks of an item's streaming windows: movies
+// must stream somewhere today, while TV shows in progress may be watched
+// anywhere unless a service is chosen
+func (options PickOptions) availability(itemType string) string {
+	if itemType == ItemTypeMovie || options.Service != "" {
+		return AvailableNow
+	}
+
+	return AvailableAny
+}
+
+// pickClause builds the conditions PickOptions add to a board's candidates
+func (t boardTable) pickClause(options PickOptions) (string, []interface{}) {
+	clause, args := t.filterClause(BoardQuery{Tags: options.Tags, Service: options.Service, Availability: options.availability(t.itemType)})
+
+	if options.NotPickedDays > 0 {
+		clause += " AND NOT EXISTS (SELECT 1 FROM picks p WHERE p.item_type = ? AND p.item_id = " + t.name + ".id AND p.picked_at >= datetime('now', ?))"
+		args = append(args, t.itemType, fmt.Sprintf("-%d days", options.NotPickedDays))
+	}
+
+	if len(options.Exclude) > 0 {
+		clause += " AND " + t.name + ".id NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(options.Exclude)), ", ") + ")"
+
+		for _, id := range options.Exclude {
+			args = append(args, id)
+		}
+	}
+
+	if len(options.VetoedBy) > 0 {
+		clause += " AND NOT EXISTS (SELECT 1 FROM profile_votes v WHERE v.item_type = ? AND v.item_id = " + t.name + ".id AND v.vote = ? AND v.profile_id IN (" +
+			strings.TrimSuffix(strings.Repeat("?, ", len(options.VetoedBy)), ", ") + "))"
+		args = append(args, t.itemType, VoteVeto)
+
+		for _, id := range options.VetoedBy {
+			args = append(args, id)
+		}
+	}
+
+	return clause, args
+}
+
+// pick draws one candidate from a board, weighted by its time on the list,
+// and records the pick. where selects the candidates; it returns 0 when there are none.
+func (s *SQLiteStore) pick(ctx context.Context, t boardTable, where string, args []interface{}) (int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+t.name+".id, CAST("+t.name+".created_at AS TEXT) FROM "+t.name+" WHERE "+where, args...)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pick candidates: %w", err)
+	}
+
+	defer rows.Close()
+
+	var ids []int
+	var weights []float64
+
+	now := time.Now().UTC()
+
+	for rows.Next() {
+		var id int
+		var created sql.NullString
+
+		if err := rows.Scan(&id, &created); err != nil {
+			return 0, fmt.Errorf("failed to scan pick candidate: %w", err)
+		}
+
+		added, _ := time.Parse(time.DateTime, created.String)
+
+		ids = append(ids, id)
+		weights = append(weights, pickWeight(added, now))
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read pick candidates: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	id := ids[weightedChoice(weights)]
+
+	if _, err := s.db.ExecContext(ctx, "INSERT INTO picks (item_type, item_id) VALUES (?, ?)", t.itemType, id); err != nil {
+		return 0, fmt.Errorf("failed to record pick: %w", err)
+	}
+
+	return id, nil
+}
+
+// PickMovie draws a random watchlist movie streaming somewhere today and
+// records the pick, or returns nil when no movie matches the options
+func (s *SQLiteStore) PickMovie(ctx context.Context, options PickOptions) (*Movie, error) {
+	clause, args := movieBoard.pickClause(options)
+
+	if options.MaxRuntime > 0 {
+		clause += " AND movies.runtime BETWEEN 1 AND ?"
+		args = append(args, options.MaxRuntime)
+	}
+
+	id, err := s.pick(ctx, movieBoard, "movies.watched = 0 AND movies.deleted_at IS NULL"+clause, args)
+
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return s.GetMovie(ctx, id)
+}
+
+// PickTVShow draws a random watchlist TV show from the options' pool and
+// records the pick, or returns nil when no show matches the options
+func (s *SQLiteStore) PickTVShow(ctx context.Context, options PickOptions) (*TVShow, error) {
+	if !ValidPool(options.Pool) {
+		return nil, fmt.Errorf("%w: unknown pool %q", ErrInvalidQuery, options.Pool)
+	}
+
+	clause, args := tvShowBoard.pickClause(options)
+	pool := tvShowPoolSQL[options.Pool]
+
+	if options.Pool == PoolAny {
+		pool = "(" + tvShowPoolSQL[PoolActive] + " OR " + tvShowPoolSQL[PoolInProgress] + ")"
+	}
+
+	id, err := s.pick(ctx, tvShowBoard, "tv_shows.watched = 0 AND tv_shows.deleted_at IS NULL AND "+pool+clause, args)
+
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return s.GetTVShow(ctx, id)
+}
@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// pickAll draws until nothing is left, excluding each pick from the next
+// draw as a reroll does, and returns every ID that came up in order
+func pickAll(t *testing.T, pick func(options PickOptions) (int, error), options PickOptions) []int {
+	t.Helper()
+
+	var ids []int
+
+	for {
+		id, err := pick(options)
+
+		if err != nil {
+			t.Fatalf("pick(%+v): %v", options, err)
+		}
+
+		if id == 0 {
+			break
+		}
+
+		if slices.Contains(ids, id) {
+			t.Fatalf("pick(%+v) offered %d again", options, id)
+		}
+
+		ids = append(ids, id)
+		options.Exclude = append(options.Exclude, id)
+	}
+
+	slices.Sort(ids)
+
+	return ids
+}
+
+func TestPickMovie(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		netflix := AvailabilityList{{Service: "Netflix"}}
+
+		short := mustAddMovie(t, store, Movie{Title: "Short", Runtime: 85, Tags: []string{"Comedy"}, Availability: netflix})
+		long := mustAddMovie(t, store, Movie{Title: "Long", Runtime: 170, Tags: []string{"Crime", "Drama"}, Availability: netflix})
+		unknown := mustAddMovie(t, store, Movie{Title: "No Runtime", Tags: []string{"Drama"}, Availability: AvailabilityList{{Service: "Hulu"}}})
+		mustAddMovie(t, store, Movie{Title: "Not Streaming", Runtime: 90, Tags: []string{"Comedy"}})
+		mustAddMovie(t, store, Movie{Title: "Left Netflix", Runtime: 90, Availability: AvailabilityList{{Service: "Netflix", Until: day(-1)}}})
+		watched := mustAddMovie(t, store, Movie{Title: "Watched", Runtime: 90, Availability: netflix})
+		trashed := mustAddMovie(t, store, Movie{Title: "Trashed", Runtime: 90, Availability: netflix})
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: watched}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		if err := store.DeleteMovie(ctx, trashed); err != nil {
+			t.Fatalf("DeleteMovie: %v", err)
+		}
+
+		vetoer, err := store.AddProfile(ctx, "Sam")
+
+		if err != nil {
+			t.Fatalf("AddProfile: %v", err)
+		}
+
+		if err := store.SetVote(ctx, Vote{ProfileID: vetoer, ItemType: ItemTypeMovie, ItemID: long, Vote: VoteVeto}); err != nil {
+			t.Fatalf("SetVote: %v", err)
+		}
+
+		pick := func(options PickOptions) (int, error) {
+			movie, err := store.PickMovie(ctx, options)
+
+			if err != nil || movie == nil {
+				return 0, err
+			}
+
+			return movie.ID, nil
+		}
+
+		tests := []struct {
+			name    string
+			options PickOptions
+			want    []int
+		}{
+			{"streaming today", PickOptions{}, []int{short, long, unknown}},
+			{"tag", PickOptions{Tags: []string{"drama"}}, []int{long, unknown}},
+			{"every tag", PickOptions{Tags: []string{"Crime", "Drama"}}, []int{long}},
+			{"service", PickOptions{Service: "Netflix"}, []int{short, long}},
+			{"max runtime leaves out unknown runtimes", PickOptions{MaxRuntime: 120}, []int{short}},
+			{"already offered", PickOptions{Exclude: []int{short}}, []int{long, unknown}},
+			{"vetoed", PickOptions{VetoedBy: []int{vetoer}}, []int{short, unknown}},
+			{"vetoed by someone else", PickOptions{VetoedBy: []int{vetoer + 1}}, []int{short, long, unknown}},
+			{"nothing left", PickOptions{Tags: []string{"Western"}}, nil},
+		}
+
+		for _, tt := range tests {
+			if got := pickAll(t, pick, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: picked %v, want %v", tt.name, got, tt.want)
+			}
+		}
+
+		// Every candidate has come up by now, so none was left unpicked today
+		if got := pickAll(t, pick, PickOptions{NotPickedDays: 1}); got != nil {
+			t.Errorf("picks within a day: picked %v, want none", got)
+		}
+	})
+}
+
+func TestPickTVShow(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		active := mustAddTVShow(t, store, TVShow{Title: "Active", ActiveSeason: true, Tags: []string{"Drama"}})
+		started := mustAddTVShow(t, store, TVShow{Title: "Started", Availability: AvailabilityList{{Service: "Max"}}})
+		finished := mustAddTVShow(t, store, TVShow{Title: "Finished"})
+		mustAddTVShow(t, store, TVShow{Title: "Untouched"})
+
+		for id, watched := range map[int]int{started: 1, finished: 2} {
+			if err := store.AddSeason(ctx, id, 1, 2); err != nil {
+				t.Fatalf("AddSeason: %v", err)
+			}
+
+			for range watched {
+				if err := store.WatchNextEpisode(ctx, id); err != nil {
+					t.Fatalf("WatchNextEpisode: %v", err)
+				}
+			}
+		}
+
+		pick := func(options PickOptions) (int, error) {
+			tvShow, err := store.PickTVShow(ctx, options)
+
+			if err != nil || tvShow == nil {
+				return 0, err
+			}
+
+			return tvShow.ID, nil
+		}
+
+		tests := []struct {
+			name    string
+			options PickOptions
+			want    []int
+		}{
+			{"any pool", PickOptions{}, []int{active, started}},
+			{"active", PickOptions{Pool: PoolActive}, []int{active}},
+			{"in progress", PickOptions{Pool: PoolInProgress}, []int{started}},
+			{"service", PickOptions{Service: "Max"}, []int{started}},
+			{"tag", PickOptions{Tags: []string{"Drama"}, Pool: PoolInProgress}, nil},
+		}
+
+		for _, tt := range tests {
+			if got := pickAll(t, pick, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: picked %v, want %v", tt.name, got, tt.want)
+			}
+		}
+
+		if _, err := store.PickTVShow(ctx, PickOptions{Pool: "someday"}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("PickTVShow with an unknown pool: error = %v, want ErrInvalidQuery", err)
+		}
+	})
+}
+
+func TestPickTVShowNextEpisode(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		lost := mustAddTVShow(t, store, TVShow{Title: "Lost"})
+		watched := mustAddTVShow(t, store, TVShow{Title: "Watched", ActiveSeason: true})
+		trashed := mustAddTVShow(t, store, TVShow{Title: "Trashed", ActiveSeason: true})
+		vetoed := mustAddTVShow(t, store, TVShow{Title: "Vetoed", ActiveSeason: true})
+
+		for _, season := range []struct{ number, episodes int }{{1, 2}, {2, 3}} {
+			if err := store.AddSeason(ctx, lost, season.number, season.episodes); err != nil {
+				t.Fatalf("AddSeason(%d): %v", season.number, err)
+			}
+		}
+
+		if err := store.MarkTVShowWatched(ctx, WatchEvent{ItemID: watched}); err != nil {
+			t.Fatalf("MarkTVShowWatched: %v", err)
+		}
+
+		if err := store.DeleteTVShow(ctx, trashed); err != nil {
+			t.Fatalf("DeleteTVShow: %v", err)
+		}
+
+		sam := mustAddProfile(t, store, "Sam")
+
+		if err := store.SetVote(ctx, Vote{ProfileID: sam, ItemType: ItemTypeTVShow, ItemID: vetoed, Vote: VoteVeto}); err != nil {
+			t.Fatalf("SetVote: %v", err)
+		}
+
+		// pickStarted returns the next episode of the show picked from those
+		// in progress, or "" when none is
+		pickStarted := func() string {
+			t.Helper()
+
+			tvShow, err := store.PickTVShow(ctx, PickOptions{Pool: PoolInProgress})
+
+			if err != nil {
+				t.Fatalf("PickTVShow: %v", err)
+			}
+
+			if tvShow == nil {
+				return ""
+			}
+
+			return tvShow.Progress.Label()
+		}
+
+		// A show joins the pool once an episode is watched, comes with the
+		// episode to watch next and leaves once it is caught up
+		steps := []struct {
+			name string
+			step func() error
+			want string
+		}{
+			{"nothing watched", func() error { return nil }, ""},
+			{"first episode", func() error { return store.WatchNextEpisode(ctx, lost) }, "S01E02 of 2"},
+			{"end of season 1", func() error { return store.WatchNextEpisode(ctx, lost) }, "S02E01 of 3"},
+			{"skip to the finale", func() error {
+				seasons, err := store.GetSeasons(ctx, lost)
+
+				if err != nil {
+					return err
+				}
+
+				return store.SetEpisodeWatched(ctx, lost, seasons[1].Episodes[2].ID, true)
+			}, ""},
+		}
+
+		for _, tt := range steps {
+			if err := tt.step(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+
+			if got := pickStarted(); got != tt.want {
+				t.Errorf("%s: picked %q, want %q", tt.name, got, tt.want)
+			}
+		}
+
+		// Watched, trashed and vetoed shows never come up, and a show picked
+		// today is left out of picks that skip recent ones
+		tvShow, err := store.PickTVShow(ctx, PickOptions{VetoedBy: []int{sam}})
+
+		if err != nil || tvShow != nil {
+			t.Errorf("PickTVShow vetoed by Sam = %+v, %v; want none", tvShow, err)
+		}
+
+		if tvShow, err := store.PickTVShow(ctx, PickOptions{}); err != nil || tvShow == nil || tvShow.ID != vetoed {
+			t.Fatalf("PickTVShow = %+v, %v; want Vetoed", tvShow, err)
+		}
+
+		if tvShow, err := store.PickTVShow(ctx, PickOptions{NotPickedDays: 1}); err != nil || tvShow != nil {
+			t.Errorf("PickTVShow skipping today's picks = %+v, %v; want none", tvShow, err)
+		}
+	})
+}
+
+func TestWeightedChoice(t *testing.T) {
+	tests := []struct {
+		weights []float64
+		want    int
+	}{
+		{[]float64{1}, 0},
+		{[]float64{0, 5, 0}, 1},
+		{[]float64{0, 0, 3}, 2},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			if got := weightedChoice(tt.weights); got != tt.want {
+				t.Fatalf("weightedChoice(%v) = %d, want %d", tt.weights, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,524 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Votes a household profile can cast on a title
+const (
+	VoteWant = "want" // want to watch
+	VoteMeh  = "meh"
+	VoteVeto = "veto" // rules the title out of picks when the profile is present
+)
+
+// MaxProfileNameLength is the longest profile name accepted
+const MaxProfileNameLength = 40
+
+// Votes lists the votes in the order the vote buttons show them
+var Votes = []string{VoteWant, VoteMeh, VoteVeto}
+
+// Errors returned when a profile or vote cannot be saved
+var (
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrProfileExists  = errors.New("profile already exists")
+	ErrInvalidVote    = errors.New("invalid vote")
+)
+
+// Profile is a member of the household. Profiles are separate from user
+// accounts: anyone using the boards can vote as any profile.
+type Profile struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Vote is one profile's interest in a movie or TV show
+type Vote struct {
+	ProfileID int
+	ItemType  string
+	ItemID    int
+	Vote      string // VoteWant, VoteMeh or VoteVeto; empty clears the vote
+}
+
+// ProfileVote is a profile with its vote on one title, empty when it has not voted
+type ProfileVote struct {
+	Profile
+	Vote string
+}
+
+// VoteTally is the household's votes on one watchlist title, by profile name
+type VoteTally struct {
+	ItemType  string
+	ItemID    int
+	Title     string
+	Year      int
+	Want      []string
+	Meh       []string
+	Veto      []string
+	Unanimous bool // every profile wants it
+}
+
+// ValidVote reports whether vote is a known vote
+func ValidVote(vote string) bool {
+	for _, known := range Votes {
+		if vote == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeProfileName trims and validates a profile's name
+func normalizeProfileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return name, fmt.Errorf("%w: name is required", ErrInvalidProfile)
+	}
+
+	if utf8.RuneCountInString(name) > MaxProfileNameLength {
+		return name, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidProfile, MaxProfileNameLength)
+	}
+
+	return name, nil
+}
+
+// validateVote checks a vote's item type and value before it is saved
+func validateVote(vote Vote) error {
+	if _, err := trashTable(vote.ItemType); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVote, err)
+	}
+
+	if vote.Vote != "" && !ValidVote(vote.Vote) {
+		return fmt.Errorf("%w: unknown vote %q", ErrInvalidVote, vote.Vote)
+	}
+
+	return nil
+}
+
+// sortTallies puts the titles the household most wants first: titles nobody
+// vetoed, then the most wants, then the fewest mehs, then by title
+func sortTallies(tallies []VoteTally) {
+	sort.SliceStable(tallies, func(i, j int) bool {
+		a, b := tallies[i], tallies[j]
+
+		switch {
+		case (len(a.Veto) == 0) != (len(b.Veto) == 0):
+			return len(a.Veto) == 0
+		case len(a.Want) != len(b.Want):
+			return len(a.Want) > len(b.Want)
+		case len(a.Meh) != len(b.Meh):
+			return len(a.Meh) < len(b.Meh)
+		default:
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		}
+	})
+}
+
+// add files a profile's vote under the matching list
+func (tally *VoteTally) add(name, vote string) {
+	switch vote {
+	case VoteWant:
+		tally.Want = append(tally.Want, name)
+	case VoteMeh:
+		tally.Meh = append(tally.Meh, name)
+	case VoteVeto:
+		tally.Veto = append(tally.Veto, name)
+	}
+}
+
+// AddProfile creates a household profile and returns its ID; names are unique ignoring case
+func (s *SQLiteStore) AddProfile(ctx context.Context, name string) (int, error) {
+	name, err := normalizeProfileName(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM profiles WHERE name = ?)", name).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check profile name: %w", err)
+		}
+
+		if exists {
+			return fmt.Errorf("%w: %s", ErrProfileExists, name)
+		}
+
+		result, err := tx.ExecContext(ctx, "INSERT INTO profiles (name, created_at) VALUES (?, ?)", name, time.Now().UTC())
+
+		if err != nil {
+			return fmt.Errorf("failed to add profile: %w", err)
+		}
+
+		id, err = result.LastInsertId()
+
+		return err
+	})
+
+	return int(id), err
+}
+
+// ListProfiles returns every household profile in name order
+func (s *SQLiteStore) ListProfiles(ctx context.Context) ([]Profile, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, created_at FROM profiles ORDER BY name")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profiles: %w", err)
+	}
+
+	defer rows.Close()
+
+	var profiles []Profile
+
+	for rows.Next() {
+		var profile Profile
+
+		if err := rows.Scan(&profile.ID, &profile.Name, &profile.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+// DeleteProfile removes a household profile along with its votes
+func (s *SQLiteStore) DeleteProfile(ctx context.Context, id int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM profile_votes WHERE profile_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete profile votes: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM profiles WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete profile: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SetVote records a profile's vote on a watchlist title, replacing any
+// earlier vote, or clears it when the vote is empty
+func (s *SQLiteStore) SetVote(ctx context.Context, vote Vote) error {
+	if err := validateVote(vote); err != nil {
+		return err
+	}
+
+	table, _ := trashTable(vote.ItemType)
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var known bool
+
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ?) AND EXISTS(SELECT 1 FROM "+table+" WHERE id = ? AND deleted_at IS NULL)",
+			vote.ProfileID, vote.ItemID).Scan(&known)
+
+		if err != nil {
+			return fmt.Errorf("failed to check vote: %w", err)
+		}
+
+		if !known {
+			return fmt.Errorf("%w: no such profile or %s", ErrInvalidVote, vote.ItemType)
+		}
+
+		if vote.Vote == "" {
+			_, err = tx.ExecContext(ctx, "DELETE FROM profile_votes WHERE profile_id = ? AND item_type = ? AND item_id = ?",
+				vote.ProfileID, vote.ItemType, vote.ItemID)
+		} else {
+			_, err = tx.ExecContext(ctx, `INSERT INTO profile_votes (profile_id, item_type, item_id, vote, updated_at) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (profile_id, item_type, item_id) DO UPDATE SET vote = excluded.vote, updated_at = excluded.updated_at`,
+				vote.ProfileID, vote.ItemType, vote.ItemID, vote.Vote, time.Now().UTC())
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to save vote: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetItemVotes returns every profile with its vote on one title, in name order
+func (s *SQLiteStore) GetItemVotes(ctx context.Context, itemType string, itemID int) ([]ProfileVote, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT p.id, p.name, p.created_at, COALESCE(v.vote, '')
+		FROM profiles p
+		LEFT JOIN profile_votes v ON v.profile_id = p.id AND v.item_type = ? AND v.item_id = ?
+		ORDER BY p.name`, itemType, itemID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+
+	defer rows.Close()
+
+	var votes []ProfileVote
+
+	for rows.Next() {
+		var vote ProfileVote
+
+		if err := rows.Scan(&vote.ID, &vote.Name, &vote.CreatedAt, &vote.Vote); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
+
+// GetVoteTallies returns the household's votes on every watchlist title of a
+// type that has any, the titles everyone most wants first
+func (s *SQLiteStore) GetVoteTallies(ctx context.Context, itemType string) ([]VoteTally, error) {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles int
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM profiles").Scan(&profiles); err != nil {
+		return nil, fmt.Errorf("failed to count profiles: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT t.id, t.title, COALESCE(t.year, 0), p.name, v.vote
+		FROM profile_votes v
+		JOIN profiles p ON p.id = v.profile_id
+		JOIN `+table+` t ON t.id = v.item_id
+		WHERE v.item_type = ? AND t.watched = 0 AND t.deleted_at IS NULL
+		ORDER BY t.id, p.name`, itemType)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vote tallies: %w", err)
+	}
+
+	defer rows.Close()
+
+	var tallies []VoteTally
+
+	for rows.Next() {
+		var id, year int
+		var title, name, vote string
+
+		if err := rows.Scan(&id, &title, &year, &name, &vote); err != nil {
+			return nil, fmt.Errorf("failed to scan vote tally: %w", err)
+		}
+
+		if len(tallies) == 0 || tallies[len(tallies)-1].ItemID != id {
+			tallies = append(tallies, VoteTally{ItemType: itemType, ItemID: id, Title: title, Year: year})
+		}
+
+		tallies[len(tallies)-1].add(name, vote)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vote tallies: %w", err)
+	}
+
+	for i := range tallies {
+		tallies[i].Unanimous = len(tallies[i].Want) == profiles
+	}
+
+	sortTallies(tallies)
+
+	return tallies, nil
+}
+
+// AddProfile creates a household profile and returns its ID; names are unique ignoring case
+func (m *MemoryStore) AddProfile(ctx context.Context, name string) (int, error) {
+	name, err := normalizeProfileName(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.profiles {
+		if strings.EqualFold(existing.Name, name) {
+			return 0, fmt.Errorf("%w: %s", ErrProfileExists, name)
+		}
+	}
+
+	profile := Profile{ID: m.nextID, Name: name, CreatedAt: time.Now().UTC()}
+	m.nextID++
+	m.profiles[profile.ID] = profile
+
+	return profile.ID, nil
+}
+
+// ListProfiles returns every household profile in name order
+func (m *MemoryStore) ListProfiles(ctx context.Context) ([]Profile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedProfiles(), nil
+}
+
+// sortedProfiles returns the profiles in name order; callers hold the lock
+func (m *MemoryStore) sortedProfiles() []Profile {
+	var profiles []Profile
+
+	for _, profile := range m.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+
+	return profiles
+}
+
+// DeleteProfile removes a household profile along with its votes
+func (m *MemoryStore) DeleteProfile(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.profiles, id)
+
+	for key := range m.votes {
+		if key.profileID == id {
+			delete(m.votes, key)
+		}
+	}
+
+	return nil
+}
+
+// SetVote records a profile's vote on a watchlist title, or clears it when the vote is empty
+func (m *MemoryStore) SetVote(ctx context.Context, vote Vote) error {
+	if err := validateVote(vote); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, known := m.profiles[vote.ProfileID]
+
+	switch vote.ItemType {
+	case ItemTypeMovie:
+		entry, ok := m.movies[vote.ItemID]
+		known = known && ok && entry.deletedAt.IsZero()
+	case ItemTypeTVShow:
+		entry, ok := m.tvShows[vote.ItemID]
+		known = known && ok && entry.deletedAt.IsZero()
+	}
+
+	if !known {
+		return fmt.Errorf("%w: no such profile or %s", ErrInvalidVote, vote.ItemType)
+	}
+
+	key := voteKey{profileID: vote.ProfileID, itemType: vote.ItemType, itemID: vote.ItemID}
+
+	if vote.Vote == "" {
+		delete(m.votes, key)
+	} else {
+		m.votes[key] = vote.Vote
+	}
+
+	return nil
+}
+
+// GetItemVotes returns every profile with its vote on one title, in name order
+func (m *MemoryStore) GetItemVotes(ctx context.Context, itemType string, itemID int) ([]ProfileVote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var votes []ProfileVote
+
+	for _, profile := range m.sortedProfiles() {
+		votes = append(votes, ProfileVote{Profile: profile, Vote: m.votes[voteKey{profileID: profile.ID, itemType: itemType, itemID: itemID}]})
+	}
+
+	return votes, nil
+}
+
+// GetVoteTallies returns the household's votes on every watchlist title of a
+// type that has any, in the same order as the SQLite store
+func (m *MemoryStore) GetVoteTallies(ctx context.Context, itemType string) ([]VoteTally, error) {
+	if _, err := trashTable(itemType); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byItem := make(map[int]*VoteTally)
+
+	for _, profile := range m.sortedProfiles() {
+		for key, vote := range m.votes {
+			if key.profileID != profile.ID || key.itemType != itemType {
+				continue
+			}
+
+			tally, ok := byItem[key.itemID]
+
+			if !ok {
+				title, year, onList := m.watchlistTitle(itemType, key.itemID)
+
+				if !onList {
+					continue
+				}
+
+				tally = &VoteTally{ItemType: itemType, ItemID: key.itemID, Title: title, Year: year}
+				byItem[key.itemID] = tally
+			}
+
+			tally.add(profile.Name, vote)
+		}
+	}
+
+	var tallies []VoteTally
+
+	for _, tally := range byItem {
+		tally.Unanimous = len(tally.Want) == len(m.profiles)
+		tallies = append(tallies, *tally)
+	}
+
+	sort.Slice(tallies, func(i, j int) bool { return tallies[i].ItemID < tallies[j].ItemID })
+	sortTallies(tallies)
+
+	return tallies, nil
+}
+
+// watchlistTitle returns the title and year of an unwatched, untrashed item; callers hold the lock
+func (m *MemoryStore) watchlistTitle(itemType string, id int) (string, int, bool) {
+	switch itemType {
+	case ItemTypeMovie:
+		if entry, ok := m.movies[id]; ok && !entry.Watched && entry.deletedAt.IsZero() {
+			return entry.Title, entry.Year, true
+		}
+	case ItemTypeTVShow:
+		if entry, ok := m.tvShows[id]; ok && !entry.Watched && entry.deletedAt.IsZero() {
+			return entry.Title, entry.Year, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// vetoedBy reports whether any of the profiles vetoed an item; callers hold the lock
+func (m *MemoryStore) vetoedBy(profileIDs []int, itemType string, itemID int) bool {
+	for _, profileID := range profileIDs {
+		if m.votes[voteKey{profileID: profileID, itemType: itemType, itemID: itemID}] == VoteVeto {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustAddProfile adds a household profile or fails the test
+func mustAddProfile(t *testing.T, store Store, name string) int {
+	t.Helper()
+
+	id, err := store.AddProfile(context.Background(), name)
+
+	if err != nil {
+		t.Fatalf("AddProfile(%q): %v", name, err)
+	}
+
+	return id
+}
+
+func TestProfiles(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		invalid := []string{"", "   ", strings.Repeat("é", MaxProfileNameLength+1)}
+
+		for _, name := range invalid {
+			if _, err := store.AddProfile(ctx, name); !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("AddProfile(%q) error = %v, want ErrInvalidProfile", name, err)
+			}
+		}
+
+		mustAddProfile(t, store, strings.Repeat("é", MaxProfileNameLength))
+		sam := mustAddProfile(t, store, " Sam ")
+		alex := mustAddProfile(t, store, "Alex")
+
+		if _, err := store.AddProfile(ctx, "SAM"); !errors.Is(err, ErrProfileExists) {
+			t.Errorf("AddProfile of a taken name in another case: error = %v, want ErrProfileExists", err)
+		}
+
+		profiles, err := store.ListProfiles(ctx)
+		var names []string
+
+		for _, profile := range profiles {
+			names = append(names, profile.Name)
+		}
+
+		if err != nil || !reflect.DeepEqual(names, []string{"Alex", "Sam", strings.Repeat("é", MaxProfileNameLength)}) {
+			t.Errorf("ListProfiles = %q, %v; want Alex, Sam and the longest name", names, err)
+		}
+
+		// Deleting a profile takes its votes with it
+		heat := mustAddMovie(t, store, Movie{Title: "Heat"})
+
+		for _, id := range []int{sam, alex} {
+			if err := store.SetVote(ctx, Vote{ProfileID: id, ItemType: ItemTypeMovie, ItemID: heat, Vote: VoteWant}); err != nil {
+				t.Fatalf("SetVote: %v", err)
+			}
+		}
+
+		if err := store.DeleteProfile(ctx, sam); err != nil {
+			t.Fatalf("DeleteProfile: %v", err)
+		}
+
+		tallies, err := store.GetVoteTallies(ctx, ItemTypeMovie)
+
+		if err != nil || len(tallies) != 1 || !reflect.DeepEqual(tallies[0].Want, []string{"Alex"}) {
+			t.Errorf("GetVoteTallies after deleting Sam = %+v, %v; want only Alex's vote", tallies, err)
+		}
+	})
+}
+
+func TestVotes(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		alex := mustAddProfile(t, store, "Alex")
+		sam := mustAddProfile(t, store, "Sam")
+
+		heat := mustAddMovie(t, store, Movie{Title: "Heat", Year: 1995})
+		arrival := mustAddMovie(t, store, Movie{Title: "Arrival", Year: 2016})
+		ronin := mustAddMovie(t, store, Movie{Title: "Ronin", Year: 1998})
+		zodiac := mustAddMovie(t, store, Movie{Title: "Zodiac", Year: 2007})
+		seen := mustAddMovie(t, store, Movie{Title: "Seen"})
+		trashed := mustAddMovie(t, store, Movie{Title: "Trashed"})
+
+		if err := store.DeleteMovie(ctx, trashed); err != nil {
+			t.Fatalf("DeleteMovie: %v", err)
+		}
+
+		invalid := []struct {
+			name string
+			vote Vote
+		}{
+			{"unknown vote", Vote{ProfileID: alex, ItemType: ItemTypeMovie, ItemID: heat, Vote: "love"}},
+			{"unknown item type", Vote{ProfileID: alex, ItemType: "book", ItemID: heat, Vote: VoteWant}},
+			{"unknown profile", Vote{ProfileID: sam + 100, ItemType: ItemTypeMovie, ItemID: heat, Vote: VoteWant}},
+			{"unknown movie", Vote{ProfileID: alex, ItemType: ItemTypeMovie, ItemID: trashed + 100, Vote: VoteWant}},
+			{"trashed movie", Vote{ProfileID: alex, ItemType: ItemTypeMovie, ItemID: trashed, Vote: VoteWant}},
+		}
+
+		for _, tt := range invalid {
+			if err := store.SetVote(ctx, tt.vote); !errors.Is(err, ErrInvalidVote) {
+				t.Errorf("%s: SetVote error = %v, want ErrInvalidVote", tt.name, err)
+			}
+		}
+
+		votes := []Vote{
+			{ProfileID: alex, ItemID: heat, Vote: VoteMeh},
+			{ProfileID: alex, ItemID: heat, Vote: VoteWant}, // replaces the meh
+			{ProfileID: sam, ItemID: heat, Vote: VoteWant},
+			{ProfileID: alex, ItemID: arrival, Vote: VoteWant},
+			{ProfileID: sam, ItemID: arrival, Vote: VoteMeh},
+			{ProfileID: alex, ItemID: ronin, Vote: VoteWant},
+			{ProfileID: sam, ItemID: ronin, Vote: VoteVeto},
+			{ProfileID: sam, ItemID: zodiac, Vote: VoteMeh},
+			{ProfileID: alex, ItemID: zodiac, Vote: VoteWant},
+			{ProfileID: alex, ItemID: zodiac, Vote: ""}, // clears the want
+			{ProfileID: alex, ItemID: seen, Vote: VoteWant},
+		}
+
+		for _, vote := range votes {
+			vote.ItemType = ItemTypeMovie
+
+			if err := store.SetVote(ctx, vote); err != nil {
+				t.Fatalf("SetVote(%+v): %v", vote, err)
+			}
+		}
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: seen, Watcher: "Alex", Rating: 4}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		itemVotes, err := store.GetItemVotes(ctx, ItemTypeMovie, zodiac)
+
+		if err != nil || len(itemVotes) != 2 || itemVotes[0].Name != "Alex" || itemVotes[0].Vote != "" || itemVotes[1].Name != "Sam" || itemVotes[1].Vote != VoteMeh {
+			t.Errorf("GetItemVotes(zodiac) = %+v, %v; want Alex without a vote and Sam's meh", itemVotes, err)
+		}
+
+		// Wanted by everyone first, vetoed last, and watched titles left out
+		tallies, err := store.GetVoteTallies(ctx, ItemTypeMovie)
+
+		if err != nil {
+			t.Fatalf("GetVoteTallies: %v", err)
+		}
+
+		want := []VoteTally{
+			{ItemType: ItemTypeMovie, ItemID: heat, Title: "Heat", Year: 1995, Want: []string{"Alex", "Sam"}, Unanimous: true},
+			{ItemType: ItemTypeMovie, ItemID: arrival, Title: "Arrival", Year: 2016, Want: []string{"Alex"}, Meh: []string{"Sam"}},
+			{ItemType: ItemTypeMovie, ItemID: zodiac, Title: "Zodiac", Year: 2007, Meh: []string{"Sam"}},
+			{ItemType: ItemTypeMovie, ItemID: ronin, Title: "Ronin", Year: 1998, Want: []string{"Alex"}, Veto: []string{"Sam"}},
+		}
+
+		if !reflect.DeepEqual(tallies, want) {
+			t.Errorf("GetVoteTallies =\n%+v\nwant\n%+v", tallies, want)
+		}
+
+		if tallies, err := store.GetVoteTallies(ctx, ItemTypeTVShow); err != nil || len(tallies) != 0 {
+			t.Errorf("GetVoteTallies(tvshow) = %+v, %v; want none", tallies, err)
+		}
+	})
+}
+
+func TestVotesFollowMerges(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		alex := mustAddProfile(t, store, "Alex")
+		sam := mustAddProfile(t, store, "Sam")
+		keep := mustAddMovie(t, store, Movie{Title: "Heat", Year: 1995})
+		merge := mustAddMovie(t, store, Movie{Title: "Heat (1995)", Year: 1995})
+
+		votes := []Vote{
+			{ProfileID: alex, ItemID: keep, Vote: VoteWant},
+			{ProfileID: alex, ItemID: merge, Vote: VoteVeto},
+			{ProfileID: sam, ItemID: merge, Vote: VoteMeh},
+		}
+
+		for _, vote := range votes {
+			vote.ItemType = ItemTypeMovie
+
+			if err := store.SetVote(ctx, vote); err != nil {
+				t.Fatalf("SetVote(%+v): %v", vote, err)
+			}
+		}
+
+		// The kept title keeps its own votes and gains the ones it lacked
+		if err := store.MergeMovies(ctx, keep, merge); err != nil {
+			t.Fatalf("MergeMovies: %v", err)
+		}
+
+		got, err := store.GetItemVotes(ctx, ItemTypeMovie, keep)
+
+		if err != nil || len(got) != 2 || got[0].Vote != VoteWant || got[1].Vote != VoteMeh {
+			t.Errorf("GetItemVotes of the kept movie = %+v, %v; want Alex's want and Sam's meh", got, err)
+		}
+
+		// Purging the merged title removes the votes left on it
+		if err := store.PurgeItem(ctx, ItemTypeMovie, merge); err != nil {
+			t.Fatalf("PurgeItem: %v", err)
+		}
+
+		got, err = store.GetItemVotes(ctx, ItemTypeMovie, merge)
+
+		if err != nil || len(got) != 2 || got[0].Vote != "" || got[1].Vote != "" {
+			t.Errorf("GetItemVotes of the purged movie = %+v, %v; want no votes", got, err)
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sort orders for board listings
+const (
+	SortDefault = ""      // streaming movies or active TV seasons first, then by year
+	SortTitle   = "title" // alphabetical, ignoring case
+	SortYear    = "year"  // release year; unknown years count as 0
+	SortAdded   = "added" // when the item was added
+)
+
+// Availability filters for board listings
+const (
+	AvailableAny  = ""
+	AvailableNow  = "now"  // streaming somewhere today
+	AvailableNone = "none" // not streaming anywhere today
+)
+
+// ErrInvalidQuery is returned for an unknown sort or filter or a cursor this store did not issue
+var ErrInvalidQuery = errors.New("invalid board query")
+
+// BoardQuery filters, sorts and pages a board's watchlist. Every filter left
+// at its zero value matches everything.
+type BoardQuery struct {
+	Tags         []string // items carrying every one of these tags
+	Service      string   // items with a window on this service; with AvailableNow, a window open today
+	YearFrom     int      // a year range leaves out items without a year
+	YearTo       int
+	Availability string
+	Sort         string
+	Desc         bool
+	Cursor       string // the NextCursor of the previous page
+	Limit        int    // 0 returns every match
+}
+
+// MoviePage is one page of a movie board listing
+type MoviePage struct {
+	Movies     []Movie
+	Total      int    // matches across all pages
+	NextCursor string // empty on the last page
+}
+
+// TVShowPage is one page of a TV show board listing
+type TVShowPage struct {
+	TVShows    []TVShow
+	Total      int
+	NextCursor string
+}
+
+// ValidSort reports whether sort is a known sort order
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortDefault, SortTitle, SortYear, SortAdded:
+		return true
+	}
+
+	return false
+}
+
+// ValidAvailability reports whether availability is a known availability filter
+func ValidAvailability(availability string) bool {
+	switch availability {
+	case AvailableAny, AvailableNow, AvailableNone:
+		return true
+	}
+
+	return false
+}
+
+// validateQuery rejects sorts and filters the stores do not understand
+func validateQuery(query BoardQuery) error {
+	if !ValidSort(query.Sort) {
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, query.Sort)
+	}
+
+	if !ValidAvailability(query.Availability) {
+		return fmt.Errorf("%w: unknown availability %q", ErrInvalidQuery, query.Availability)
+	}
+
+	if query.Limit < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidQuery)
+	}
+
+	return nil
+}
+
+// encodeCursor packs the sort key values of a page's last item into an opaque cursor
+func encodeCursor(values []interface{}) (string, error) {
+	data, err := json.Marshal(values)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor unpacks a cursor into sort key values: whole numbers come back
+// as int64 and everything else as strings
+func decodeCursor(cursor string, keys int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+
+	var raw []interface{}
+
+	if err := decoder.Decode(&raw); err != nil || len(raw) != keys {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	values := make([]interface{}, len(raw))
+
+	for i, value := range raw {
+		switch v := value.(type) {
+		case json.Number:
+			n, err := v.Int64()
+
+			if err != nil {
+				return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+			}
+
+			values[i] = n
+		case string:
+			values[i] = v
+		default:
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+		}
+	}
+
+	return values, nil
+}
+
+// boardTable describes the table behind a board for query building
+type boardTable struct {
+	name     string
+	itemType string
+	first    string // what the default sort puts first
+}
+
+var (
+	movieBoard  = boardTable{name: "movies", itemType: ItemTypeMovie, first: "EXISTS (SELECT 1 FROM availability WHERE item_type = 'movie' AND item_id = movies.id AND " + currentAvailabilitySQL + ")"}
+	tvShowBoard = boardTable{name: "tv_shows", itemType: ItemTypeTVShow, first: "COALESCE(tv_shows.active_season, 0)"}
+)
+
+// sortKeys returns the expressions a board is ordered by, ending with the ID
+// so every item has a distinct position for cursors
+func (t boardTable) sortKeys(sort string) []string {
+	id := t.name + ".id"
+	year := "COALESCE(" + t.name + ".year, 0)"
+	// Rows written before created_at had a default may lack it; a NULL key
+	// would never compare in the cursor's seek
+	added := "COALESCE(CAST(" + t.name + ".created_at AS TEXT), '')"
+
+	switch sort {
+	case SortTitle:
+		return []string{t.name + ".title COLLATE NOCASE", id}
+	case SortYear:
+		return []string{year, id}
+	case SortAdded:
+		return []string{added, id}
+	default:
+		return []string{t.first, year, added, id}
+	}
+}
+
+// filterClause builds the WHERE conditions for a query's filters
+func (t boardTable) filterClause(query BoardQuery) (string, []interface{}) {
+	clause, args := tagFilterClause(t.itemType, t.name+".id", query.Tags)
+	current := "EXISTS (SELECT 1 FROM availability a WHERE a.item_type = ? AND a.item_id = " + t.name + ".id AND " + currentAvailabilitySQL + ")"
+
+	if service := strings.TrimSpace(query.Service); service != "" {
+		window := "SELECT 1 FROM availability a WHERE a.item_type = ? AND a.item_id = " + t.name + ".id AND a.service = ? COLLATE NOCASE"
+
+		if query.Availability == AvailableNow {
+			window += " AND " + currentAvailabilitySQL
+		}
+
+		clause += " AND EXISTS (" + window + ")"
+		args = append(args, t.itemType, service)
+	}
+
+	switch query.Availability {
+	case AvailableNow:
+		clause += " AND " + current
+		args = append(args, t.itemType)
+	case AvailableNone:
+		clause += " AND NOT " + current
+		args = append(args, t.itemType)
+	}
+
+	if query.YearFrom > 0 || query.YearTo > 0 {
+		clause += " AND " + t.name + ".year > 0"
+	}
+
+	if query.YearFrom > 0 {
+		clause += " AND " + t.name + ".year >= ?"
+		args = append(args, query.YearFrom)
+	}
+
+	if query.YearTo > 0 {
+		clause += " AND " + t.name + ".year <= ?"
+		args = append(args, query.YearTo)
+	}
+
+	return clause, args
+}
+
+// listQuery runs a board listing: the total count, then one page of rows from
+// the cursor on. scan reads the rows and returns the last row's ID so the next
+// cursor can be taken from it.
+func (s *SQLiteStore) listQuery(ctx context.Context, t boardTable, columns string, query BoardQuery, scan func(rows *sql.Rows) (int, int, error)) (int, string, error) {
+	if err := validateQuery(query); err != nil {
+		return 0, "", err
+	}
+
+	filter, args := t.filterClause(query)
+	from := " FROM " + t.name + " WHERE watched = 0 AND deleted_at IS NULL" + filter
+
+	var total int
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return 0, "", fmt.Errorf("failed to count %s: %w", t.name, err)
+	}
+
+	keys := t.sortKeys(query.Sort)
+	direction, seek := " ASC", " > "
+
+	if query.Desc {
+		direction, seek = " DESC", " < "
+	}
+
+	if query.Cursor != "" {
+		values, err := decodeCursor(query.Cursor, len(keys))
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		from += " AND (" + strings.Join(keys, ", ") + ")" + seek + "(" + strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ") + ")"
+		args = append(args, values...)
+	}
+
+	order := make([]string, len(keys))
+
+	for i, key := range keys {
+		order[i] = key + direction
+	}
+
+	statement := "SELECT " + columns + from + " ORDER BY " + strings.Join(order, ", ")
+
+	if query.Limit > 0 {
+		// One extra row tells whether there is a next page
+		statement += fmt.Sprintf(" LIMIT %d", query.Limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to query %s: %w", t.name, err)
+	}
+
+	count, lastID, err := scan(rows)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	if query.Limit == 0 || count <= query.Limit {
+		return total, "", nil
+	}
+
+	values := make([]interface{}, len(keys))
+	pointers := make([]interface{}, len(keys))
+
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := s.db.QueryRowContext(ctx, "SELECT "+strings.Join(keys, ", ")+" FROM "+t.name+" WHERE id = ?", lastID).Scan(pointers...); err != nil {
+		return 0, "", fmt.Errorf("failed to read cursor: %w", err)
+	}
+
+	cursor, err := encodeCursor(values)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return total, cursor, nil
+}
+
+// ListMovies returns one page of the movie watchlist
+func (s *SQLiteStore) ListMovies(ctx context.Context, query BoardQuery) (MoviePage, error) {
+	var page MoviePage
+
+	total, cursor, err := s.listQuery(ctx, movieBoard, movieColumns, query, func(rows *sql.Rows) (int, int, error) {
+		movies, err := scanMovies(rows)
+
+		if err != nil || len(movies) == 0 {
+			return 0, 0, err
+		}
+
+		if query.Limit > 0 && len(movies) > query.Limit {
+			page.Movies = movies[:query.Limit]
+		} else {
+			page.Movies = movies
+		}
+
+		return len(movies), page.Movies[len(page.Movies)-1].ID, nil
+	})
+
+	if err != nil {
+		return MoviePage{}, err
+	}
+
+	page.Total = total
+	page.NextCursor = cursor
+
+	return page, nil
+}
+
+// ListTVShows returns one page of the TV show watchlist
+func (s *SQLiteStore) ListTVShows(ctx context.Context, query BoardQuery) (TVShowPage, error) {
+	var page TVShowPage
+
+	total, cursor, err := s.listQuery(ctx, tvShowBoard, tvShowColumns, query, func(rows *sql.Rows) (int, int, error) {
+		tvShows, err := scanTVShows(rows)
+
+		if err != nil || len(tvShows) == 0 {
+			return 0, 0, err
+		}
+
+		if query.Limit > 0 && len(tvShows) > query.Limit {
+			page.TVShows = tvShows[:query.Limit]
+		} else {
+			page.TVShows = tvShows
+		}
+
+		return len(tvShows), page.TVShows[len(page.TVShows)-1].ID, nil
+	})
+
+	if err != nil {
+		return TVShowPage{}, err
+	}
+
+	page.Total = total
+	page.NextCursor = cursor
+
+	return page, nil
+}
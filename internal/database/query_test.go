@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// seedBoard adds movies with tied years and titles differing only in case, so
+// paging has to fall back to the ID to keep every movie in one place
+func seedBoard(t *testing.T, store Store) {
+	t.Helper()
+
+	movies := []Movie{
+		{Title: "alien", Year: 1979, Tags: []string{"Horror", "Sci-Fi"}, Availability: AvailabilityList{{Service: "Hulu"}}},
+		{Title: "Aliens", Year: 1986, Tags: []string{"Action", "Sci-Fi"}},
+		{Title: "Heat", Year: 1995, Tags: []string{"Crime"}, Availability: AvailabilityList{{Service: "Netflix"}}},
+		{Title: "heat", Year: 1986, Tags: []string{"Crime"}},
+		{Title: "Ronin", Year: 1998, Tags: []string{"Action"}, Availability: AvailabilityList{{Service: "Netflix"}}},
+		{Title: "Untitled", Tags: []string{"Drama"}},
+		{Title: "Zodiac", Year: 2007, Tags: []string{"Crime", "Drama"}},
+	}
+
+	for _, movie := range movies {
+		mustAddMovie(t, store, movie)
+	}
+}
+
+func TestListMoviesPaging(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		seedBoard(t, store)
+
+		// Older rows may have no creation time at all
+		if sqlite, ok := store.(*SQLiteStore); ok {
+			if _, err := sqlite.db.ExecContext(ctx, "UPDATE movies SET created_at = NULL WHERE title IN ('Heat', 'Ronin')"); err != nil {
+				t.Fatalf("clear created_at: %v", err)
+			}
+		}
+
+		for _, sort := range []string{SortDefault, SortTitle, SortYear, SortAdded} {
+			for _, desc := range []bool{false, true} {
+				for _, limit := range []int{1, 2, 3, 7} {
+					query := BoardQuery{Sort: sort, Desc: desc}
+					all, err := store.ListMovies(ctx, query)
+
+					if err != nil {
+						t.Fatalf("ListMovies(%+v): %v", query, err)
+					}
+
+					if all.Total != 7 || len(all.Movies) != 7 || all.NextCursor != "" {
+						t.Fatalf("ListMovies(%+v) = %d of %d, cursor %q; want all 7", query, len(all.Movies), all.Total, all.NextCursor)
+					}
+
+					var paged []int
+
+					query.Limit = limit
+
+					for pages := 0; ; pages++ {
+						if pages > 7 {
+							t.Fatalf("sort %q desc %v limit %d: paging never ended", sort, desc, limit)
+						}
+
+						page, err := store.ListMovies(ctx, query)
+
+						if err != nil {
+							t.Fatalf("ListMovies(%+v): %v", query, err)
+						}
+
+						if page.Total != 7 || len(page.Movies) > limit {
+							t.Errorf("sort %q desc %v limit %d: page of %d with total %d", sort, desc, limit, len(page.Movies), page.Total)
+						}
+
+						paged = append(paged, movieIDs(page.Movies)...)
+
+						if page.NextCursor == "" {
+							break
+						}
+
+						query.Cursor = page.NextCursor
+					}
+
+					if want := movieIDs(all.Movies); !reflect.DeepEqual(paged, want) {
+						t.Errorf("sort %q desc %v limit %d: pages = %v, want %v", sort, desc, limit, paged, want)
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestListMoviesFilters(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		seedBoard(t, store)
+
+		tests := []struct {
+			name  string
+			query BoardQuery
+			want  []string
+		}{
+			{"one tag", BoardQuery{Tags: []string{"crime"}}, []string{"heat", "Heat", "Zodiac"}},
+			{"every tag", BoardQuery{Tags: []string{"Crime", "Drama"}}, []string{"Zodiac"}},
+			{"year range", BoardQuery{YearFrom: 1980, YearTo: 1995}, []string{"Aliens", "heat", "Heat"}},
+			{"year from leaves out unknown", BoardQuery{YearFrom: 2000}, []string{"Zodiac"}},
+			{"service", BoardQuery{Service: "netflix"}, []string{"Heat", "Ronin"}},
+			{"tag and service", BoardQuery{Tags: []string{"Sci-Fi"}, Service: "Hulu"}, []string{"alien"}},
+			{"nothing", BoardQuery{Tags: []string{"Western"}}, nil},
+		}
+
+		for _, tt := range tests {
+			tt.query.Sort = SortTitle
+			page, err := store.ListMovies(context.Background(), tt.query)
+
+			if err != nil {
+				t.Fatalf("%s: ListMovies: %v", tt.name, err)
+			}
+
+			var titles []string
+
+			for _, movie := range page.Movies {
+				titles = append(titles, movie.Title)
+			}
+
+			if !sameTitles(titles, tt.want) || page.Total != len(tt.want) {
+				t.Errorf("%s: ListMovies = %q (total %d), want %q", tt.name, titles, page.Total, tt.want)
+			}
+		}
+	})
+}
+
+// sameTitles compares title lists, letting titles that differ only in case
+// come in either order since the title sort ignores case
+func sameTitles(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	counts := make(map[string]int)
+
+	for i := range got {
+		counts[got[i]]++
+		counts[want[i]]--
+	}
+
+	for _, count := range counts {
+		if count != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestListMoviesRejectsBadQueries(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		seedBoard(t, store)
+
+		titlePage, err := store.ListMovies(ctx, BoardQuery{Sort: SortTitle, Limit: 2})
+
+		if err != nil || titlePage.NextCursor == "" {
+			t.Fatalf("ListMovies by title = %v, %v; want a next page", titlePage.NextCursor, err)
+		}
+
+		tests := []struct {
+			name  string
+			query BoardQuery
+		}{
+			{"unknown sort", BoardQuery{Sort: "rating"}},
+			{"unknown availability", BoardQuery{Availability: "soon"}},
+			{"negative limit", BoardQuery{Limit: -1}},
+			{"malformed cursor", BoardQuery{Cursor: "not a cursor!", Limit: 2}},
+			{"cursor from another sort", BoardQuery{Sort: SortDefault, Cursor: titlePage.NextCursor, Limit: 2}},
+		}
+
+		for _, tt := range tests {
+			if _, err := store.ListMovies(ctx, tt.query); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("%s: ListMovies error = %v, want ErrInvalidQuery", tt.name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// searchTerms splits a user query into lowercase terms, dropping punctuation
+// that would otherwise be interpreted as FTS5 query syntax
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ftsMatchQuery turns a user query into an FTS5 MATCH expression where every
+// term must match as a prefix, which is what search-as-you-type needs
+func ftsMatchQuery(query string) string {
+	terms := searchTerms(query)
+	phrases := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		phrases = append(phrases, `"`+term+`"*`)
+	}
+
+	return strings.Join(phrases, " ")
+}
+
+// SearchMovies returns watchlist movies matching the query, best match first.
+// Title hits outrank tag and streaming hits, which outrank notes.
+func (s *SQLiteStore) SearchMovies(ctx context.Context, query string) ([]Movie, error) {
+	match := ftsMatchQuery(query)
+
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+movieColumns+`
+	FROM movies_fts
+	JOIN movies ON movies.id = movies_fts.rowid
+	WHERE movies_fts MATCH ? AND movies.watched = 0 AND movies.deleted_at IS NULL
+	ORDER BY bm25(movies_fts, 10.0, 1.0, 3.0, 3.0)`, match)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+
+	return scanMovies(rows)
+}
+
+// SearchTVShows returns watchlist TV shows matching the query, best match first
+func (s *SQLiteStore) SearchTVShows(ctx context.Context, query string) ([]TVShow, error) {
+	match := ftsMatchQuery(query)
+
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+tvShowColumns+`
+	FROM tv_shows_fts
+	JOIN tv_shows ON tv_shows.id = tv_shows_fts.rowid
+	WHERE tv_shows_fts MATCH ? AND tv_shows.watched = 0 AND tv_shows.deleted_at IS NULL
+	ORDER BY bm25(tv_shows_fts, 10.0, 1.0, 3.0, 3.0)`, match)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tv shows: %w", err)
+	}
+
+	return scanTVShows(rows)
+}
+
+// matchesAllPrefixes reports whether every term prefixes some word in the fields
+func matchesAllPrefixes(terms []string, fields ...string) bool {
+	var words []string
+
+	for _, field := range fields {
+		words = append(words, searchTerms(field)...)
+	}
+
+	for _, term := range terms {
+		found := false
+
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
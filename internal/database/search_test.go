@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSearchMovies(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		heat := mustAddMovie(t, store, Movie{Title: "Heat", Year: 1995, Tags: []string{"Crime"}})
+		ronin := mustAddMovie(t, store, Movie{Title: "Ronin", Notes: "a heated car chase", Availability: AvailabilityList{{Service: "Netflix"}}})
+		zodiac := mustAddMovie(t, store, Movie{Title: "Zodiac", Tags: []string{"Crime", "Mystery"}})
+		watched := mustAddMovie(t, store, Movie{Title: "Heat Wave"})
+		trashed := mustAddMovie(t, store, Movie{Title: "Heatwave"})
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: watched}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		if err := store.DeleteMovie(ctx, trashed); err != nil {
+			t.Fatalf("DeleteMovie: %v", err)
+		}
+
+		tests := []struct {
+			query string
+			want  []int
+			first int // the result that must rank first, 0 when order is free
+		}{
+			{"heat", []int{heat, ronin}, heat},
+			{"HEA", []int{heat, ronin}, heat},
+			{"crime", []int{heat, zodiac}, 0},
+			{"crime myst", []int{zodiac}, 0},
+			{"netflix", []int{ronin}, 0},
+			{`"chase" (car*)`, []int{ronin}, 0},
+			{"eat", nil, 0},
+			{"  ", nil, 0},
+			{"*:(", nil, 0},
+		}
+
+		for _, tt := range tests {
+			movies, err := store.SearchMovies(ctx, tt.query)
+
+			if err != nil {
+				t.Fatalf("SearchMovies(%q): %v", tt.query, err)
+			}
+
+			got := movieIDs(movies)
+
+			if tt.first != 0 && (len(got) == 0 || got[0] != tt.first) {
+				t.Errorf("SearchMovies(%q) = %v, want %d first", tt.query, got, tt.first)
+			}
+
+			slices.Sort(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchMovies(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		}
+
+		// Edits reach the index
+		if err := store.UpdateMovie(ctx, Movie{ID: zodiac, Title: "Zodiac", Notes: "Fincher"}); err != nil {
+			t.Fatalf("UpdateMovie: %v", err)
+		}
+
+		for query, want := range map[string][]int{"fincher": {zodiac}, "mystery": nil} {
+			movies, err := store.SearchMovies(ctx, query)
+
+			if err != nil || !reflect.DeepEqual(movieIDs(movies), want) {
+				t.Errorf("SearchMovies(%q) after the edit = %v, %v; want %v", query, movieIDs(movies), err, want)
+			}
+		}
+	})
+}
+
+func TestFTSMatchQuery(t *testing.T) {
+	tests := map[string]string{
+		"Heat":             `"heat"*`,
+		"lord of the":      `"lord"* "of"* "the"*`,
+		`"quoted" OR NEAR`: `"quoted"* "or"* "near"*`,
+		"-*()":             "",
+	}
+
+	for query, want := range tests {
+		if got := ftsMatchQuery(query); got != want {
+			t.Errorf("ftsMatchQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// MovieStore persists the movie board
+type MovieStore interface {
+	GetAllMovies(ctx context.Context) ([]Movie, error)
+	ListMovies(ctx context.Context, query BoardQuery) (MoviePage, error)
+	GetMovie(ctx context.Context, id int) (*Movie, error)
+	GetMovieByIMDbID(ctx context.Context, imdbID string) (*Movie, error)
+	AddMovie(ctx context.Context, movie Movie) (int, error)
+	UpdateMovie(ctx context.Context, movie Movie) error
+	DeleteMovie(ctx context.Context, id int) error // moves the movie to the trash; ErrItemNotFound when it is not on the board
+	GetMovieCount(ctx context.Context) (int, error)
+	PickMovie(ctx context.Context, options PickOptions) (*Movie, error) // records the pick
+	SearchMovies(ctx context.Context, query string) ([]Movie, error)
+	MarkMovieWatched(ctx context.Context, event WatchEvent) error
+	ReturnMovieToWatchlist(ctx context.Context, id int) error
+	GetWatchedMovies(ctx context.Context) ([]WatchedMovie, error)
+}
+
+// TVShowStore persists the TV shows board
+type TVShowStore interface {
+	GetAllTVShows(ctx context.Context) ([]TVShow, error)
+	ListTVShows(ctx context.Context, query BoardQuery) (TVShowPage, error)
+	GetTVShow(ctx context.Context, id int) (*TVShow, error)
+	GetTVShowByIMDbID(ctx context.Context, imdbID string) (*TVShow, error)
+	AddTVShow(ctx context.Context, tvShow TVShow) (int, error)
+	UpdateTVShow(ctx context.Context, tvShow TVShow) error
+	DeleteTVShow(ctx context.Context, id int) error // moves the TV show to the trash; ErrItemNotFound when it is not on the board
+	GetTVShowCount(ctx context.Context) (int, error)
+	PickTVShow(ctx context.Context, options PickOptions) (*TVShow, error) // records the pick
+	SearchTVShows(ctx context.Context, query string) ([]TVShow, error)
+	MarkTVShowWatched(ctx context.Context, event WatchEvent) error
+	ReturnTVShowToWatchlist(ctx context.Context, id int) error
+	GetWatchedTVShows(ctx context.Context) ([]WatchedTVShow, error)
+}
+
+// EpisodeStore tracks season and episode progress for TV shows
+type EpisodeStore interface {
+	GetSeasons(ctx context.Context, tvShowID int) ([]Season, error)
+	AddSeason(ctx context.Context, tvShowID, number, episodeCount int) error
+	DeleteSeason(ctx context.Context, tvShowID, seasonID int) error
+	SetEpisodeWatched(ctx context.Context, tvShowID, episodeID int, watched bool) error
+	WatchNextEpisode(ctx context.Context, tvShowID int) error
+}
+
+// TrashStore restores or permanently removes soft-deleted movies and TV shows
+type TrashStore interface {
+	GetTrash(ctx context.Context) ([]TrashItem, error)
+	RestoreItem(ctx context.Context, itemType string, id int) error
+	PurgeItem(ctx context.Context, itemType string, id int) error
+	PurgeTrashBefore(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// TagStore lists the tags in use on each board
+type TagStore interface {
+	GetTags(ctx context.Context, itemType string) ([]string, error)
+}
+
+// AuditStore reads the change history recorded for movies and TV shows
+type AuditStore interface {
+	GetItemHistory(ctx context.Context, itemType string, itemID int) ([]AuditEntry, error)
+}
+
+// DuplicateStore finds likely duplicate movies and TV shows and merges them
+type DuplicateStore interface {
+	FindMovieDuplicates(ctx context.Context, movie Movie) ([]Movie, error)
+	FindTVShowDuplicates(ctx context.Context, tvShow TVShow) ([]TVShow, error)
+	GetDuplicateMovies(ctx context.Context) ([][]Movie, error)
+	GetDuplicateTVShows(ctx context.Context) ([][]TVShow, error)
+	MergeMovies(ctx context.Context, keepID, mergeID int) error // mergeID goes to the trash
+	MergeTVShows(ctx context.Context, keepID, mergeID int) error
+}
+
+// MetadataStore finds the items the enrichment job should look up and fills
+// in what it finds
+type MetadataStore interface {
+	GetMoviesMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]Movie, error)
+	GetTVShowsMissingMetadata(ctx context.Context, checkedBefore time.Time, limit int) ([]TVShow, error)
+	MarkMetadataChecked(ctx context.Context, itemType string, id int) error
+	FillMovieMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error)
+	FillTVShowMetadata(ctx context.Context, id int, fill MetadataFill) (bool, error)
+}
+
+// PosterStore lists the posters the image cache should hold
+type PosterStore interface {
+	GetPosterURLs(ctx context.Context) ([]string, error) // includes trashed items, which may be restored
+}
+
+// IMDbDatasetStore holds the local copy of IMDb's title dataset used for
+// offline autocomplete and IMDb link validation
+type IMDbDatasetStore interface {
+	ReplaceIMDbTitles(ctx context.Context, titles iter.Seq2[IMDbTitle, error]) (int, error)
+	SearchIMDbTitles(ctx context.Context, itemType, query string, limit int) ([]IMDbTitle, error)
+	GetIMDbTitle(ctx context.Context, id string) (*IMDbTitle, error) // nil when the dataset has no such title
+	CountIMDbTitles(ctx context.Context) (int, error)
+}
+
+// UserStore holds the local accounts that can sign in
+type UserStore interface {
+	AddUser(ctx context.Context, user User) (int, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	CountUsers(ctx context.Context) (int, error)
+}
+
+// SessionStore holds the signed-in browsers of each account
+type SessionStore interface {
+	CreateSession(ctx context.Context, session Session) error
+	GetSessionUser(ctx context.Context, tokenHash string) (*User, error) // nil for unknown or expired sessions
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteExpiredSessions(ctx context.Context) (int, error)
+}
+
+// APITokenStore holds the tokens scripts and automations use instead of a session
+type APITokenStore interface {
+	CreateAPIToken(ctx context.Context, token APIToken, tokenHash string) (int, error)
+	ListAPITokens(ctx context.Context, userID int) ([]APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, id int) error
+	UseAPIToken(ctx context.Context, tokenHash string) (*APIToken, *User, error) // nils for unknown tokens
+}
+
+// ProfileStore holds the household's profiles and their votes on titles
+type ProfileStore interface {
+	AddProfile(ctx context.Context, name string) (int, error)
+	ListProfiles(ctx context.Context) ([]Profile, error)
+	DeleteProfile(ctx context.Context, id int) error // also deletes the profile's votes
+	SetVote(ctx context.Context, vote Vote) error
+	GetItemVotes(ctx context.Context, itemType string, itemID int) ([]ProfileVote, error)
+	GetVoteTallies(ctx context.Context, itemType string) ([]VoteTally, error)
+}
+
+// BackupStore takes online snapshots of a database-backed store. Only the
+// SQLite backend implements it; the in-memory store has nothing to back up.
+type BackupStore interface {
+	Backup(ctx context.Context, dir string) (string, error)
+}
+
+// Store is the full persistence backend used by the server
+type Store interface {
+	MovieStore
+	TVShowStore
+	EpisodeStore
+	TrashStore
+	TagStore
+	AuditStore
+	DuplicateStore
+	MetadataStore
+	PosterStore
+	IMDbDatasetStore
+	UserStore
+	SessionStore
+	APITokenStore
+	ProfileStore
+	Close() error
+}
+
+// Compile-time checks that both backends satisfy Store
+var (
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+
+	_ BackupStore = (*SQLiteStore)(nil)
+)
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// eachStore runs test once against every backend
+func eachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Helper()
+
+	for name, store := range TestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			test(t, store)
+		})
+	}
+}
+
+// mustAddMovie adds a movie or fails the test
+func mustAddMovie(t *testing.T, store Store, movie Movie) int {
+	t.Helper()
+
+	id, err := store.AddMovie(context.Background(), movie)
+
+	if err != nil {
+		t.Fatalf("AddMovie(%q): %v", movie.Title, err)
+	}
+
+	return id
+}
+
+// mustAddTVShow adds a TV show or fails the test
+func mustAddTVShow(t *testing.T, store Store, tvShow TVShow) int {
+	t.Helper()
+
+	id, err := store.AddTVShow(context.Background(), tvShow)
+
+	if err != nil {
+		t.Fatalf("AddTVShow(%q): %v", tvShow.Title, err)
+	}
+
+	return id
+}
+
+func TestMovieRoundTrip(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		id := mustAddMovie(t, store, Movie{
+			Title:    "  Arrival ",
+			Year:     2016,
+			Runtime:  116,
+			Tags:     []string{"Sci-Fi", "Drama"},
+			Notes:    "Denis Villeneuve",
+			IMDBLink: "https://www.imdb.com/title/tt2543164/",
+			Details:  Details{Director: "Denis Villeneuve", Cast: []string{"Amy Adams", " ", "Jeremy Renner"}},
+		})
+
+		movie, err := store.GetMovie(ctx, id)
+
+		if err != nil || movie == nil {
+			t.Fatalf("GetMovie(%d) = %v, %v", id, movie, err)
+		}
+
+		if movie.Title != "Arrival" || movie.Year != 2016 || movie.Runtime != 116 || movie.Director != "Denis Villeneuve" {
+			t.Errorf("GetMovie(%d) = %+v", id, movie)
+		}
+
+		if len(movie.Tags) != 2 || len(movie.Cast) != 2 {
+			t.Errorf("tags = %q, cast = %q; want 2 of each", movie.Tags, movie.Cast)
+		}
+
+		byIMDb, err := store.GetMovieByIMDbID(ctx, "tt2543164")
+
+		if err != nil || byIMDb == nil || byIMDb.ID != id {
+			t.Errorf("GetMovieByIMDbID = %v, %v; want movie %d", byIMDb, err, id)
+		}
+	})
+}
+
+func TestAddRejectsInvalidItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		movie Movie
+	}{
+		{"empty title", Movie{Title: "  "}},
+		{"year too early", Movie{Title: "Old", Year: MinYear - 1}},
+		{"year too late", Movie{Title: "Future", Year: MaxYear + 1}},
+		{"negative runtime", Movie{Title: "Short", Runtime: -1}},
+		{"runtime too long", Movie{Title: "Long", Runtime: MaxRuntime + 1}},
+		{"poster not http", Movie{Title: "Poster", Details: Details{PosterURL: "file:///etc/passwd"}}},
+	}
+
+	eachStore(t, func(t *testing.T, store Store) {
+		for _, tt := range tests {
+			if _, err := store.AddMovie(context.Background(), tt.movie); !errors.Is(err, ErrInvalidItem) {
+				t.Errorf("%s: AddMovie error = %v, want ErrInvalidItem", tt.name, err)
+			}
+		}
+
+		if _, err := store.AddTVShow(context.Background(), TVShow{Title: ""}); !errors.Is(err, ErrInvalidItem) {
+			t.Errorf("AddTVShow with no title: error = %v, want ErrInvalidItem", err)
+		}
+	})
+}
+
+func TestUpdateMissingItems(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		trashedMovie := mustAddMovie(t, store, Movie{Title: "Trashed Movie"})
+		trashedShow := mustAddTVShow(t, store, TVShow{Title: "Trashed Show"})
+
+		if err := store.DeleteMovie(ctx, trashedMovie); err != nil {
+			t.Fatalf("DeleteMovie: %v", err)
+		}
+
+		if err := store.DeleteTVShow(ctx, trashedShow); err != nil {
+			t.Fatalf("DeleteTVShow: %v", err)
+		}
+
+		tests := []struct {
+			name   string
+			update func() error
+		}{
+			{"unknown movie", func() error { return store.UpdateMovie(ctx, Movie{ID: 9999, Title: "Nope"}) }},
+			{"trashed movie", func() error { return store.UpdateMovie(ctx, Movie{ID: trashedMovie, Title: "Changed"}) }},
+			{"unknown TV show", func() error { return store.UpdateTVShow(ctx, TVShow{ID: 9999, Title: "Nope"}) }},
+			{"trashed TV show", func() error { return store.UpdateTVShow(ctx, TVShow{ID: trashedShow, Title: "Changed"}) }},
+		}
+
+		for _, tt := range tests {
+			if err := tt.update(); !errors.Is(err, ErrItemNotFound) {
+				t.Errorf("%s: error = %v, want ErrItemNotFound", tt.name, err)
+			}
+		}
+
+		trash, err := store.GetTrash(ctx)
+
+		if err != nil {
+			t.Fatalf("GetTrash: %v", err)
+		}
+
+		for _, item := range trash {
+			if item.Title == "Changed" {
+				t.Errorf("update changed trashed %s %d", item.ItemType, item.ID)
+			}
+		}
+	})
+}
+
+func TestUpdateKeepsWatched(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		id := mustAddMovie(t, store, Movie{Title: "Heat", Year: 1995})
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: id, Watcher: "sam"}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		// The edit form never sends the watched flag
+		if err := store.UpdateMovie(ctx, Movie{ID: id, Title: "Heat", Year: 1995, Notes: "rewatch"}); err != nil {
+			t.Fatalf("UpdateMovie: %v", err)
+		}
+
+		watched, err := store.GetWatchedMovies(ctx)
+
+		if err != nil {
+			t.Fatalf("GetWatchedMovies: %v", err)
+		}
+
+		if len(watched) != 1 || watched[0].ID != id || !watched[0].Watched || watched[0].Notes != "rewatch" {
+			t.Errorf("GetWatchedMovies = %+v, want the updated movie still watched", watched)
+		}
+	})
+}
+
+func TestDeletedItemsLeaveTheBoard(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		keep := mustAddMovie(t, store, Movie{Title: "Keep"})
+		drop := mustAddMovie(t, store, Movie{Title: "Drop"})
+
+		if err := store.DeleteMovie(ctx, drop); err != nil {
+			t.Fatalf("DeleteMovie: %v", err)
+		}
+
+		if movie, err := store.GetMovie(ctx, drop); err != nil || movie != nil {
+			t.Errorf("GetMovie(trashed) = %v, %v; want nil", movie, err)
+		}
+
+		count, err := store.GetMovieCount(ctx)
+
+		if err != nil || count != 1 {
+			t.Errorf("GetMovieCount = %d, %v; want 1", count, err)
+		}
+
+		movies, err := store.GetAllMovies(ctx)
+
+		if err != nil || len(movies) != 1 || movies[0].ID != keep {
+			t.Errorf("GetAllMovies = %+v, %v; want only movie %d", movies, err, keep)
+		}
+	})
+}
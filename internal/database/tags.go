@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tagJoin names the join table linking an item type to its tags
+type tagJoin struct {
+	table  string
+	column string
+}
+
+// tagJoins maps item types to their tag join tables
+var tagJoins = map[string]tagJoin{
+	ItemTypeMovie:  {table: "movie_tags", column: "movie_id"},
+	ItemTypeTVShow: {table: "tv_show_tags", column: "tv_show_id"},
+}
+
+// normalizeTags trims, splits on commas, de-duplicates case-insensitively and
+// sorts tags, returning nil when none are left
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+
+	var normalized []string
+
+	for _, value := range tags {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.Join(strings.Fields(tag), " ")
+			key := strings.ToLower(tag)
+
+			if tag == "" || seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return strings.ToLower(normalized[i]) < strings.ToLower(normalized[j])
+	})
+
+	return normalized
+}
+
+// hasAllTags reports whether tags contains every wanted tag, ignoring case
+func hasAllTags(tags, wanted []string) bool {
+	have := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		have[strings.ToLower(tag)] = true
+	}
+
+	for _, tag := range wanted {
+		if !have[strings.ToLower(tag)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tagFilterClause returns a WHERE fragment keeping only items that carry every tag
+func tagFilterClause(itemType, idColumn string, tags []string) (string, []interface{}) {
+	tags = normalizeTags(tags)
+
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	join := tagJoins[itemType]
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+	args := make([]interface{}, 0, len(tags)+1)
+
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+
+	args = append(args, len(tags))
+
+	clause := " AND " + idColumn + " IN (SELECT j." + join.column + " FROM " + join.table + " j JOIN tags ON tags.id = j.tag_id" +
+		" WHERE tags.name IN (" + placeholders + ") GROUP BY j." + join.column + " HAVING COUNT(*) = ?)"
+
+	return clause, args
+}
+
+// setItemTags replaces the tags attached to a movie or TV show, creating new tags as needed
+func setItemTags(ctx context.Context, q queryer, itemType string, itemID int, tags []string) error {
+	join, ok := tagJoins[itemType]
+
+	if !ok {
+		return fmt.Errorf("unknown item type: %s", itemType)
+	}
+
+	if _, err := q.ExecContext(ctx, "DELETE FROM "+join.table+" WHERE "+join.column+" = ?", itemID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	for _, tag := range normalizeTags(tags) {
+		if _, err := q.ExecContext(ctx, "INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING", tag); err != nil {
+			return fmt.Errorf("failed to create tag %q: %w", tag, err)
+		}
+
+		_, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO "+join.table+" ("+join.column+", tag_id) SELECT ?, id FROM tags WHERE name = ?", itemID, tag)
+
+		if err != nil {
+			return fmt.Errorf("failed to attach tag %q: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// GetTags returns every tag attached to at least one item of the given type
+func (s *SQLiteStore) GetTags(ctx context.Context, itemType string) ([]string, error) {
+	join, ok := tagJoins[itemType]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown item type: %s", itemType)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT tags.name FROM tags JOIN "+join.table+" j ON j.tag_id = tags.id ORDER BY tags.name")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+
+	defer rows.Close()
+
+	var tags []string
+
+	for rows.Next() {
+		var tag string
+
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"", " , "}, nil},
+		{[]string{"Sci-Fi, drama", "  Crime  "}, []string{"Crime", "drama", "Sci-Fi"}},
+		{[]string{"Drama", "drama", "DRAMA,drama"}, []string{"Drama"}},
+		{[]string{"Film   Noir"}, []string{"Film Noir"}},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		heat := mustAddMovie(t, store, Movie{Title: "Heat", Tags: []string{"Crime, drama", " crime "}})
+		mustAddMovie(t, store, Movie{Title: "Arrival", Tags: []string{"Sci-Fi"}})
+		mustAddTVShow(t, store, TVShow{Title: "Lost", Tags: []string{"Mystery"}})
+
+		movie, err := store.GetMovie(ctx, heat)
+
+		if err != nil || movie == nil || !reflect.DeepEqual(movie.Tags, []string{"Crime", "drama"}) {
+			t.Fatalf("GetMovie(%d) = %+v, %v; want tags Crime and drama", heat, movie, err)
+		}
+
+		// Each board lists only the tags its own items carry
+		boards := []struct {
+			itemType string
+			want     []string
+		}{
+			{ItemTypeMovie, []string{"Crime", "drama", "Sci-Fi"}},
+			{ItemTypeTVShow, []string{"Mystery"}},
+		}
+
+		for _, tt := range boards {
+			if got, err := store.GetTags(ctx, tt.itemType); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTags(%s) = %q, %v; want %q", tt.itemType, got, err, tt.want)
+			}
+		}
+
+		if _, err := store.GetTags(ctx, "book"); err == nil {
+			t.Error("GetTags of an unknown item type succeeded")
+		}
+
+		// Editing an item replaces its tags, and tags no item carries drop out
+		movie.Tags = []string{"Thriller", "Sci-Fi"}
+
+		if err := store.UpdateMovie(ctx, *movie); err != nil {
+			t.Fatalf("UpdateMovie: %v", err)
+		}
+
+		if got, err := store.GetTags(ctx, ItemTypeMovie); err != nil || !reflect.DeepEqual(got, []string{"Sci-Fi", "Thriller"}) {
+			t.Errorf("GetTags after editing = %q, %v; want Sci-Fi and Thriller", got, err)
+		}
+
+		// Filters match tags ignoring case and need every tag given
+		filters := []struct {
+			tags []string
+			want []string
+		}{
+			{[]string{"sci-fi"}, []string{"Arrival", "Heat"}},
+			{[]string{"SCI-FI", "thriller"}, []string{"Heat"}},
+			{[]string{"Crime"}, nil},
+		}
+
+		for _, tt := range filters {
+			page, err := store.ListMovies(ctx, BoardQuery{Sort: SortTitle, Tags: tt.tags})
+			var titles []string
+
+			for _, movie := range page.Movies {
+				titles = append(titles, movie.Title)
+			}
+
+			if err != nil || !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("ListMovies(tags %q) = %q, %v; want %q", tt.tags, titles, err, tt.want)
+			}
+		}
+
+		if page, err := store.ListTVShows(ctx, BoardQuery{Tags: []string{"mystery"}}); err != nil || len(page.TVShows) != 1 || page.TVShows[0].Title != "Lost" {
+			t.Errorf("ListTVShows(tag mystery) = %+v, %v; want Lost", page.TVShows, err)
+		}
+	})
+}
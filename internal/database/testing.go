@@ -0,0 +1,20 @@
+package database
+
+import "testing"
+
+// TestStores returns a migrated SQLite store in a temporary directory and an
+// in-memory store, keyed by backend name, so tests in any package can check
+// a behaviour against both. The SQLite store is closed when the test ends.
+func TestStores(t testing.TB) map[string]Store {
+	t.Helper()
+
+	sqlite, err := InitDB(t.TempDir(), "test")
+
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+
+	t.Cleanup(func() { sqlite.Close() })
+
+	return map[string]Store{"sqlite": sqlite, "memory": NewMemoryStore()}
+}
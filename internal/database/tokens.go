@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scopes an API token can be granted
+const (
+	ScopeRead  = "read"  // GET requests: boards, search, history and the JSON API
+	ScopeWrite = "write" // requests that change something, within the user's role
+	ScopeAI    = "ai"    // questions to the AI assistant
+)
+
+// Scopes lists every token scope in the order forms show them
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAI}
+
+// tokenTouchInterval is how stale a token's last-used time may get before a
+// request updates it, so busy clients do not write on every call
+const tokenTouchInterval = time.Minute
+
+// Errors returned for API tokens
+var (
+	ErrInvalidToken  = errors.New("invalid API token")
+	ErrTokenNotFound = errors.New("API token not found")
+)
+
+// APIToken is a user's credential for scripts and automations. Only a hash of
+// the secret is stored; it is shown once, when the token is created.
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt time.Time // zero when the token has never been used
+}
+
+// HasScope reports whether the token was granted scope
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// normalizeAPIToken trims and validates a token before it is saved
+func normalizeAPIToken(token APIToken, tokenHash string) (APIToken, error) {
+	token.Name = strings.TrimSpace(token.Name)
+
+	if token.Name == "" {
+		return token, fmt.Errorf("%w: name is required", ErrInvalidToken)
+	}
+
+	if len(token.Scopes) == 0 {
+		return token, fmt.Errorf("%w: at least one scope is required", ErrInvalidToken)
+	}
+
+	for _, scope := range token.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return token, fmt.Errorf("%w: unknown scope %q", ErrInvalidToken, scope)
+		}
+	}
+
+	// Store scopes once each, in the order of Scopes
+	var scopes []string
+
+	for _, scope := range Scopes {
+		if slices.Contains(token.Scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	token.Scopes = scopes
+
+	if tokenHash == "" {
+		return token, fmt.Errorf("%w: token hash is required", ErrInvalidToken)
+	}
+
+	return token, nil
+}
+
+// CreateAPIToken stores a user's token under the hash of its secret and returns its ID
+func (s *SQLiteStore) CreateAPIToken(ctx context.Context, token APIToken, tokenHash string) (int, error) {
+	token, err := normalizeAPIToken(token, tokenHash)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO api_tokens (user_id, name, token_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)",
+		token.UserID, token.Name, tokenHash, strings.Join(token.Scopes, ","), time.Now().UTC())
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+
+	return int(id), err
+}
+
+// ListAPITokens returns a user's tokens, newest first
+func (s *SQLiteStore) ListAPITokens(ctx context.Context, userID int) ([]APIToken, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, name, scopes, created_at, last_used_at
+		FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC`, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+
+	defer rows.Close()
+
+	var tokens []APIToken
+
+	for rows.Next() {
+		var token APIToken
+		var scopes string
+		var lastUsedAt sql.NullTime
+
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+
+		token.Scopes = strings.Split(scopes, ",")
+		token.LastUsedAt = lastUsedAt.Time
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes one of a user's tokens
+func (s *SQLiteStore) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	if removed, err := result.RowsAffected(); err == nil && removed == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// UseAPIToken returns the token stored under a hash and the user it belongs
+// to, recording that it was used; both are nil for an unknown token
+func (s *SQLiteStore) UseAPIToken(ctx context.Context, tokenHash string) (*APIToken, *User, error) {
+	var token APIToken
+	var user User
+	var scopes string
+	var lastUsedAt sql.NullTime
+
+	err := s.db.QueryRowContext(ctx, "SELECT api_tokens.id, api_tokens.user_id, api_tokens.name, api_tokens.scopes, api_tokens.created_at, api_tokens.last_used_at, "+
+		userColumns+" FROM api_tokens JOIN users ON users.id = api_tokens.user_id WHERE api_tokens.token_hash = ?", tokenHash).
+		Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &lastUsedAt,
+			&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+	token.LastUsedAt = lastUsedAt.Time
+
+	now := time.Now().UTC()
+
+	if now.Sub(token.LastUsedAt) >= tokenTouchInterval {
+		if _, err := s.db.ExecContext(ctx, "UPDATE api_tokens SET last_used_at = ? WHERE id = ?", now, token.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to record API token use: %w", err)
+		}
+
+		token.LastUsedAt = now
+	}
+
+	return &token, &user, nil
+}
+
+// CreateAPIToken stores a user's token under the hash of its secret and returns its ID
+func (m *MemoryStore) CreateAPIToken(ctx context.Context, token APIToken, tokenHash string) (int, error) {
+	token, err := normalizeAPIToken(token, tokenHash)
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	token.CreatedAt = time.Now().UTC()
+	m.nextID++
+	m.apiTokens[tokenHash] = token
+
+	return token.ID, nil
+}
+
+// ListAPITokens returns a user's tokens, newest first
+func (m *MemoryStore) ListAPITokens(ctx context.Context, userID int) ([]APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tokens []APIToken
+
+	for _, token := range m.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID > tokens[j].ID
+	})
+
+	return tokens, nil
+}
+
+// DeleteAPIToken revokes one of a user's tokens
+func (m *MemoryStore) DeleteAPIToken(ctx context.Context, userID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tokenHash, token := range m.apiTokens {
+		if token.ID == id && token.UserID == userID {
+			delete(m.apiTokens, tokenHash)
+
+			return nil
+		}
+	}
+
+	return ErrTokenNotFound
+}
+
+// UseAPIToken returns the token stored under a hash and the user it belongs
+// to, recording that it was used; both are nil for an unknown token
+func (m *MemoryStore) UseAPIToken(ctx context.Context, tokenHash string) (*APIToken, *User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.apiTokens[tokenHash]
+
+	if !ok {
+		return nil, nil, nil
+	}
+
+	user, ok := m.users[token.UserID]
+
+	if !ok {
+		return nil, nil, nil
+	}
+
+	if now := time.Now().UTC(); now.Sub(token.LastUsedAt) >= tokenTouchInterval {
+		token.LastUsedAt = now
+		m.apiTokens[tokenHash] = token
+	}
+
+	return &token, &user, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAPITokens(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		owner, err := store.AddUser(ctx, User{Username: "sam", PasswordHash: "hash", Role: RoleEditor})
+
+		if err != nil {
+			t.Fatalf("AddUser: %v", err)
+		}
+
+		invalid := []struct {
+			name      string
+			token     APIToken
+			tokenHash string
+		}{
+			{"no name", APIToken{UserID: owner, Name: " ", Scopes: []string{ScopeRead}}, "hash-1"},
+			{"no scopes", APIToken{UserID: owner, Name: "script"}, "hash-1"},
+			{"unknown scope", APIToken{UserID: owner, Name: "script", Scopes: []string{ScopeRead, "admin"}}, "hash-1"},
+			{"no hash", APIToken{UserID: owner, Name: "script", Scopes: []string{ScopeRead}}, ""},
+		}
+
+		for _, tt := range invalid {
+			if _, err := store.CreateAPIToken(ctx, tt.token, tt.tokenHash); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%s: CreateAPIToken error = %v, want ErrInvalidToken", tt.name, err)
+			}
+		}
+
+		id, err := store.CreateAPIToken(ctx, APIToken{UserID: owner, Name: " script ", Scopes: []string{ScopeAI, ScopeRead, ScopeAI}}, "hash-1")
+
+		if err != nil {
+			t.Fatalf("CreateAPIToken: %v", err)
+		}
+
+		token, user, err := store.UseAPIToken(ctx, "hash-1")
+
+		if err != nil || token == nil || user == nil {
+			t.Fatalf("UseAPIToken = %v, %v, %v; want the token and its user", token, user, err)
+		}
+
+		if token.ID != id || token.Name != "script" || !reflect.DeepEqual(token.Scopes, []string{ScopeRead, ScopeAI}) || user.Username != "sam" {
+			t.Errorf("UseAPIToken = %+v for %+v", token, user)
+		}
+
+		for scope, want := range map[string]bool{ScopeRead: true, ScopeWrite: false, ScopeAI: true} {
+			if token.HasScope(scope) != want {
+				t.Errorf("HasScope(%q) = %v, want %v", scope, !want, want)
+			}
+		}
+
+		if token, user, err := store.UseAPIToken(ctx, "hash-2"); token != nil || user != nil || err != nil {
+			t.Errorf("UseAPIToken(unknown) = %v, %v, %v; want nils", token, user, err)
+		}
+
+		if err := store.DeleteAPIToken(ctx, owner+1, id); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("DeleteAPIToken by another user: error = %v, want ErrTokenNotFound", err)
+		}
+
+		if err := store.DeleteAPIToken(ctx, owner, id); err != nil {
+			t.Fatalf("DeleteAPIToken: %v", err)
+		}
+
+		if token, _, err := store.UseAPIToken(ctx, "hash-1"); token != nil || err != nil {
+			t.Errorf("UseAPIToken after delete = %v, %v; want nil", token, err)
+		}
+	})
+}
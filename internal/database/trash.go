@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// TrashItem is a soft-deleted movie or TV show awaiting restore or purge
+type TrashItem struct {
+	ItemType  string
+	ID        int
+	Title     string
+	Year      int
+	DeletedAt time.Time
+}
+
+// trashTables maps item types to the tables that support soft delete
+var trashTables = map[string]string{
+	ItemTypeMovie:  "movies",
+	ItemTypeTVShow: "tv_shows",
+}
+
+// trashTable returns the table for an item type, rejecting unknown types
+func trashTable(itemType string) (string, error) {
+	table, ok := trashTables[itemType]
+
+	if !ok {
+		return "", fmt.Errorf("unknown item type: %s", itemType)
+	}
+
+	return table, nil
+}
+
+// GetTrash returns every trashed movie and TV show, most recently deleted first
+func (s *SQLiteStore) GetTrash(ctx context.Context) ([]TrashItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT 'movie', id, title, year, deleted_at FROM movies WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'tvshow', id, title, year, deleted_at FROM tv_shows WHERE deleted_at IS NOT NULL
+	ORDER BY 5 DESC`)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+
+	defer rows.Close()
+
+	var items []TrashItem
+
+	for rows.Next() {
+		var item TrashItem
+
+		if err := rows.Scan(&item.ItemType, &item.ID, &item.Title, &item.Year, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// RestoreItem takes a movie or TV show back out of the trash
+func (s *SQLiteStore) RestoreItem(ctx context.Context, itemType string, id int) error {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Restoring %s %d from the trash", itemType, id)
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", itemType, err)
+		}
+
+		if restored, _ := result.RowsAffected(); restored == 0 {
+			return nil
+		}
+
+		return recordAudit(ctx, tx, itemType, id, AuditRestore, nil, nil)
+	})
+}
+
+// PurgeItem permanently deletes a trashed movie or TV show and its history
+func (s *SQLiteStore) PurgeItem(ctx context.Context, itemType string, id int) error {
+	table, err := trashTable(itemType)
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Purging %s %d from the trash", itemType, id)
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = ? AND deleted_at IS NOT NULL", id)
+
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", itemType, err)
+		}
+
+		if purged, _ := result.RowsAffected(); purged == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM watch_events WHERE item_type = ? AND item_id = ?", itemType, id); err != nil {
+			return fmt.Errorf("failed to purge watch history: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM profile_votes WHERE item_type = ? AND item_id = ?", itemType, id); err != nil {
+			return fmt.Errorf("failed to purge votes: %w", err)
+		}
+
+		return recordAudit(ctx, tx, itemType, id, AuditPurge, nil, nil)
+	})
+}
+
+// PurgeTrashBefore permanently deletes items trashed before the cutoff and
+// returns how many were removed
+func (s *SQLiteStore) PurgeTrashBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	items, err := s.GetTrash(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, item := range items {
+		if !item.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		if err := s.PurgeItem(ctx, item.ItemType, item.ID); err != nil {
+			return purged, err
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
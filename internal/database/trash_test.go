@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		restored := mustAddMovie(t, store, Movie{Title: "Restored", Year: 2001})
+		purged := mustAddMovie(t, store, Movie{Title: "Purged"})
+		show := mustAddTVShow(t, store, TVShow{Title: "Show"})
+		live := mustAddMovie(t, store, Movie{Title: "Live"})
+
+		for _, step := range []struct {
+			itemType string
+			id       int
+		}{{ItemTypeMovie, restored}, {ItemTypeMovie, purged}, {ItemTypeTVShow, show}} {
+			var err error
+
+			if step.itemType == ItemTypeMovie {
+				err = store.DeleteMovie(ctx, step.id)
+			} else {
+				err = store.DeleteTVShow(ctx, step.id)
+			}
+
+			if err != nil {
+				t.Fatalf("delete %s %d: %v", step.itemType, step.id, err)
+			}
+		}
+
+		if err := store.DeleteMovie(ctx, purged); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("DeleteMovie of a trashed movie: error = %v, want ErrItemNotFound", err)
+		}
+
+		if err := store.DeleteTVShow(ctx, 9999); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("DeleteTVShow of an unknown show: error = %v, want ErrItemNotFound", err)
+		}
+
+		trash, err := store.GetTrash(ctx)
+
+		if err != nil || len(trash) != 3 {
+			t.Fatalf("GetTrash = %+v, %v; want 3 items", trash, err)
+		}
+
+		tests := []struct {
+			name    string
+			run     func() error
+			wantErr bool
+		}{
+			{"restore", func() error { return store.RestoreItem(ctx, ItemTypeMovie, restored) }, false},
+			{"restore again", func() error { return store.RestoreItem(ctx, ItemTypeMovie, restored) }, false},
+			{"purge", func() error { return store.PurgeItem(ctx, ItemTypeMovie, purged) }, false},
+			{"purge a live movie", func() error { return store.PurgeItem(ctx, ItemTypeMovie, live) }, false},
+			{"restore unknown type", func() error { return store.RestoreItem(ctx, "book", show) }, true},
+			{"purge unknown type", func() error { return store.PurgeItem(ctx, "book", show) }, true},
+		}
+
+		for _, tt := range tests {
+			if err := tt.run(); (err != nil) != tt.wantErr {
+				t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+		}
+
+		for id, want := range map[int]bool{restored: true, purged: false, live: true} {
+			if movie, err := store.GetMovie(ctx, id); err != nil || (movie != nil) != want {
+				t.Errorf("GetMovie(%d) = %v, %v; want found %v", id, movie, err, want)
+			}
+		}
+
+		if n, err := store.PurgeTrashBefore(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+			t.Errorf("PurgeTrashBefore an hour ago = %d, %v; want 0", n, err)
+		}
+
+		if n, err := store.PurgeTrashBefore(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+			t.Errorf("PurgeTrashBefore an hour from now = %d, %v; want the show", n, err)
+		}
+
+		if trash, err := store.GetTrash(ctx); err != nil || len(trash) != 0 {
+			t.Errorf("GetTrash after purging = %+v, %v; want empty", trash, err)
+		}
+	})
+}
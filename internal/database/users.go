@@ -0,0 +1,352 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Roles a user can have, each allowed everything the one before it is
+const (
+	RoleViewer = "viewer" // browse the boards and use the picker
+	RoleEditor = "editor" // also add, edit, delete and watch items and ask the AI
+	RoleAdmin  = "admin"  // also purge the trash, merge duplicates and import files
+)
+
+// roleRanks orders the roles from least to most trusted
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Errors returned when a user cannot be added
+var (
+	ErrInvalidUser = errors.New("invalid user")
+	ErrUserExists  = errors.New("user already exists")
+)
+
+// User is a local account that can sign in to the web interface
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session is a signed-in browser, identified by a hash of its cookie's token
+type Session struct {
+	TokenHash string
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// HasRole reports whether the user is allowed what role is
+func (u User) HasRole(role string) bool {
+	return roleRanks[u.Role] >= roleRanks[role] && ValidRole(role)
+}
+
+// normalizeUser trims and validates a user before it is saved
+func normalizeUser(user User) (User, error) {
+	user.Username = strings.TrimSpace(user.Username)
+
+	if user.Username == "" || strings.ContainsFunc(user.Username, func(r rune) bool { return r <= ' ' }) {
+		return user, fmt.Errorf("%w: username must be non-empty with no spaces", ErrInvalidUser)
+	}
+
+	if !ValidRole(user.Role) {
+		return user, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, user.Role)
+	}
+
+	if user.PasswordHash == "" {
+		return user, fmt.Errorf("%w: password is required", ErrInvalidUser)
+	}
+
+	return user, nil
+}
+
+// AddUser creates an account and returns its ID; usernames are unique ignoring case
+func (s *SQLiteStore) AddUser(ctx context.Context, user User) (int, error) {
+	user, err := normalizeUser(user)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", user.Username).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check username: %w", err)
+		}
+
+		if exists {
+			return fmt.Errorf("%w: %s", ErrUserExists, user.Username)
+		}
+
+		result, err := tx.ExecContext(ctx, "INSERT INTO users (username, password_hash, role, created_at) VALUES (?, ?, ?, ?)",
+			user.Username, user.PasswordHash, user.Role, time.Now().UTC())
+
+		if err != nil {
+			return fmt.Errorf("failed to add user: %w", err)
+		}
+
+		id, err = result.LastInsertId()
+
+		return err
+	})
+
+	return int(id), err
+}
+
+// userColumns is the column list every user query selects, in scanUser order
+const userColumns = "users.id, users.username, users.password_hash, users.role, users.created_at"
+
+// scanUser reads one users row
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUserByUsername returns the account with a username, ignoring case, or nil
+func (s *SQLiteStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = ?", strings.TrimSpace(username)))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListUsers returns every account in username order
+func (s *SQLiteStore) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY username")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+
+	defer rows.Close()
+
+	var users []User
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// CountUsers returns how many accounts exist; sign-in is only required once there is one
+func (s *SQLiteStore) CountUsers(ctx context.Context) (int, error) {
+	var count int
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+// CreateSession records a sign-in
+func (s *SQLiteStore) CreateSession(ctx context.Context, session Session) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		session.TokenHash, session.UserID, time.Now().UTC(), session.ExpiresAt.UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionUser returns the account signed in with a session, or nil when
+// the session does not exist or has expired
+func (s *SQLiteStore) GetSessionUser(ctx context.Context, tokenHash string) (*User, error) {
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+
+		" FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.token_hash = ? AND sessions.expires_at > ?",
+		tokenHash, time.Now().UTC()))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteSession signs a session out
+func (s *SQLiteStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = ?", tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions past their expiry and returns how many it removed
+func (s *SQLiteStore) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", time.Now().UTC())
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+
+	return int(removed), err
+}
+
+// AddUser creates an account and returns its ID; usernames are unique ignoring case
+func (m *MemoryStore) AddUser(ctx context.Context, user User) (int, error) {
+	user, err := normalizeUser(user)
+
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			return 0, fmt.Errorf("%w: %s", ErrUserExists, user.Username)
+		}
+	}
+
+	user.ID = m.nextID
+	user.CreatedAt = time.Now().UTC()
+	m.nextID++
+	m.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+// GetUserByUsername returns the account with a username, ignoring case, or nil
+func (m *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if strings.EqualFold(user.Username, strings.TrimSpace(username)) {
+			return &user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ListUsers returns every account in username order
+func (m *MemoryStore) ListUsers(ctx context.Context) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []User
+
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Username) < strings.ToLower(users[j].Username)
+	})
+
+	return users, nil
+}
+
+// CountUsers returns how many accounts exist
+func (m *MemoryStore) CountUsers(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.users), nil
+}
+
+// CreateSession records a sign-in
+func (m *MemoryStore) CreateSession(ctx context.Context, session Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[session.TokenHash] = session
+
+	return nil
+}
+
+// GetSessionUser returns the account signed in with a session, or nil when
+// the session does not exist or has expired
+func (m *MemoryStore) GetSessionUser(ctx context.Context, tokenHash string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[tokenHash]
+
+	if !ok || !session.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+
+	user, ok := m.users[session.UserID]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &user, nil
+}
+
+// DeleteSession signs a session out
+func (m *MemoryStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, tokenHash)
+
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions past their expiry and returns how many it removed
+func (m *MemoryStore) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+
+	for tokenHash, session := range m.sessions {
+		if !session.ExpiresAt.After(time.Now()) {
+			delete(m.sessions, tokenHash)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUsers(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		invalid := []struct {
+			name string
+			user User
+		}{
+			{"no username", User{Username: " ", PasswordHash: "hash", Role: RoleViewer}},
+			{"space in username", User{Username: "sam smith", PasswordHash: "hash", Role: RoleViewer}},
+			{"unknown role", User{Username: "sam", PasswordHash: "hash", Role: "owner"}},
+			{"no password", User{Username: "sam", Role: RoleViewer}},
+		}
+
+		for _, tt := range invalid {
+			if _, err := store.AddUser(ctx, tt.user); !errors.Is(err, ErrInvalidUser) {
+				t.Errorf("%s: AddUser error = %v, want ErrInvalidUser", tt.name, err)
+			}
+		}
+
+		if count, err := store.CountUsers(ctx); err != nil || count != 0 {
+			t.Fatalf("CountUsers after invalid adds = %d, %v; want 0", count, err)
+		}
+
+		sam, err := store.AddUser(ctx, User{Username: " sam ", PasswordHash: "hash", Role: RoleEditor})
+
+		if err != nil {
+			t.Fatalf("AddUser: %v", err)
+		}
+
+		if _, err := store.AddUser(ctx, User{Username: "alex", PasswordHash: "hash", Role: RoleAdmin}); err != nil {
+			t.Fatalf("AddUser: %v", err)
+		}
+
+		if _, err := store.AddUser(ctx, User{Username: "SAM", PasswordHash: "other", Role: RoleViewer}); !errors.Is(err, ErrUserExists) {
+			t.Errorf("AddUser of a taken username in another case: error = %v, want ErrUserExists", err)
+		}
+
+		user, err := store.GetUserByUsername(ctx, " Sam")
+
+		if err != nil || user == nil || user.ID != sam || user.Username != "sam" || user.Role != RoleEditor || user.CreatedAt.IsZero() {
+			t.Errorf("GetUserByUsername ignoring case = %+v, %v; want sam", user, err)
+		}
+
+		if user, err := store.GetUserByUsername(ctx, "nobody"); err != nil || user != nil {
+			t.Errorf("GetUserByUsername(unknown) = %+v, %v; want nil", user, err)
+		}
+
+		users, err := store.ListUsers(ctx)
+		var names []string
+
+		for _, user := range users {
+			names = append(names, user.Username)
+		}
+
+		if err != nil || !reflect.DeepEqual(names, []string{"alex", "sam"}) {
+			t.Errorf("ListUsers = %q, %v; want alex and sam", names, err)
+		}
+
+		if count, err := store.CountUsers(ctx); err != nil || count != 2 {
+			t.Errorf("CountUsers = %d, %v; want 2", count, err)
+		}
+	})
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		role, needs string
+		want        bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleEditor, false},
+		{RoleEditor, RoleViewer, true},
+		{RoleEditor, RoleAdmin, false},
+		{RoleAdmin, RoleEditor, true},
+		{"", RoleViewer, false},
+		{RoleAdmin, "owner", false},
+	}
+
+	for _, tt := range tests {
+		if got := (User{Role: tt.role}).HasRole(tt.needs); got != tt.want {
+			t.Errorf("%q HasRole(%q) = %v, want %v", tt.role, tt.needs, got, tt.want)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		sam, err := store.AddUser(ctx, User{Username: "sam", PasswordHash: "hash", Role: RoleViewer})
+
+		if err != nil {
+			t.Fatalf("AddUser: %v", err)
+		}
+
+		sessions := map[string]time.Time{
+			"live":    time.Now().Add(time.Hour),
+			"expired": time.Now().Add(-time.Minute),
+		}
+
+		for hash, expires := range sessions {
+			if err := store.CreateSession(ctx, Session{TokenHash: hash, UserID: sam, ExpiresAt: expires}); err != nil {
+				t.Fatalf("CreateSession(%s): %v", hash, err)
+			}
+		}
+
+		for hash, want := range map[string]bool{"live": true, "expired": false, "unknown": false} {
+			if user, err := store.GetSessionUser(ctx, hash); err != nil || (user != nil) != want || (user != nil && user.ID != sam) {
+				t.Errorf("GetSessionUser(%s) = %+v, %v; want found %v", hash, user, err, want)
+			}
+		}
+
+		if removed, err := store.DeleteExpiredSessions(ctx); err != nil || removed != 1 {
+			t.Errorf("DeleteExpiredSessions = %d, %v; want 1", removed, err)
+		}
+
+		if err := store.DeleteSession(ctx, "live"); err != nil {
+			t.Fatalf("DeleteSession: %v", err)
+		}
+
+		if user, err := store.GetSessionUser(ctx, "live"); err != nil || user != nil {
+			t.Errorf("GetSessionUser after signing out = %+v, %v; want nil", user, err)
+		}
+	})
+}
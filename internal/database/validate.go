@@ -0,0 +1,121 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Years accepted for movies and TV shows; 0 means the year is unknown
+const (
+	MinYear = 1900
+	MaxYear = 3000
+)
+
+// MaxRuntime is the longest movie runtime accepted, in minutes; 0 means the runtime is unknown
+const MaxRuntime = 1000
+
+// ErrInvalidItem is returned when a movie or TV show fails validation
+var ErrInvalidItem = errors.New("invalid item")
+
+// validateItem checks the fields movies and TV shows share
+func validateItem(title string, year int) error {
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidItem)
+	}
+
+	if year != 0 && (year < MinYear || year > MaxYear) {
+		return fmt.Errorf("%w: year %d is outside %d-%d", ErrInvalidItem, year, MinYear, MaxYear)
+	}
+
+	return nil
+}
+
+// normalizeMovie trims and validates a movie before it is saved
+func normalizeMovie(movie Movie) (Movie, error) {
+	movie.Title = strings.TrimSpace(movie.Title)
+	movie.IMDBLink = strings.TrimSpace(movie.IMDBLink)
+
+	if err := validateItem(movie.Title, movie.Year); err != nil {
+		return movie, err
+	}
+
+	if movie.Runtime < 0 || movie.Runtime > MaxRuntime {
+		return movie, fmt.Errorf("%w: runtime %d is outside 0-%d minutes", ErrInvalidItem, movie.Runtime, MaxRuntime)
+	}
+
+	details, err := normalizeDetails(movie.Details)
+
+	if err != nil {
+		return movie, err
+	}
+
+	availability, err := normalizeAvailability(movie.Availability)
+
+	if err != nil {
+		return movie, err
+	}
+
+	movie.Tags = normalizeTags(movie.Tags)
+	movie.Availability = availability
+	movie.Details = details
+
+	return movie, nil
+}
+
+// normalizeTVShow trims and validates a TV show before it is saved
+func normalizeTVShow(tvShow TVShow) (TVShow, error) {
+	tvShow.Title = strings.TrimSpace(tvShow.Title)
+	tvShow.IMDBLink = strings.TrimSpace(tvShow.IMDBLink)
+
+	if err := validateItem(tvShow.Title, tvShow.Year); err != nil {
+		return tvShow, err
+	}
+
+	details, err := normalizeDetails(tvShow.Details)
+
+	if err != nil {
+		return tvShow, err
+	}
+
+	availability, err := normalizeAvailability(tvShow.Availability)
+
+	if err != nil {
+		return tvShow, err
+	}
+
+	tvShow.Tags = normalizeTags(tvShow.Tags)
+	tvShow.Availability = availability
+	tvShow.Details = details
+
+	return tvShow, nil
+}
+
+// normalizeDetails trims the metadata fields and rejects poster URLs that are not http or https
+func normalizeDetails(details Details) (Details, error) {
+	details.PosterURL = strings.TrimSpace(details.PosterURL)
+	details.Director = strings.TrimSpace(details.Director)
+
+	var cast []string
+
+	for _, name := range details.Cast {
+		if name = strings.TrimSpace(name); name != "" {
+			cast = append(cast, name)
+		}
+	}
+
+	details.Cast = cast
+
+	if details.PosterURL == "" {
+		return details, nil
+	}
+
+	poster, err := url.Parse(details.PosterURL)
+
+	if err != nil || (poster.Scheme != "http" && poster.Scheme != "https") || poster.Host == "" {
+		return details, fmt.Errorf("%w: poster URL %q is not an http or https URL", ErrInvalidItem, details.PosterURL)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Item types shared by every table that can point at either board
+const (
+	ItemTypeMovie  = "movie"
+	ItemTypeTVShow = "tvshow"
+)
+
+// WatchEvent records one viewing of a movie or TV show
+type WatchEvent struct {
+	ID        int
+	ItemType  string
+	ItemID    int
+	Watcher   string
+	WatchedAt time.Time
+	Rating    int
+	Review    string
+}
+
+// WatchedMovie is a movie in the watched archive with its viewings, newest first
+type WatchedMovie struct {
+	Movie
+	Events []WatchEvent
+}
+
+// WatchedTVShow is a TV show in the watched archive with its viewings, newest first
+type WatchedTVShow struct {
+	TVShow
+	Events []WatchEvent
+}
+
+// averageRating returns the mean rating across the given viewings; imported
+// viewings without a rating (0) are left out
+func averageRating(events []WatchEvent) float64 {
+	total, rated := 0, 0
+
+	for _, event := range events {
+		if event.Rating > 0 {
+			total += event.Rating
+			rated++
+		}
+	}
+
+	if rated == 0 {
+		return 0
+	}
+
+	return float64(total) / float64(rated)
+}
+
+// AverageRating returns the mean rating across all viewings of the movie
+func (m WatchedMovie) AverageRating() float64 {
+	return averageRating(m.Events)
+}
+
+// AverageRating returns the mean rating across all viewings of the TV show
+func (t WatchedTVShow) AverageRating() float64 {
+	return averageRating(t.Events)
+}
+
+// addWatchEvent records a viewing and moves the item off the watchlist in one transaction
+func (s *SQLiteStore) addWatchEvent(ctx context.Context, table string, event WatchEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	watchedAt := event.WatchedAt
+
+	if watchedAt.IsZero() {
+		watchedAt = time.Now()
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO watch_events (item_type, item_id, watcher, watched_at, rating, review)
+	VALUES (?, ?, ?, ?, ?, ?)`, event.ItemType, event.ItemID, event.Watcher, watchedAt.UTC(), event.Rating, event.Review)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert watch event: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET watched = 1 WHERE id = ?", event.ItemID); err != nil {
+		return fmt.Errorf("failed to mark %s watched: %w", event.ItemType, err)
+	}
+
+	return tx.Commit()
+}
+
+// getWatchEvents returns all viewings of the given item type, grouped by item ID
+func (s *SQLiteStore) getWatchEvents(ctx context.Context, itemType string) (map[int][]WatchEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT id, item_type, item_id, watcher, watched_at, rating, COALESCE(review, '')
+	FROM watch_events
+	WHERE item_type = ?
+	ORDER BY watched_at DESC, id DESC`, itemType)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watch events: %w", err)
+	}
+
+	defer rows.Close()
+
+	events := make(map[int][]WatchEvent)
+
+	for rows.Next() {
+		var event WatchEvent
+
+		if err := rows.Scan(&event.ID, &event.ItemType, &event.ItemID, &event.Watcher, &event.WatchedAt, &event.Rating, &event.Review); err != nil {
+			return nil, fmt.Errorf("failed to scan watch event: %w", err)
+		}
+
+		events[event.ItemID] = append(events[event.ItemID], event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkMovieWatched records a viewing and moves the movie into the watched archive
+func (s *SQLiteStore) MarkMovieWatched(ctx context.Context, event WatchEvent) error {
+	logger.Info("Marking movie %d watched by %s (rating %d)", event.ItemID, event.Watcher, event.Rating)
+
+	event.ItemType = ItemTypeMovie
+
+	if err := s.addWatchEvent(ctx, "movies", event); err != nil {
+		logger.ErrorWithErr("Failed to mark movie watched", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ReturnMovieToWatchlist moves a watched movie back onto the watchlist, keeping its history
+func (s *SQLiteStore) ReturnMovieToWatchlist(ctx context.Context, id int) error {
+	logger.Info("Returning movie %d to the watchlist", id)
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE movies SET watched = 0 WHERE id = ?", id); err != nil {
+		logger.ErrorWithErr("Failed to return movie to watchlist", err)
+
+		return fmt.Errorf("failed to return movie to watchlist: %w", err)
+	}
+
+	return nil
+}
+
+// GetWatchedMovies returns the watched archive, most recently watched first
+func (s *SQLiteStore) GetWatchedMovies(ctx context.Context) ([]WatchedMovie, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+movieColumns+`
+	FROM movies
+	LEFT JOIN (
+		SELECT item_id, MAX(watched_at) AS last_watched
+		FROM watch_events WHERE item_type = 'movie' GROUP BY item_id
+	) w ON w.item_id = movies.id
+	WHERE movies.watched = 1 AND movies.deleted_at IS NULL
+	ORDER BY w.last_watched DESC, movies.title`)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watched movies: %w", err)
+	}
+
+	movies, err := scanMovies(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.getWatchEvents(ctx, ItemTypeMovie)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make([]WatchedMovie, 0, len(movies))
+
+	for _, movie := range movies {
+		watched = append(watched, WatchedMovie{Movie: movie, Events: events[movie.ID]})
+	}
+
+	return watched, nil
+}
+
+// MarkTVShowWatched records a viewing and moves the TV show into the watched archive
+func (s *SQLiteStore) MarkTVShowWatched(ctx context.Context, event WatchEvent) error {
+	logger.Info("Marking TV show %d watched by %s (rating %d)", event.ItemID, event.Watcher, event.Rating)
+
+	event.ItemType = ItemTypeTVShow
+
+	if err := s.addWatchEvent(ctx, "tv_shows", event); err != nil {
+		logger.ErrorWithErr("Failed to mark TV show watched", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ReturnTVShowToWatchlist moves a watched TV show back onto the watchlist, keeping its history
+func (s *SQLiteStore) ReturnTVShowToWatchlist(ctx context.Context, id int) error {
+	logger.Info("Returning TV show %d to the watchlist", id)
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE tv_shows SET watched = 0 WHERE id = ?", id); err != nil {
+		logger.ErrorWithErr("Failed to return TV show to watchlist", err)
+
+		return fmt.Errorf("failed to return tv show to watchlist: %w", err)
+	}
+
+	return nil
+}
+
+// GetWatchedTVShows returns the watched archive, most recently watched first
+func (s *SQLiteStore) GetWatchedTVShows(ctx context.Context) ([]WatchedTVShow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+tvShowColumns+`
+	FROM tv_shows
+	LEFT JOIN (
+		SELECT item_id, MAX(watched_at) AS last_watched
+		FROM watch_events WHERE item_type = 'tvshow' GROUP BY item_id
+	) w ON w.item_id = tv_shows.id
+	WHERE tv_shows.watched = 1 AND tv_shows.deleted_at IS NULL
+	ORDER BY w.last_watched DESC, tv_shows.title`)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watched tv shows: %w", err)
+	}
+
+	tvShows, err := scanTVShows(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.getWatchEvents(ctx, ItemTypeTVShow)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make([]WatchedTVShow, 0, len(tvShows))
+
+	for _, tvShow := range tvShows {
+		watched = append(watched, WatchedTVShow{TVShow: tvShow, Events: events[tvShow.ID]})
+	}
+
+	return watched, nil
+}
+
+// Stars renders the rating as filled and empty stars for display, or "" when unrated
+func (e WatchEvent) Stars() string {
+	if e.Rating <= 0 {
+		return ""
+	}
+
+	return strings.Repeat("★", e.Rating) + strings.Repeat("☆", 5-e.Rating)
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchHistory(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		heat := mustAddMovie(t, store, Movie{Title: "Heat"})
+		arrival := mustAddMovie(t, store, Movie{Title: "Arrival"})
+		mustAddMovie(t, store, Movie{Title: "Ronin"})
+		day := func(month time.Month) time.Time { return time.Date(2024, month, 1, 20, 0, 0, 0, time.UTC) }
+
+		viewings := []WatchEvent{
+			{ItemID: heat, Watcher: "sam", WatchedAt: day(time.January), Rating: 3, Review: "Long"},
+			{ItemID: heat, Watcher: "alex", WatchedAt: day(time.March), Rating: 5},
+			// An imported viewing without a rating
+			{ItemID: arrival, Watcher: "sam", WatchedAt: day(time.February)},
+		}
+
+		for _, event := range viewings {
+			if err := store.MarkMovieWatched(ctx, event); err != nil {
+				t.Fatalf("MarkMovieWatched(%+v): %v", event, err)
+			}
+		}
+
+		// The archive lists the most recently watched first, each with its
+		// viewings newest first
+		watched, err := store.GetWatchedMovies(ctx)
+
+		if err != nil || len(watched) != 2 || watched[0].ID != heat || watched[1].ID != arrival {
+			t.Fatalf("GetWatchedMovies = %+v, %v; want Heat then Arrival", watched, err)
+		}
+
+		events := watched[0].Events
+
+		if len(events) != 2 || !events[0].WatchedAt.Equal(day(time.March)) || events[0].Watcher != "alex" || events[1].Review != "Long" || events[1].ItemType != ItemTypeMovie {
+			t.Errorf("Heat's viewings = %+v, want alex's then sam's", events)
+		}
+
+		ratings := []struct {
+			movie WatchedMovie
+			want  float64
+		}{
+			{watched[0], 4},
+			{watched[1], 0},
+		}
+
+		for _, tt := range ratings {
+			if got := tt.movie.AverageRating(); got != tt.want {
+				t.Errorf("%s AverageRating = %v, want %v", tt.movie.Title, got, tt.want)
+			}
+		}
+
+		if page, err := store.ListMovies(ctx, BoardQuery{}); err != nil || len(page.Movies) != 1 || page.Movies[0].Title != "Ronin" {
+			t.Errorf("ListMovies with two watched = %+v, %v; want only Ronin", page.Movies, err)
+		}
+
+		// A movie back on the watchlist keeps its history for the next viewing
+		if err := store.ReturnMovieToWatchlist(ctx, heat); err != nil {
+			t.Fatalf("ReturnMovieToWatchlist: %v", err)
+		}
+
+		if watched, err := store.GetWatchedMovies(ctx); err != nil || len(watched) != 1 || watched[0].ID != arrival {
+			t.Errorf("GetWatchedMovies after returning Heat = %+v, %v; want Arrival", watched, err)
+		}
+
+		if err := store.MarkMovieWatched(ctx, WatchEvent{ItemID: heat, Watcher: "sam", WatchedAt: day(time.April), Rating: 1}); err != nil {
+			t.Fatalf("MarkMovieWatched: %v", err)
+		}
+
+		if watched, err := store.GetWatchedMovies(ctx); err != nil || len(watched) != 2 || len(watched[0].Events) != 3 || watched[0].AverageRating() != 3 {
+			t.Errorf("GetWatchedMovies after watching Heat again = %+v, %v; want Heat first with three viewings", watched, err)
+		}
+	})
+}
+
+func TestTVShowWatchHistory(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		lost := mustAddTVShow(t, store, TVShow{Title: "Lost"})
+
+		if err := store.MarkTVShowWatched(ctx, WatchEvent{ItemID: lost, Watcher: "sam", Rating: 2, Review: "That ending"}); err != nil {
+			t.Fatalf("MarkTVShowWatched: %v", err)
+		}
+
+		watched, err := store.GetWatchedTVShows(ctx)
+
+		if err != nil || len(watched) != 1 || len(watched[0].Events) != 1 || watched[0].Events[0].ItemType != ItemTypeTVShow || watched[0].Events[0].WatchedAt.IsZero() {
+			t.Fatalf("GetWatchedTVShows = %+v, %v; want Lost with one dated viewing", watched, err)
+		}
+
+		if got := watched[0].AverageRating(); got != 2 {
+			t.Errorf("AverageRating = %v, want 2", got)
+		}
+
+		if err := store.ReturnTVShowToWatchlist(ctx, lost); err != nil {
+			t.Fatalf("ReturnTVShowToWatchlist: %v", err)
+		}
+
+		if watched, err := store.GetWatchedTVShows(ctx); err != nil || len(watched) != 0 {
+			t.Errorf("GetWatchedTVShows after returning Lost = %+v, %v; want none", watched, err)
+		}
+
+		if tvShow, err := store.GetTVShow(ctx, lost); err != nil || tvShow == nil || tvShow.Watched {
+			t.Errorf("GetTVShow after returning = %+v, %v; want it on the watchlist", tvShow, err)
+		}
+	})
+}
+
+func TestStars(t *testing.T) {
+	tests := []struct {
+		rating int
+		want   string
+	}{
+		{0, ""},
+		{-1, ""},
+		{1, "★☆☆☆☆"},
+		{5, "★★★★★"},
+	}
+
+	for _, tt := range tests {
+		if got := (WatchEvent{Rating: tt.rating}).Stars(); got != tt.want {
+			t.Errorf("Stars(%d) = %q, want %q", tt.rating, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// APIPrefix is the path every JSON API route starts with
+const APIPrefix = "/api/v1/"
+
+// maxAPIBodyBytes bounds the JSON body of an API request
+const maxAPIBodyBytes = 1 << 20
+
+// apiError is the body of every API error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// writeAPIJSON writes v as the JSON body of a response
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to encode API response", err)
+		status, body = http.StatusInternalServerError, []byte(`{"error":"failed to encode response"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// WriteAPIError writes an error response with a message for the client; the
+// server uses it to refuse API requests before they reach a handler
+func WriteAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, apiError{Error: message})
+}
+
+// writeAPIMethodNotAllowed answers a method a route does not support
+func writeAPIMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	WriteAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// apiETag is the entity tag of a resource: a hash of its JSON encoding, so it
+// changes whenever any field does
+func apiETag(v interface{}) (string, error) {
+	body, err := json.Marshal(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag.
+// Weak tags compare by their value, since every representation is JSON.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeAPIResource writes a resource with its ETag, or 304 Not Modified when
+// the client's If-None-Match already names it
+func writeAPIResource(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	etag, err := apiETag(v)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to encode API response", err)
+		WriteAPIError(w, http.StatusInternalServerError, "failed to encode response")
+
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+
+	if header := r.Header.Get("If-None-Match"); header != "" && r.Method == "GET" && etagMatches(header, etag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	writeAPIJSON(w, status, v)
+}
+
+// checkIfMatch answers 412 Precondition Failed, and returns false, when the
+// request's If-Match header does not name the current version of a resource
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current interface{}) bool {
+	header := r.Header.Get("If-Match")
+
+	if header == "" {
+		return true
+	}
+
+	etag, err := apiETag(current)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusInternalServerError, "failed to encode resource")
+
+		return false
+	}
+
+	if !etagMatches(header, etag) {
+		w.Header().Set("ETag", etag)
+		WriteAPIError(w, http.StatusPreconditionFailed, "resource has changed")
+
+		return false
+	}
+
+	return true
+}
+
+// readAPIBody decodes a JSON request body into v, answering 415 or 400 and
+// returning false when the body is not JSON
+func readAPIBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		WriteAPIError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodyBytes))
+
+	if err := decoder.Decode(v); err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+
+		return false
+	}
+
+	return true
+}
+
+// apiItemID reads the item ID that follows a collection's path
+func apiItemID(path, collection string) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(path, collection+"/"))
+
+	return id, err == nil && id > 0
+}
+
+// apiSaveError answers a failed add or update: 400 with the validation message
+// for items the store rejected, 404 for items that are gone and 500 otherwise
+func apiSaveError(w http.ResponseWriter, action string, err error) {
+	status := saveErrorStatus(err)
+
+	if status == http.StatusBadRequest || status == http.StatusNotFound {
+		WriteAPIError(w, status, err.Error())
+
+		return
+	}
+
+	logger.ErrorWithErr("Failed to %s", err, action)
+	WriteAPIError(w, status, "failed to "+action)
+}
+
+// apiListError answers a failed listing, pick or search: 400 for filters the
+// store rejected and 500 otherwise
+func apiListError(w http.ResponseWriter, action string, err error) {
+	status := listErrorStatus(err)
+
+	if status == http.StatusBadRequest {
+		WriteAPIError(w, status, err.Error())
+
+		return
+	}
+
+	logger.ErrorWithErr("Failed to %s", err, action)
+	WriteAPIError(w, status, "failed to "+action)
+}
+
+// apiSearchResults is the body of a search
+type apiSearchResults struct {
+	Movies  []database.Movie  `json:"movies"`
+	TVShows []database.TVShow `json:"tv_shows"`
+}
+
+// APISearchHandler runs a full-text search across both boards, or the one the
+// type parameter names
+func APISearchHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore) {
+	if r.Method != "GET" {
+		writeAPIMethodNotAllowed(w, "GET")
+
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	scope := r.URL.Query().Get("type")
+
+	if query == "" {
+		WriteAPIError(w, http.StatusBadRequest, "q is required")
+
+		return
+	}
+
+	if scope != "" && scope != database.ItemTypeMovie && scope != database.ItemTypeTVShow {
+		WriteAPIError(w, http.StatusBadRequest, "type must be movie or tvshow")
+
+		return
+	}
+
+	results := apiSearchResults{Movies: []database.Movie{}, TVShows: []database.TVShow{}}
+
+	if scope != database.ItemTypeTVShow {
+		found, err := movies.SearchMovies(r.Context(), query)
+
+		if err != nil {
+			apiListError(w, "search movies", err)
+
+			return
+		}
+
+		results.Movies = apiMovies(found)
+	}
+
+	if scope != database.ItemTypeMovie {
+		found, err := tvShows.SearchTVShows(r.Context(), query)
+
+		if err != nil {
+			apiListError(w, "search TV shows", err)
+
+			return
+		}
+
+		results.TVShows = apiTVShows(found)
+	}
+
+	writeAPIResource(w, r, http.StatusOK, results)
+}
+
+// APIDocHandler serves the API's OpenAPI document from the static directory
+func APIDocHandler(w http.ResponseWriter, r *http.Request, staticDir string) {
+	if r.Method != "GET" {
+		writeAPIMethodNotAllowed(w, "GET")
+
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(staticDir, "openapi.json"))
+}
+
+// APINotFoundHandler answers API paths that match no route
+func APINotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteAPIError(w, http.StatusNotFound, "no such API route: "+r.URL.Path)
+}
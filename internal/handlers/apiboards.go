@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// API collection paths for the two boards
+const (
+	apiMoviesPath  = APIPrefix + "movies"
+	apiTVShowsPath = APIPrefix + "tvshows"
+)
+
+// apiMoviePage is the body of a movie listing
+type apiMoviePage struct {
+	Items      []database.Movie `json:"items"`
+	Total      int              `json:"total"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// apiTVShowPage is the body of a TV show listing
+type apiTVShowPage struct {
+	Items      []database.TVShow `json:"items"`
+	Total      int               `json:"total"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// apiDuplicateMovies is the body of a 409 answer to a movie that looks like a duplicate
+type apiDuplicateMovies struct {
+	Error      string           `json:"error"`
+	Duplicates []database.Movie `json:"duplicates"`
+}
+
+// apiDuplicateTVShows is the body of a 409 answer to a TV show that looks like a duplicate
+type apiDuplicateTVShows struct {
+	Error      string            `json:"error"`
+	Duplicates []database.TVShow `json:"duplicates"`
+}
+
+// apiMovie returns movie with its nil lists made empty, so tags, availability
+// and cast encode as [] rather than null
+func apiMovie(movie database.Movie) database.Movie {
+	movie.Tags = emptyIfNil(movie.Tags)
+	movie.Availability = emptyIfNil(movie.Availability)
+	movie.Cast = emptyIfNil(movie.Cast)
+
+	return movie
+}
+
+// apiMovies applies apiMovie to each movie, returning an empty list for none
+func apiMovies(movies []database.Movie) []database.Movie {
+	result := make([]database.Movie, 0, len(movies))
+
+	for _, movie := range movies {
+		result = append(result, apiMovie(movie))
+	}
+
+	return result
+}
+
+// apiTVShow returns tvShow with its nil lists made empty, like apiMovie
+func apiTVShow(tvShow database.TVShow) database.TVShow {
+	tvShow.Tags = emptyIfNil(tvShow.Tags)
+	tvShow.Availability = emptyIfNil(tvShow.Availability)
+	tvShow.Cast = emptyIfNil(tvShow.Cast)
+
+	return tvShow
+}
+
+// apiTVShows applies apiTVShow to each TV show, returning an empty list for none
+func apiTVShows(tvShows []database.TVShow) []database.TVShow {
+	result := make([]database.TVShow, 0, len(tvShows))
+
+	for _, tvShow := range tvShows {
+		result = append(result, apiTVShow(tvShow))
+	}
+
+	return result
+}
+
+// emptyIfNil returns an empty slice in place of nil
+func emptyIfNil[S ~[]E, E any](s S) S {
+	if s == nil {
+		return S{}
+	}
+
+	return s
+}
+
+// APIMoviesHandler lists the movie watchlist with the board's filters, sort
+// and cursor on GET, and adds a movie on POST
+func APIMoviesHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
+	switch r.Method {
+	case "GET":
+		query, err := parseBoardQuery(r.URL.Query())
+
+		if err != nil {
+			WriteAPIError(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		page, err := store.ListMovies(r.Context(), query)
+
+		if err != nil {
+			apiListError(w, "list movies", err)
+
+			return
+		}
+
+		writeAPIResource(w, r, http.StatusOK, apiMoviePage{
+			Items:      apiMovies(page.Movies),
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+		})
+	case "POST":
+		var movie database.Movie
+
+		if !readAPIBody(w, r, &movie) {
+			return
+		}
+
+		movie.ID, movie.Watched = 0, false
+
+		if !checkAPIMovie(w, r, movie, duplicates, dataset) {
+			return
+		}
+
+		id, err := store.AddMovie(r.Context(), movie)
+
+		if err != nil {
+			apiSaveError(w, "add movie", err)
+
+			return
+		}
+
+		w.Header().Set("Location", apiMoviesPath+"/"+strconv.Itoa(id))
+		writeAPIMovie(w, r, http.StatusCreated, store, id)
+	default:
+		writeAPIMethodNotAllowed(w, "GET", "POST")
+	}
+}
+
+// APIMovieHandler reads, replaces or trashes one movie, with If-Match guarding
+// the changes, and answers POST to the random path with a pick
+func APIMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
+	if r.URL.Path == apiMoviesPath+"/random" {
+		apiPickMovie(w, r, store)
+
+		return
+	}
+
+	id, ok := apiItemID(r.URL.Path, apiMoviesPath)
+
+	if !ok {
+		WriteAPIError(w, http.StatusNotFound, "movie not found")
+
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "PUT" && r.Method != "DELETE" {
+		writeAPIMethodNotAllowed(w, "GET", "PUT", "DELETE")
+
+		return
+	}
+
+	current, err := store.GetMovie(r.Context(), id)
+
+	if err != nil {
+		apiListError(w, "get movie", err)
+
+		return
+	}
+
+	if current == nil {
+		WriteAPIError(w, http.StatusNotFound, "movie not found")
+
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeAPIResource(w, r, http.StatusOK, apiMovie(*current))
+	case "PUT":
+		var movie database.Movie
+
+		if !readAPIBody(w, r, &movie) || !checkIfMatch(w, r, apiMovie(*current)) {
+			return
+		}
+
+		movie.ID = id
+
+		if !checkAPIMovie(w, r, movie, duplicates, dataset) {
+			return
+		}
+
+		if err := store.UpdateMovie(r.Context(), movie); err != nil {
+			apiSaveError(w, "update movie", err)
+
+			return
+		}
+
+		writeAPIMovie(w, r, http.StatusOK, store, id)
+	case "DELETE":
+		if !checkIfMatch(w, r, apiMovie(*current)) {
+			return
+		}
+
+		if err := store.DeleteMovie(r.Context(), id); err != nil {
+			apiSaveError(w, "delete movie", err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checkAPIMovie runs the checks the add form and edit modal make before a
+// movie is saved, answering 400 for an unknown IMDb ID and 409 for a likely
+// duplicate unless allow_duplicate=true is in the query
+func checkAPIMovie(w http.ResponseWriter, r *http.Request, movie database.Movie, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) bool {
+	if !knownIMDbLink(r.Context(), dataset, movie.IMDBLink) {
+		WriteAPIError(w, http.StatusBadRequest, "unknown IMDb ID: "+database.IMDbID(movie.IMDBLink))
+
+		return false
+	}
+
+	if allowDuplicate(r) {
+		return true
+	}
+
+	matches, err := duplicates.FindMovieDuplicates(r.Context(), movie)
+
+	if err != nil {
+		apiListError(w, "check for duplicate movies", err)
+
+		return false
+	}
+
+	if len(matches) > 0 {
+		writeAPIJSON(w, http.StatusConflict, apiDuplicateMovies{Error: "likely duplicate; retry with allow_duplicate=true to save anyway", Duplicates: apiMovies(matches)})
+
+		return false
+	}
+
+	return true
+}
+
+// writeAPIMovie answers a save with the movie as stored
+func writeAPIMovie(w http.ResponseWriter, r *http.Request, status int, store database.MovieStore, id int) {
+	movie, err := store.GetMovie(r.Context(), id)
+
+	if err != nil {
+		apiListError(w, "get saved movie", err)
+
+		return
+	}
+
+	if movie == nil {
+		WriteAPIError(w, http.StatusNotFound, "movie not found")
+
+		return
+	}
+
+	writeAPIResource(w, r, status, apiMovie(*movie))
+}
+
+// apiPickMovie picks a movie with the picker's filters, given as query or form
+// parameters, and records the pick. It answers 404 when nothing matches.
+func apiPickMovie(w http.ResponseWriter, r *http.Request, store database.MovieStore) {
+	if r.Method != "POST" {
+		writeAPIMethodNotAllowed(w, "POST")
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	options, err := parsePickForm(r)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "invalid picker filters: "+err.Error())
+
+		return
+	}
+
+	movie, err := store.PickMovie(r.Context(), options)
+
+	if err != nil {
+		apiListError(w, "pick a movie", err)
+
+		return
+	}
+
+	if movie == nil {
+		WriteAPIError(w, http.StatusNotFound, "no movie matches the filters")
+
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, apiMovie(*movie))
+}
+
+// APITVShowsHandler lists the TV show watchlist with the board's filters, sort
+// and cursor on GET, and adds a TV show on POST
+func APITVShowsHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
+	switch r.Method {
+	case "GET":
+		query, err := parseBoardQuery(r.URL.Query())
+
+		if err != nil {
+			WriteAPIError(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		page, err := store.ListTVShows(r.Context(), query)
+
+		if err != nil {
+			apiListError(w, "list TV shows", err)
+
+			return
+		}
+
+		writeAPIResource(w, r, http.StatusOK, apiTVShowPage{
+			Items:      apiTVShows(page.TVShows),
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+		})
+	case "POST":
+		var tvShow database.TVShow
+
+		if !readAPIBody(w, r, &tvShow) {
+			return
+		}
+
+		tvShow.ID, tvShow.Watched = 0, false
+
+		if !checkAPITVShow(w, r, tvShow, duplicates, dataset) {
+			return
+		}
+
+		id, err := store.AddTVShow(r.Context(), tvShow)
+
+		if err != nil {
+			apiSaveError(w, "add TV show", err)
+
+			return
+		}
+
+		w.Header().Set("Location", apiTVShowsPath+"/"+strconv.Itoa(id))
+		writeAPITVShow(w, r, http.StatusCreated, store, id)
+	default:
+		writeAPIMethodNotAllowed(w, "GET", "POST")
+	}
+}
+
+// APITVShowHandler reads, replaces or trashes one TV show, with If-Match
+// guarding the changes, and answers POST to the random path with a pick
+func APITVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
+	if r.URL.Path == apiTVShowsPath+"/random" {
+		apiPickTVShow(w, r, store)
+
+		return
+	}
+
+	id, ok := apiItemID(r.URL.Path, apiTVShowsPath)
+
+	if !ok {
+		WriteAPIError(w, http.StatusNotFound, "TV show not found")
+
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "PUT" && r.Method != "DELETE" {
+		writeAPIMethodNotAllowed(w, "GET", "PUT", "DELETE")
+
+		return
+	}
+
+	current, err := store.GetTVShow(r.Context(), id)
+
+	if err != nil {
+		apiListError(w, "get TV show", err)
+
+		return
+	}
+
+	if current == nil {
+		WriteAPIError(w, http.StatusNotFound, "TV show not found")
+
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeAPIResource(w, r, http.StatusOK, apiTVShow(*current))
+	case "PUT":
+		var tvShow database.TVShow
+
+		if !readAPIBody(w, r, &tvShow) || !checkIfMatch(w, r, apiTVShow(*current)) {
+			return
+		}
+
+		tvShow.ID = id
+
+		if !checkAPITVShow(w, r, tvShow, duplicates, dataset) {
+			return
+		}
+
+		if err := store.UpdateTVShow(r.Context(), tvShow); err != nil {
+			apiSaveError(w, "update TV show", err)
+
+			return
+		}
+
+		writeAPITVShow(w, r, http.StatusOK, store, id)
+	case "DELETE":
+		if !checkIfMatch(w, r, apiTVShow(*current)) {
+			return
+		}
+
+		if err := store.DeleteTVShow(r.Context(), id); err != nil {
+			apiSaveError(w, "delete TV show", err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checkAPITVShow runs the checks the add form and edit modal make before a TV
+// show is saved, answering 400 for an unknown IMDb ID and 409 for a likely
+// duplicate unless allow_duplicate=true is in the query
+func checkAPITVShow(w http.ResponseWriter, r *http.Request, tvShow database.TVShow, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) bool {
+	if !knownIMDbLink(r.Context(), dataset, tvShow.IMDBLink) {
+		WriteAPIError(w, http.StatusBadRequest, "unknown IMDb ID: "+database.IMDbID(tvShow.IMDBLink))
+
+		return false
+	}
+
+	if allowDuplicate(r) {
+		return true
+	}
+
+	matches, err := duplicates.FindTVShowDuplicates(r.Context(), tvShow)
+
+	if err != nil {
+		apiListError(w, "check for duplicate TV shows", err)
+
+		return false
+	}
+
+	if len(matches) > 0 {
+		writeAPIJSON(w, http.StatusConflict, apiDuplicateTVShows{Error: "likely duplicate; retry with allow_duplicate=true to save anyway", Duplicates: apiTVShows(matches)})
+
+		return false
+	}
+
+	return true
+}
+
+// writeAPITVShow answers a save with the TV show as stored
+func writeAPITVShow(w http.ResponseWriter, r *http.Request, status int, store database.TVShowStore, id int) {
+	tvShow, err := store.GetTVShow(r.Context(), id)
+
+	if err != nil {
+		apiListError(w, "get saved TV show", err)
+
+		return
+	}
+
+	if tvShow == nil {
+		WriteAPIError(w, http.StatusNotFound, "TV show not found")
+
+		return
+	}
+
+	writeAPIResource(w, r, status, apiTVShow(*tvShow))
+}
+
+// apiPickTVShow picks what to watch next with the picker's filters, given as
+// query or form parameters, and records the pick. It answers 404 when nothing matches.
+func apiPickTVShow(w http.ResponseWriter, r *http.Request, store database.TVShowStore) {
+	if r.Method != "POST" {
+		writeAPIMethodNotAllowed(w, "POST")
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		WriteAPIError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	options, err := parsePickForm(r)
+
+	if err != nil {
+		WriteAPIError(w, http.StatusBadRequest, "invalid picker filters: "+err.Error())
+
+		return
+	}
+
+	tvShow, err := store.PickTVShow(r.Context(), options)
+
+	if err != nil {
+		apiListError(w, "pick a TV show", err)
+
+		return
+	}
+
+	if tvShow == nil {
+		WriteAPIError(w, http.StatusNotFound, "no TV show matches the filters")
+
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, apiTVShow(*tvShow))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// serveMovieAPI sends one request to the movie API routes
+func serveMovieAPI(store database.Store, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	if body != "" && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+
+	if path == apiMoviesPath || strings.HasPrefix(path, apiMoviesPath+"?") {
+		APIMoviesHandler(w, r, store, store, store)
+	} else {
+		APIMovieHandler(w, r, store, store, store)
+	}
+
+	return w
+}
+
+func TestAPIMovies(t *testing.T) {
+	for name, store := range database.TestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			id, err := store.AddMovie(ctx, database.Movie{Title: "Heat", Year: 1995})
+
+			if err != nil {
+				t.Fatalf("AddMovie: %v", err)
+			}
+
+			trashed, err := store.AddMovie(ctx, database.Movie{Title: "Gone", Year: 2001})
+
+			if err != nil {
+				t.Fatalf("AddMovie: %v", err)
+			}
+
+			if err := store.DeleteMovie(ctx, trashed); err != nil {
+				t.Fatalf("DeleteMovie: %v", err)
+			}
+
+			moviePath := apiMoviesPath + "/" + strconv.Itoa(id)
+			etag := serveMovieAPI(store, "GET", moviePath, "", nil).Header().Get("ETag")
+
+			tests := []struct {
+				name   string
+				method string
+				path   string
+				body   string
+				header http.Header
+				want   int
+			}{
+				{"list", "GET", apiMoviesPath, "", nil, http.StatusOK},
+				{"bad sort", "GET", apiMoviesPath + "?sort=rating", "", nil, http.StatusBadRequest},
+				{"get", "GET", moviePath, "", nil, http.StatusOK},
+				{"not modified", "GET", moviePath, "", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+				{"get trashed", "GET", apiMoviesPath + "/" + strconv.Itoa(trashed), "", nil, http.StatusNotFound},
+				{"get unknown", "GET", apiMoviesPath + "/9999", "", nil, http.StatusNotFound},
+				{"add", "POST", apiMoviesPath, `{"title":"Ronin","year":1998}`, nil, http.StatusCreated},
+				{"add duplicate", "POST", apiMoviesPath, `{"title":"heat","year":1995}`, nil, http.StatusConflict},
+				{"add invalid", "POST", apiMoviesPath, `{"title":"","year":1995}`, nil, http.StatusBadRequest},
+				{"add not JSON", "POST", apiMoviesPath, `title=Ronin`, http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}, http.StatusUnsupportedMediaType},
+				{"replace stale", "PUT", moviePath, `{"title":"Heat","year":1995,"runtime":170}`, http.Header{"If-Match": {`"stale"`}}, http.StatusPreconditionFailed},
+				{"replace", "PUT", moviePath, `{"title":"Heat","year":1995,"runtime":170}`, http.Header{"If-Match": {etag}}, http.StatusOK},
+				{"replace trashed", "PUT", apiMoviesPath + "/" + strconv.Itoa(trashed), `{"title":"Gone"}`, nil, http.StatusNotFound},
+				{"patch", "PATCH", moviePath, `{}`, nil, http.StatusMethodNotAllowed},
+				{"delete", "DELETE", moviePath, "", nil, http.StatusNoContent},
+				{"get deleted", "GET", moviePath, "", nil, http.StatusNotFound},
+				{"delete again", "DELETE", moviePath, "", nil, http.StatusNotFound},
+				{"add second duplicate", "POST", apiMoviesPath, `{"title":"Ronin","year":1998}`, nil, http.StatusConflict},
+				{"add duplicate anyway", "POST", apiMoviesPath + "?allow_duplicate=true", `{"title":"ronin","year":1998}`, nil, http.StatusCreated},
+			}
+
+			for _, tt := range tests {
+				w := serveMovieAPI(store, tt.method, tt.path, tt.body, tt.header)
+
+				if w.Code != tt.want {
+					t.Errorf("%s: %s %s = %d, want %d (%s)", tt.name, tt.method, tt.path, w.Code, tt.want, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAPIMovieEmptyLists(t *testing.T) {
+	for name, store := range database.TestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			id, err := store.AddMovie(context.Background(), database.Movie{Title: "Plain"})
+
+			if err != nil {
+				t.Fatalf("AddMovie: %v", err)
+			}
+
+			w := serveMovieAPI(store, "GET", apiMoviesPath+"/"+strconv.Itoa(id), "", nil)
+
+			var body map[string]json.RawMessage
+
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body.String(), err)
+			}
+
+			for _, field := range []string{"tags", "availability", "cast"} {
+				if got := string(body[field]); got != "[]" {
+					t.Errorf("%s = %s, want []", field, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// safeNext returns where to send a user after signing in: a path on this
+// site, or the home page for anything that could leave it
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+
+	return next
+}
+
+// renderLogin renders the sign-in page with a status other than 200 OK for failures
+func renderLogin(w http.ResponseWriter, r *http.Request, status int, data LoginData) {
+	tmpl, err := parseTemplate("web/templates/login.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data.Title = "Sign in - Homenet"
+	data.CSRFToken = auth.CSRFTokenFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+
+	if err := tmpl.Execute(w, data); err != nil {
+		logger.ErrorWithErr("Failed to render sign-in page", err)
+	}
+}
+
+// LoginHandler shows the sign-in page and signs users in, starting a session
+// that lasts sessionLifetime
+func LoginHandler(w http.ResponseWriter, r *http.Request, users database.UserStore, sessions database.SessionStore, sessionLifetime time.Duration) {
+	switch r.Method {
+	case "GET":
+		next := safeNext(r.URL.Query().Get("next"))
+
+		if auth.UserFromContext(r.Context()) != nil {
+			http.Redirect(w, r, next, http.StatusSeeOther)
+
+			return
+		}
+
+		renderLogin(w, r, http.StatusOK, LoginData{Next: next})
+	case "POST":
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+
+			return
+		}
+
+		username := strings.TrimSpace(r.FormValue("username"))
+		next := safeNext(r.FormValue("next"))
+
+		user, err := users.GetUserByUsername(r.Context(), username)
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to look up user", err)
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+
+			return
+		}
+
+		if !auth.CheckPassword(user, r.FormValue("password")) {
+			logger.Warn("Failed sign-in for %q from %s", username, r.RemoteAddr)
+			renderLogin(w, r, http.StatusUnauthorized, LoginData{Next: next, Username: username, Error: "Invalid username or password"})
+
+			return
+		}
+
+		token, err := auth.NewToken()
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to start session", err)
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+
+			return
+		}
+
+		expires := time.Now().Add(sessionLifetime)
+		session := database.Session{TokenHash: auth.HashToken(token), UserID: user.ID, ExpiresAt: expires}
+
+		if err := sessions.CreateSession(r.Context(), session); err != nil {
+			logger.ErrorWithErr("Failed to start session", err)
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+
+			return
+		}
+
+		logger.Info("User %s signed in", user.Username)
+		auth.SetSessionCookie(w, r, token, expires)
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LogoutHandler ends the request's session and returns to the sign-in page
+func LogoutHandler(w http.ResponseWriter, r *http.Request, sessions database.SessionStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if token := auth.SessionToken(r); token != "" {
+		if err := sessions.DeleteSession(r.Context(), auth.HashToken(token)); err != nil {
+			logger.ErrorWithErr("Failed to end session", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	auth.ClearSessionCookie(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// AccountHandler renders the signed-in user's menu for the navigation bar,
+// or nothing when accounts are not set up
+func AccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	user := auth.UserFromContext(r.Context())
+
+	if user == nil {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	renderPartial(w, "account-menu", AccountData{Username: user.Username, Role: user.Role, CSRFToken: auth.CSRFTokenFromContext(r.Context())})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// formAvailability pairs the service, available_from and available_until rows
+// of the add and edit forms into availability windows
+func formAvailability(r *http.Request) database.AvailabilityList {
+	services := r.Form["service"]
+	from := r.Form["available_from"]
+	until := r.Form["available_until"]
+
+	var windows database.AvailabilityList
+
+	for i, service := range services {
+		window := database.Availability{Service: service}
+
+		if i < len(from) {
+			window.From = from[i]
+		}
+
+		if i < len(until) {
+			window.Until = until[i]
+		}
+
+		windows = append(windows, window)
+	}
+
+	return windows
+}
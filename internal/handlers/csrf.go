@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CSRFFailureHandler answers a change refused by the CSRF checks. HTMX requests
+// get a notice appended to the page, which utils.js lets through despite the
+// 403; API clients get a JSON error.
+func CSRFFailureHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, APIPrefix):
+		WriteAPIError(w, http.StatusForbidden, "CSRF check failed")
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("X-CSRF-Error", "true")
+		w.Header().Set("HX-Retarget", "body")
+		w.Header().Set("HX-Reswap", "beforeend")
+		renderPartialStatus(w, http.StatusForbidden, "csrf-error", nil)
+	default:
+		http.Error(w, "Forbidden: this page is out of date, reload it and try again", http.StatusForbidden)
+	}
+}
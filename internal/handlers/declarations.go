@@ -1,6 +1,6 @@
 package handlers
 
-// Genres list for templating (fallback defaults)
+// Genres are the suggested tags offered by the add and edit forms (fallback defaults)
 var Genres = []string{
 	"Action",
 	"Adventure",
@@ -39,6 +39,7 @@ var Navigation = []NavItem{
 	{URL: "/", Label: "Home", Icon: "M3 12l2-2m0 0l7-7 7 7M5 10v10a1 1 0 001 1h3m10-11l2 2m-2-2v10a1 1 0 01-1 1h-3m-6 0a1 1 0 001-1v-4a1 1 0 011-1h2a1 1 0 011 1v4a1 1 0 001 1m-6 0h6"},
 	{URL: "/movie-board", Label: "Movie Board", Icon: "M7 4V2a1 1 0 011-1h4a1 1 0 011 1v2h4a1 1 0 011 1v14a1 1 0 01-1 1H3a1 1 0 01-1-1V5a1 1 0 011-1h4zM9 4V3h6v1H9z"},
 	{URL: "/tv-shows-board", Label: "TV Shows Board", Icon: "M15 10l4.553-2.276A1 1 0 0121 8.618v6.764a1 1 0 01-1.447.894L15 14M5 18h8a2 2 0 002-2V8a2 2 0 00-2-2H5a2 2 0 00-2 2v8a2 2 0 002 2z"},
+	{URL: "/household", Label: "Household", Icon: "M17 20h5v-2a3 3 0 00-5.356-1.857M17 20H7m10 0v-2c0-.656-.126-1.283-.356-1.857M7 20H2v-2a3 3 0 015.356-1.857M7 20v-2c0-.656.126-1.283.356-1.857m0 0a5.002 5.002 0 019.288 0M15 7a3 3 0 11-6 0 3 3 0 016 0zm6 3a2 2 0 11-4 0 2 2 0 014 0zM7 10a2 2 0 11-4 0 2 2 0 014 0z"},
 	{URL: "/ai", Label: "AI", Icon: "M8.625 12a.375.375 0 1 1-.75 0 .375.375 0 0 1 .75 0Zm0 0H8.25m4.125 0a.375.375 0 1 1-.75 0 .375.375 0 0 1 .75 0Zm0 0H12m4.125 0a.375.375 0 1 1-.75 0 .375.375 0 0 1 .75 0Zm0 0h-.375M21 12c0 4.556-4.03 8.25-9 8.25a9.764 9.764 0 0 1-2.555-.337A5.972 5.972 0 0 1 5.41 20.97a5.969 5.969 0 0 1-.474-.065 4.48 4.48 0 0 0 .978-2.025c.09-.457-.133-.901-.467-1.226C3.93 16.178 3 14.189 3 12c0-4.556 4.03-8.25 9-8.25s9 3.694 9 8.25Z"},
 }
 
@@ -103,18 +104,17 @@ var BadgeColors = map[string]string{
 
 // MovieFormText defines text for movie forms
 var MovieFormText = FormText{
-	AddNew:       "Add New Movie",
-	Edit:         "Edit Movie",
-	Cancel:       "Cancel",
-	Save:         "Add Movie",
-	Delete:       "Delete",
-	Title:        "Movie Title",
-	Year:         "Year",
-	Genre:        "Genre",
-	Streaming:    "Streaming Service",
-	IMDBLink:     "IMDB Link (Optional)",
-	Notes:        "Notes (Optional)",
-	AvailableNow: "Available Now",
+	AddNew:    "Add New Movie",
+	Edit:      "Edit Movie",
+	Cancel:    "Cancel",
+	Save:      "Add Movie",
+	Delete:    "Delete",
+	Title:     "Movie Title",
+	Year:      "Year",
+	Tags:      "Tags",
+	Streaming: "Streaming Availability",
+	IMDBLink:  "IMDB Link (Optional)",
+	Notes:     "Notes (Optional)",
 }
 
 // TVShowFormText defines text for TV show forms
@@ -126,8 +126,8 @@ var TVShowFormText = FormText{
 	Delete:       "Delete",
 	Title:        "TV Show Title",
 	Year:         "Year",
-	Genre:        "Genre",
-	Streaming:    "Streaming Service",
+	Tags:         "Tags",
+	Streaming:    "Streaming Availability",
 	IMDBLink:     "IMDB Link (Optional)",
 	Notes:        "Notes (Optional)",
 	ActiveSeason: "Active Season",
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Where the add form and edit modal show a duplicate warning
+const (
+	addDuplicateTarget  = "#add-duplicate-warning"
+	editDuplicateTarget = "#edit-duplicate-warning"
+)
+
+// allowDuplicate reports whether the user chose to save despite a duplicate warning
+func allowDuplicate(r *http.Request) bool {
+	return r.FormValue("allow_duplicate") == "true"
+}
+
+// renderDuplicateWarning answers an add or edit with a warning about likely
+// duplicates instead of saving. The 409 status keeps the form open; HTMX swaps
+// the warning into the form rather than the list.
+func renderDuplicateWarning(w http.ResponseWriter, target string, data DuplicateWarningData) {
+	w.Header().Set("HX-Retarget", target)
+	w.Header().Set("HX-Reswap", "innerHTML")
+
+	renderPartialStatus(w, http.StatusConflict, "duplicate-warning", data)
+}
+
+// DuplicatesHandler handles the page listing likely duplicate movies and TV shows
+func DuplicatesHandler(w http.ResponseWriter, r *http.Request, duplicates database.DuplicateStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	data, err := loadDuplicates(r, duplicates)
+
+	if err != nil {
+		http.Error(w, "Failed to find duplicates: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/duplicates.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data.Title = "Duplicates"
+	data.Navigation = SetActiveNavigation("/duplicates")
+	data.CSRFToken = auth.CSRFTokenFromContext(r.Context())
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// MergeDuplicatesHandler merges every listed item into the one being kept and
+// returns the refreshed duplicate groups
+func MergeDuplicatesHandler(w http.ResponseWriter, r *http.Request, duplicates database.DuplicateStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	itemType := r.FormValue("type")
+	keepID, err := strconv.Atoi(r.FormValue("keep"))
+
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+		return
+	}
+
+	var merge func(keepID, mergeID int) error
+
+	switch itemType {
+	case database.ItemTypeMovie:
+		merge = func(keepID, mergeID int) error { return duplicates.MergeMovies(r.Context(), keepID, mergeID) }
+	case database.ItemTypeTVShow:
+		merge = func(keepID, mergeID int) error { return duplicates.MergeTVShows(r.Context(), keepID, mergeID) }
+	default:
+		http.Error(w, "Invalid item type", http.StatusBadRequest)
+
+		return
+	}
+
+	for _, value := range strings.Split(r.FormValue("merge"), ",") {
+		mergeID, err := strconv.Atoi(strings.TrimSpace(value))
+
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+			return
+		}
+
+		if err := merge(keepID, mergeID); err != nil {
+			logger.ErrorWithErr("Failed to merge duplicates", err)
+			http.Error(w, "Failed to merge: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	data, err := loadDuplicates(r, duplicates)
+
+	if err != nil {
+		http.Error(w, "Failed to find duplicates: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "duplicate-groups", data)
+}
+
+// loadDuplicates builds the duplicate groups of both boards
+func loadDuplicates(r *http.Request, duplicates database.DuplicateStore) (DuplicatesData, error) {
+	var data DuplicatesData
+
+	movieGroups, err := duplicates.GetDuplicateMovies(r.Context())
+
+	if err != nil {
+		return data, err
+	}
+
+	tvShowGroups, err := duplicates.GetDuplicateTVShows(r.Context())
+
+	if err != nil {
+		return data, err
+	}
+
+	for _, group := range movieGroups {
+		items := make([]DuplicateItem, 0, len(group))
+
+		for _, movie := range group {
+			items = append(items, DuplicateItem{ItemType: database.ItemTypeMovie, ID: movie.ID, Title: movie.Title, Year: movie.Year, Tags: movie.Tags,
+				Availability: movie.Availability, Notes: movie.Notes, IMDBLink: movie.IMDBLink, Watched: movie.Watched})
+		}
+
+		data.MovieGroups = append(data.MovieGroups, duplicateGroup(items))
+	}
+
+	for _, group := range tvShowGroups {
+		items := make([]DuplicateItem, 0, len(group))
+
+		for _, tvShow := range group {
+			items = append(items, DuplicateItem{ItemType: database.ItemTypeTVShow, ID: tvShow.ID, Title: tvShow.Title, Year: tvShow.Year, Tags: tvShow.Tags,
+				Availability: tvShow.Availability, Notes: tvShow.Notes, IMDBLink: tvShow.IMDBLink, Watched: tvShow.Watched})
+		}
+
+		data.TVShowGroups = append(data.TVShowGroups, duplicateGroup(items))
+	}
+
+	return data, nil
+}
+
+// duplicateGroup fills in what each item's merge button sends and suggests
+// keeping the item with the most fields filled in
+func duplicateGroup(items []DuplicateItem) []DuplicateItem {
+	suggested := 0
+
+	for i := range items {
+		var others []string
+
+		for _, other := range items {
+			if other.ID != items[i].ID {
+				others = append(others, strconv.Itoa(other.ID))
+			}
+		}
+
+		items[i].MergeIDs = strings.Join(others, ",")
+
+		if items[i].richness() > items[suggested].richness() {
+			suggested = i
+		}
+	}
+
+	items[suggested].Suggested = true
+
+	return items
+}
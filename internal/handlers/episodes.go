@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// maxEpisodesPerSeason bounds the episode count accepted when adding a season
+const maxEpisodesPerSeason = 100
+
+// renderEpisodeManager renders the episodes modal for a show, with an optional error message
+func renderEpisodeManager(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore, tvShowID int, message string) {
+	tvShow, err := tvShows.GetTVShow(r.Context(), tvShowID)
+
+	if err != nil {
+		http.Error(w, "Failed to get TV show: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if tvShow == nil {
+		http.Error(w, "TV show not found", http.StatusNotFound)
+
+		return
+	}
+
+	seasons, err := episodes.GetSeasons(r.Context(), tvShowID)
+
+	if err != nil {
+		http.Error(w, "Failed to get seasons: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	nextSeason := 1
+
+	if len(seasons) > 0 {
+		nextSeason = seasons[len(seasons)-1].Number + 1
+	}
+
+	renderPartial(w, "episode-manager", EpisodeData{TVShow: *tvShow, Seasons: seasons, NextSeason: nextSeason, Error: message})
+}
+
+// formInt reads a required integer form value
+func formInt(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(r.FormValue(name))
+}
+
+// EpisodesHandler returns the season and episode manager for a TV show
+func EpisodesHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	renderEpisodeManager(w, r, tvShows, episodes, id, "")
+}
+
+// AddSeasonHandler adds a season with a number of episodes to a TV show
+func AddSeasonHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	number, err := formInt(r, "season")
+
+	if err != nil || number < 0 {
+		renderEpisodeManager(w, r, tvShows, episodes, id, "Season number must be zero or more")
+
+		return
+	}
+
+	count, err := formInt(r, "episodes")
+
+	if err != nil || count < 1 || count > maxEpisodesPerSeason {
+		renderEpisodeManager(w, r, tvShows, episodes, id, "Episode count must be between 1 and "+strconv.Itoa(maxEpisodesPerSeason))
+
+		return
+	}
+
+	err = episodes.AddSeason(r.Context(), id, number, count)
+
+	if errors.Is(err, database.ErrSeasonExists) {
+		renderEpisodeManager(w, r, tvShows, episodes, id, "Season "+strconv.Itoa(number)+" already exists")
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to add season", err)
+		http.Error(w, "Failed to add season: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderEpisodeManager(w, r, tvShows, episodes, id, "")
+}
+
+// DeleteSeasonHandler removes a season and its episodes from a TV show
+func DeleteSeasonHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	seasonID, err := formInt(r, "season_id")
+
+	if err != nil {
+		http.Error(w, "Invalid season ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := episodes.DeleteSeason(r.Context(), id, seasonID); err != nil {
+		http.Error(w, "Failed to delete season: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderEpisodeManager(w, r, tvShows, episodes, id, "")
+}
+
+// EpisodeWatchedHandler marks a single episode watched or unwatched
+func EpisodeWatchedHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	episodeID, err := formInt(r, "episode_id")
+
+	if err != nil {
+		http.Error(w, "Invalid episode ID", http.StatusBadRequest)
+
+		return
+	}
+
+	watched := r.FormValue("watched") == "true"
+
+	if err := episodes.SetEpisodeWatched(r.Context(), id, episodeID, watched); err != nil {
+		http.Error(w, "Failed to update episode: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderEpisodeManager(w, r, tvShows, episodes, id, "")
+}
+
+// WatchNextEpisodeHandler marks a show's next episode watched and returns its new progress badge
+func WatchNextEpisodeHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, episodes database.EpisodeStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := episodes.WatchNextEpisode(r.Context(), id); err != nil {
+		http.Error(w, "Failed to mark episode watched: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	tvShow, err := tvShows.GetTVShow(r.Context(), id)
+
+	if err != nil || tvShow == nil {
+		http.Error(w, "TV show not found", http.StatusNotFound)
+
+		return
+	}
+
+	renderPartial(w, "tvshow-progress", tvShow)
+}
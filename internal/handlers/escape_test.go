@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+func TestMovieTextIsEscaped(t *testing.T) {
+	// Pages are read from web/templates
+	t.Chdir("../..")
+
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	title := "<script>alert(1)</script>"
+	notes := `She said "not tonight" & left`
+	id, err := store.AddMovie(ctx, database.Movie{Title: title, Notes: notes})
+
+	if err != nil {
+		t.Fatalf("AddMovie: %v", err)
+	}
+
+	board := httptest.NewRecorder()
+	MovieBoardHandlerWithConfig(board, httptest.NewRequest("GET", "/movie-board", nil), nil, store, store)
+
+	// Saving the same text through the edit form renders the list again
+	form := url.Values{"id": {strconv.Itoa(id)}, "title": {title}, "notes": {notes}}
+	r := httptest.NewRequest("PUT", "/movie-board/edit", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	edit := httptest.NewRecorder()
+	EditMovieHandler(edit, r, store, store, store)
+
+	// The row shows the title as text, and the edit button carries both
+	// values in attributes the edit form is filled from
+	want := []string{
+		"&lt;script&gt;alert(1)&lt;/script&gt;</h4>",
+		`data-movie-title="&lt;script&gt;alert(1)&lt;/script&gt;"`,
+		`data-movie-notes="She said &#34;not tonight&#34; &amp; left"`,
+	}
+
+	for name, w := range map[string]*httptest.ResponseRecorder{"board": board, "edit": edit} {
+		body := w.Body.String()
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s = %d, %q; want 200", name, w.Code, body)
+		}
+
+		if strings.Contains(body, title) || strings.Contains(body, notes) {
+			t.Errorf("%s renders the text unescaped", name)
+		}
+
+		for _, s := range want {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s does not contain %s", name, s)
+			}
+		}
+	}
+}
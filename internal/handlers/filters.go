@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// Board list page sizes; the limit parameter picks another size up to the maximum
+const (
+	BoardPageSize    = 50
+	MaxBoardPageSize = 200
+)
+
+// Sort orders offered by the boards' filter bar
+var BoardSorts = []SortOption{
+	{Value: database.SortDefault, Label: "Default"},
+	{Value: database.SortTitle, Label: "Title"},
+	{Value: database.SortYear, Label: "Year"},
+	{Value: database.SortAdded, Label: "Date added"},
+}
+
+// boardValues returns the query parameters a board list is shown with. HTMX
+// requests carry the board's URL in HX-Current-URL, so list fragments
+// returned after an add, edit or delete keep the board's filters, sort and page.
+func boardValues(r *http.Request) url.Values {
+	if current := r.Header.Get("HX-Current-URL"); current != "" {
+		if u, err := url.Parse(current); err == nil {
+			return u.Query()
+		}
+	}
+
+	return r.URL.Query()
+}
+
+// isListRequest reports whether a board request only wants its list, as when
+// the filter bar or pager swaps it. History restores still need the full page.
+func isListRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-History-Restore-Request") != "true"
+}
+
+// parseBoardQuery reads a board's filters, sort and page from its query parameters
+func parseBoardQuery(values url.Values) (database.BoardQuery, error) {
+	query := database.BoardQuery{
+		Tags:         values["tag"],
+		Service:      strings.TrimSpace(values.Get("service")),
+		Availability: values.Get("available"),
+		Sort:         values.Get("sort"),
+		Cursor:       values.Get("cursor"),
+		Limit:        BoardPageSize,
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"year_from", &query.YearFrom}, {"year_to", &query.YearTo}, {"limit", &query.Limit}} {
+		text := strings.TrimSpace(values.Get(param.name))
+
+		if text == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(text)
+
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("%w: invalid %s %q", database.ErrInvalidQuery, param.name, text)
+		}
+
+		*param.value = n
+	}
+
+	query.Limit = min(query.Limit, MaxBoardPageSize)
+
+	// Titles read best A to Z; every other order starts with the newest or most relevant
+	switch values.Get("dir") {
+	case "":
+		query.Desc = query.Sort != database.SortTitle
+	case "asc":
+		query.Desc = false
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("%w: invalid dir %q", database.ErrInvalidQuery, values.Get("dir"))
+	}
+
+	return query, nil
+}
+
+// listErrorStatus returns 400 for filters, sorts or cursors the store rejected and 500 otherwise
+func listErrorStatus(err error) int {
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// cloneValues copies query parameters so links can change them
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+
+	for name, list := range values {
+		clone[name] = append([]string{}, list...)
+	}
+
+	return clone
+}
+
+// boardURL links to a board with the given parameters at cursor, or at the
+// first page when cursor is empty. Empty parameters are left out.
+func boardURL(path string, values url.Values, cursor string) string {
+	link := make(url.Values)
+
+	for name, list := range values {
+		for _, value := range list {
+			if value != "" && name != "cursor" {
+				link.Add(name, value)
+			}
+		}
+	}
+
+	if cursor != "" {
+		link.Set("cursor", cursor)
+	}
+
+	if len(link) == 0 {
+		return path
+	}
+
+	return path + "?" + link.Encode()
+}
+
+// boardFilters builds the filter bar state for a board's parameters
+func boardFilters(path, target string, values url.Values) BoardFilters {
+	withoutTags := cloneValues(values)
+	delete(withoutTags, "tag")
+
+	return BoardFilters{
+		Path:         path,
+		Target:       target,
+		Tags:         values["tag"],
+		Service:      values.Get("service"),
+		YearFrom:     values.Get("year_from"),
+		YearTo:       values.Get("year_to"),
+		Availability: values.Get("available"),
+		Sort:         values.Get("sort"),
+		Dir:          values.Get("dir"),
+		Limit:        values.Get("limit"),
+		ClearTagsURL: boardURL(path, withoutTags, ""),
+		Sorts:        BoardSorts,
+	}
+}
+
+// boardPager links to the first and next pages of a board list, or is nil when everything fits on one page
+func boardPager(path, target string, values url.Values, nextCursor string) *BoardPager {
+	pager := BoardPager{Target: target}
+
+	if values.Get("cursor") != "" {
+		pager.FirstURL = boardURL(path, values, "")
+	}
+
+	if nextCursor != "" {
+		pager.NextURL = boardURL(path, values, nextCursor)
+	}
+
+	if pager.FirstURL == "" && pager.NextURL == "" {
+		return nil
+	}
+
+	return &pager
+}
+
+// movieList loads the page of the movie watchlist a board URL shows
+func movieList(r *http.Request, store database.MovieStore, values url.Values) (MovieBoardData, error) {
+	query, err := parseBoardQuery(values)
+
+	if err != nil {
+		return MovieBoardData{}, err
+	}
+
+	page, err := store.ListMovies(r.Context(), query)
+
+	if err != nil {
+		return MovieBoardData{}, err
+	}
+
+	return MovieBoardData{
+		Movies:     page.Movies,
+		MovieCount: page.Total,
+		Pager:      boardPager("/movie-board", "#movie-list", values, page.NextCursor),
+	}, nil
+}
+
+// tvShowList loads the page of the TV show watchlist a board URL shows
+func tvShowList(r *http.Request, store database.TVShowStore, values url.Values) (TVShowBoardData, error) {
+	query, err := parseBoardQuery(values)
+
+	if err != nil {
+		return TVShowBoardData{}, err
+	}
+
+	page, err := store.ListTVShows(r.Context(), query)
+
+	if err != nil {
+		return TVShowBoardData{}, err
+	}
+
+	return TVShowBoardData{
+		TVShows:     page.TVShows,
+		TVShowCount: page.Total,
+		Pager:       boardPager("/tv-shows-board", "#tvshow-list", values, page.NextCursor),
+	}, nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+func TestParseBoardQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    database.BoardQuery
+		wantErr bool
+	}{
+		{query: "", want: database.BoardQuery{Desc: true, Limit: BoardPageSize}},
+		{query: "sort=title", want: database.BoardQuery{Sort: database.SortTitle, Limit: BoardPageSize}},
+		{query: "sort=title&dir=desc", want: database.BoardQuery{Sort: database.SortTitle, Desc: true, Limit: BoardPageSize}},
+		{query: "sort=year&dir=asc&cursor=abc", want: database.BoardQuery{Sort: database.SortYear, Cursor: "abc", Limit: BoardPageSize}},
+		{
+			query: "tag=Crime&tag=Drama&service=+Netflix+&available=now&year_from=1990&year_to=2000&limit=5",
+			want: database.BoardQuery{
+				Tags:         []string{"Crime", "Drama"},
+				Service:      "Netflix",
+				Availability: database.AvailableNow,
+				YearFrom:     1990,
+				YearTo:       2000,
+				Desc:         true,
+				Limit:        5,
+			},
+		},
+		{query: "limit=100000", want: database.BoardQuery{Desc: true, Limit: MaxBoardPageSize}},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+		{query: "year_from=-1", wantErr: true},
+		{query: "year_to=199x", wantErr: true},
+		{query: "dir=up", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+
+		got, err := parseBoardQuery(values)
+
+		if tt.wantErr {
+			if !errors.Is(err, database.ErrInvalidQuery) {
+				t.Errorf("parseBoardQuery(%q) error = %v, want ErrInvalidQuery", tt.query, err)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseBoardQuery(%q) = %+v, %v; want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
 	"os/exec"
 	"strconv"
 	"strings"
 
+	"github.com/pwnderpants/homenet/internal/auth"
 	"github.com/pwnderpants/homenet/internal/config"
 	"github.com/pwnderpants/homenet/internal/database"
 	"github.com/pwnderpants/homenet/internal/logger"
@@ -29,6 +31,20 @@ func SetActiveNavigation(currentPath string) []NavItem {
 	return nav
 }
 
+// saveErrorStatus returns 400 for items or availability windows the store
+// rejected as invalid, 404 for items that are gone and 500 otherwise
+func saveErrorStatus(err error) int {
+	if errors.Is(err, database.ErrInvalidItem) || errors.Is(err, database.ErrInvalidAvailability) {
+		return http.StatusBadRequest
+	}
+
+	if errors.Is(err, database.ErrItemNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
 // HomeHandlerWithConfig handles the main page request with configuration
 func HomeHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 	tmpl, err := template.ParseFiles("web/templates/index.html")
@@ -53,8 +69,9 @@ func HomeHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.C
 		Count:             0,
 		Genres:            genres,
 		StreamingServices: streamingServices,
-		YearRange:         YearRange{Min: 1900, Max: 3000},
+		YearRange:         YearRange{Min: database.MinYear, Max: database.MaxYear},
 		Navigation:        SetActiveNavigation("/"),
+		CSRFToken:         auth.CSRFTokenFromContext(r.Context()),
 		FeatureCards:      FeatureCards,
 	}
 
@@ -68,8 +85,25 @@ func HomeHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.C
 }
 
 // MovieBoardHandlerWithConfig handles the movie board page request with configuration
-func MovieBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
-	tmpl, err := template.ParseFiles("web/templates/movie-board.html")
+func MovieBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config, store database.MovieStore, tagStore database.TagStore) {
+	// Get the page of movies the URL's filters, sort and cursor select
+	values := r.URL.Query()
+	data, err := movieList(r, store, values)
+
+	if err != nil {
+		http.Error(w, "Failed to load movies: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	// The filter bar and pager only swap the list
+	if isListRequest(r) {
+		renderPartial(w, "movie-list-fragment", data)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/movie-board.html")
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -77,11 +111,10 @@ func MovieBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *co
 		return
 	}
 
-	// Get movies from database
-	movies, err := database.GetAllMovies()
+	inUse, err := tagStore.GetTags(r.Context(), database.ItemTypeMovie)
 
 	if err != nil {
-		http.Error(w, "Failed to load movies: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to load tags: "+err.Error(), http.StatusInternalServerError)
 
 		return
 	}
@@ -107,18 +140,18 @@ func MovieBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *co
 		badgeColors = cfg.BadgeColors
 	}
 
-	data := MovieBoardData{
-		Title:             "Movie Board",
-		Movies:            movies,
-		MovieCount:        len(movies),
-		Genres:            genres,
-		StreamingServices: streamingServices,
-		YearRange:         YearRange{Min: 1900, Max: 3000},
-		Navigation:        SetActiveNavigation("/movie-board"),
-		FormText:          MovieFormText,
-		Colors:            colors,
-		BadgeColors:       badgeColors,
-	}
+	data.Title = "Movie Board"
+	data.Tags = tagOptions(genres, inUse)
+	data.TagFilters = tagFilters("/movie-board", values, inUse)
+	data.Filters = boardFilters("/movie-board", "#movie-list", values)
+	data.StreamingServices = streamingServices
+	data.YearRange = YearRange{Min: database.MinYear, Max: database.MaxYear}
+	data.MaxRuntime = database.MaxRuntime
+	data.Navigation = SetActiveNavigation("/movie-board")
+	data.CSRFToken = auth.CSRFTokenFromContext(r.Context())
+	data.FormText = MovieFormText
+	data.Colors = colors
+	data.BadgeColors = badgeColors
 
 	err = tmpl.Execute(w, data)
 
@@ -141,6 +174,7 @@ func AiHandler(w http.ResponseWriter, r *http.Request) {
 	data := PageData{
 		Title:      "Homenet AI",
 		Navigation: SetActiveNavigation("/ai"),
+		CSRFToken:  auth.CSRFTokenFromContext(r.Context()),
 	}
 
 	err = tmpl.Execute(w, data)
@@ -153,8 +187,25 @@ func AiHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // TVShowBoardHandlerWithConfig handles the TV show board page request with configuration
-func TVShowBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
-	tmpl, err := template.ParseFiles("web/templates/tv-shows-board.html")
+func TVShowBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config, store database.TVShowStore, tagStore database.TagStore) {
+	// Get the page of TV shows the URL's filters, sort and cursor select
+	values := r.URL.Query()
+	data, err := tvShowList(r, store, values)
+
+	if err != nil {
+		http.Error(w, "Failed to load TV shows: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	// The filter bar and pager only swap the list
+	if isListRequest(r) {
+		renderPartial(w, "tvshow-list-fragment", data)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/tv-shows-board.html")
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -162,11 +213,10 @@ func TVShowBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *c
 		return
 	}
 
-	// Get TV shows from database
-	tvShows, err := database.GetAllTVShows()
+	inUse, err := tagStore.GetTags(r.Context(), database.ItemTypeTVShow)
 
 	if err != nil {
-		http.Error(w, "Failed to load TV shows: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to load tags: "+err.Error(), http.StatusInternalServerError)
 
 		return
 	}
@@ -192,18 +242,17 @@ func TVShowBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *c
 		badgeColors = cfg.BadgeColors
 	}
 
-	data := TVShowBoardData{
-		Title:             "TV Shows Board",
-		TVShows:           tvShows,
-		TVShowCount:       len(tvShows),
-		Genres:            genres,
-		StreamingServices: streamingServices,
-		YearRange:         YearRange{Min: 1900, Max: 3000},
-		Navigation:        SetActiveNavigation("/tv-shows-board"),
-		FormText:          TVShowFormText,
-		Colors:            colors,
-		BadgeColors:       badgeColors,
-	}
+	data.Title = "TV Shows Board"
+	data.Tags = tagOptions(genres, inUse)
+	data.TagFilters = tagFilters("/tv-shows-board", values, inUse)
+	data.Filters = boardFilters("/tv-shows-board", "#tvshow-list", values)
+	data.StreamingServices = streamingServices
+	data.YearRange = YearRange{Min: database.MinYear, Max: database.MaxYear}
+	data.Navigation = SetActiveNavigation("/tv-shows-board")
+	data.CSRFToken = auth.CSRFTokenFromContext(r.Context())
+	data.FormText = TVShowFormText
+	data.Colors = colors
+	data.BadgeColors = badgeColors
 
 	err = tmpl.Execute(w, data)
 
@@ -215,7 +264,7 @@ func TVShowBoardHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *c
 }
 
 // AddMovieHandler handles adding a new movie
-func AddMovieHandler(w http.ResponseWriter, r *http.Request) {
+func AddMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
@@ -234,11 +283,10 @@ func AddMovieHandler(w http.ResponseWriter, r *http.Request) {
 
 	title := r.FormValue("title")
 	yearStr := r.FormValue("year")
-	genre := r.FormValue("genre")
-	streaming := r.FormValue("streaming")
+	tags := formTags(r)
+	availability := formAvailability(r)
 	notes := r.FormValue("notes")
 	imdbLink := r.FormValue("imdb_link")
-	availableNow := r.FormValue("available_now") == "on"
 
 	if title == "" {
 		logger.Warn("Empty movie title received")
@@ -247,6 +295,12 @@ func AddMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !knownIMDbLink(r.Context(), dataset, imdbLink) {
+		http.Error(w, "Unknown IMDb ID: "+database.IMDbID(imdbLink), http.StatusBadRequest)
+
+		return
+	}
+
 	// Parse year
 	year := 0
 
@@ -261,25 +315,54 @@ func AddMovieHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logger.Info("Adding new movie: %s (%d) - Genre: %s, Streaming: %s", title, year, genre, streaming)
+	runtime, err := formRuntime(r)
+
+	if err != nil {
+		logger.ErrorWithErr("Invalid runtime format in AddMovieHandler", err)
+		http.Error(w, "Invalid runtime", http.StatusBadRequest)
+
+		return
+	}
+
+	logger.Info("Adding new movie: %s (%d) - Tags: %s, Services: %d", title, year, strings.Join(tags, ", "), len(availability))
 
 	// Create new movie
 	newMovie := Movie{
 		Title:        title,
 		Year:         year,
-		Genre:        genre,
-		Streaming:    streaming,
+		Runtime:      runtime,
+		Tags:         tags,
+		Availability: availability,
 		Notes:        notes,
 		IMDBLink:     imdbLink,
-		AvailableNow: availableNow,
+		Details:      formDetails(r),
+	}
+
+	// Warn about likely duplicates unless the user already chose to save anyway
+	if !allowDuplicate(r) {
+		matches, err := duplicates.FindMovieDuplicates(r.Context(), newMovie)
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to check for duplicate movies", err)
+			http.Error(w, "Failed to check for duplicates: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		if len(matches) > 0 {
+			logger.Info("%s looks like a duplicate of %d existing movies", newMovie.Title, len(matches))
+			renderDuplicateWarning(w, addDuplicateTarget, DuplicateWarningData{ItemType: database.ItemTypeMovie, Form: "add", SaveURL: "/movie-board/add", Movies: matches})
+
+			return
+		}
 	}
 
 	// Add to database
-	movieID, err := database.AddMovie(newMovie)
+	movieID, err := store.AddMovie(r.Context(), newMovie)
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to add movie to database", err)
-		http.Error(w, "Failed to add movie: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to add movie: "+err.Error(), saveErrorStatus(err))
 
 		return
 	}
@@ -288,112 +371,23 @@ func AddMovieHandler(w http.ResponseWriter, r *http.Request) {
 	newMovie.ID = movieID
 
 	// Get all movies from database to return the complete updated list
-	allMovies, err := database.GetAllMovies()
+	data, err := movieList(r, store, boardValues(r))
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to get all movies after adding", err)
-		http.Error(w, "Failed to get movies: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get movies: "+err.Error(), listErrorStatus(err))
 
 		return
 	}
 
-	logger.Info("Movie added successfully, total movies: %d", len(allMovies))
-
-	// Return the complete movie list HTML for HTMX to replace
-	w.Header().Set("Content-Type", "text/html")
-
-	// Generate HTML for all movies
-	var moviesHTML string
+	logger.Info("Movie added successfully, total movies: %d", data.MovieCount)
 
-	for _, movie := range allMovies {
-		movieHTML := `
-		<div class="bg-gray-700 rounded-lg p-4 border border-gray-600">
-			<div class="flex justify-between items-start">
-				<div class="flex-1">
-					<h4 class="text-lg font-semibold text-white">` + movie.Title + `</h4>
-					<div class="flex items-center space-x-4 mt-2 text-sm text-gray-300">`
-
-		if movie.Year > 0 {
-			movieHTML += `<span class="bg-gray-600 px-2 py-1 rounded">` + strconv.Itoa(movie.Year) + `</span>`
-		}
-
-		if movie.Genre != "" {
-			movieHTML += `<span class="bg-blue-600 px-2 py-1 rounded">` + movie.Genre + `</span>`
-		}
-
-		if movie.Streaming != "" {
-			movieHTML += `<span class="bg-green-600 px-2 py-1 rounded">` + movie.Streaming + `</span>`
-		}
-
-		if movie.AvailableNow {
-			movieHTML += `<span class="bg-green-500 px-2 py-1 rounded text-black font-semibold">Available Now</span>`
-		}
-
-		movieHTML += `
-					</div>`
-
-		if movie.IMDBLink != "" {
-			movieHTML += `<div class="mt-2"><a href="` + movie.IMDBLink + `" target="_blank" class="text-blue-400 hover:text-blue-300 text-sm">View on IMDB</a></div>`
-		}
-
-		if movie.Notes != "" {
-			movieHTML += `<p class="text-gray-400 mt-2 text-sm">` + movie.Notes + `</p>`
-		}
-
-		movieHTML += `
-				</div>
-				<div class="flex space-x-2">
-					<button 
-						data-movie-id="` + strconv.Itoa(movie.ID) + `"
-						data-movie-title="` + movie.Title + `"
-						data-movie-year="` + strconv.Itoa(movie.Year) + `"
-						data-movie-genre="` + movie.Genre + `"
-						data-movie-streaming="` + movie.Streaming + `"
-						data-movie-notes="` + movie.Notes + `"
-						data-movie-imdb="` + movie.IMDBLink + `"
-						data-movie-available-now="` + strconv.FormatBool(movie.AvailableNow) + `"
-						onclick="openEditModal(this)"
-						class="text-blue-400 hover:text-blue-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M11 5H6a2 2 0 00-2 2v11a2 2 0 002 2h11a2 2 0 002-2v-5m-1.414-9.414a2 2 0 112.828 2.828L11.828 15H9v-2.828l8.586-8.586z"></path>
-						</svg>
-					</button>
-					<button 
-						data-movie-id="` + strconv.Itoa(movie.ID) + `"
-						class="delete-movie-btn text-red-400 hover:text-red-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"></path>
-						</svg>
-					</button>
-				</div>
-			</div>
-		</div>`
-
-		moviesHTML += movieHTML
-	}
-
-	// If no movies, show the "no movies" message
-	if len(allMovies) == 0 {
-		moviesHTML = `
-		<div class="text-center py-8">
-			<svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-				<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-			</svg>
-			<p class="text-gray-400">No movies added yet. Add your first movie above!</p>
-		</div>`
-	}
-
-	// Create the response with out-of-band update for movie count
-	response := moviesHTML + `
-	<div id="movie-count" hx-swap-oob="true">
-		` + strconv.Itoa(len(allMovies)) + ` movies in your list
-	</div>`
-
-	w.Write([]byte(response))
+	// Return the refreshed movie list for HTMX to swap in
+	renderPartial(w, "movie-list-fragment", data)
 }
 
 // AddTVShowHandler handles adding a new TV show
-func AddTVShowHandler(w http.ResponseWriter, r *http.Request) {
+func AddTVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
@@ -412,8 +406,8 @@ func AddTVShowHandler(w http.ResponseWriter, r *http.Request) {
 
 	title := r.FormValue("title")
 	yearStr := r.FormValue("year")
-	genre := r.FormValue("genre")
-	streaming := r.FormValue("streaming")
+	tags := formTags(r)
+	availability := formAvailability(r)
 	notes := r.FormValue("notes")
 	imdbLink := r.FormValue("imdb_link")
 	activeSeason := r.FormValue("active_season") == "on"
@@ -425,6 +419,12 @@ func AddTVShowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !knownIMDbLink(r.Context(), dataset, imdbLink) {
+		http.Error(w, "Unknown IMDb ID: "+database.IMDbID(imdbLink), http.StatusBadRequest)
+
+		return
+	}
+
 	// Parse year
 	year := 0
 
@@ -439,25 +439,45 @@ func AddTVShowHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logger.Info("Adding new TV show: %s (%d) - Genre: %s, Streaming: %s, Active Season: %t", title, year, genre, streaming, activeSeason)
+	logger.Info("Adding new TV show: %s (%d) - Tags: %s, Services: %d, Active Season: %t", title, year, strings.Join(tags, ", "), len(availability), activeSeason)
 
 	// Create new TV show
 	newTVShow := TVShow{
 		Title:        title,
 		Year:         year,
-		Genre:        genre,
-		Streaming:    streaming,
+		Tags:         tags,
+		Availability: availability,
 		Notes:        notes,
 		IMDBLink:     imdbLink,
 		ActiveSeason: activeSeason,
+		Details:      formDetails(r),
+	}
+
+	// Warn about likely duplicates unless the user already chose to save anyway
+	if !allowDuplicate(r) {
+		matches, err := duplicates.FindTVShowDuplicates(r.Context(), newTVShow)
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to check for duplicate TV shows", err)
+			http.Error(w, "Failed to check for duplicates: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		if len(matches) > 0 {
+			logger.Info("%s looks like a duplicate of %d existing TV shows", newTVShow.Title, len(matches))
+			renderDuplicateWarning(w, addDuplicateTarget, DuplicateWarningData{ItemType: database.ItemTypeTVShow, Form: "add", SaveURL: "/tv-shows-board/add", TVShows: matches})
+
+			return
+		}
 	}
 
 	// Add to database
-	tvShowID, err := database.AddTVShow(newTVShow)
+	tvShowID, err := store.AddTVShow(r.Context(), newTVShow)
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to add TV show to database", err)
-		http.Error(w, "Failed to add TV show: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to add TV show: "+err.Error(), saveErrorStatus(err))
 
 		return
 	}
@@ -466,173 +486,135 @@ func AddTVShowHandler(w http.ResponseWriter, r *http.Request) {
 	newTVShow.ID = tvShowID
 
 	// Get all TV shows from database to return the complete updated list
-	allTVShows, err := database.GetAllTVShows()
+	data, err := tvShowList(r, store, boardValues(r))
 
 	if err != nil {
 		logger.ErrorWithErr("Failed to get all TV shows after adding", err)
-		http.Error(w, "Failed to get TV shows: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get TV shows: "+err.Error(), listErrorStatus(err))
 
 		return
 	}
 
-	logger.Info("TV show added successfully, total TV shows: %d", len(allTVShows))
+	logger.Info("TV show added successfully, total TV shows: %d", data.TVShowCount)
 
-	// Return the complete TV show list HTML for HTMX to replace
-	w.Header().Set("Content-Type", "text/html")
+	// Return the refreshed TV show list for HTMX to swap in
+	renderPartial(w, "tvshow-list-fragment", data)
+}
 
-	// Generate HTML for all TV shows
-	var tvShowsHTML string
+// DeleteMovieHandler moves a movie to the trash and offers an undo toast
+func DeleteMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
-	for _, tvShow := range allTVShows {
-		tvShowHTML := `
-		<div class="bg-gray-700 rounded-lg p-4 border border-gray-600">
-			<div class="flex justify-between items-start">
-				<div class="flex-1">
-					<h4 class="text-lg font-semibold text-white">` + tvShow.Title + `</h4>
-					<div class="flex items-center space-x-4 mt-2 text-sm text-gray-300">`
+		return
+	}
 
-		if tvShow.Year > 0 {
-			tvShowHTML += `<span class="bg-gray-600 px-2 py-1 rounded">` + strconv.Itoa(tvShow.Year) + `</span>`
-		}
+	// Extract movie ID from URL
+	path := r.URL.Path
+	idStr := path[len("/movie-board/delete/"):]
+	id, err := strconv.Atoi(idStr)
 
-		if tvShow.Genre != "" {
-			tvShowHTML += `<span class="bg-blue-600 px-2 py-1 rounded">` + tvShow.Genre + `</span>`
-		}
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
 
-		if tvShow.Streaming != "" {
-			tvShowHTML += `<span class="bg-green-600 px-2 py-1 rounded">` + tvShow.Streaming + `</span>`
-		}
+		return
+	}
 
-		if tvShow.ActiveSeason {
-			tvShowHTML += `<span class="bg-yellow-500 px-2 py-1 rounded text-black font-semibold">Active Season</span>`
-		}
+	movie, err := store.GetMovie(r.Context(), id)
 
-		tvShowHTML += `
-					</div>`
+	if err != nil {
+		http.Error(w, "Failed to get movie: "+err.Error(), http.StatusInternalServerError)
 
-		if tvShow.IMDBLink != "" {
-			tvShowHTML += `<div class="mt-2"><a href="` + tvShow.IMDBLink + `" target="_blank" class="text-blue-400 hover:text-blue-300 text-sm">View on IMDB</a></div>`
-		}
+		return
+	}
 
-		if tvShow.Notes != "" {
-			tvShowHTML += `<p class="text-gray-400 mt-2 text-sm">` + tvShow.Notes + `</p>`
-		}
+	if movie == nil {
+		http.Error(w, "Movie not found", http.StatusNotFound)
 
-		tvShowHTML += `
-				</div>
-				<div class="flex space-x-2">
-					<button 
-						data-tvshow-id="` + strconv.Itoa(tvShow.ID) + `"
-						data-tvshow-title="` + tvShow.Title + `"
-						data-tvshow-year="` + strconv.Itoa(tvShow.Year) + `"
-						data-tvshow-genre="` + tvShow.Genre + `"
-						data-tvshow-streaming="` + tvShow.Streaming + `"
-						data-tvshow-notes="` + tvShow.Notes + `"
-						data-tvshow-imdb="` + tvShow.IMDBLink + `"
-						data-tvshow-active-season="` + strconv.FormatBool(tvShow.ActiveSeason) + `"
-						onclick="openEditModal(this)"
-						class="text-blue-400 hover:text-blue-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M11 5H6a2 2 0 00-2 2v11a2 2 0 002 2h11a2 2 0 002-2v-5m-1.414-9.414a2 2 0 112.828 2.828L11.828 15H9v-2.828l8.586-8.586z"></path>
-						</svg>
-					</button>
-					<button 
-						data-tvshow-id="` + strconv.Itoa(tvShow.ID) + `"
-						class="delete-tvshow-btn text-red-400 hover:text-red-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"></path>
-						</svg>
-					</button>
-				</div>
-			</div>
-		</div>`
+		return
+	}
 
-		tvShowsHTML += tvShowHTML
+	// Move to the trash
+	err = store.DeleteMovie(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, "Failed to delete movie: "+err.Error(), saveErrorStatus(err))
+
+		return
 	}
 
-	// If no TV shows, show the "no TV shows" message
-	if len(allTVShows) == 0 {
-		tvShowsHTML = `
-		<div class="text-center py-8">
-			<svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-				<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-			</svg>
-			<p class="text-gray-400">No TV shows added yet. Add your first TV show above!</p>
-		</div>`
+	data, err := movieList(r, store, boardValues(r))
+
+	if err != nil {
+		http.Error(w, "Failed to get movies: "+err.Error(), listErrorStatus(err))
+
+		return
 	}
 
-	// Create the response with out-of-band update for TV show count
-	response := tvShowsHTML + `
-	<div id="tvshow-count" hx-swap-oob="true">
-		` + strconv.Itoa(len(allTVShows)) + ` TV shows in your list
-	</div>`
+	// Return the refreshed list with an out-of-band undo toast
+	data.Undo = &UndoToast{ID: id, Title: movie.Title, RestoreURL: "/movie-board/restore", Target: "#movie-list"}
 
-	w.Write([]byte(response))
+	renderPartial(w, "movie-list-fragment", data)
 }
 
-// DeleteMovieHandler handles deleting a movie
-func DeleteMovieHandler(w http.ResponseWriter, r *http.Request) {
+// DeleteTVShowHandler moves a TV show to the trash and offers an undo toast
+func DeleteTVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore) {
 	if r.Method != "DELETE" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
 		return
 	}
 
-	// Extract movie ID from URL
+	// Extract TV show ID from URL
 	path := r.URL.Path
-	idStr := path[len("/movie-board/delete/"):]
+	idStr := path[len("/tv-shows-board/delete/"):]
 	id, err := strconv.Atoi(idStr)
 
 	if err != nil {
-		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
 
 		return
 	}
 
-	// Delete from database
-	err = database.DeleteMovie(id)
+	tvShow, err := store.GetTVShow(r.Context(), id)
+
 	if err != nil {
-		http.Error(w, "Failed to delete movie: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get TV show: "+err.Error(), http.StatusInternalServerError)
 
 		return
 	}
 
-	// Return success status
-	w.WriteHeader(http.StatusOK)
-}
-
-// DeleteTVShowHandler handles deleting a TV show
-func DeleteTVShowHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "DELETE" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if tvShow == nil {
+		http.Error(w, "TV show not found", http.StatusNotFound)
 
 		return
 	}
 
-	// Extract TV show ID from URL
-	path := r.URL.Path
-	idStr := path[len("/tv-shows-board/delete/"):]
-	id, err := strconv.Atoi(idStr)
+	// Move to the trash
+	err = store.DeleteTVShow(r.Context(), id)
 
 	if err != nil {
-		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+		http.Error(w, "Failed to delete TV show: "+err.Error(), saveErrorStatus(err))
+
 		return
 	}
 
-	// Delete from database
-	err = database.DeleteTVShow(id)
+	data, err := tvShowList(r, store, boardValues(r))
 
 	if err != nil {
-		http.Error(w, "Failed to delete TV show: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get TV shows: "+err.Error(), listErrorStatus(err))
+
 		return
 	}
 
-	// Return success status
-	w.WriteHeader(http.StatusOK)
+	// Return the refreshed list with an out-of-band undo toast
+	data.Undo = &UndoToast{ID: id, Title: tvShow.Title, RestoreURL: "/tv-shows-board/restore", Target: "#tvshow-list"}
+
+	renderPartial(w, "tvshow-list-fragment", data)
 }
 
 // EditMovieHandler handles editing an existing movie
-func EditMovieHandler(w http.ResponseWriter, r *http.Request) {
+func EditMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
 	if r.Method != "PUT" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
@@ -651,11 +633,10 @@ func EditMovieHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.FormValue("id")
 	title := r.FormValue("title")
 	yearStr := r.FormValue("year")
-	genre := r.FormValue("genre")
-	streaming := r.FormValue("streaming")
+	tags := formTags(r)
+	availability := formAvailability(r)
 	notes := r.FormValue("notes")
 	imdbLink := r.FormValue("imdb_link")
-	availableNow := r.FormValue("available_now") == "on"
 
 	if title == "" {
 		http.Error(w, "Title is required", http.StatusBadRequest)
@@ -663,6 +644,12 @@ func EditMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !knownIMDbLink(r.Context(), dataset, imdbLink) {
+		http.Error(w, "Unknown IMDb ID: "+database.IMDbID(imdbLink), http.StatusBadRequest)
+
+		return
+	}
+
 	// Parse movie ID
 	id, err := strconv.Atoi(idStr)
 
@@ -684,130 +671,69 @@ func EditMovieHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	runtime, err := formRuntime(r)
+
+	if err != nil {
+		http.Error(w, "Invalid runtime", http.StatusBadRequest)
+
+		return
+	}
+
 	// Update movie in database
 	updatedMovie := Movie{
 		ID:           id,
 		Title:        title,
 		Year:         year,
-		Genre:        genre,
-		Streaming:    streaming,
+		Runtime:      runtime,
+		Tags:         tags,
+		Availability: availability,
 		Notes:        notes,
 		IMDBLink:     imdbLink,
-		AvailableNow: availableNow,
+		Details:      formDetails(r),
 	}
 
-	err = database.UpdateMovie(updatedMovie)
+	// Warn about likely duplicates unless the user already chose to save anyway
+	if !allowDuplicate(r) {
+		matches, err := duplicates.FindMovieDuplicates(r.Context(), updatedMovie)
 
-	if err != nil {
-		http.Error(w, "Failed to update movie: "+err.Error(), http.StatusInternalServerError)
-
-		return
-	}
-
-	// Get all movies from database to return the complete updated list
-	allMovies, err := database.GetAllMovies()
-
-	if err != nil {
-		http.Error(w, "Failed to get movies: "+err.Error(), http.StatusInternalServerError)
-
-		return
-	}
-
-	// Return the complete movie list HTML for HTMX to replace
-	w.Header().Set("Content-Type", "text/html")
-
-	// Generate HTML for all movies
-	var moviesHTML string
-
-	for _, movie := range allMovies {
-		movieHTML := `
-		<div class="bg-gray-700 rounded-lg p-4 border border-gray-600">
-			<div class="flex justify-between items-start">
-				<div class="flex-1">
-					<h4 class="text-lg font-semibold text-white">` + movie.Title + `</h4>
-					<div class="flex items-center space-x-4 mt-2 text-sm text-gray-300">`
-
-		if movie.Year > 0 {
-			movieHTML += `<span class="bg-gray-600 px-2 py-1 rounded">` + strconv.Itoa(movie.Year) + `</span>`
-		}
+		if err != nil {
+			logger.ErrorWithErr("Failed to check for duplicate movies", err)
+			http.Error(w, "Failed to check for duplicates: "+err.Error(), http.StatusInternalServerError)
 
-		if movie.Genre != "" {
-			movieHTML += `<span class="bg-blue-600 px-2 py-1 rounded">` + movie.Genre + `</span>`
+			return
 		}
 
-		if movie.Streaming != "" {
-			movieHTML += `<span class="bg-green-600 px-2 py-1 rounded">` + movie.Streaming + `</span>`
-		}
+		if len(matches) > 0 {
+			logger.Info("%s looks like a duplicate of %d existing movies", updatedMovie.Title, len(matches))
+			renderDuplicateWarning(w, editDuplicateTarget, DuplicateWarningData{ItemType: database.ItemTypeMovie, Form: "edit", SaveURL: "/movie-board/edit", Movies: matches})
 
-		if movie.AvailableNow {
-			movieHTML += `<span class="bg-green-500 px-2 py-1 rounded text-black font-semibold">Available Now</span>`
+			return
 		}
+	}
 
-		movieHTML += `
-					</div>`
+	err = store.UpdateMovie(r.Context(), updatedMovie)
 
-		if movie.IMDBLink != "" {
-			movieHTML += `<div class="mt-2"><a href="` + movie.IMDBLink + `" target="_blank" class="text-blue-400 hover:text-blue-300 text-sm">View on IMDB</a></div>`
-		}
+	if err != nil {
+		http.Error(w, "Failed to update movie: "+err.Error(), saveErrorStatus(err))
 
-		if movie.Notes != "" {
-			movieHTML += `<p class="text-gray-400 mt-2 text-sm">` + movie.Notes + `</p>`
-		}
+		return
+	}
 
-		movieHTML += `
-				</div>
-				<div class="flex space-x-2">
-					<button 
-						data-movie-id="` + strconv.Itoa(movie.ID) + `"
-						data-movie-title="` + movie.Title + `"
-						data-movie-year="` + strconv.Itoa(movie.Year) + `"
-						data-movie-genre="` + movie.Genre + `"
-						data-movie-streaming="` + movie.Streaming + `"
-						data-movie-notes="` + movie.Notes + `"
-						data-movie-imdb="` + movie.IMDBLink + `"
-						data-movie-available-now="` + strconv.FormatBool(movie.AvailableNow) + `"
-						onclick="openEditModal(this)"
-						class="text-blue-400 hover:text-blue-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M11 5H6a2 2 0 00-2 2v11a2 2 0 002 2h11a2 2 0 002-2v-5m-1.414-9.414a2 2 0 112.828 2.828L11.828 15H9v-2.828l8.586-8.586z"></path>
-						</svg>
-					</button>
-					<button 
-						data-movie-id="` + strconv.Itoa(movie.ID) + `"
-						class="delete-movie-btn text-red-400 hover:text-red-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"></path>
-						</svg>
-					</button>
-				</div>
-			</div>
-		</div>`
+	// Get all movies from database to return the complete updated list
+	data, err := movieList(r, store, boardValues(r))
 
-		moviesHTML += movieHTML
-	}
+	if err != nil {
+		http.Error(w, "Failed to get movies: "+err.Error(), listErrorStatus(err))
 
-	// If no movies, show the "no movies" message
-	if len(allMovies) == 0 {
-		moviesHTML = `
-		<div class="text-center py-8">
-			<svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-				<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-			</svg>
-			<p class="text-gray-400">No movies added yet. Add your first movie above!</p>
-		</div>`
+		return
 	}
 
-	// Create the response with out-of-band update for movie count
-	response := moviesHTML + `
-	<div id="movie-count" hx-swap-oob="true">
-		` + strconv.Itoa(len(allMovies)) + ` movies in your list
-	</div>`
-
-	w.Write([]byte(response))
+	// Return the refreshed movie list for HTMX to swap in
+	renderPartial(w, "movie-list-fragment", data)
 }
 
 // EditTVShowHandler handles editing an existing TV show
-func EditTVShowHandler(w http.ResponseWriter, r *http.Request) {
+func EditTVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore, duplicates database.DuplicateStore, dataset database.IMDbDatasetStore) {
 	if r.Method != "PUT" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
@@ -826,8 +752,8 @@ func EditTVShowHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.FormValue("id")
 	title := r.FormValue("title")
 	yearStr := r.FormValue("year")
-	genre := r.FormValue("genre")
-	streaming := r.FormValue("streaming")
+	tags := formTags(r)
+	availability := formAvailability(r)
 	notes := r.FormValue("notes")
 	imdbLink := r.FormValue("imdb_link")
 	activeSeason := r.FormValue("active_season") == "on"
@@ -838,6 +764,12 @@ func EditTVShowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !knownIMDbLink(r.Context(), dataset, imdbLink) {
+		http.Error(w, "Unknown IMDb ID: "+database.IMDbID(imdbLink), http.StatusBadRequest)
+
+		return
+	}
+
 	// Parse TV show ID
 	id, err := strconv.Atoi(idStr)
 
@@ -865,203 +797,119 @@ func EditTVShowHandler(w http.ResponseWriter, r *http.Request) {
 		ID:           id,
 		Title:        title,
 		Year:         year,
-		Genre:        genre,
-		Streaming:    streaming,
+		Tags:         tags,
+		Availability: availability,
 		Notes:        notes,
 		IMDBLink:     imdbLink,
 		ActiveSeason: activeSeason,
+		Details:      formDetails(r),
 	}
 
-	err = database.UpdateTVShow(updatedTVShow)
-
-	if err != nil {
-		http.Error(w, "Failed to update TV show: "+err.Error(), http.StatusInternalServerError)
-
-		return
-	}
-
-	// Get all TV shows from database to return the complete updated list
-	allTVShows, err := database.GetAllTVShows()
-
-	if err != nil {
-		http.Error(w, "Failed to get TV shows: "+err.Error(), http.StatusInternalServerError)
-
-		return
-	}
-
-	// Return the complete TV show list HTML for HTMX to replace
-	w.Header().Set("Content-Type", "text/html")
-
-	// Generate HTML for all TV shows
-	var tvShowsHTML string
+	// Warn about likely duplicates unless the user already chose to save anyway
+	if !allowDuplicate(r) {
+		matches, err := duplicates.FindTVShowDuplicates(r.Context(), updatedTVShow)
 
-	for _, tvShow := range allTVShows {
-		tvShowHTML := `
-		<div class="bg-gray-700 rounded-lg p-4 border border-gray-600">
-			<div class="flex justify-between items-start">
-				<div class="flex-1">
-					<h4 class="text-lg font-semibold text-white">` + tvShow.Title + `</h4>
-					<div class="flex items-center space-x-4 mt-2 text-sm text-gray-300">`
-
-		if tvShow.Year > 0 {
-			tvShowHTML += `<span class="bg-gray-600 px-2 py-1 rounded">` + strconv.Itoa(tvShow.Year) + `</span>`
-		}
+		if err != nil {
+			logger.ErrorWithErr("Failed to check for duplicate TV shows", err)
+			http.Error(w, "Failed to check for duplicates: "+err.Error(), http.StatusInternalServerError)
 
-		if tvShow.Genre != "" {
-			tvShowHTML += `<span class="bg-blue-600 px-2 py-1 rounded">` + tvShow.Genre + `</span>`
+			return
 		}
 
-		if tvShow.Streaming != "" {
-			tvShowHTML += `<span class="bg-green-600 px-2 py-1 rounded">` + tvShow.Streaming + `</span>`
-		}
+		if len(matches) > 0 {
+			logger.Info("%s looks like a duplicate of %d existing TV shows", updatedTVShow.Title, len(matches))
+			renderDuplicateWarning(w, editDuplicateTarget, DuplicateWarningData{ItemType: database.ItemTypeTVShow, Form: "edit", SaveURL: "/tv-shows-board/edit", TVShows: matches})
 
-		if tvShow.ActiveSeason {
-			tvShowHTML += `<span class="bg-yellow-500 px-2 py-1 rounded text-black font-semibold">Active Season</span>`
+			return
 		}
+	}
 
-		tvShowHTML += `
-					</div>`
+	err = store.UpdateTVShow(r.Context(), updatedTVShow)
 
-		if tvShow.IMDBLink != "" {
-			tvShowHTML += `<div class="mt-2"><a href="` + tvShow.IMDBLink + `" target="_blank" class="text-blue-400 hover:text-blue-300 text-sm">View on IMDB</a></div>`
-		}
+	if err != nil {
+		http.Error(w, "Failed to update TV show: "+err.Error(), saveErrorStatus(err))
 
-		if tvShow.Notes != "" {
-			tvShowHTML += `<p class="text-gray-400 mt-2 text-sm">` + tvShow.Notes + `</p>`
-		}
+		return
+	}
 
-		tvShowHTML += `
-				</div>
-				<div class="flex space-x-2">
-					<button 
-						data-tvshow-id="` + strconv.Itoa(tvShow.ID) + `"
-						data-tvshow-title="` + tvShow.Title + `"
-						data-tvshow-year="` + strconv.Itoa(tvShow.Year) + `"
-						data-tvshow-genre="` + tvShow.Genre + `"
-						data-tvshow-streaming="` + tvShow.Streaming + `"
-						data-tvshow-notes="` + tvShow.Notes + `"
-						data-tvshow-imdb="` + tvShow.IMDBLink + `"
-						data-tvshow-active-season="` + strconv.FormatBool(tvShow.ActiveSeason) + `"
-						onclick="openEditModal(this)"
-						class="text-blue-400 hover:text-blue-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M11 5H6a2 2 0 00-2 2v11a2 2 0 002 2h11a2 2 0 002-2v-5m-1.414-9.414a2 2 0 112.828 2.828L11.828 15H9v-2.828l8.586-8.586z"></path>
-						</svg>
-					</button>
-					<button 
-						data-tvshow-id="` + strconv.Itoa(tvShow.ID) + `"
-						class="delete-tvshow-btn text-red-400 hover:text-red-300 transition-colors duration-200">
-						<svg class="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-							<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16"></path>
-						</svg>
-					</button>
-				</div>
-			</div>
-		</div>`
+	// Get all TV shows from database to return the complete updated list
+	data, err := tvShowList(r, store, boardValues(r))
 
-		tvShowsHTML += tvShowHTML
-	}
+	if err != nil {
+		http.Error(w, "Failed to get TV shows: "+err.Error(), listErrorStatus(err))
 
-	// If no TV shows, show the "no TV shows" message
-	if len(allTVShows) == 0 {
-		tvShowsHTML = `
-		<div class="text-center py-8">
-			<svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-				<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-			</svg>
-			<p class="text-gray-400">No TV shows added yet. Add your first TV show above!</p>
-		</div>`
+		return
 	}
 
-	// Create the response with out-of-band update for TV show count
-	response := tvShowsHTML + `
-	<div id="tvshow-count" hx-swap-oob="true">
-		` + strconv.Itoa(len(allTVShows)) + ` TV shows in your list
-	</div>`
-
-	w.Write([]byte(response))
+	// Return the refreshed TV show list for HTMX to swap in
+	renderPartial(w, "tvshow-list-fragment", data)
 }
 
-// RandomMovieHandler handles getting a random movie
-func RandomMovieHandler(w http.ResponseWriter, r *http.Request) {
+// SearchHandler handles full-text search across movies and TV shows. The type
+// parameter narrows results to one board ("movie" or "tvshow"); an empty query
+// on a board returns the board's current page so clearing the search box restores it.
+func SearchHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 
 		return
 	}
 
-	// Get random movie from database
-	randomMovie, err := database.GetRandomMovie()
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	scope := r.URL.Query().Get("type")
 
-	if err != nil {
-		http.Error(w, "Failed to get random movie: "+err.Error(), http.StatusInternalServerError)
+	logger.Debug("Search request: %q (type: %s)", query, scope)
 
-		return
-	}
+	data := SearchData{Query: query}
 
-	if randomMovie == nil {
-		// No movies in database
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(`
-			<div class="text-center py-8">
-				<svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-					<path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-				</svg>
-				<p class="text-gray-400">No movies in your list yet. Add some movies first!</p>
-			</div>
-		`))
-		return
-	}
+	var err error
 
-	// Return the random movie HTML for the modal
-	w.Header().Set("Content-Type", "text/html")
+	if scope == "movie" || scope == "" {
+		if query == "" && scope == "movie" {
+			var list MovieBoardData
 
-	movieHTML := `
-		<div class="bg-gray-700 rounded-lg p-6 border border-gray-600">
-			<div class="text-center mb-4">
-				<h3 class="text-2xl font-bold text-white mb-2">🎬 Your Random Movie Pick!</h3>
-				<p class="text-gray-300">Here's what you should watch tonight:</p>
-			</div>
-			<div class="space-y-4">
-				<div class="text-center">
-					<h4 class="text-xl font-semibold text-white mb-2">` + randomMovie.Title + `</h4>
-					<div class="flex items-center justify-center space-x-4 text-sm text-gray-300">`
+			list, err = movieList(r, movies, boardValues(r))
+			data.Movies, data.Pager = list.Movies, list.Pager
+		} else {
+			data.Movies, err = movies.SearchMovies(r.Context(), query)
+		}
 
-	if randomMovie.Year > 0 {
-		movieHTML += `<span class="bg-gray-600 px-2 py-1 rounded">` + strconv.Itoa(randomMovie.Year) + `</span>`
-	}
+		if err != nil {
+			logger.ErrorWithErr("Movie search failed", err)
+			http.Error(w, "Failed to search movies: "+err.Error(), http.StatusInternalServerError)
 
-	if randomMovie.Genre != "" {
-		movieHTML += `<span class="bg-blue-600 px-2 py-1 rounded">` + randomMovie.Genre + `</span>`
+			return
+		}
 	}
 
-	if randomMovie.Streaming != "" {
-		movieHTML += `<span class="bg-green-600 px-2 py-1 rounded">` + randomMovie.Streaming + `</span>`
-	}
+	if scope == "tvshow" || scope == "" {
+		if query == "" && scope == "tvshow" {
+			var list TVShowBoardData
 
-	movieHTML += `
-					</div>
-				</div>`
+			list, err = tvShowList(r, tvShows, boardValues(r))
+			data.TVShows, data.Pager = list.TVShows, list.Pager
+		} else {
+			data.TVShows, err = tvShows.SearchTVShows(r.Context(), query)
+		}
 
-	if randomMovie.IMDBLink != "" {
-		movieHTML += `
-				<div class="text-center">
-					<a href="` + randomMovie.IMDBLink + `" target="_blank" class="text-blue-400 hover:text-blue-300 text-sm">View on IMDB</a>
-				</div>`
-	}
+		if err != nil {
+			logger.ErrorWithErr("TV show search failed", err)
+			http.Error(w, "Failed to search TV shows: "+err.Error(), http.StatusInternalServerError)
 
-	if randomMovie.Notes != "" {
-		movieHTML += `
-				<div class="text-center">
-					<p class="text-gray-400 text-sm italic">"` + randomMovie.Notes + `"</p>
-				</div>`
+			return
+		}
 	}
 
-	movieHTML += `
-			</div>
-		</div>`
-
-	w.Write([]byte(movieHTML))
+	switch scope {
+	case "movie":
+		renderPartial(w, "movie-search-results", data)
+	case "tvshow":
+		renderPartial(w, "tvshow-search-results", data)
+	case "":
+		renderPartial(w, "search-results", data)
+	default:
+		http.Error(w, "Invalid search type", http.StatusBadRequest)
+	}
 }
 
 // FortuneHandlerWithConfig handles getting a fortune with configuration
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// historyItemTitle returns the most recent title recorded in an item's history
+func historyItemTitle(entries []database.AuditEntry) string {
+	title := ""
+
+	for _, entry := range entries {
+		for _, change := range entry.Changes() {
+			if change.Field != "title" {
+				continue
+			}
+
+			if change.After != "" {
+				title = change.After
+			} else {
+				title = change.Before
+			}
+		}
+	}
+
+	return title
+}
+
+// HistoryHandler handles the audit history page for a movie or TV show
+func HistoryHandler(w http.ResponseWriter, r *http.Request, audit database.AuditStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	itemType := r.URL.Query().Get("type")
+
+	if itemType != database.ItemTypeMovie && itemType != database.ItemTypeTVShow {
+		http.Error(w, "Invalid item type", http.StatusBadRequest)
+
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+		return
+	}
+
+	entries, err := audit.GetItemHistory(r.Context(), itemType, id)
+
+	if err != nil {
+		http.Error(w, "Failed to load history: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if len(entries) == 0 {
+		http.Error(w, "No history for this item", http.StatusNotFound)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/history.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data := HistoryData{
+		Title:      "History",
+		Navigation: SetActiveNavigation("/history"),
+		CSRFToken:  auth.CSRFTokenFromContext(r.Context()),
+		ItemType:   itemType,
+		ItemID:     id,
+		ItemTitle:  historyItemTitle(entries),
+		Entries:    entries,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
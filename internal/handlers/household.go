@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// renderProfileList renders the household page's profile list fragment
+func renderProfileList(w http.ResponseWriter, r *http.Request, profiles database.ProfileStore, message string) {
+	list, err := profiles.ListProfiles(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load profiles: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "profile-list", HouseholdData{Profiles: list, Error: message})
+}
+
+// HouseholdHandler handles the household page, where profiles are added and removed
+func HouseholdHandler(w http.ResponseWriter, r *http.Request, profiles database.ProfileStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/household.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	list, err := profiles.ListProfiles(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load profiles: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data := HouseholdData{
+		Title:      "Household",
+		Navigation: SetActiveNavigation("/household"),
+		CSRFToken:  auth.CSRFTokenFromContext(r.Context()),
+		Profiles:   list,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// AddProfileHandler adds a household profile and returns the refreshed profile list
+func AddProfileHandler(w http.ResponseWriter, r *http.Request, profiles database.ProfileStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+
+		return
+	}
+
+	name := r.FormValue("name")
+	_, err := profiles.AddProfile(r.Context(), name)
+
+	if errors.Is(err, database.ErrInvalidProfile) || errors.Is(err, database.ErrProfileExists) {
+		renderProfileList(w, r, profiles, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to add profile", err)
+		http.Error(w, "Failed to add profile: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	logger.Info("Added household profile %q", name)
+	renderProfileList(w, r, profiles, "")
+}
+
+// DeleteProfileHandler removes a household profile and its votes and returns
+// the refreshed profile list
+func DeleteProfileHandler(w http.ResponseWriter, r *http.Request, profiles database.ProfileStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := profiles.DeleteProfile(r.Context(), id); err != nil {
+		logger.ErrorWithErr("Failed to delete profile", err)
+		http.Error(w, "Failed to delete profile: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	logger.Info("Deleted household profile %d", id)
+	renderProfileList(w, r, profiles, "")
+}
+
+// itemTitle returns the title of a movie or TV show on a board, or false when there is none
+func itemTitle(ctx context.Context, movies database.MovieStore, tvShows database.TVShowStore, itemType string, id int) (string, bool, error) {
+	switch itemType {
+	case database.ItemTypeMovie:
+		movie, err := movies.GetMovie(ctx, id)
+
+		if err != nil || movie == nil {
+			return "", false, err
+		}
+
+		return movie.Title, true, nil
+	case database.ItemTypeTVShow:
+		tvShow, err := tvShows.GetTVShow(ctx, id)
+
+		if err != nil || tvShow == nil {
+			return "", false, err
+		}
+
+		return tvShow.Title, true, nil
+	}
+
+	return "", false, nil
+}
+
+// renderVoteManager renders the votes modal for a title, with an optional error message
+func renderVoteManager(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore, profiles database.ProfileStore, itemType string, id int, message string) {
+	title, found, err := itemTitle(r.Context(), movies, tvShows, itemType, id)
+
+	if err != nil {
+		http.Error(w, "Failed to get title: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !found {
+		http.Error(w, "Title not found", http.StatusNotFound)
+
+		return
+	}
+
+	votes, err := profiles.GetItemVotes(r.Context(), itemType, id)
+
+	if err != nil {
+		http.Error(w, "Failed to get votes: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "vote-manager", VoteData{
+		ItemType:  itemType,
+		ItemID:    id,
+		ItemTitle: title,
+		Votes:     votes,
+		Choices:   database.Votes,
+		Error:     message,
+	})
+}
+
+// VotesHandler returns the vote manager for a movie or TV show
+func VotesHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore, profiles database.ProfileStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+
+		return
+	}
+
+	renderVoteManager(w, r, movies, tvShows, profiles, r.URL.Query().Get("type"), id, "")
+}
+
+// SetVoteHandler records or clears a profile's vote on a title and returns the
+// refreshed vote manager
+func SetVoteHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore, profiles database.ProfileStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+
+		return
+	}
+
+	profileID, err := formInt(r, "profile")
+
+	if err != nil {
+		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+
+		return
+	}
+
+	vote := database.Vote{ProfileID: profileID, ItemType: r.FormValue("type"), ItemID: id, Vote: r.FormValue("vote")}
+	err = profiles.SetVote(r.Context(), vote)
+
+	if errors.Is(err, database.ErrInvalidVote) {
+		renderVoteManager(w, r, movies, tvShows, profiles, vote.ItemType, id, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to save vote", err)
+		http.Error(w, "Failed to save vote: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	logger.Debug("Profile %d voted %q on %s %d", profileID, vote.Vote, vote.ItemType, id)
+	renderVoteManager(w, r, movies, tvShows, profiles, vote.ItemType, id, "")
+}
+
+// WantedHandler returns a board's "what everyone wants" view: its watchlist
+// titles with votes, the ones the household most wants first
+func WantedHandler(w http.ResponseWriter, r *http.Request, profiles database.ProfileStore, itemType string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	tallies, err := profiles.GetVoteTallies(r.Context(), itemType)
+
+	if err != nil {
+		http.Error(w, "Failed to get votes: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	list, err := profiles.ListProfiles(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load profiles: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "wanted-list", WantedData{ItemType: itemType, Tallies: tallies, Profiles: len(list)})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+	"github.com/pwnderpants/homenet/internal/media"
+)
+
+// posterPathPattern matches the file part of a cached poster path, such as
+// 0123456789abcdef0123456789abcdef-w185.jpg
+var posterPathPattern = regexp.MustCompile(`^([0-9a-f]{32})-(w[0-9]+)\.jpg$`)
+
+// mediaCacheControl lets browsers keep posters for a year; a poster's path
+// changes whenever its URL does
+const mediaCacheControl = "public, max-age=31536000, immutable"
+
+// MediaHandler serves cached posters from /media/posters/. A poster not
+// cached yet is downloaded first, as long as an item on the boards uses it.
+func MediaHandler(w http.ResponseWriter, r *http.Request, cache *media.Cache, posters database.PosterStore) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	match := posterPathPattern.FindStringSubmatch(strings.TrimPrefix(r.URL.Path, "/media/posters/"))
+
+	if match == nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	key, size := match[1], match[2]
+
+	if _, ok := media.Sizes[size]; !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if !cache.Has(key) {
+		posterURL, err := findPoster(r, posters, key)
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to list poster URLs", err)
+			http.Error(w, "Failed to load poster", http.StatusInternalServerError)
+
+			return
+		}
+
+		if posterURL == "" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if err := cache.Fetch(r.Context(), posterURL); err != nil {
+			logger.Warn("Failed to cache poster %s: %v", posterURL, err)
+			http.Error(w, "Failed to download poster", http.StatusBadGateway)
+
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	http.ServeFile(w, r, cache.Path(key, size))
+}
+
+// findPoster returns the poster URL with a cache key, or "" when no item uses one
+func findPoster(r *http.Request, posters database.PosterStore, key string) (string, error) {
+	urls, err := posters.GetPosterURLs(r.Context())
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, posterURL := range urls {
+		if database.PosterKey(posterURL) == key {
+			return posterURL, nil
+		}
+	}
+
+	return "", nil
+}
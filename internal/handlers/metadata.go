@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+	"github.com/pwnderpants/homenet/internal/metadata"
+)
+
+// Lookup-as-you-type waits for this many characters and offers at most
+// maxSuggestions matches
+const (
+	minSuggestQuery = 2
+	maxSuggestions  = 8
+)
+
+// metadataItemType reads and checks the type parameter of a lookup
+func metadataItemType(r *http.Request) (string, bool) {
+	itemType := r.URL.Query().Get("type")
+
+	return itemType, itemType == database.ItemTypeMovie || itemType == database.ItemTypeTVShow
+}
+
+// formDetails reads the poster, director and comma separated cast fields of
+// the add form and edit modal
+func formDetails(r *http.Request) database.Details {
+	details := database.Details{
+		PosterURL: r.FormValue("poster_url"),
+		Director:  r.FormValue("director"),
+	}
+
+	for _, member := range strings.Split(r.FormValue("cast"), ",") {
+		if member = strings.TrimSpace(member); member != "" {
+			details.Cast = append(details.Cast, member)
+		}
+	}
+
+	return details
+}
+
+// knownIMDbLink reports whether the IMDb ID in a link is in the imported
+// dataset. Links without an ID, and every link before a dataset is imported,
+// pass; a failed check is logged rather than blocking the save.
+func knownIMDbLink(ctx context.Context, dataset database.IMDbDatasetStore, link string) bool {
+	id := database.IMDbID(link)
+
+	if id == "" {
+		return true
+	}
+
+	title, err := dataset.GetIMDbTitle(ctx, id)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to check IMDb ID %s", err, id)
+
+		return true
+	}
+
+	if title != nil {
+		return true
+	}
+
+	count, err := dataset.CountIMDbTitles(ctx)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to count IMDb titles", err)
+
+		return true
+	}
+
+	return count == 0
+}
+
+// MetadataSearchHandler offers the provider's matches for a title being typed
+// in the add form. It renders nothing when lookups are turned off.
+func MetadataSearchHandler(w http.ResponseWriter, r *http.Request, provider metadata.MetadataProvider) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	itemType, ok := metadataItemType(r)
+
+	if !ok {
+		http.Error(w, "Unknown item type", http.StatusBadRequest)
+
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("title"))
+	data := MetadataSuggestionsData{ItemType: itemType}
+
+	if provider == nil || len(query) < minSuggestQuery {
+		renderPartial(w, "metadata-suggestions", data)
+
+		return
+	}
+
+	data.Provider = provider.Name()
+
+	matches, err := provider.Search(r.Context(), itemType, query)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to search %s for %q", err, provider.Name(), query)
+		data.Error = "Could not reach " + provider.Name() + "."
+	}
+
+	data.Matches = matches[:min(maxSuggestions, len(matches))]
+
+	renderPartial(w, "metadata-suggestions", data)
+}
+
+// MetadataDetailsHandler returns as JSON the provider's details for a match
+// chosen from the suggestions, which the add form copies into its fields
+func MetadataDetailsHandler(w http.ResponseWriter, r *http.Request, provider metadata.MetadataProvider) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if provider == nil {
+		http.Error(w, "Metadata lookups are turned off", http.StatusNotFound)
+
+		return
+	}
+
+	itemType, ok := metadataItemType(r)
+
+	if !ok {
+		http.Error(w, "Unknown item type", http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := provider.Lookup(r.Context(), itemType, r.URL.Query().Get("id"))
+
+	if errors.Is(err, metadata.ErrInvalidID) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if errors.Is(err, metadata.ErrNotFound) {
+		http.Error(w, "Title not found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to look up %s title", err, provider.Name())
+		http.Error(w, "Could not reach "+provider.Name(), http.StatusBadGateway)
+
+		return
+	}
+
+	if itemType == database.ItemTypeMovie && result.Runtime > database.MaxRuntime {
+		result.Runtime = 0
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.ErrorWithErr("Failed to write metadata details", err)
+	}
+}
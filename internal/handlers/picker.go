@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/config"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// DefaultPickDays is how long a pick is kept out of the picker unless the form says otherwise
+const DefaultPickDays = 7
+
+// Pools offered by the TV show picker
+var PickerPools = []SortOption{
+	{Value: database.PoolAny, Label: "Active season or in progress"},
+	{Value: database.PoolActive, Label: "Active season"},
+	{Value: database.PoolInProgress, Label: "Started, not finished"},
+}
+
+// formRuntime reads an optional runtime in minutes from a form
+func formRuntime(r *http.Request) (int, error) {
+	text := strings.TrimSpace(r.FormValue("runtime"))
+
+	if text == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(text)
+}
+
+// parsePickForm reads the picker's filters, including who is watching tonight.
+// Previous picks are only excluded on a reroll.
+func parsePickForm(r *http.Request) (database.PickOptions, error) {
+	options := database.PickOptions{
+		Service:       strings.TrimSpace(r.FormValue("service")),
+		Pool:          r.FormValue("pool"),
+		NotPickedDays: DefaultPickDays,
+	}
+
+	if !database.ValidPool(options.Pool) {
+		return options, fmt.Errorf("unknown pool %q", options.Pool)
+	}
+
+	if tag := strings.TrimSpace(r.FormValue("tag")); tag != "" {
+		options.Tags = []string{tag}
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"max_runtime", &options.MaxRuntime}, {"days", &options.NotPickedDays}} {
+		text := strings.TrimSpace(r.FormValue(param.name))
+
+		if text == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(text)
+
+		if err != nil || n < 0 {
+			return options, fmt.Errorf("invalid %s %q", param.name, text)
+		}
+
+		*param.value = n
+	}
+
+	for _, value := range r.Form["present"] {
+		id, err := strconv.Atoi(value)
+
+		if err != nil {
+			return options, fmt.Errorf("invalid present %q", value)
+		}
+
+		options.VetoedBy = append(options.VetoedBy, id)
+	}
+
+	if r.FormValue("reroll") != "true" {
+		return options, nil
+	}
+
+	for _, value := range r.Form["exclude"] {
+		id, err := strconv.Atoi(value)
+
+		if err != nil {
+			return options, fmt.Errorf("invalid exclude %q", value)
+		}
+
+		options.Exclude = append(options.Exclude, id)
+	}
+
+	return options, nil
+}
+
+// pickerData fills in the picker form around a pick
+func pickerData(cfg *config.Config, options database.PickOptions, inUse []string, household []database.Profile, pickedID int) PickerData {
+	genres := Genres
+	streamingServices := StreamingServices
+
+	if cfg != nil {
+		genres = cfg.Genres
+		streamingServices = cfg.StreamingServices
+	}
+
+	data := PickerData{
+		Service:           options.Service,
+		Pool:              options.Pool,
+		Days:              options.NotPickedDays,
+		Exclude:           options.Exclude,
+		Tags:              tagOptions(genres, inUse),
+		StreamingServices: streamingServices,
+	}
+
+	if len(options.Tags) > 0 {
+		data.Tag = options.Tags[0]
+	}
+
+	for _, profile := range household {
+		data.Household = append(data.Household, PresentProfile{Profile: profile, Present: slices.Contains(options.VetoedBy, profile.ID)})
+	}
+
+	if options.MaxRuntime > 0 {
+		data.MaxRuntime = options.MaxRuntime
+	}
+
+	// A reroll leaves out everything offered so far, including this pick
+	if pickedID != 0 {
+		data.Exclude = append(append([]int{}, options.Exclude...), pickedID)
+	}
+
+	return data
+}
+
+// readPickRequest checks a picker request and reads its filters, the board's
+// tags in use and the household's profiles, writing the error response and
+// returning false when it cannot
+func readPickRequest(w http.ResponseWriter, r *http.Request, tagStore database.TagStore, profiles database.ProfileStore, itemType string) (database.PickOptions, []string, []database.Profile, bool) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return database.PickOptions{}, nil, nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return database.PickOptions{}, nil, nil, false
+	}
+
+	options, err := parsePickForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid picker filters: "+err.Error(), http.StatusBadRequest)
+
+		return database.PickOptions{}, nil, nil, false
+	}
+
+	inUse, err := tagStore.GetTags(r.Context(), itemType)
+
+	if err != nil {
+		http.Error(w, "Failed to load tags: "+err.Error(), http.StatusInternalServerError)
+
+		return database.PickOptions{}, nil, nil, false
+	}
+
+	household, err := profiles.ListProfiles(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load profiles: "+err.Error(), http.StatusInternalServerError)
+
+		return database.PickOptions{}, nil, nil, false
+	}
+
+	return options, inUse, household, true
+}
+
+// RandomMovieHandler picks a random movie streaming today that matches the
+// picker's filters, favouring movies that have been on the list longest
+func RandomMovieHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, store database.MovieStore, tagStore database.TagStore, profiles database.ProfileStore) {
+	options, inUse, household, ok := readPickRequest(w, r, tagStore, profiles, database.ItemTypeMovie)
+
+	if !ok {
+		return
+	}
+
+	movie, err := store.PickMovie(r.Context(), options)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to pick a random movie", err)
+		http.Error(w, "Failed to get random movie: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	pickedID := 0
+
+	if movie != nil {
+		pickedID = movie.ID
+		logger.Info("Picked random movie: %s", movie.Title)
+	}
+
+	data := pickerData(cfg, options, inUse, household, pickedID)
+	data.Path = "/movie-board/random"
+	data.Target = "#random-movie-modal-content"
+	data.Runtimes = true
+	data.Movie = movie
+
+	renderPartial(w, "random-movie", data)
+}
+
+// RandomTVShowHandler picks what to watch next from TV shows with an active
+// season or started but not finished, using the same filters as the movie picker
+func RandomTVShowHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config, store database.TVShowStore, tagStore database.TagStore, profiles database.ProfileStore) {
+	options, inUse, household, ok := readPickRequest(w, r, tagStore, profiles, database.ItemTypeTVShow)
+
+	if !ok {
+		return
+	}
+
+	tvShow, err := store.PickTVShow(r.Context(), options)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to pick a random TV show", err)
+		http.Error(w, "Failed to get random TV show: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	pickedID := 0
+
+	if tvShow != nil {
+		pickedID = tvShow.ID
+		logger.Info("Picked random TV show: %s", tvShow.Title)
+	}
+
+	data := pickerData(cfg, options, inUse, household, pickedID)
+	data.Path = "/tv-shows-board/random"
+	data.Target = "#random-tvshow-modal-content"
+	data.Pools = PickerPools
+	data.TVShow = tvShow
+
+	renderPartial(w, "random-tvshow", data)
+}
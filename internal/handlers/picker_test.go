@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+func TestParsePickForm(t *testing.T) {
+	tests := []struct {
+		form    string
+		want    database.PickOptions
+		wantErr bool
+	}{
+		{form: "", want: database.PickOptions{NotPickedDays: DefaultPickDays}},
+		{
+			form: "tag=+Comedy+&service=Netflix&max_runtime=120&days=0&present=1&present=3",
+			want: database.PickOptions{Tags: []string{"Comedy"}, Service: "Netflix", MaxRuntime: 120, VetoedBy: []int{1, 3}},
+		},
+		{form: "pool=started&days=7", want: database.PickOptions{Pool: database.PoolInProgress, NotPickedDays: 7}},
+		{form: "exclude=4&exclude=5", want: database.PickOptions{NotPickedDays: DefaultPickDays}},
+		{form: "reroll=true&exclude=4&exclude=5", want: database.PickOptions{NotPickedDays: DefaultPickDays, Exclude: []int{4, 5}}},
+		{form: "pool=someday", wantErr: true},
+		{form: "max_runtime=-1", wantErr: true},
+		{form: "days=week", wantErr: true},
+		{form: "present=sam", wantErr: true},
+		{form: "reroll=true&exclude=last", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/pick", strings.NewReader(tt.form))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		got, err := parsePickForm(r)
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePickForm(%q) = %+v, want an error", tt.form, got)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePickForm(%q) = %+v, %v; want %+v", tt.form, got, err, tt.want)
+		}
+	}
+}
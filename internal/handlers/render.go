@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// partialsGlob matches the shared partials used by pages and HTMX fragments
+const partialsGlob = "web/templates/partials/*.html"
+
+// parseTemplate parses a page template together with the shared partials
+func parseTemplate(page string) (*template.Template, error) {
+	tmpl, err := template.ParseFiles(page)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl.ParseGlob(partialsGlob)
+}
+
+// renderPartial renders a named partial as an HTML fragment for HTMX
+func renderPartial(w http.ResponseWriter, name string, data interface{}) {
+	renderPartialStatus(w, http.StatusOK, name, data)
+}
+
+// renderPartialStatus renders a named partial with a status other than 200 OK
+func renderPartialStatus(w http.ResponseWriter, status int, name string, data interface{}) {
+	tmpl, err := template.ParseGlob(partialsGlob)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to parse partial templates", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	// Render into a buffer first so a template error can still become a 500
+	var buf bytes.Buffer
+
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		logger.ErrorWithErr("Failed to render partial %s", err, name)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// tokenOwner returns the user managing API tokens, answering 403 and returning
+// nil unless the request comes from a browser session, so a token cannot mint
+// or revoke tokens
+func tokenOwner(w http.ResponseWriter, r *http.Request) *database.User {
+	user := auth.UserFromContext(r.Context())
+
+	if user == nil || auth.APITokenFromContext(r.Context()) != nil {
+		http.Error(w, "API tokens are managed from a signed-in browser", http.StatusForbidden)
+
+		return nil
+	}
+
+	return user
+}
+
+// renderTokenList renders the API token list fragment for a user
+func renderTokenList(w http.ResponseWriter, r *http.Request, tokens database.APITokenStore, user *database.User, newToken, message string) {
+	list, err := tokens.ListAPITokens(r.Context(), user.ID)
+
+	if err != nil {
+		http.Error(w, "Failed to load API tokens: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "token-list", SettingsData{SignedIn: true, Tokens: list, Scopes: database.Scopes, NewToken: newToken, Error: message})
+}
+
+// SettingsHandler handles the settings page, where users manage their API tokens
+func SettingsHandler(w http.ResponseWriter, r *http.Request, tokens database.APITokenStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/settings.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data := SettingsData{
+		Title:      "Settings",
+		Navigation: SetActiveNavigation("/settings"),
+		CSRFToken:  auth.CSRFTokenFromContext(r.Context()),
+		Scopes:     database.Scopes,
+	}
+
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		data.SignedIn = true
+		data.Tokens, err = tokens.ListAPITokens(r.Context(), user.ID)
+
+		if err != nil {
+			http.Error(w, "Failed to load API tokens: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// CreateAPITokenHandler creates an API token for the signed-in user and
+// returns the token list with its secret shown once
+func CreateAPITokenHandler(w http.ResponseWriter, r *http.Request, tokens database.APITokenStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	user := tokenOwner(w, r)
+
+	if user == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+
+		return
+	}
+
+	secret, err := auth.NewAPIToken()
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to create API token", err)
+		http.Error(w, "Failed to create API token", http.StatusInternalServerError)
+
+		return
+	}
+
+	token := database.APIToken{UserID: user.ID, Name: r.FormValue("name"), Scopes: r.Form["scope"]}
+	_, err = tokens.CreateAPIToken(r.Context(), token, auth.HashToken(secret))
+
+	if errors.Is(err, database.ErrInvalidToken) {
+		renderTokenList(w, r, tokens, user, "", err.Error())
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to create API token", err)
+		http.Error(w, "Failed to create API token: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	logger.Info("User %s created API token %q", user.Username, token.Name)
+	renderTokenList(w, r, tokens, user, secret, "")
+}
+
+// RevokeAPITokenHandler deletes one of the signed-in user's API tokens and
+// returns the refreshed token list
+func RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request, tokens database.APITokenStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	user := tokenOwner(w, r)
+
+	if user == nil {
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+
+		return
+	}
+
+	err = tokens.DeleteAPIToken(r.Context(), user.ID, id)
+
+	if errors.Is(err, database.ErrTokenNotFound) {
+		http.Error(w, "API token not found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to revoke API token", err)
+		http.Error(w, "Failed to revoke API token: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	logger.Info("User %s revoked API token %d", user.Username, id)
+	renderTokenList(w, r, tokens, user, "", "")
+}
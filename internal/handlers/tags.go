@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// formTags collects the tags chosen in the multi-select plus any typed as new tags
+func formTags(r *http.Request) []string {
+	return append(r.Form["tags"], r.FormValue("new_tags"))
+}
+
+// containsTag reports whether tags holds tag, ignoring case
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tagOptions merges the configured suggestions with the tags already in use
+func tagOptions(suggested, inUse []string) []string {
+	var options []string
+
+	for _, tag := range append(append([]string{}, suggested...), inUse...) {
+		if !containsTag(options, tag) {
+			options = append(options, tag)
+		}
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		return strings.ToLower(options[i]) < strings.ToLower(options[j])
+	})
+
+	return options
+}
+
+// tagFilters builds the board's filter pills, each linking to the board with
+// that tag toggled and the other filters kept
+func tagFilters(path string, values url.Values, tags []string) []TagFilter {
+	selected := values["tag"]
+	filters := make([]TagFilter, 0, len(tags))
+
+	for _, tag := range tags {
+		isSelected := containsTag(selected, tag)
+
+		var next []string
+
+		for _, s := range selected {
+			if !strings.EqualFold(s, tag) {
+				next = append(next, s)
+			}
+		}
+
+		if !isSelected {
+			next = append(next, tag)
+		}
+
+		toggled := cloneValues(values)
+		toggled["tag"] = next
+
+		filters = append(filters, TagFilter{Name: tag, Selected: isSelected, URL: boardURL(path, toggled, "")})
+	}
+
+	return filters
+}
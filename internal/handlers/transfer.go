@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+	"github.com/pwnderpants/homenet/internal/transfer"
+)
+
+// maxImportSize caps the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// exportContentTypes maps export formats to their response content types
+var exportContentTypes = map[string]string{
+	transfer.FormatCSV:  "text/csv; charset=utf-8",
+	transfer.FormatJSON: "application/json",
+}
+
+// DataHandler handles the import and export page
+func DataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/data.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data := DataPageData{
+		Title:      "Import & Export",
+		Navigation: SetActiveNavigation("/data"),
+		CSRFToken:  auth.CSRFTokenFromContext(r.Context()),
+	}
+
+	for _, source := range transfer.Sources {
+		data.Sources = append(data.Sources, SourceOption{Value: source, Label: transfer.SourceLabel(source)})
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// ExportHandler downloads every movie or TV show, watched ones included, as CSV or JSON
+func ExportHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	itemType := r.URL.Query().Get("type")
+	format := r.URL.Query().Get("format")
+
+	if !transfer.ValidFormat(format) {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+
+		return
+	}
+
+	var name string
+	var write func() error
+
+	switch itemType {
+	case database.ItemTypeMovie:
+		all, err := transfer.CollectMovies(r.Context(), movies)
+
+		if err != nil {
+			http.Error(w, "Failed to get movies: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		name = "movies"
+		write = func() error { return transfer.ExportMovies(w, format, all) }
+	case database.ItemTypeTVShow:
+		all, err := transfer.CollectTVShows(r.Context(), tvShows)
+
+		if err != nil {
+			http.Error(w, "Failed to get TV shows: "+err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		name = "tv-shows"
+		write = func() error { return transfer.ExportTVShows(w, format, all) }
+	default:
+		http.Error(w, "Invalid item type", http.StatusBadRequest)
+
+		return
+	}
+
+	logger.Info("Exporting %s as %s", name, format)
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"homenet-%s-%s.%s\"", name, time.Now().Format("2006-01-02"), format))
+
+	if err := write(); err != nil {
+		logger.ErrorWithErr("Failed to write export", err)
+	}
+}
+
+// ImportHandler imports an uploaded CSV or JSON file and renders a report of
+// what was added, updated, skipped and which rows failed
+func ImportHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore, duplicates database.DuplicateStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		logger.ErrorWithErr("Form parsing error in ImportHandler", err)
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+
+	if err != nil {
+		http.Error(w, "Choose a file to import", http.StatusBadRequest)
+
+		return
+	}
+
+	defer file.Close()
+
+	itemType := r.FormValue("type")
+	mode := r.FormValue("mode")
+	format := transfer.FormatFromName(header.Filename)
+
+	if format == "" {
+		http.Error(w, "Import files must end in .csv or .json", http.StatusBadRequest)
+
+		return
+	}
+
+	if !transfer.ValidMode(mode) {
+		http.Error(w, "Invalid import mode", http.StatusBadRequest)
+
+		return
+	}
+
+	logger.Info("Importing %s from %s (%s, %s)", itemType, header.Filename, format, mode)
+
+	var report *transfer.Report
+
+	switch itemType {
+	case database.ItemTypeMovie:
+		report, err = transfer.ImportMovies(r.Context(), movies, duplicates, file, format, mode)
+	case database.ItemTypeTVShow:
+		report, err = transfer.ImportTVShows(r.Context(), tvShows, duplicates, file, format, mode)
+	default:
+		http.Error(w, "Invalid item type", http.StatusBadRequest)
+
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to read import file", err)
+		http.Error(w, "Failed to read "+header.Filename+": "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	renderPartial(w, "import-report", ImportReportData{FileName: header.Filename, Report: report})
+}
+
+// ImportPreviewHandler reads an uploaded Letterboxd, IMDb or Trakt export and
+// renders what importing it would add, merge and skip, without saving anything
+func ImportPreviewHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore) {
+	externalImport(w, r, movies, tvShows, false)
+}
+
+// ExternalImportHandler imports an uploaded Letterboxd, IMDb or Trakt export
+// and renders a report of what was added, updated, skipped and which entries
+// failed, as ImportHandler does
+func ExternalImportHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore) {
+	externalImport(w, r, movies, tvShows, true)
+}
+
+// externalImport plans an external import from the uploaded file and renders
+// the plan, or when apply is set carries it out and renders its report
+func externalImport(w http.ResponseWriter, r *http.Request, movies database.MovieStore, tvShows database.TVShowStore, apply bool) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		logger.ErrorWithErr("Form parsing error in externalImport", err)
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+
+	if err != nil {
+		http.Error(w, "Choose a file to import", http.StatusBadRequest)
+
+		return
+	}
+
+	defer file.Close()
+
+	source := r.FormValue("source")
+
+	if !transfer.ValidSource(source) {
+		http.Error(w, "Invalid import source", http.StatusBadRequest)
+
+		return
+	}
+
+	entries, err := transfer.ParseSource(source, file)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to read external import file", err)
+		http.Error(w, "Failed to read "+header.Filename+": "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	plan, err := transfer.BuildPlan(r.Context(), movies, tvShows, source, r.FormValue("watcher"), entries)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to plan external import", err)
+		http.Error(w, "Failed to plan import: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if apply {
+		logger.Info("Importing %s from %s", transfer.SourceLabel(source), header.Filename)
+		report := transfer.Apply(r.Context(), movies, tvShows, plan)
+		renderPartial(w, "import-report", ImportReportData{FileName: header.Filename, Report: report})
+
+		return
+	}
+
+	renderPartial(w, "import-plan", ImportPlanData{FileName: header.Filename, SourceLabel: transfer.SourceLabel(source), Plan: plan})
+}
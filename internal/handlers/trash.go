@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/config"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// RestoreMovieHandler undoes a movie delete and returns the refreshed list
+func RestoreMovieHandler(w http.ResponseWriter, r *http.Request, movies database.MovieStore, trash database.TrashStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := trash.RestoreItem(r.Context(), database.ItemTypeMovie, id); err != nil {
+		http.Error(w, "Failed to restore movie: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data, err := movieList(r, movies, boardValues(r))
+
+	if err != nil {
+		http.Error(w, "Failed to get movies: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	renderPartial(w, "movie-list-fragment", data)
+}
+
+// RestoreTVShowHandler undoes a TV show delete and returns the refreshed list
+func RestoreTVShowHandler(w http.ResponseWriter, r *http.Request, tvShows database.TVShowStore, trash database.TrashStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := trash.RestoreItem(r.Context(), database.ItemTypeTVShow, id); err != nil {
+		http.Error(w, "Failed to restore TV show: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data, err := tvShowList(r, tvShows, boardValues(r))
+
+	if err != nil {
+		http.Error(w, "Failed to get TV shows: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	renderPartial(w, "tvshow-list-fragment", data)
+}
+
+// TrashHandlerWithConfig handles the trash page
+func TrashHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config, trash database.TrashStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	tmpl, err := parseTemplate("web/templates/trash.html")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	items, err := trash.GetTrash(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load trash: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data := TrashData{
+		Title:         "Trash",
+		Navigation:    SetActiveNavigation("/trash"),
+		CSRFToken:     auth.CSRFTokenFromContext(r.Context()),
+		Items:         items,
+		RetentionDays: cfg.Trash.RetentionDays,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+// parseTrashForm reads the item type and ID submitted from the trash page
+func parseTrashForm(r *http.Request) (string, int, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", 0, err
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+
+	return r.FormValue("type"), id, err
+}
+
+// renderTrashList renders the refreshed trash list fragment
+func renderTrashList(w http.ResponseWriter, r *http.Request, trash database.TrashStore) {
+	items, err := trash.GetTrash(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to load trash: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "trash-list", TrashData{Items: items})
+}
+
+// RestoreTrashHandler restores an item from the trash page
+func RestoreTrashHandler(w http.ResponseWriter, r *http.Request, trash database.TrashStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	itemType, id, err := parseTrashForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := trash.RestoreItem(r.Context(), itemType, id); err != nil {
+		http.Error(w, "Failed to restore item: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	renderTrashList(w, r, trash)
+}
+
+// PurgeTrashHandler permanently deletes an item from the trash page
+func PurgeTrashHandler(w http.ResponseWriter, r *http.Request, trash database.TrashStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	itemType, id, err := parseTrashForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := trash.PurgeItem(r.Context(), itemType, id); err != nil {
+		logger.ErrorWithErr("Failed to purge trash item", err)
+		http.Error(w, "Failed to purge item: "+err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	renderTrashList(w, r, trash)
+}
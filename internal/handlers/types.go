@@ -1,5 +1,11 @@
 package handlers
 
+import (
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/metadata"
+	"github.com/pwnderpants/homenet/internal/transfer"
+)
+
 // YearRange for form inputs
 type YearRange struct {
 	Min int
@@ -14,6 +20,7 @@ type PageData struct {
 	StreamingServices []string
 	YearRange         YearRange
 	Navigation        []NavItem
+	CSRFToken         string
 	FeatureCards      []FeatureCard
 }
 
@@ -22,13 +29,19 @@ type MovieBoardData struct {
 	Title             string
 	Movies            []Movie
 	MovieCount        int
-	Genres            []string
+	Tags              []string
+	TagFilters        []TagFilter
+	Filters           BoardFilters
 	StreamingServices []string
 	YearRange         YearRange
+	MaxRuntime        int
 	Navigation        []NavItem
+	CSRFToken         string
 	FormText          FormText
 	Colors            ColorScheme
 	BadgeColors       map[string]string
+	Pager             *BoardPager
+	Undo              *UndoToast
 }
 
 // TVShowBoardData represents the data for the TV show board page
@@ -36,13 +49,135 @@ type TVShowBoardData struct {
 	Title             string
 	TVShows           []TVShow
 	TVShowCount       int
-	Genres            []string
+	Tags              []string
+	TagFilters        []TagFilter
+	Filters           BoardFilters
 	StreamingServices []string
 	YearRange         YearRange
 	Navigation        []NavItem
+	CSRFToken         string
 	FormText          FormText
 	Colors            ColorScheme
 	BadgeColors       map[string]string
+	Pager             *BoardPager
+	Undo              *UndoToast
+}
+
+// TagFilter is one tag pill in a board's filter bar
+type TagFilter struct {
+	Name     string
+	Selected bool
+	URL      string // the board with this tag toggled
+}
+
+// BoardFilters is a board's filter and sort state as shown in its filter bar
+type BoardFilters struct {
+	Path         string // the board's URL path
+	Target       string // the list element the filter bar swaps
+	Tags         []string
+	Service      string
+	YearFrom     string
+	YearTo       string
+	Availability string
+	Sort         string
+	Dir          string
+	Limit        string
+	ClearTagsURL string
+	Sorts        []SortOption
+}
+
+// SortOption is one choice in a menu such as the filter bar's sort order or the TV show picker's pools
+type SortOption struct {
+	Value string
+	Label string
+}
+
+// BoardPager links to the first and next pages of a board list
+type BoardPager struct {
+	Target   string // the list element the links swap
+	FirstURL string // set past the first page
+	NextURL  string // set when more items follow
+}
+
+// SearchData represents the data for search result fragments. An empty query
+// on a board shows the board's list, with its pager.
+type SearchData struct {
+	Query   string
+	Movies  []Movie
+	TVShows []TVShow
+	Pager   *BoardPager
+}
+
+// WatchedData represents the data for the watched archive fragments
+type WatchedData struct {
+	Movies      []database.WatchedMovie
+	TVShows     []database.WatchedTVShow
+	MovieCount  int
+	TVShowCount int
+}
+
+// EpisodeData represents the data for the season and episode manager
+type EpisodeData struct {
+	TVShow     TVShow
+	Seasons    []database.Season
+	NextSeason int
+	Error      string
+}
+
+// UndoToast describes the toast shown after an item is moved to the trash
+type UndoToast struct {
+	ID         int
+	Title      string
+	RestoreURL string
+	Target     string // list element the restore response is swapped into
+}
+
+// TrashData represents the data for the trash page and its fragments
+type TrashData struct {
+	Title         string
+	Navigation    []NavItem
+	CSRFToken     string
+	Items         []database.TrashItem
+	RetentionDays int
+}
+
+// HistoryData represents the data for an item's audit history page
+type HistoryData struct {
+	Title      string
+	Navigation []NavItem
+	CSRFToken  string
+	ItemType   string
+	ItemID     int
+	ItemTitle  string
+	Entries    []database.AuditEntry
+}
+
+// LoginData represents the data for the sign-in page
+type LoginData struct {
+	Title     string
+	CSRFToken string
+	Next      string // where to go after signing in
+	Username  string
+	Error     string
+}
+
+// AccountData represents the data for the signed-in user's nav menu
+type AccountData struct {
+	Username  string
+	Role      string
+	CSRFToken string
+}
+
+// SettingsData represents the data for the settings page and its API token list
+type SettingsData struct {
+	Title      string
+	Navigation []NavItem
+	CSRFToken  string
+	SignedIn   bool // false when accounts are not set up
+	Tokens     []database.APIToken
+	Scopes     []string
+	NewToken   string // the secret of a token just created, shown once
+	Error      string
 }
 
 // NavItem represents a navigation item
@@ -84,10 +219,146 @@ type FormText struct {
 	Delete       string
 	Title        string
 	Year         string
-	Genre        string
+	Tags         string
 	Streaming    string
 	IMDBLink     string
 	Notes        string
 	ActiveSeason string
-	AvailableNow string
+}
+
+// DataPageData represents the data for the import and export page
+type DataPageData struct {
+	Title      string
+	Navigation []NavItem
+	CSRFToken  string
+	Sources    []SourceOption
+}
+
+// SourceOption is an external import source offered on the import and export page
+type SourceOption struct {
+	Value string
+	Label string
+}
+
+// ImportReportData is the result of an import shown on the import and export page
+type ImportReportData struct {
+	FileName string
+	Report   *transfer.Report
+}
+
+// ImportPlanData is the preview, or after importing the result, of an external import
+type ImportPlanData struct {
+	FileName    string
+	SourceLabel string
+	Plan        *transfer.Plan
+}
+
+// PickerData is the random picker's pick and the filters it was drawn with.
+// Exclude lists the IDs a reroll leaves out, including the current pick.
+type PickerData struct {
+	Path              string // where the picker's form posts
+	Target            string
+	Runtimes          bool         // whether the board has runtimes to filter on
+	Pools             []SortOption // the pools a TV show can be drawn from
+	Movie             *Movie
+	TVShow            *TVShow
+	Pool              string
+	Tag               string
+	Service           string
+	MaxRuntime        int
+	Days              int
+	Exclude           []int
+	Household         []PresentProfile // who can be marked as watching tonight
+	Tags              []string
+	StreamingServices []string
+}
+
+// PresentProfile is a household profile offered by the picker, checked when
+// the profile is watching tonight
+type PresentProfile struct {
+	database.Profile
+	Present bool
+}
+
+// HouseholdData represents the data for the household page and its profile list
+type HouseholdData struct {
+	Title      string
+	Navigation []NavItem
+	CSRFToken  string
+	Profiles   []database.Profile
+	Error      string
+}
+
+// VoteData is the vote manager for one title, shown in a board's votes modal
+type VoteData struct {
+	ItemType  string
+	ItemID    int
+	ItemTitle string
+	Votes     []database.ProfileVote
+	Choices   []string
+	Error     string
+}
+
+// WantedData is a board's "what everyone wants" view
+type WantedData struct {
+	ItemType string
+	Tallies  []database.VoteTally
+	Profiles int // how many profiles can vote
+}
+
+// DuplicateWarningData is the warning shown by the add form or edit modal when
+// the title being saved is likely already on a board
+type DuplicateWarningData struct {
+	ItemType string
+	Form     string // "add" or "edit"
+	SaveURL  string
+	Movies   []database.Movie
+	TVShows  []database.TVShow
+}
+
+// DuplicatesData represents the data for the duplicates page and its fragment
+type DuplicatesData struct {
+	Title        string
+	Navigation   []NavItem
+	CSRFToken    string
+	MovieGroups  [][]DuplicateItem
+	TVShowGroups [][]DuplicateItem
+}
+
+// DuplicateItem is one movie or TV show in a group of likely duplicates.
+// MergeIDs lists the rest of the group, which keeping this item merges in.
+type DuplicateItem struct {
+	ItemType     string
+	ID           int
+	Title        string
+	Year         int
+	Tags         []string
+	Availability database.AvailabilityList
+	Notes        string
+	IMDBLink     string
+	Watched      bool
+	MergeIDs     string
+	Suggested    bool
+}
+
+// richness counts the fields an item has filled in, to suggest which duplicate to keep
+func (d DuplicateItem) richness() int {
+	count := len(d.Tags) + len(d.Availability)
+
+	for _, filled := range []bool{d.Year != 0, d.IMDBLink != "", d.Notes != "", d.Watched} {
+		if filled {
+			count++
+		}
+	}
+
+	return count
+}
+
+// MetadataSuggestionsData is the list of provider matches offered under the
+// add form's title while it is typed
+type MetadataSuggestionsData struct {
+	ItemType string
+	Provider string
+	Matches  []metadata.Match
+	Error    string
 }
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// parseWatchForm reads a watch event from the submitted form; watcher and a
+// 1-5 rating are required, the review and date are optional
+func parseWatchForm(r *http.Request) (database.WatchEvent, error) {
+	var event database.WatchEvent
+
+	if err := r.ParseForm(); err != nil {
+		return event, err
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+
+	if err != nil {
+		return event, fmt.Errorf("invalid ID")
+	}
+
+	event.ItemID = id
+	event.Watcher = strings.TrimSpace(r.FormValue("watcher"))
+	event.Review = strings.TrimSpace(r.FormValue("review"))
+
+	if event.Watcher == "" {
+		return event, fmt.Errorf("watcher is required")
+	}
+
+	event.Rating, err = strconv.Atoi(r.FormValue("rating"))
+
+	if err != nil || event.Rating < 1 || event.Rating > 5 {
+		return event, fmt.Errorf("rating must be between 1 and 5")
+	}
+
+	if dateStr := r.FormValue("watched_at"); dateStr != "" {
+		event.WatchedAt, err = time.ParseInLocation("2006-01-02", dateStr, time.Local)
+
+		if err != nil {
+			return event, fmt.Errorf("invalid watch date")
+		}
+	}
+
+	return event, nil
+}
+
+// parseIDForm reads the item ID from a submitted form
+func parseIDForm(r *http.Request) (int, error) {
+	if err := r.ParseForm(); err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(r.FormValue("id"))
+}
+
+// WatchMovieHandler records a viewing and moves the movie off the watchlist
+func WatchMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	event, err := parseWatchForm(r)
+
+	if err != nil {
+		logger.Warn("Invalid watch form for movie: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := store.MarkMovieWatched(r.Context(), event); err != nil {
+		http.Error(w, "Failed to mark movie watched: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data, err := movieList(r, store, boardValues(r))
+
+	if err != nil {
+		http.Error(w, "Failed to get movies: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	// Return the refreshed watchlist for HTMX to swap in
+	renderPartial(w, "movie-list-fragment", data)
+}
+
+// WatchedMoviesHandler returns the watched movie archive
+func WatchedMoviesHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	watched, err := store.GetWatchedMovies(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to get watched movies: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "watched-movie-list", WatchedData{Movies: watched})
+}
+
+// UnwatchMovieHandler returns a watched movie to the watchlist, keeping its history
+func UnwatchMovieHandler(w http.ResponseWriter, r *http.Request, store database.MovieStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := store.ReturnMovieToWatchlist(r.Context(), id); err != nil {
+		http.Error(w, "Failed to return movie to watchlist: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	watched, err := store.GetWatchedMovies(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to get watched movies: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	count, err := store.GetMovieCount(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to count movies: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	// Return the refreshed archive plus an out-of-band watchlist count update
+	renderPartial(w, "watched-movie-fragment", WatchedData{Movies: watched, MovieCount: count})
+}
+
+// WatchTVShowHandler records a viewing and moves the TV show off the watchlist
+func WatchTVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	event, err := parseWatchForm(r)
+
+	if err != nil {
+		logger.Warn("Invalid watch form for TV show: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := store.MarkTVShowWatched(r.Context(), event); err != nil {
+		http.Error(w, "Failed to mark TV show watched: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	data, err := tvShowList(r, store, boardValues(r))
+
+	if err != nil {
+		http.Error(w, "Failed to get TV shows: "+err.Error(), listErrorStatus(err))
+
+		return
+	}
+
+	// Return the refreshed watchlist for HTMX to swap in
+	renderPartial(w, "tvshow-list-fragment", data)
+}
+
+// WatchedTVShowsHandler returns the watched TV show archive
+func WatchedTVShowsHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	watched, err := store.GetWatchedTVShows(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to get watched TV shows: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderPartial(w, "watched-tvshow-list", WatchedData{TVShows: watched})
+}
+
+// UnwatchTVShowHandler returns a watched TV show to the watchlist, keeping its history
+func UnwatchTVShowHandler(w http.ResponseWriter, r *http.Request, store database.TVShowStore) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := parseIDForm(r)
+
+	if err != nil {
+		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+
+		return
+	}
+
+	if err := store.ReturnTVShowToWatchlist(r.Context(), id); err != nil {
+		http.Error(w, "Failed to return TV show to watchlist: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	watched, err := store.GetWatchedTVShows(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to get watched TV shows: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	count, err := store.GetTVShowCount(r.Context())
+
+	if err != nil {
+		http.Error(w, "Failed to count TV shows: "+err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	// Return the refreshed archive plus an out-of-band watchlist count update
+	renderPartial(w, "watched-tvshow-fragment", WatchedData{TVShows: watched, TVShowCount: count})
+}
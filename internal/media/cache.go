@@ -0,0 +1,304 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for posters that are not JPEG
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Sizes are the poster widths the cache generates, by the name used in their
+// paths: w185 for cards and w500 for the random picker
+var Sizes = map[string]int{
+	"w185": 185,
+	"w500": 500,
+}
+
+const (
+	downloadTimeout  = 30 * time.Second
+	maxDownloadBytes = 10 << 20
+	maxImagePixels   = 25_000_000
+	jpegQuality      = 85
+)
+
+// ErrTooLarge is returned for posters over maxDownloadBytes or maxImagePixels
+var ErrTooLarge = errors.New("image too large")
+
+// ErrBlockedAddress is returned when a poster URL resolves to an address on
+// this machine or the local network, which the server must not be made to
+// request on a user's behalf
+var ErrBlockedAddress = errors.New("poster host is not a public address")
+
+// Cache keeps downloaded posters, resized to each of Sizes and re-encoded as
+// JPEG, in a directory next to the database. Files are named by
+// database.PosterKey and never change, so they can be cached forever.
+type Cache struct {
+	dir    string
+	client *http.Client
+}
+
+// SyncResult counts what one Sync did
+type SyncResult struct {
+	Fetched int
+	Failed  int
+	Removed int
+}
+
+// New creates a cache that stores posters under dir
+func New(dir string) *Cache {
+	return &Cache{
+		dir: filepath.Join(dir, "posters"),
+		client: &http.Client{
+			Timeout: downloadTimeout,
+			// Checking at dial time covers redirects and hosts that resolve
+			// differently from one lookup to the next
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: downloadTimeout, Control: refusePrivate}).DialContext,
+				TLSHandshakeTimeout: downloadTimeout,
+			},
+		},
+	}
+}
+
+// refusePrivate is a dialer control that refuses connections to loopback,
+// private, link-local and other non-public addresses
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil {
+		return err
+	}
+
+	if !publicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+
+	return nil
+}
+
+// publicAddress reports whether addr is a unicast address outside the
+// loopback, private and link-local ranges
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast()
+}
+
+// Path returns the file a poster size is stored in, cached or not
+func (c *Cache) Path(key, size string) string {
+	return filepath.Join(c.dir, key+"-"+size+".jpg")
+}
+
+// Has reports whether every size of a poster is cached
+func (c *Cache) Has(key string) bool {
+	for size := range Sizes {
+		if _, err := os.Stat(c.Path(key, size)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Fetch downloads a poster and writes each of its sizes
+func (c *Cache) Fetch(ctx context.Context, posterURL string) error {
+	img, err := c.download(ctx, posterURL)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	key := database.PosterKey(posterURL)
+
+	for size, width := range Sizes {
+		if err := c.write(c.Path(key, size), resize(img, width)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// download fetches and decodes an image
+func (c *Cache) download(ctx context.Context, posterURL string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, posterURL, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build poster request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to download poster: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("poster download returned %d", resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("poster is %s, not an image", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadBytes+1))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to download poster: %w", err)
+	}
+
+	if len(data) > maxDownloadBytes {
+		return nil, ErrTooLarge
+	}
+
+	// A small file can declare huge dimensions, so check them before decoding
+	// allocates the pixels
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode poster: %w", err)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("poster is %dx%d pixels", config.Width, config.Height)
+	}
+
+	if config.Width > maxImagePixels/config.Height {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode poster: %w", err)
+	}
+
+	return img, nil
+}
+
+// write encodes an image as JPEG through a temporary file, so a request never
+// sees a half-written poster
+func (c *Cache) write(path string, img image.Image) error {
+	tmp, err := os.CreateTemp(c.dir, ".poster-*")
+
+	if err != nil {
+		return fmt.Errorf("failed to create poster file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to encode poster: %w", err)
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to write poster: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write poster: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save poster: %w", err)
+	}
+
+	return nil
+}
+
+// Sync downloads every listed poster that is not cached yet and removes the
+// files of posters no longer listed. A poster that fails to download is
+// logged and tried again on the next sync.
+func (c *Cache) Sync(ctx context.Context, posterURLs []string) (SyncResult, error) {
+	var result SyncResult
+
+	for _, posterURL := range posterURLs {
+		if c.Has(database.PosterKey(posterURL)) {
+			continue
+		}
+
+		if err := c.Fetch(ctx, posterURL); err != nil {
+			logger.Warn("Failed to cache poster %s: %v", posterURL, err)
+			result.Failed++
+
+			continue
+		}
+
+		result.Fetched++
+	}
+
+	removed, err := c.RemoveOrphans(posterURLs)
+	result.Removed = removed
+
+	return result, err
+}
+
+// RemoveOrphans deletes cached files whose poster is not listed, returning how
+// many files it removed
+func (c *Cache) RemoveOrphans(posterURLs []string) (int, error) {
+	entries, err := os.ReadDir(c.dir)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cached posters: %w", err)
+	}
+
+	keep := make(map[string]bool, len(posterURLs))
+
+	for _, posterURL := range posterURLs {
+		keep[database.PosterKey(posterURL)] = true
+	}
+
+	removed := 0
+
+	for _, entry := range entries {
+		key, _, _ := strings.Cut(entry.Name(), "-")
+
+		if entry.IsDir() || keep[key] || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove orphaned poster: %w", err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
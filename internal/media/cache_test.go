@@ -0,0 +1,253 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:93.184.216.34", true},
+	}
+
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestFetchRefusesLocalHosts(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	err := New(t.TempDir()).Fetch(context.Background(), server.URL+"/poster.png")
+
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch from %s: error = %v, want ErrBlockedAddress", server.URL, err)
+	}
+
+	if requested {
+		t.Error("Fetch reached the local server")
+	}
+}
+
+// encodePNG returns a PNG of a plain image
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// withDimensions rewrites a PNG's header to declare other dimensions, as a
+// small file claiming to be a huge image would
+func withDimensions(data []byte, width, height uint32) []byte {
+	data = bytes.Clone(data)
+
+	// The IHDR chunk follows the 8-byte signature: length, type, then width and height
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	return data
+}
+
+// testCache returns a cache whose client may reach the local test server
+func testCache(t *testing.T, server *httptest.Server) *Cache {
+	t.Helper()
+
+	cache := New(t.TempDir())
+	cache.client = server.Client()
+
+	return cache
+}
+
+func TestFetch(t *testing.T) {
+	poster := encodePNG(t, 600, 900)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		wantErr     error // nil for any error when failing is true
+		failing     bool
+	}{
+		{name: "poster", contentType: "image/png", body: poster, status: http.StatusOK},
+		{name: "no content type", body: poster, status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound, failing: true},
+		{name: "not an image", contentType: "text/html", body: []byte("<html>"), status: http.StatusOK, failing: true},
+		{name: "corrupt", contentType: "image/png", body: poster[:40], status: http.StatusOK, failing: true},
+		{name: "huge dimensions", contentType: "image/png", body: withDimensions(poster, 10000, 10000), status: http.StatusOK, wantErr: ErrTooLarge, failing: true},
+		{name: "no pixels", contentType: "image/png", body: withDimensions(poster, 0, 900), status: http.StatusOK, failing: true},
+		{name: "huge file", contentType: "image/png", body: make([]byte, maxDownloadBytes+1), status: http.StatusOK, wantErr: ErrTooLarge, failing: true},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.contentType != "" {
+				w.Header().Set("Content-Type", tt.contentType)
+			}
+
+			w.WriteHeader(tt.status)
+			w.Write(tt.body)
+		}))
+
+		cache := testCache(t, server)
+		posterURL := server.URL + "/poster.png"
+		err := cache.Fetch(context.Background(), posterURL)
+
+		server.Close()
+
+		if tt.failing {
+			if err == nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: Fetch error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+
+			if cache.Has(database.PosterKey(posterURL)) {
+				t.Errorf("%s: a failed fetch left a cached poster", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: Fetch: %v", tt.name, err)
+		}
+
+		for size, width := range Sizes {
+			file, err := os.Open(cache.Path(database.PosterKey(posterURL), size))
+
+			if err != nil {
+				t.Fatalf("%s: open %s: %v", tt.name, size, err)
+			}
+
+			config, err := jpeg.DecodeConfig(file)
+			file.Close()
+
+			if err != nil || config.Width != width || config.Height != width*3/2 {
+				t.Errorf("%s: %s is %dx%d, %v; want %dx%d JPEG", tt.name, size, config.Width, config.Height, err, width, width*3/2)
+			}
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+
+	// Left half black, right half white
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+
+	tests := []struct {
+		width      int
+		wantWidth  int
+		wantHeight int
+	}{
+		{width: 2, wantWidth: 2, wantHeight: 1},
+		{width: 4, wantWidth: 4, wantHeight: 2},
+		{width: 10, wantWidth: 4, wantHeight: 2},
+		{width: 1, wantWidth: 1, wantHeight: 1},
+	}
+
+	for _, tt := range tests {
+		got := resize(src, tt.width)
+
+		if bounds := got.Bounds(); bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+			t.Errorf("resize to %d = %dx%d, want %dx%d", tt.width, bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+		}
+	}
+
+	halved := resize(src, 2)
+
+	if r, _, _, _ := halved.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("left pixel red = %d, want black", r)
+	}
+
+	if r, _, _, _ := halved.At(1, 0).RGBA(); r != 0xffff {
+		t.Errorf("right pixel red = %d, want white", r)
+	}
+
+	if r, _, _, _ := resize(src, 1).At(0, 0).RGBA(); r < 0x7000 || r > 0x8fff {
+		t.Errorf("single pixel red = %d, want the average grey", r)
+	}
+}
+
+func TestSyncRemovesOrphans(t *testing.T) {
+	poster := encodePNG(t, 100, 150)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(poster)
+	}))
+	defer server.Close()
+
+	cache := testCache(t, server)
+	ctx := context.Background()
+	keep, drop := server.URL+"/keep.png", server.URL+"/drop.png"
+
+	result, err := cache.Sync(ctx, []string{keep, drop})
+
+	if err != nil || result != (SyncResult{Fetched: 2}) {
+		t.Fatalf("first Sync = %+v, %v; want 2 fetched", result, err)
+	}
+
+	result, err = cache.Sync(ctx, []string{keep})
+
+	if err != nil || result != (SyncResult{Removed: len(Sizes)}) {
+		t.Errorf("second Sync = %+v, %v; want the dropped poster's %d files removed", result, err, len(Sizes))
+	}
+
+	if !cache.Has(database.PosterKey(keep)) || cache.Has(database.PosterKey(drop)) {
+		t.Errorf("after Sync: has kept poster %v, has dropped poster %v", cache.Has(database.PosterKey(keep)), cache.Has(database.PosterKey(drop)))
+	}
+}
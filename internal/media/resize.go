@@ -0,0 +1,44 @@
+package media
+
+import (
+	"image"
+	"image/color"
+)
+
+// resize scales an image down to width, keeping its aspect ratio, by
+// averaging the source pixels each destination pixel covers. Images already
+// no wider than width are returned unchanged.
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+
+	if bounds.Dx() <= width {
+		return src
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// datasetSearchLimit is how many titles a dataset search returns
+const datasetSearchLimit = 10
+
+// Dataset looks titles up in the IMDb dataset imported with
+// import-imdb-dataset, without any network access. It knows titles, years,
+// runtimes and genres but no posters, directors or cast.
+type Dataset struct {
+	store database.IMDbDatasetStore
+}
+
+// NewDataset creates a provider over the imported IMDb dataset
+func NewDataset(store database.IMDbDatasetStore) *Dataset {
+	return &Dataset{store: store}
+}
+
+// Name returns the provider's name
+func (d *Dataset) Name() string {
+	return "the IMDb dataset"
+}
+
+// Search returns the titles starting with a query; none until a dataset is imported
+func (d *Dataset) Search(ctx context.Context, itemType, query string) ([]Match, error) {
+	titles, err := d.store.SearchIMDbTitles(ctx, itemType, query, datasetSearchLimit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(titles))
+
+	for _, title := range titles {
+		matches = append(matches, Match{
+			ID:       title.ID,
+			ItemType: title.ItemType,
+			Title:    title.Title,
+			Year:     title.Year,
+		})
+	}
+
+	return matches, nil
+}
+
+// Lookup returns the details of a title by its IMDb ID, which is the dataset's ID
+func (d *Dataset) Lookup(ctx context.Context, itemType, id string) (*Result, error) {
+	if database.IMDbID(id) != id {
+		return nil, fmt.Errorf("%w: %q is not an IMDb ID", ErrInvalidID, id)
+	}
+
+	title, err := d.store.GetIMDbTitle(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if title == nil || title.ItemType != itemType {
+		return nil, ErrNotFound
+	}
+
+	result := &Result{
+		Title:  title.Title,
+		Year:   title.Year,
+		Genres: title.Genres,
+		IMDbID: title.ID,
+	}
+
+	if itemType == database.ItemTypeMovie {
+		result.Runtime = title.Runtime
+	}
+
+	return result, nil
+}
+
+// FindByIMDbID returns the details of the title with an IMDb ID
+func (d *Dataset) FindByIMDbID(ctx context.Context, itemType, imdbID string) (*Result, error) {
+	result, err := d.Lookup(ctx, itemType, imdbID)
+
+	if err == ErrNotFound {
+		return nil, nil
+	}
+
+	return result, err
+}
@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// Enricher fills in the fields a provider knows on items that lack them
+type Enricher struct {
+	Provider MetadataProvider
+	Store    database.MetadataStore
+}
+
+// EnrichResult counts what one enrichment run did
+type EnrichResult struct {
+	Checked int
+	Updated int
+}
+
+// Run looks up to limit movies and limit TV shows missing metadata that were
+// not looked up since checkedBefore. A provider error ends the run early.
+func (e Enricher) Run(ctx context.Context, checkedBefore time.Time, limit int) (EnrichResult, error) {
+	var result EnrichResult
+
+	movies, err := e.Store.GetMoviesMissingMetadata(ctx, checkedBefore, limit)
+
+	if err != nil {
+		return result, err
+	}
+
+	for _, movie := range movies {
+		found, err := e.find(ctx, database.ItemTypeMovie, movie.Title, movie.Year, movie.IMDBLink)
+
+		if err != nil {
+			return result, fmt.Errorf("failed to look up movie %q: %w", movie.Title, err)
+		}
+
+		if found != nil {
+			changed, err := e.Store.FillMovieMetadata(ctx, movie.ID, found.fill())
+
+			// The movie was trashed since the run listed it
+			if errors.Is(err, database.ErrItemNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return result, err
+			}
+
+			if changed {
+				result.Updated++
+			}
+		}
+
+		if err := e.Store.MarkMetadataChecked(ctx, database.ItemTypeMovie, movie.ID); err != nil {
+			return result, err
+		}
+
+		result.Checked++
+	}
+
+	tvShows, err := e.Store.GetTVShowsMissingMetadata(ctx, checkedBefore, limit)
+
+	if err != nil {
+		return result, err
+	}
+
+	for _, tvShow := range tvShows {
+		found, err := e.find(ctx, database.ItemTypeTVShow, tvShow.Title, tvShow.Year, tvShow.IMDBLink)
+
+		if err != nil {
+			return result, fmt.Errorf("failed to look up TV show %q: %w", tvShow.Title, err)
+		}
+
+		if found != nil {
+			changed, err := e.Store.FillTVShowMetadata(ctx, tvShow.ID, found.fill())
+
+			if errors.Is(err, database.ErrItemNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return result, err
+			}
+
+			if changed {
+				result.Updated++
+			}
+		}
+
+		if err := e.Store.MarkMetadataChecked(ctx, database.ItemTypeTVShow, tvShow.ID); err != nil {
+			return result, err
+		}
+
+		result.Checked++
+	}
+
+	return result, nil
+}
+
+// find returns the provider's details for an item: by its IMDb ID when it has
+// one, otherwise by the search result whose title and year agree with it. It
+// returns nil when nothing matches closely enough to trust.
+func (e Enricher) find(ctx context.Context, itemType, title string, year int, imdbLink string) (*Result, error) {
+	if imdbID := database.IMDbID(imdbLink); imdbID != "" {
+		return e.Provider.FindByIMDbID(ctx, itemType, imdbID)
+	}
+
+	matches, err := e.Provider.Search(ctx, itemType, title)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		if database.NormalizeTitle(match.Title) != database.NormalizeTitle(title) {
+			continue
+		}
+
+		if year != 0 && match.Year != 0 && year != match.Year {
+			continue
+		}
+
+		result, err := e.Provider.Lookup(ctx, itemType, match.ID)
+
+		if err == ErrNotFound {
+			return nil, nil
+		}
+
+		return result, err
+	}
+
+	logger.Debug("No %s metadata match for %q", e.Provider.Name(), title)
+
+	return nil, nil
+}
+
+// fill returns what a result can fill in on an item; the store writes each
+// field only where the item's own is empty
+func (r *Result) fill() database.MetadataFill {
+	return database.MetadataFill{
+		Year:     r.Year,
+		Runtime:  r.Runtime,
+		IMDBLink: r.IMDbID,
+		Tags:     r.Genres,
+		Details:  database.Details{PosterURL: r.PosterURL, Director: r.Director, Cast: r.Cast},
+	}
+}
@@ -0,0 +1,205 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// fakeProvider answers lookups from fixed results and records each call.
+// before runs ahead of every answer, as an edit made during a lookup would.
+type fakeProvider struct {
+	byIMDbID map[string]*Result
+	matches  []Match
+	byID     map[string]*Result
+	calls    []string
+	before   func(call string)
+}
+
+func (p *fakeProvider) Name() string {
+	return "Fake"
+}
+
+func (p *fakeProvider) record(call string) {
+	p.calls = append(p.calls, call)
+
+	if p.before != nil {
+		p.before(call)
+	}
+}
+
+func (p *fakeProvider) Search(ctx context.Context, itemType, query string) ([]Match, error) {
+	p.record("search " + query)
+
+	var matches []Match
+
+	for _, match := range p.matches {
+		if match.ItemType == itemType {
+			matches = append(matches, match)
+		}
+	}
+
+	return matches, nil
+}
+
+func (p *fakeProvider) Lookup(ctx context.Context, itemType, id string) (*Result, error) {
+	p.record("lookup " + id)
+
+	if result, ok := p.byID[id]; ok {
+		return result, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+func (p *fakeProvider) FindByIMDbID(ctx context.Context, itemType, imdbID string) (*Result, error) {
+	p.record("find " + imdbID)
+
+	return p.byIMDbID[imdbID], nil
+}
+
+func TestEnricherFind(t *testing.T) {
+	heat := &Result{Title: "Heat", Year: 1995}
+	remake := &Result{Title: "The Thing", Year: 2011}
+	provider := &fakeProvider{
+		byIMDbID: map[string]*Result{"tt0113277": heat},
+		matches: []Match{
+			{ID: "1", ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995},
+			{ID: "2", ItemType: database.ItemTypeMovie, Title: "Thing", Year: 2011},
+			{ID: "3", ItemType: database.ItemTypeMovie, Title: "The Thing", Year: 1982},
+		},
+		byID: map[string]*Result{"1": heat, "2": remake},
+	}
+
+	tests := []struct {
+		name      string
+		title     string
+		year      int
+		imdbLink  string
+		want      *Result
+		wantCalls []string
+	}{
+		{"by IMDb ID before the title", "Something Else", 0, "https://www.imdb.com/title/tt0113277/", heat, []string{"find tt0113277"}},
+		{"unknown IMDb ID without a search", "Heat", 1995, "tt0000001", nil, []string{"find tt0000001"}},
+		{"by title and year", "heat", 1995, "", heat, []string{"search heat", "lookup 1"}},
+		{"by normalized title", "The Thing!", 2011, "", remake, []string{"search The Thing!", "lookup 2"}},
+		{"by title without a year", "THING", 0, "", remake, []string{"search THING", "lookup 2"}},
+		{"year disagrees", "Heat", 2001, "", nil, []string{"search Heat"}},
+		{"match gone by lookup", "The Thing", 1982, "", nil, []string{"search The Thing", "lookup 3"}},
+		{"no match", "Zodiac", 0, "", nil, []string{"search Zodiac"}},
+	}
+
+	for _, tt := range tests {
+		provider.calls = nil
+
+		got, err := Enricher{Provider: provider}.find(context.Background(), database.ItemTypeMovie, tt.title, tt.year, tt.imdbLink)
+
+		if err != nil || got != tt.want {
+			t.Errorf("%s: find = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+
+		if !reflect.DeepEqual(provider.calls, tt.wantCalls) {
+			t.Errorf("%s: calls = %q, want %q", tt.name, provider.calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestEnricherRun(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+
+	mustAdd := func(movie database.Movie) int {
+		id, err := store.AddMovie(ctx, movie)
+
+		if err != nil {
+			t.Fatalf("AddMovie: %v", err)
+		}
+
+		return id
+	}
+
+	heat := mustAdd(database.Movie{Title: "Heat", IMDBLink: "tt0113277", Tags: []string{"Favourites"}, Details: database.Details{Director: "Mann, M."}})
+	ronin := mustAdd(database.Movie{Title: "Ronin", Year: 1998})
+	trashed := mustAdd(database.Movie{Title: "Trashed", Year: 2000})
+	zodiac := mustAdd(database.Movie{Title: "Zodiac"})
+
+	lost, err := store.AddTVShow(ctx, database.TVShow{Title: "Lost"})
+
+	if err != nil {
+		t.Fatalf("AddTVShow: %v", err)
+	}
+
+	cast := []string{"Robert De Niro", "Jean Reno"}
+	provider := &fakeProvider{
+		byIMDbID: map[string]*Result{
+			"tt0113277": {Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime"}, Director: "Michael Mann", PosterURL: "https://images.test/heat.jpg", IMDbID: "tt0113277"},
+		},
+		matches: []Match{
+			{ID: "ronin", ItemType: database.ItemTypeMovie, Title: "Ronin", Year: 1998},
+			{ID: "trashed", ItemType: database.ItemTypeMovie, Title: "Trashed", Year: 2000},
+			{ID: "lost", ItemType: database.ItemTypeTVShow, Title: "Lost", Year: 2004},
+		},
+		byID: map[string]*Result{
+			"ronin":   {Title: "Ronin", Year: 1998, Runtime: 122, Cast: cast, IMDbID: "tt0122690"},
+			"trashed": {Title: "Trashed", Year: 2000, Runtime: 90},
+			"lost":    {Title: "Lost", Year: 2004, Runtime: 44, Genres: []string{"Drama"}, IMDbID: "tt0411008"},
+		},
+	}
+
+	// Edits made while the provider answers: a runtime typed in for Ronin,
+	// which the lookup must not replace, and Trashed moved to the trash
+	provider.before = func(call string) {
+		switch call {
+		case "lookup ronin":
+			if err := store.UpdateMovie(ctx, database.Movie{ID: ronin, Title: "Ronin", Year: 1998, Runtime: 121, Notes: "director's cut"}); err != nil {
+				t.Fatalf("UpdateMovie: %v", err)
+			}
+		case "lookup trashed":
+			if err := store.DeleteMovie(ctx, trashed); err != nil {
+				t.Fatalf("DeleteMovie: %v", err)
+			}
+		}
+	}
+
+	result, err := Enricher{Provider: provider, Store: store}.Run(ctx, time.Now().Add(time.Minute), 10)
+
+	if err != nil || result != (EnrichResult{Checked: 4, Updated: 3}) {
+		t.Fatalf("Run = %+v, %v; want 4 checked and 3 updated", result, err)
+	}
+
+	wantMovies := map[int]database.Movie{
+		heat: {
+			ID: heat, Title: "Heat", Year: 1995, Runtime: 170, Tags: []string{"Favourites"}, IMDBLink: "tt0113277",
+			Details: database.Details{PosterURL: "https://images.test/heat.jpg", Director: "Mann, M."},
+		},
+		ronin: {
+			ID: ronin, Title: "Ronin", Year: 1998, Runtime: 121, Notes: "director's cut", IMDBLink: database.IMDbLink("tt0122690"),
+			Details: database.Details{Cast: cast},
+		},
+		zodiac: {ID: zodiac, Title: "Zodiac"},
+	}
+
+	for id, want := range wantMovies {
+		movie, err := store.GetMovie(ctx, id)
+
+		if err != nil || movie == nil || !reflect.DeepEqual(*movie, want) {
+			t.Errorf("movie %d after Run = %+v, %v; want %+v", id, movie, err, want)
+		}
+	}
+
+	tvShow, err := store.GetTVShow(ctx, lost)
+
+	if err != nil || tvShow == nil || tvShow.Year != 2004 || !reflect.DeepEqual(tvShow.Tags, []string{"Drama"}) || tvShow.IMDBLink != database.IMDbLink("tt0411008") {
+		t.Errorf("TV show after Run = %+v, %v; want its year, genre and IMDb link filled in", tvShow, err)
+	}
+
+	// Items looked up since the cutoff wait for the next one
+	provider.calls = nil
+
+	if result, err := (Enricher{Provider: provider, Store: store}).Run(ctx, time.Now().Add(-time.Minute), 10); err != nil || result != (EnrichResult{}) || provider.calls != nil {
+		t.Errorf("second Run = %+v, %v, calls %q; want nothing looked up", result, err, provider.calls)
+	}
+}
@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/config"
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// Provider names accepted in the metadata.provider setting
+const (
+	ProviderTMDb = "tmdb"
+	ProviderOMDb = "omdb"
+)
+
+// CastLimit is how many cast members a lookup keeps, top billed first
+const CastLimit = 5
+
+// requestTimeout bounds every call to a provider
+const requestTimeout = 10 * time.Second
+
+// Errors a provider returns for titles it cannot look up
+var (
+	ErrNotFound  = errors.New("title not found")
+	ErrInvalidID = errors.New("invalid title ID")
+)
+
+// Match is one search result offered while a title is typed
+type Match struct {
+	ID        string // the provider's ID, passed back to Lookup
+	ItemType  string
+	Title     string
+	Year      int
+	PosterURL string
+}
+
+// Result is everything a provider knows about one title
+type Result struct {
+	Title     string   `json:"title"`
+	Year      int      `json:"year"`
+	Genres    []string `json:"genres"`
+	Runtime   int      `json:"runtime"` // minutes; movies only
+	PosterURL string   `json:"poster_url"`
+	Director  string   `json:"director"` // a TV show's creator
+	Cast      []string `json:"cast"`
+	IMDbID    string   `json:"imdb_id"`
+}
+
+// MetadataProvider looks titles up in an online movie and TV database
+type MetadataProvider interface {
+	Name() string
+	Search(ctx context.Context, itemType, query string) ([]Match, error)
+	Lookup(ctx context.Context, itemType, id string) (*Result, error)
+	FindByIMDbID(ctx context.Context, itemType, imdbID string) (*Result, error) // nil when the provider does not know the ID
+}
+
+// NewProvider returns the provider the configuration selects, or nil when
+// lookups are turned off or the provider has no API key
+func NewProvider(cfg *config.Config) (MetadataProvider, error) {
+	client := &http.Client{Timeout: requestTimeout}
+
+	switch cfg.Metadata.Provider {
+	case "":
+		return nil, nil
+	case ProviderTMDb:
+		if cfg.Metadata.TMDb.APIKey == "" {
+			return nil, nil
+		}
+
+		return NewTMDb(cfg.Metadata.TMDb.BaseURL, cfg.Metadata.TMDb.ImageBaseURL, cfg.Metadata.TMDb.APIKey, client), nil
+	case ProviderOMDb:
+		if cfg.Metadata.OMDb.APIKey == "" {
+			return nil, nil
+		}
+
+		return NewOMDb(cfg.Metadata.OMDb.BaseURL, cfg.Metadata.OMDb.APIKey, client), nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider: %s", cfg.Metadata.Provider)
+	}
+}
+
+// getJSON fetches a provider URL with any extra headers and decodes its JSON
+// body into v
+func getJSON(ctx context.Context, client *http.Client, address string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		// The URL may carry an API key, so only the cause is kept
+		var urlErr *url.Error
+
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("failed to reach metadata provider: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+		return fmt.Errorf("metadata provider returned %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode metadata response: %w", err)
+	}
+
+	return nil
+}
+
+// yearOf reads the year at the start of a date such as 2010-07-15 or a
+// range such as 2008–2013, returning 0 when there is none
+func yearOf(date string) int {
+	year := 0
+
+	for i, r := range date {
+		if i == 4 || r < '0' || r > '9' {
+			break
+		}
+
+		year = year*10 + int(r-'0')
+	}
+
+	if year < database.MinYear || year > database.MaxYear {
+		return 0
+	}
+
+	return year
+}
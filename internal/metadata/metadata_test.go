@@ -0,0 +1,248 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+const testAPIKey = "secret-key"
+
+// tmdbStub answers TMDb's search, details and find endpoints for Heat and
+// Lost, rejecting requests without the bearer token
+func tmdbStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/search/movie": `{"results":[{"id":949,"title":"Heat","release_date":"1995-12-15","poster_path":"/heat.jpg"},{"id":1,"title":"Heat","release_date":""}]}`,
+		"/movie/949": `{"id":949,"title":"Heat","release_date":"1995-12-15","poster_path":"/heat.jpg","runtime":170,"imdb_id":"tt0113277",
+			"genres":[{"name":"Crime"},{"name":"Drama"}],
+			"credits":{"cast":[{"name":"Al Pacino"},{"name":"Robert De Niro"},{"name":"Val Kilmer"},{"name":"Jon Voight"},{"name":"Tom Sizemore"},{"name":"Diane Venora"}],
+				"crew":[{"name":"Art Linson","job":"Producer"},{"name":"Michael Mann","job":"Director"}]}}`,
+		"/tv/4607": `{"id":4607,"name":"Lost","first_air_date":"2004-09-22","poster_path":"","runtime":0,
+			"created_by":[{"name":"J. J. Abrams"}],"external_ids":{"imdb_id":"tt0411008"}}`,
+		"/find/tt0113277": `{"movie_results":[{"id":949}],"tv_results":[]}`,
+		"/find/tt0411008": `{"movie_results":[],"tv_results":[{"id":4607}]}`,
+		"/find/tt0000001": `{"movie_results":[],"tv_results":[]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAPIKey || r.URL.Query().Has("api_key") {
+			http.Error(w, `{"status_message":"Invalid API key"}`, http.StatusUnauthorized)
+
+			return
+		}
+
+		body, ok := responses[r.URL.Path]
+
+		if !ok {
+			http.Error(w, `{"status_message":"The resource you requested could not be found."}`, http.StatusNotFound)
+
+			return
+		}
+
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestTMDb(t *testing.T) {
+	server := tmdbStub(t)
+	tmdb := NewTMDb(server.URL+"/", "https://images.test/w500", testAPIKey, server.Client())
+	ctx := context.Background()
+
+	matches, err := tmdb.Search(ctx, database.ItemTypeMovie, "heat")
+	wantMatches := []Match{
+		{ID: "949", ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, PosterURL: "https://images.test/w500/heat.jpg"},
+		{ID: "1", ItemType: database.ItemTypeMovie, Title: "Heat"},
+	}
+
+	if err != nil || !reflect.DeepEqual(matches, wantMatches) {
+		t.Errorf("Search = %+v, %v; want %+v", matches, err, wantMatches)
+	}
+
+	heat := &Result{
+		Title:     "Heat",
+		Year:      1995,
+		Genres:    []string{"Crime", "Drama"},
+		Runtime:   170,
+		PosterURL: "https://images.test/w500/heat.jpg",
+		Director:  "Michael Mann",
+		Cast:      []string{"Al Pacino", "Robert De Niro", "Val Kilmer", "Jon Voight", "Tom Sizemore"},
+		IMDbID:    "tt0113277",
+	}
+	lost := &Result{Title: "Lost", Year: 2004, Director: "J. J. Abrams", IMDbID: "tt0411008"}
+
+	tests := []struct {
+		name    string
+		lookup  func() (*Result, error)
+		want    *Result
+		wantErr error
+	}{
+		{"lookup a movie", func() (*Result, error) { return tmdb.Lookup(ctx, database.ItemTypeMovie, "949") }, heat, nil},
+		{"lookup a TV show", func() (*Result, error) { return tmdb.Lookup(ctx, database.ItemTypeTVShow, "4607") }, lost, nil},
+		{"lookup an unknown ID", func() (*Result, error) { return tmdb.Lookup(ctx, database.ItemTypeMovie, "2") }, nil, ErrNotFound},
+		{"lookup a malformed ID", func() (*Result, error) { return tmdb.Lookup(ctx, database.ItemTypeMovie, "../949") }, nil, ErrInvalidID},
+		{"find a movie", func() (*Result, error) { return tmdb.FindByIMDbID(ctx, database.ItemTypeMovie, "tt0113277") }, heat, nil},
+		{"find a TV show", func() (*Result, error) { return tmdb.FindByIMDbID(ctx, database.ItemTypeTVShow, "tt0411008") }, lost, nil},
+		{"find a movie's ID as a TV show", func() (*Result, error) { return tmdb.FindByIMDbID(ctx, database.ItemTypeTVShow, "tt0113277") }, nil, nil},
+		{"find an unknown ID", func() (*Result, error) { return tmdb.FindByIMDbID(ctx, database.ItemTypeMovie, "tt0000001") }, nil, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.lookup()
+
+		if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %+v, %v; want %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := NewTMDb(server.URL, "", "wrong-key", server.Client()).Search(ctx, database.ItemTypeMovie, "heat"); err == nil {
+		t.Error("Search with a wrong key succeeded")
+	}
+}
+
+// omdbStub answers OMDb's search and ID lookups for Heat and Lost, which has
+// "N/A" for the fields OMDb doesn't know
+func omdbStub(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	titles := map[string]string{
+		"tt0113277": `{"Title":"Heat","Year":"1995","Runtime":"170 min","Genre":"Crime, Drama","Director":"Michael Mann","Writer":"Michael Mann",
+			"Actors":"Al Pacino, Robert De Niro, Val Kilmer","Poster":"https://images.test/heat.jpg","imdbID":"tt0113277","Type":"movie","Response":"True"}`,
+		"tt0411008": `{"Title":"Lost","Year":"2004–2010","Runtime":"N/A","Genre":"N/A","Director":"N/A","Writer":"J.J. Abrams, Jeffrey Lieber",
+			"Actors":"N/A","Poster":"N/A","imdbID":"tt0411008","Type":"series","Response":"True"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		switch {
+		case query.Get("apikey") != testAPIKey:
+			http.Error(w, `{"Response":"False","Error":"Invalid API key!"}`, http.StatusUnauthorized)
+		case query.Get("s") == "heat" && query.Get("type") == "movie":
+			fmt.Fprint(w, `{"Search":[{"Title":"Heat","Year":"1995","imdbID":"tt0113277","Poster":"N/A"}],"Response":"True"}`)
+		case query.Has("s"):
+			fmt.Fprint(w, `{"Response":"False","Error":"Movie not found!"}`)
+		case query.Get("i") == "tt9999999":
+			fmt.Fprint(w, `{"Response":"False","Error":"Something went wrong."}`)
+		case query.Get("i") == "tt0000404":
+			http.NotFound(w, r)
+		case titles[query.Get("i")] != "":
+			fmt.Fprint(w, titles[query.Get("i")])
+		default:
+			fmt.Fprint(w, `{"Response":"False","Error":"Movie not found!"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestOMDb(t *testing.T) {
+	server := omdbStub(t)
+	omdb := NewOMDb(server.URL, testAPIKey, server.Client())
+	ctx := context.Background()
+
+	searches := []struct {
+		itemType, query string
+		want            []Match
+	}{
+		{database.ItemTypeMovie, "heat", []Match{{ID: "tt0113277", ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995}}},
+		{database.ItemTypeTVShow, "heat", nil},
+	}
+
+	for _, tt := range searches {
+		if got, err := omdb.Search(ctx, tt.itemType, tt.query); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%s, %q) = %+v, %v; want %+v", tt.itemType, tt.query, got, err, tt.want)
+		}
+	}
+
+	heat := &Result{
+		Title:     "Heat",
+		Year:      1995,
+		Genres:    []string{"Crime", "Drama"},
+		Runtime:   170,
+		PosterURL: "https://images.test/heat.jpg",
+		Director:  "Michael Mann",
+		Cast:      []string{"Al Pacino", "Robert De Niro", "Val Kilmer"},
+		IMDbID:    "tt0113277",
+	}
+	lost := &Result{Title: "Lost", Year: 2004, Director: "J.J. Abrams", IMDbID: "tt0411008"}
+
+	tests := []struct {
+		name    string
+		lookup  func() (*Result, error)
+		want    *Result
+		wantErr error
+	}{
+		{"lookup a movie", func() (*Result, error) { return omdb.Lookup(ctx, database.ItemTypeMovie, "tt0113277") }, heat, nil},
+		{"lookup a link", func() (*Result, error) {
+			return omdb.Lookup(ctx, database.ItemTypeMovie, "https://www.imdb.com/title/tt0113277/")
+		}, heat, nil},
+		{"lookup N/A fields", func() (*Result, error) { return omdb.Lookup(ctx, database.ItemTypeTVShow, "tt0411008") }, lost, nil},
+		{"lookup an unknown ID", func() (*Result, error) { return omdb.Lookup(ctx, database.ItemTypeMovie, "tt0000001") }, nil, ErrNotFound},
+		{"lookup answered 404", func() (*Result, error) { return omdb.Lookup(ctx, database.ItemTypeMovie, "tt0000404") }, nil, ErrNotFound},
+		{"lookup a malformed ID", func() (*Result, error) { return omdb.Lookup(ctx, database.ItemTypeMovie, "949") }, nil, ErrInvalidID},
+		{"find a movie", func() (*Result, error) { return omdb.FindByIMDbID(ctx, database.ItemTypeMovie, "tt0113277") }, heat, nil},
+		{"find an unknown ID", func() (*Result, error) { return omdb.FindByIMDbID(ctx, database.ItemTypeMovie, "tt0000001") }, nil, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.lookup()
+
+		if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %+v, %v; want %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := omdb.Lookup(ctx, database.ItemTypeMovie, "tt9999999"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup with a provider error: error = %v, want the provider's message", err)
+	}
+}
+
+func TestProviderErrorsHideKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	address := server.URL
+	server.Close()
+
+	ctx := context.Background()
+	providers := []MetadataProvider{
+		NewTMDb(address, "", testAPIKey, http.DefaultClient),
+		NewOMDb(address, testAPIKey, http.DefaultClient),
+	}
+
+	for _, provider := range providers {
+		_, err := provider.Search(ctx, database.ItemTypeMovie, "heat")
+
+		if err == nil || strings.Contains(err.Error(), testAPIKey) || strings.Contains(err.Error(), address) {
+			t.Errorf("%s: Search of a closed server: error = %v, want one without the URL or key", provider.Name(), err)
+		}
+	}
+}
+
+func TestYearOf(t *testing.T) {
+	tests := map[string]int{
+		"2010-07-15": 2010,
+		"2008–2013":  2008,
+		"1995":       1995,
+		"":           0,
+		"N/A":        0,
+		"0042":       0,
+	}
+
+	for date, want := range tests {
+		if got := yearOf(date); got != want {
+			t.Errorf("yearOf(%q) = %d, want %d", date, got, want)
+		}
+	}
+}
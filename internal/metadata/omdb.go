@@ -0,0 +1,197 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// OMDb looks titles up in the Open Movie Database API, keyed by IMDb ID
+type OMDb struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewOMDb creates an OMDb provider
+func NewOMDb(baseURL, apiKey string, client *http.Client) *OMDb {
+	return &OMDb{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  client,
+	}
+}
+
+// omdbTitle holds the fields OMDb returns for a title; unknown values are "N/A"
+type omdbTitle struct {
+	Title    string `json:"Title"`
+	Year     string `json:"Year"`
+	Runtime  string `json:"Runtime"`
+	Genre    string `json:"Genre"`
+	Director string `json:"Director"`
+	Writer   string `json:"Writer"`
+	Actors   string `json:"Actors"`
+	Poster   string `json:"Poster"`
+	IMDbID   string `json:"imdbID"`
+	Type     string `json:"Type"`
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+}
+
+// Name returns the provider's name
+func (o *OMDb) Name() string {
+	return "OMDb"
+}
+
+// get calls the API with the key and parameters. OMDb only takes the key in
+// the query string, which getJSON keeps out of its errors. OMDb answers 200
+// with Response "False" for unknown titles, which becomes ErrNotFound.
+func (o *OMDb) get(ctx context.Context, params url.Values, v interface{ failure() (string, bool) }) error {
+	params.Set("apikey", o.apiKey)
+
+	if err := getJSON(ctx, o.client, o.baseURL+"/?"+params.Encode(), nil, v); err != nil {
+		return err
+	}
+
+	message, failed := v.failure()
+
+	switch {
+	case !failed:
+		return nil
+	case strings.Contains(strings.ToLower(message), "not found"):
+		return ErrNotFound
+	default:
+		return fmt.Errorf("metadata provider error: %s", message)
+	}
+}
+
+// failure reports OMDb's error message when a lookup failed
+func (t *omdbTitle) failure() (string, bool) {
+	return t.Error, t.Response == "False"
+}
+
+// omdbSearch is OMDb's search response
+type omdbSearch struct {
+	Search   []omdbTitle `json:"Search"`
+	Response string      `json:"Response"`
+	Error    string      `json:"Error"`
+}
+
+// failure reports OMDb's error message when a search failed
+func (s *omdbSearch) failure() (string, bool) {
+	return s.Error, s.Response == "False"
+}
+
+// omdbType returns OMDb's type parameter for an item type
+func omdbType(itemType string) string {
+	if itemType == database.ItemTypeTVShow {
+		return "series"
+	}
+
+	return "movie"
+}
+
+// known returns "" for OMDb's "N/A"
+func known(value string) string {
+	if value == "N/A" {
+		return ""
+	}
+
+	return strings.TrimSpace(value)
+}
+
+// splitNames splits OMDb's comma separated genre and actor lists
+func splitNames(value string) []string {
+	var names []string
+
+	for _, name := range strings.Split(known(value), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Search returns the titles matching a query
+func (o *OMDb) Search(ctx context.Context, itemType, query string) ([]Match, error) {
+	var response omdbSearch
+
+	err := o.get(ctx, url.Values{"s": {query}, "type": {omdbType(itemType)}}, &response)
+
+	if err == ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(response.Search))
+
+	for _, title := range response.Search {
+		matches = append(matches, Match{
+			ID:        title.IMDbID,
+			ItemType:  itemType,
+			Title:     title.Title,
+			Year:      yearOf(title.Year),
+			PosterURL: known(title.Poster),
+		})
+	}
+
+	return matches, nil
+}
+
+// Lookup returns the details of a title by its IMDb ID, which is OMDb's ID
+func (o *OMDb) Lookup(ctx context.Context, itemType, id string) (*Result, error) {
+	imdbID := database.IMDbID(id)
+
+	if imdbID == "" {
+		return nil, fmt.Errorf("%w: %q is not an IMDb ID", ErrInvalidID, id)
+	}
+
+	var title omdbTitle
+
+	if err := o.get(ctx, url.Values{"i": {imdbID}, "type": {omdbType(itemType)}}, &title); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Title:     title.Title,
+		Year:      yearOf(title.Year),
+		Genres:    splitNames(title.Genre),
+		PosterURL: known(title.Poster),
+		Director:  known(title.Director),
+		IMDbID:    database.IMDbID(title.IMDbID),
+	}
+
+	if itemType == database.ItemTypeMovie {
+		result.Runtime, _ = strconv.Atoi(strings.TrimSuffix(known(title.Runtime), " min"))
+	}
+
+	// OMDb lists a series' creators as its writers
+	if writers := splitNames(title.Writer); result.Director == "" && len(writers) > 0 {
+		result.Director = writers[0]
+	}
+
+	cast := splitNames(title.Actors)
+	result.Cast = cast[:min(CastLimit, len(cast))]
+
+	return result, nil
+}
+
+// FindByIMDbID returns the details of the title with an IMDb ID
+func (o *OMDb) FindByIMDbID(ctx context.Context, itemType, imdbID string) (*Result, error) {
+	result, err := o.Lookup(ctx, itemType, imdbID)
+
+	if err == ErrNotFound {
+		return nil, nil
+	}
+
+	return result, err
+}
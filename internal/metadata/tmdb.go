@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// TMDb looks titles up in The Movie Database's v3 API
+type TMDb struct {
+	baseURL      string
+	imageBaseURL string
+	apiKey       string // the API read access token, sent as a bearer token
+	client       *http.Client
+}
+
+// NewTMDb creates a TMDb provider; imageBaseURL prefixes poster paths and
+// includes the image size, such as https://image.tmdb.org/t/p/w500
+func NewTMDb(baseURL, imageBaseURL, apiKey string, client *http.Client) *TMDb {
+	return &TMDb{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		imageBaseURL: strings.TrimSuffix(imageBaseURL, "/"),
+		apiKey:       apiKey,
+		client:       client,
+	}
+}
+
+// tmdbPerson is a cast or crew member, or a show's creator
+type tmdbPerson struct {
+	Name string `json:"name"`
+	Job  string `json:"job"`
+}
+
+// tmdbTitle holds the fields TMDb's movie and TV endpoints return; movies use
+// title and release_date, TV shows name and first_air_date
+type tmdbTitle struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Name         string `json:"name"`
+	ReleaseDate  string `json:"release_date"`
+	FirstAirDate string `json:"first_air_date"`
+	PosterPath   string `json:"poster_path"`
+	Runtime      int    `json:"runtime"`
+	IMDbID       string `json:"imdb_id"`
+	Genres       []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	CreatedBy []tmdbPerson `json:"created_by"`
+	Credits   struct {
+		Cast []tmdbPerson `json:"cast"`
+		Crew []tmdbPerson `json:"crew"`
+	} `json:"credits"`
+	ExternalIDs struct {
+		IMDbID string `json:"imdb_id"`
+	} `json:"external_ids"`
+}
+
+// Name returns the provider's name
+func (t *TMDb) Name() string {
+	return "TMDb"
+}
+
+// kind returns TMDb's path segment for an item type
+func (t *TMDb) kind(itemType string) string {
+	if itemType == database.ItemTypeTVShow {
+		return "tv"
+	}
+
+	return "movie"
+}
+
+// get calls an API path with parameters, authenticating in a header so the
+// key never appears in a URL
+func (t *TMDb) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	header := http.Header{"Authorization": {"Bearer " + t.apiKey}}
+
+	return getJSON(ctx, t.client, t.baseURL+path+"?"+params.Encode(), header, v)
+}
+
+// poster returns the full URL of a poster path, or "" when there is none
+func (t *TMDb) poster(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return t.imageBaseURL + path
+}
+
+// Search returns the titles matching a query
+func (t *TMDb) Search(ctx context.Context, itemType, query string) ([]Match, error) {
+	var response struct {
+		Results []tmdbTitle `json:"results"`
+	}
+
+	if err := t.get(ctx, "/search/"+t.kind(itemType), url.Values{"query": {query}}, &response); err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(response.Results))
+
+	for _, title := range response.Results {
+		matches = append(matches, Match{
+			ID:        strconv.Itoa(title.ID),
+			ItemType:  itemType,
+			Title:     title.Title + title.Name,
+			Year:      yearOf(title.ReleaseDate + title.FirstAirDate),
+			PosterURL: t.poster(title.PosterPath),
+		})
+	}
+
+	return matches, nil
+}
+
+// Lookup returns the details of a title by its TMDb ID
+func (t *TMDb) Lookup(ctx context.Context, itemType, id string) (*Result, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("%w: %q is not a TMDb ID", ErrInvalidID, id)
+	}
+
+	var title tmdbTitle
+
+	params := url.Values{"append_to_response": {"credits,external_ids"}}
+
+	if err := t.get(ctx, "/"+t.kind(itemType)+"/"+id, params, &title); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Title:     title.Title + title.Name,
+		Year:      yearOf(title.ReleaseDate + title.FirstAirDate),
+		PosterURL: t.poster(title.PosterPath),
+		IMDbID:    database.IMDbID(title.IMDbID + " " + title.ExternalIDs.IMDbID),
+	}
+
+	if itemType == database.ItemTypeMovie {
+		result.Runtime = title.Runtime
+	}
+
+	for _, genre := range title.Genres {
+		result.Genres = append(result.Genres, genre.Name)
+	}
+
+	for _, member := range title.Credits.Crew {
+		if member.Job == "Director" {
+			result.Director = member.Name
+
+			break
+		}
+	}
+
+	if result.Director == "" && len(title.CreatedBy) > 0 {
+		result.Director = title.CreatedBy[0].Name
+	}
+
+	for _, member := range title.Credits.Cast[:min(CastLimit, len(title.Credits.Cast))] {
+		result.Cast = append(result.Cast, member.Name)
+	}
+
+	return result, nil
+}
+
+// FindByIMDbID returns the details of the title with an IMDb ID
+func (t *TMDb) FindByIMDbID(ctx context.Context, itemType, imdbID string) (*Result, error) {
+	var response struct {
+		MovieResults []tmdbTitle `json:"movie_results"`
+		TVResults    []tmdbTitle `json:"tv_results"`
+	}
+
+	if err := t.get(ctx, "/find/"+imdbID, url.Values{"external_source": {"imdb_id"}}, &response); err != nil {
+		return nil, err
+	}
+
+	results := response.MovieResults
+
+	if itemType == database.ItemTypeTVShow {
+		results = response.TVResults
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return t.Lookup(ctx, itemType, strconv.Itoa(results[0].ID))
+}
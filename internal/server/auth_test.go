@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/config"
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+const testPassword = "correct horse"
+
+// testSite is the full handler, middleware included, over a memory store
+type testSite struct {
+	store   database.Store
+	handler http.Handler
+}
+
+// newTestSite serves the routes over a new memory store holding one account
+// per role given, named after it. Pages are read from web/templates, so the
+// test runs from the repository root.
+func newTestSite(t *testing.T, roles ...string) *testSite {
+	t.Helper()
+	t.Chdir("../..")
+
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	hash, err := auth.HashPassword(testPassword)
+
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	for _, role := range roles {
+		if _, err := store.AddUser(ctx, database.User{Username: role, PasswordHash: hash, Role: role}); err != nil {
+			t.Fatalf("AddUser(%s): %v", role, err)
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.Auth.SessionHours = 1
+
+	return &testSite{store: store, handler: New(cfg, store).Handler()}
+}
+
+// signIn starts a session for a user in the store and returns its token
+func (s *testSite) signIn(t *testing.T, username string, expires time.Time) string {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := s.store.GetUserByUsername(ctx, username)
+
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByUsername(%s) = %v, %v", username, user, err)
+	}
+
+	token, err := auth.NewToken()
+
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	if err := s.store.CreateSession(ctx, database.Session{TokenHash: auth.HashToken(token), UserID: user.ID, ExpiresAt: expires}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	return token
+}
+
+// serve sends a request as a browser would: with the session cookie when
+// session is set, a CSRF cookie, and the CSRF token of whichever applies
+func (s *testSite) serve(method, path, session string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: auth.CSRFCookie, Value: testCSRFSecret})
+	r.Header.Set(auth.CSRFHeader, auth.CSRFToken(testCSRFSecret))
+
+	if session != "" {
+		r.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: session})
+		r.Header.Set(auth.CSRFHeader, auth.CSRFToken(session))
+	}
+
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+
+	return w
+}
+
+// sessionCookie returns the session cookie a response sets, or nil
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == auth.SessionCookie {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestOpenWithoutAccounts(t *testing.T) {
+	site := newTestSite(t)
+
+	if w := site.serve("GET", "/trash", "", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /trash without accounts = %d, want 200", w.Code)
+	}
+
+	if w := site.serve("POST", "/trash/purge", "", url.Values{"type": {"book"}, "id": {"1"}}); w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+		t.Errorf("POST /trash/purge without accounts = %d, want it to reach the handler", w.Code)
+	}
+}
+
+func TestSignInAndOut(t *testing.T) {
+	site := newTestSite(t, database.RoleEditor)
+
+	// Anonymous requests are sent to sign in
+	anonymous := []struct {
+		path     string
+		want     int
+		location string
+	}{
+		{"/movie-board?sort=title", http.StatusSeeOther, "/login?next=%2Fmovie-board%3Fsort%3Dtitle"},
+		{"/api/v1/movies", http.StatusUnauthorized, ""},
+		{"/login", http.StatusOK, ""},
+	}
+
+	for _, tt := range anonymous {
+		w := site.serve("GET", tt.path, "", nil)
+
+		if w.Code != tt.want || w.Header().Get("Location") != tt.location {
+			t.Errorf("anonymous GET %s = %d to %q, want %d to %q", tt.path, w.Code, w.Header().Get("Location"), tt.want, tt.location)
+		}
+	}
+
+	for _, password := range []string{"wrong password", ""} {
+		w := site.serve("POST", "/login", "", url.Values{"username": {"editor"}, "password": {password}})
+
+		if w.Code != http.StatusUnauthorized || sessionCookie(w) != nil {
+			t.Errorf("sign-in with password %q = %d, cookie %v; want 401 and no session", password, w.Code, sessionCookie(w))
+		}
+	}
+
+	if w := site.serve("POST", "/login", "", url.Values{"username": {"nobody"}, "password": {testPassword}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("sign-in as an unknown user = %d, want 401", w.Code)
+	}
+
+	// A sign-in only returns to paths on the site
+	w := site.serve("POST", "/login", "", url.Values{"username": {" editor "}, "password": {testPassword}, "next": {"//evil.example/"}})
+	cookie := sessionCookie(w)
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" || cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("sign-in = %d to %q, cookie %+v; want a redirect home with an HttpOnly session", w.Code, w.Header().Get("Location"), cookie)
+	}
+
+	session := cookie.Value
+
+	if w := site.serve("GET", "/account", session, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "editor") {
+		t.Errorf("GET /account signed in = %d, %q; want the account menu", w.Code, w.Body.String())
+	}
+
+	if w := site.serve("GET", "/login?next=/trash", session, nil); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/trash" {
+		t.Errorf("GET /login signed in = %d to %q, want a redirect to next", w.Code, w.Header().Get("Location"))
+	}
+
+	w = site.serve("POST", "/logout", session, nil)
+
+	if cookie := sessionCookie(w); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" || cookie == nil || cookie.MaxAge >= 0 {
+		t.Errorf("sign-out = %d to %q, cookie %+v; want a redirect to /login clearing the cookie", w.Code, w.Header().Get("Location"), cookie)
+	}
+
+	if w := site.serve("GET", "/account", session, nil); w.Code != http.StatusSeeOther {
+		t.Errorf("GET /account after signing out = %d, want a redirect to sign in", w.Code)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	site := newTestSite(t, database.RoleViewer)
+	live := site.signIn(t, database.RoleViewer, time.Now().Add(time.Hour))
+	expired := site.signIn(t, database.RoleViewer, time.Now().Add(-time.Minute))
+
+	if w := site.serve("GET", "/account", live, nil); w.Code != http.StatusOK {
+		t.Errorf("GET /account with a live session = %d, want 200", w.Code)
+	}
+
+	if w := site.serve("GET", "/account", expired, nil); w.Code != http.StatusSeeOther {
+		t.Errorf("GET /account with an expired session = %d, want a redirect to sign in", w.Code)
+	}
+
+	if w := site.serve("GET", "/account", "made-up", nil); w.Code != http.StatusSeeOther {
+		t.Errorf("GET /account with an unknown session = %d, want a redirect to sign in", w.Code)
+	}
+
+	if removed, err := site.store.DeleteExpiredSessions(context.Background()); err != nil || removed != 1 {
+		t.Errorf("DeleteExpiredSessions = %d, %v; want the expired session", removed, err)
+	}
+
+	if w := site.serve("GET", "/account", live, nil); w.Code != http.StatusOK {
+		t.Errorf("GET /account after the cleanup = %d, want the live session kept", w.Code)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	site := newTestSite(t, database.RoleViewer, database.RoleEditor, database.RoleAdmin)
+	sessions := make(map[string]string)
+
+	for _, role := range []string{database.RoleViewer, database.RoleEditor, database.RoleAdmin} {
+		sessions[role] = site.signIn(t, role, time.Now().Add(time.Hour))
+	}
+
+	// Forms that fail validation show a request got past requireRole without
+	// changing anything
+	bad := url.Values{"id": {"x"}}
+
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{database.RoleViewer, "GET", "/trash", http.StatusOK},
+		{database.RoleViewer, "GET", "/household", http.StatusOK},
+		{database.RoleViewer, "POST", "/movie-board/add", http.StatusForbidden},
+		{database.RoleViewer, "POST", "/tv-shows-board/watch", http.StatusForbidden},
+		{database.RoleViewer, "POST", "/household/profiles/add", http.StatusForbidden},
+		{database.RoleViewer, "POST", "/api/v1/movies", http.StatusForbidden},
+		{database.RoleViewer, "POST", "/ai/query", http.StatusForbidden},
+		{database.RoleViewer, "POST", "/votes/set", http.StatusBadRequest},
+		{database.RoleEditor, "DELETE", "/movie-board/delete/x", http.StatusBadRequest},
+		{database.RoleViewer, "DELETE", "/movie-board/delete/x", http.StatusForbidden},
+		{database.RoleEditor, "POST", "/trash/restore", http.StatusBadRequest},
+		{database.RoleEditor, "POST", "/trash/purge", http.StatusForbidden},
+		{database.RoleEditor, "POST", "/import", http.StatusForbidden},
+		{database.RoleEditor, "POST", "/import/preview", http.StatusForbidden},
+		{database.RoleEditor, "POST", "/import/external", http.StatusForbidden},
+		{database.RoleEditor, "POST", "/duplicates/merge", http.StatusForbidden},
+		{database.RoleAdmin, "POST", "/trash/purge", http.StatusBadRequest},
+		{database.RoleAdmin, "POST", "/duplicates/merge", http.StatusBadRequest},
+		{database.RoleAdmin, "POST", "/import", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := site.serve(tt.method, tt.path, sessions[tt.role], bad)
+
+		if w.Code != tt.want {
+			t.Errorf("%s %s as %s = %d, want %d (%s)", tt.method, tt.path, tt.role, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+		}
+	}
+
+	if w := site.serve("POST", "/api/v1/movies", sessions[database.RoleViewer], nil); !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("API refusal = %q, want a JSON error", w.Body.String())
+	}
+}
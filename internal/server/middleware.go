@@ -0,0 +1,351 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/handlers"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// actorHeaders are set by authenticating reverse proxies to name the user; they
+// are only believed on requests from a trusted proxy
+var actorHeaders = []string{"Remote-User", "X-Forwarded-User"}
+
+// publicPaths can be reached without signing in; a trailing slash covers the
+// whole subtree
+var publicPaths = []string{"/login", "/logout", "/static/"}
+
+// parseTrustedProxies reads the configured proxy addresses and CIDR ranges,
+// logging and skipping entries that are neither
+func parseTrustedProxies(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+
+		if err != nil {
+			logger.Warn("Ignoring trusted proxy %q: not an IP address or CIDR range", entry)
+
+			continue
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes
+}
+
+// trustedProxy reports whether a client address is one of the trusted proxies
+func trustedProxy(host string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestActor names who made a request, preferring the signed-in user, then
+// the user named by a trusted proxy, then the client address
+func requestActor(r *http.Request, proxies []netip.Prefix) string {
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		return user.Username
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if trustedProxy(host, proxies) {
+		for _, header := range actorHeaders {
+			if user := r.Header.Get(header); user != "" {
+				return user
+			}
+		}
+	}
+
+	return host
+}
+
+// withActor attributes store changes made while handling a request to its actor
+func (s *Server) withActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := database.WithActor(r.Context(), requestActor(r, s.trustedProxies))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isPublicPath reports whether a path can be reached without signing in
+func isPublicPath(path string) bool {
+	for _, public := range publicPaths {
+		if path == public || strings.HasSuffix(public, "/") && strings.HasPrefix(path, public) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aiQueryPath is the route API tokens need the ai scope for
+const aiQueryPath = "/ai/query"
+
+// requestScope is the API token scope a request needs: ai for questions to the
+// assistant, read for GET and HEAD, and write for everything else
+func requestScope(r *http.Request) string {
+	switch {
+	case r.URL.Path == aiQueryPath:
+		return database.ScopeAI
+	case r.Method == "GET" || r.Method == "HEAD":
+		return database.ScopeRead
+	default:
+		return database.ScopeWrite
+	}
+}
+
+// withSession puts the user signed in with the request's API token or session
+// cookie in its context. Once any account exists, requests without either are
+// turned away from everything but the public paths; until then the site stays open.
+func (s *Server) withSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bearer := auth.BearerToken(r); bearer != "" {
+			s.serveWithAPIToken(w, r, bearer, next)
+
+			return
+		}
+
+		if token := auth.SessionToken(r); token != "" {
+			user, err := s.store.GetSessionUser(r.Context(), auth.HashToken(token))
+
+			if err != nil {
+				logger.ErrorWithErr("Failed to load session", err)
+				http.Error(w, "Failed to load session", http.StatusInternalServerError)
+
+				return
+			}
+
+			if user != nil {
+				next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+
+				return
+			}
+		}
+
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		count, err := s.store.CountUsers(r.Context())
+
+		if err != nil {
+			logger.ErrorWithErr("Failed to count users", err)
+			http.Error(w, "Failed to load session", http.StatusInternalServerError)
+
+			return
+		}
+
+		if count == 0 {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		denyAnonymous(w, r)
+	})
+}
+
+// serveWithAPIToken handles a request made with an API token, refusing unknown
+// tokens with 401 and tokens without the scope the request needs with 403
+func (s *Server) serveWithAPIToken(w http.ResponseWriter, r *http.Request, bearer string, next http.Handler) {
+	token, user, err := s.store.UseAPIToken(r.Context(), auth.HashToken(bearer))
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to load API token", err)
+		http.Error(w, "Failed to load API token", http.StatusInternalServerError)
+
+		return
+	}
+
+	if token == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		denyAPIToken(w, r, http.StatusUnauthorized, "invalid API token")
+
+		return
+	}
+
+	if scope := requestScope(r); !token.HasScope(scope) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		denyAPIToken(w, r, http.StatusForbidden, "API token lacks the "+scope+" scope")
+
+		return
+	}
+
+	ctx := auth.WithAPIToken(auth.WithUser(r.Context(), user), token)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// denyAPIToken refuses a request made with an API token, as JSON on the API
+func denyAPIToken(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, handlers.APIPrefix) {
+		handlers.WriteAPIError(w, status, message)
+
+		return
+	}
+
+	http.Error(w, strings.ToUpper(message[:1])+message[1:], status)
+}
+
+// safeMethods never change anything, so they skip the CSRF checks
+var safeMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// withCSRF puts the CSRF token pages embed in the request's context and refuses
+// changes whose token does not match or that come from another site. The token
+// is derived from the session, or from a random cookie for browsers without
+// one, so it must run after withSession. API token requests are exempt, since
+// browsers never send a bearer token on their own.
+func withCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.APITokenFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		secret := auth.CSRFSecret(r)
+		user := auth.UserFromContext(r.Context())
+
+		if user != nil {
+			secret = auth.SessionToken(r)
+		} else if secret == "" {
+			generated, err := auth.NewToken()
+
+			if err != nil {
+				logger.ErrorWithErr("Failed to generate CSRF secret", err)
+				http.Error(w, "Failed to generate CSRF secret", http.StatusInternalServerError)
+
+				return
+			}
+
+			// A browser that has never visited has no page token to send, so
+			// only its next request can pass
+			auth.SetCSRFCookie(w, r, generated)
+			secret = generated
+		}
+
+		if !safeMethods[r.Method] {
+			if reason := csrfFailure(r, secret, user); reason != "" {
+				logger.Warn("Refused %s %s from %s: %s", r.Method, r.URL.Path, r.RemoteAddr, reason)
+				handlers.CSRFFailureHandler(w, r)
+
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithCSRFToken(r.Context(), auth.CSRFToken(secret))))
+	})
+}
+
+// csrfFailure returns why a change must be refused, or "" when it may go ahead.
+// Sec-Fetch-Site and Origin catch cross-site requests from browsers that send
+// them; the token catches the rest. Scripts calling the JSON API without a
+// session have no token to send and are only held to the header checks.
+func csrfFailure(r *http.Request, secret string, user *database.User) string {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return "Sec-Fetch-Site is " + site
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		parsed, err := url.Parse(origin)
+
+		if err != nil || parsed.Host != r.Host {
+			return "Origin " + origin + " does not match host " + r.Host
+		}
+	}
+
+	if user == nil && strings.HasPrefix(r.URL.Path, handlers.APIPrefix) {
+		return ""
+	}
+
+	token := r.Header.Get(auth.CSRFHeader)
+
+	if token == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		token = r.PostFormValue(auth.CSRFField)
+	}
+
+	if !auth.ValidCSRFToken(secret, token) {
+		return "missing or invalid CSRF token"
+	}
+
+	return ""
+}
+
+// denyAnonymous answers a request that needs a signed-in user: API clients get
+// a JSON error, HTMX requests are sent to the sign-in page in full, and page
+// loads are redirected there with a way back
+func denyAnonymous(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, handlers.APIPrefix):
+		handlers.WriteAPIError(w, http.StatusUnauthorized, "sign-in required")
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("HX-Redirect", "/login")
+		http.Error(w, "Sign-in required", http.StatusUnauthorized)
+	case r.Method == "GET" || r.Method == "HEAD":
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	default:
+		http.Error(w, "Sign-in required", http.StatusUnauthorized)
+	}
+}
+
+// requireRole wraps a route so that only users with at least role can make
+// requests that change something; reads stay open to every signed-in user.
+// Without accounts set up there is no user to check and every request passes.
+func (s *Server) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := auth.UserFromContext(r.Context())
+
+		if r.Method == "GET" || r.Method == "HEAD" || user == nil || user.HasRole(role) {
+			next(w, r)
+
+			return
+		}
+
+		logger.Warn("User %s (%s) was refused %s %s", user.Username, user.Role, r.Method, r.URL.Path)
+
+		if strings.HasPrefix(r.URL.Path, handlers.APIPrefix) {
+			handlers.WriteAPIError(w, http.StatusForbidden, role+" role required")
+
+			return
+		}
+
+		http.Error(w, "Forbidden: "+role+" role required", http.StatusForbidden)
+	}
+}
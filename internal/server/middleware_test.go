@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/auth"
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// csrfRequest describes one request through withCSRF
+type csrfRequest struct {
+	name     string
+	method   string
+	path     string
+	signedIn bool // carries a session cookie and its user
+	cookie   bool // carries the CSRF cookie of a browser without a session
+	apiToken bool // authenticated by withSession with an API token
+	token    string
+	form     bool // sends the token as a form field rather than a header
+	header   map[string]string
+	want     int
+}
+
+const (
+	testSession    = "session-token"
+	testCSRFSecret = "csrf-secret"
+)
+
+func TestWithCSRF(t *testing.T) {
+	sessionToken := auth.CSRFToken(testSession)
+	cookieToken := auth.CSRFToken(testCSRFSecret)
+
+	tests := []csrfRequest{
+		{name: "read", method: "GET", path: "/movies", want: http.StatusOK},
+		{name: "signed in with header token", method: "POST", path: "/movies", signedIn: true, token: sessionToken, want: http.StatusOK},
+		{name: "signed in with form token", method: "POST", path: "/movies", signedIn: true, token: sessionToken, form: true, want: http.StatusOK},
+		{name: "signed in without token", method: "POST", path: "/movies", signedIn: true, want: http.StatusForbidden},
+		{name: "signed in with cookie token", method: "DELETE", path: "/movies/1", signedIn: true, cookie: true, token: cookieToken, want: http.StatusForbidden},
+		{name: "signed in API without token", method: "POST", path: "/api/v1/movies", signedIn: true, want: http.StatusForbidden},
+		{name: "no session with cookie token", method: "POST", path: "/movies", cookie: true, token: cookieToken, want: http.StatusOK},
+		{name: "no session without cookie", method: "POST", path: "/movies", token: cookieToken, want: http.StatusForbidden},
+		{name: "no session API script", method: "POST", path: "/api/v1/movies", want: http.StatusOK},
+		{name: "API token", method: "POST", path: "/api/v1/movies", apiToken: true, want: http.StatusOK},
+		{
+			name: "same origin", method: "POST", path: "/movies", signedIn: true, token: sessionToken,
+			header: map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, want: http.StatusOK,
+		},
+		{
+			name: "cross site", method: "POST", path: "/movies", signedIn: true, token: sessionToken,
+			header: map[string]string{"Sec-Fetch-Site": "cross-site"}, want: http.StatusForbidden,
+		},
+		{
+			name: "other origin", method: "POST", path: "/api/v1/movies",
+			header: map[string]string{"Origin": "http://evil.example"}, want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		w := serveCSRF(tt)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d (%s)", tt.name, tt.method, tt.path, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+// serveCSRF sends a request through withCSRF to a handler that answers 200
+func serveCSRF(tt csrfRequest) *httptest.ResponseRecorder {
+	var body *strings.Reader
+
+	if tt.form {
+		body = strings.NewReader(url.Values{auth.CSRFField: {tt.token}}.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+
+	r := httptest.NewRequest(tt.method, tt.path, body)
+
+	if tt.form {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else if tt.token != "" {
+		r.Header.Set(auth.CSRFHeader, tt.token)
+	}
+
+	for name, value := range tt.header {
+		r.Header.Set(name, value)
+	}
+
+	if tt.cookie {
+		r.AddCookie(&http.Cookie{Name: auth.CSRFCookie, Value: testCSRFSecret})
+	}
+
+	ctx := r.Context()
+	user := &database.User{ID: 1, Username: "sam", Role: database.RoleEditor}
+
+	if tt.signedIn {
+		r.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: testSession})
+		ctx = auth.WithUser(ctx, user)
+	}
+
+	if tt.apiToken {
+		ctx = auth.WithAPIToken(auth.WithUser(ctx, user), &database.APIToken{ID: 1, UserID: 1, Scopes: []string{database.ScopeWrite}})
+	}
+
+	w := httptest.NewRecorder()
+
+	withCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.APITokenFromContext(r.Context()) == nil && auth.CSRFTokenFromContext(r.Context()) == "" {
+			http.Error(w, "no CSRF token for the page", http.StatusInternalServerError)
+		}
+	})).ServeHTTP(w, r.WithContext(ctx))
+
+	return w
+}
+
+func TestWithCSRFSetsCookieOnce(t *testing.T) {
+	w := serveCSRF(csrfRequest{method: "GET", path: "/"})
+	cookies := w.Result().Cookies()
+
+	if len(cookies) != 1 || cookies[0].Name != auth.CSRFCookie || cookies[0].Value == "" {
+		t.Fatalf("first visit cookies = %v, want a CSRF cookie", cookies)
+	}
+
+	if w := serveCSRF(csrfRequest{method: "GET", path: "/", cookie: true}); len(w.Result().Cookies()) != 0 {
+		t.Errorf("return visit cookies = %v, want none", w.Result().Cookies())
+	}
+
+	if w := serveCSRF(csrfRequest{method: "GET", path: "/", signedIn: true}); len(w.Result().Cookies()) != 0 {
+		t.Errorf("signed-in cookies = %v, want none", w.Result().Cookies())
+	}
+}
+
+func TestRequestScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/movies", database.ScopeRead},
+		{"HEAD", "/api/v1/movies", database.ScopeRead},
+		{"POST", "/movies", database.ScopeWrite},
+		{"PUT", "/api/v1/movies/1", database.ScopeWrite},
+		{"DELETE", "/api/v1/movies/1", database.ScopeWrite},
+		{"POST", aiQueryPath, database.ScopeAI},
+		{"GET", aiQueryPath, database.ScopeAI},
+	}
+
+	for _, tt := range tests {
+		if got := requestScope(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("requestScope(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWithSessionAPITokens(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	owner, err := store.AddUser(ctx, database.User{Username: "sam", PasswordHash: "hash", Role: database.RoleEditor})
+
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+
+	tokens := map[string][]string{
+		"hn_reader": {database.ScopeRead},
+		"hn_writer": {database.ScopeRead, database.ScopeWrite},
+		"hn_ai":     {database.ScopeAI},
+	}
+
+	for secret, scopes := range tokens {
+		if _, err := store.CreateAPIToken(ctx, database.APIToken{UserID: owner, Name: secret, Scopes: scopes}, auth.HashToken(secret)); err != nil {
+			t.Fatalf("CreateAPIToken: %v", err)
+		}
+	}
+
+	server := &Server{store: store}
+	handler := server.withSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := auth.UserFromContext(r.Context()); user == nil || user.ID != owner || auth.APITokenFromContext(r.Context()) == nil {
+			http.Error(w, "request is not signed in as the token's user", http.StatusInternalServerError)
+		}
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		bearer string
+		want   int
+	}{
+		{"read with read", "GET", "/api/v1/movies", "hn_reader", http.StatusOK},
+		{"write with read", "POST", "/api/v1/movies", "hn_reader", http.StatusForbidden},
+		{"write with write", "DELETE", "/api/v1/movies/1", "hn_writer", http.StatusOK},
+		{"page with write", "POST", "/movies", "hn_writer", http.StatusOK},
+		{"ask with write", "POST", aiQueryPath, "hn_writer", http.StatusForbidden},
+		{"ask with ai", "POST", aiQueryPath, "hn_ai", http.StatusOK},
+		{"read with ai", "GET", "/api/v1/movies", "hn_ai", http.StatusForbidden},
+		{"unknown token", "GET", "/api/v1/movies", "hn_unknown", http.StatusUnauthorized},
+		{"no token", "GET", "/api/v1/movies", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+
+		if tt.bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d (%s)", tt.name, tt.method, tt.path, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+func TestRequestActor(t *testing.T) {
+	proxies := parseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.5 ", "::1", "not-an-address"})
+
+	if len(proxies) != 3 {
+		t.Fatalf("parseTrustedProxies kept %v, want 3 entries", proxies)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		signedIn   bool
+		want       string
+	}{
+		{"signed in beats header", "10.1.2.3:4000", "Remote-User", true, "sam"},
+		{"trusted range", "10.1.2.3:4000", "Remote-User", false, "alex"},
+		{"trusted address", "192.168.1.5:4000", "X-Forwarded-User", false, "alex"},
+		{"trusted IPv6", "[::1]:4000", "Remote-User", false, "alex"},
+		{"untrusted client", "192.168.1.6:4000", "Remote-User", false, "192.168.1.6"},
+		{"trusted without header", "10.1.2.3:4000", "", false, "10.1.2.3"},
+		{"no port", "192.168.1.6", "Remote-User", false, "192.168.1.6"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+
+		if tt.header != "" {
+			r.Header.Set(tt.header, "alex")
+		}
+
+		if tt.signedIn {
+			r = r.WithContext(auth.WithUser(r.Context(), &database.User{Username: "sam"}))
+		}
+
+		if got := requestActor(r, proxies); got != tt.want {
+			t.Errorf("%s: requestActor = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.2.3:4000"
+	r.Header.Set("Remote-User", "alex")
+
+	if got := requestActor(r, nil); got != "10.1.2.3" {
+		t.Errorf("requestActor with no trusted proxies = %q, want the client address", got)
+	}
+}
@@ -1,58 +1,231 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"time"
 
 	"github.com/pwnderpants/homenet/internal/config"
 	"github.com/pwnderpants/homenet/internal/database"
 	"github.com/pwnderpants/homenet/internal/handlers"
 	"github.com/pwnderpants/homenet/internal/logger"
+	"github.com/pwnderpants/homenet/internal/media"
+	"github.com/pwnderpants/homenet/internal/metadata"
+)
+
+// trashPurgeInterval is how often trashed items past their retention are purged
+const trashPurgeInterval = time.Hour
+
+// backupCheckInterval is how often the backup scheduler checks whether a snapshot is due
+const backupCheckInterval = time.Minute
+
+// sessionCleanupInterval is how often expired sessions are deleted
+const sessionCleanupInterval = time.Hour
+
+// posterSyncInterval is how often posters are cached and orphaned ones removed
+const posterSyncInterval = time.Hour
+
+// Enrichment looks up at most enrichBatchSize movies and TV shows per run and
+// retries an item that still lacks fields after enrichRecheckAfter
+const (
+	enrichBatchSize    = 50
+	enrichRecheckAfter = 30 * 24 * time.Hour
 )
 
 // Server represents the HTTP server
 type Server struct {
-	addr   string
-	config *config.Config
+	addr     string
+	config   *config.Config
+	store    database.Store
+	provider metadata.MetadataProvider // nil when online lookups are turned off
+	lookup   metadata.MetadataProvider // the provider, or the offline IMDb dataset without one
+	posters  *media.Cache
+
+	trustedProxies []netip.Prefix // clients whose Remote-User headers are believed
 }
 
-// New creates a new server instance
-func New(cfg *config.Config) *Server {
-	return &Server{
-		addr:   ":" + cfg.Server.Port,
-		config: cfg,
+// New creates a new server instance backed by the given store
+func New(cfg *config.Config, store database.Store) *Server {
+	provider, err := metadata.NewProvider(cfg)
+
+	if err != nil {
+		logger.ErrorWithErr("Metadata lookups are turned off", err)
+	}
+
+	server := &Server{
+		addr:     ":" + cfg.Server.Port,
+		config:   cfg,
+		store:    store,
+		provider: provider,
+		lookup:   provider,
+		posters:  media.New(cfg.Database.MediaDir),
+
+		trustedProxies: parseTrustedProxies(cfg.Server.TrustedProxies),
 	}
+
+	if provider == nil {
+		server.lookup = metadata.NewDataset(store)
+	}
+
+	return server
+}
+
+// Handler returns the server's routes behind its session, CSRF and actor middleware
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	s.SetupRoutes(mux)
+
+	return s.withSession(withCSRF(s.withActor(mux)))
 }
 
-// SetupRoutes configures all the routes for the server
-func (s *Server) SetupRoutes() {
+// SetupRoutes configures all the routes for the server on mux. Routes that
+// change the boards are wrapped in requireRole: editors may change items and
+// admins may also purge the trash, merge duplicates and import.
+func (s *Server) SetupRoutes(mux *http.ServeMux) {
 	// Serve static files
 	fs := http.FileServer(http.Dir(s.config.Static.Dir))
 
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	mux.Handle("/static/", http.StripPrefix("/static/", fs))
+
+	// Account routes
+	mux.HandleFunc("/login", s.createLoginHandler())
+	mux.HandleFunc("/logout", s.createLogoutHandler())
+	mux.HandleFunc("/account", handlers.AccountHandler)
+	mux.HandleFunc("/settings", s.createSettingsHandler())
+	mux.HandleFunc("/settings/tokens", s.createCreateAPITokenHandler())
+	mux.HandleFunc("/settings/tokens/revoke", s.createRevokeAPITokenHandler())
+
+	// Serve cached posters
+	mux.HandleFunc("/media/", s.createMediaHandler())
 
 	// Handle main routes
-	http.HandleFunc("/", s.createHomeHandler())
+	mux.HandleFunc("/", s.createHomeHandler())
 
 	// Movie board routes
-	http.HandleFunc("/movie-board", s.createMovieBoardHandler())
-	http.HandleFunc("/movie-board/add", handlers.AddMovieHandler)
-	http.HandleFunc("/movie-board/edit", handlers.EditMovieHandler)
-	http.HandleFunc("/movie-board/delete/", handlers.DeleteMovieHandler)
-	http.HandleFunc("/movie-board/random", handlers.RandomMovieHandler)
+	mux.HandleFunc("/movie-board", s.createMovieBoardHandler())
+	mux.HandleFunc("/movie-board/add", s.requireRole(database.RoleEditor, s.createAddMovieHandler()))
+	mux.HandleFunc("/movie-board/edit", s.requireRole(database.RoleEditor, s.createEditMovieHandler()))
+	mux.HandleFunc("/movie-board/delete/", s.requireRole(database.RoleEditor, s.createDeleteMovieHandler()))
+	mux.HandleFunc("/movie-board/restore", s.requireRole(database.RoleEditor, s.createRestoreMovieHandler()))
+	mux.HandleFunc("/movie-board/random", s.createRandomMovieHandler())
+	mux.HandleFunc("/movie-board/watch", s.requireRole(database.RoleEditor, s.createWatchMovieHandler()))
+	mux.HandleFunc("/movie-board/watched", s.createWatchedMoviesHandler())
+	mux.HandleFunc("/movie-board/unwatch", s.requireRole(database.RoleEditor, s.createUnwatchMovieHandler()))
+	mux.HandleFunc("/movie-board/wanted", s.createWantedHandler(database.ItemTypeMovie))
 
 	// TV Shows board routes
-	http.HandleFunc("/tv-shows-board", s.createTVShowBoardHandler())
-	http.HandleFunc("/tv-shows-board/add", handlers.AddTVShowHandler)
-	http.HandleFunc("/tv-shows-board/edit", handlers.EditTVShowHandler)
-	http.HandleFunc("/tv-shows-board/delete/", handlers.DeleteTVShowHandler)
+	mux.HandleFunc("/tv-shows-board", s.createTVShowBoardHandler())
+	mux.HandleFunc("/tv-shows-board/add", s.requireRole(database.RoleEditor, s.createAddTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/edit", s.requireRole(database.RoleEditor, s.createEditTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/delete/", s.requireRole(database.RoleEditor, s.createDeleteTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/random", s.createRandomTVShowHandler())
+	mux.HandleFunc("/tv-shows-board/restore", s.requireRole(database.RoleEditor, s.createRestoreTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/watch", s.requireRole(database.RoleEditor, s.createWatchTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/watched", s.createWatchedTVShowsHandler())
+	mux.HandleFunc("/tv-shows-board/unwatch", s.requireRole(database.RoleEditor, s.createUnwatchTVShowHandler()))
+	mux.HandleFunc("/tv-shows-board/wanted", s.createWantedHandler(database.ItemTypeTVShow))
+	mux.HandleFunc("/tv-shows-board/episodes", s.createEpisodesHandler())
+	mux.HandleFunc("/tv-shows-board/episodes/watch", s.requireRole(database.RoleEditor, s.createEpisodeWatchedHandler()))
+	mux.HandleFunc("/tv-shows-board/episodes/next", s.requireRole(database.RoleEditor, s.createWatchNextEpisodeHandler()))
+	mux.HandleFunc("/tv-shows-board/seasons/add", s.requireRole(database.RoleEditor, s.createAddSeasonHandler()))
+	mux.HandleFunc("/tv-shows-board/seasons/delete", s.requireRole(database.RoleEditor, s.createDeleteSeasonHandler()))
+
+	// Household routes; any account may vote, since profiles are not accounts
+	mux.HandleFunc("/household", s.createHouseholdHandler())
+	mux.HandleFunc("/household/profiles/add", s.requireRole(database.RoleEditor, s.createAddProfileHandler()))
+	mux.HandleFunc("/household/profiles/delete", s.requireRole(database.RoleEditor, s.createDeleteProfileHandler()))
+	mux.HandleFunc("/votes", s.createVotesHandler())
+	// Viewers may vote: a vote only records a household member's wish and
+	// changes no item, like using the picker
+	mux.HandleFunc("/votes/set", s.createSetVoteHandler())
+
+	// Trash routes
+	mux.HandleFunc("/trash", s.createTrashHandler())
+	mux.HandleFunc("/trash/restore", s.requireRole(database.RoleEditor, s.createRestoreTrashHandler()))
+	mux.HandleFunc("/trash/purge", s.requireRole(database.RoleAdmin, s.createPurgeTrashHandler()))
+
+	// History route
+	mux.HandleFunc("/history", s.createHistoryHandler())
+
+	// Import and export routes
+	mux.HandleFunc("/data", handlers.DataHandler)
+	mux.HandleFunc("/export", s.createExportHandler())
+	mux.HandleFunc("/import", s.requireRole(database.RoleAdmin, s.createImportHandler()))
+	mux.HandleFunc("/import/preview", s.requireRole(database.RoleAdmin, s.createImportPreviewHandler()))
+	mux.HandleFunc("/import/external", s.requireRole(database.RoleAdmin, s.createExternalImportHandler()))
+
+	// Duplicate routes
+	mux.HandleFunc("/duplicates", s.createDuplicatesHandler())
+	mux.HandleFunc("/duplicates/merge", s.requireRole(database.RoleAdmin, s.createMergeDuplicatesHandler()))
+
+	// Metadata lookup routes
+	mux.HandleFunc("/metadata/search", s.createMetadataSearchHandler())
+	mux.HandleFunc("/metadata/details", s.createMetadataDetailsHandler())
+
+	// Search route
+	mux.HandleFunc("/search", s.createSearchHandler())
+
+	// JSON API routes
+	mux.HandleFunc(handlers.APIPrefix, handlers.APINotFoundHandler)
+	mux.HandleFunc(handlers.APIPrefix+"openapi.json", s.createAPIDocHandler())
+	mux.HandleFunc(handlers.APIPrefix+"movies", s.requireRole(database.RoleEditor, s.createAPIMoviesHandler()))
+	mux.HandleFunc(handlers.APIPrefix+"movies/", s.requireRole(database.RoleEditor, s.createAPIMovieHandler()))
+	mux.HandleFunc(handlers.APIPrefix+"movies/random", s.createAPIMovieHandler())
+	mux.HandleFunc(handlers.APIPrefix+"tvshows", s.requireRole(database.RoleEditor, s.createAPITVShowsHandler()))
+	mux.HandleFunc(handlers.APIPrefix+"tvshows/", s.requireRole(database.RoleEditor, s.createAPITVShowHandler()))
+	mux.HandleFunc(handlers.APIPrefix+"tvshows/random", s.createAPITVShowHandler())
+	mux.HandleFunc(handlers.APIPrefix+"search", s.createAPISearchHandler())
 
 	// Fortune route
-	http.HandleFunc("/fortune", s.createFortuneHandler())
+	mux.HandleFunc("/fortune", s.createFortuneHandler())
 
 	// AI routes
-	http.HandleFunc("/ai", handlers.AiHandler)
-	http.HandleFunc("/ai/query", s.createAIQueryHandler())
+	mux.HandleFunc("/ai", handlers.AiHandler)
+	mux.HandleFunc("/ai/query", s.requireRole(database.RoleEditor, s.createAIQueryHandler()))
+}
+
+// createLoginHandler creates a handler that uses the server's store and configuration
+func (s *Server) createLoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.LoginHandler(w, r, s.store, s.store, time.Duration(s.config.Auth.SessionHours)*time.Hour)
+	}
+}
+
+// createLogoutHandler creates a handler that uses the server's store
+func (s *Server) createLogoutHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.LogoutHandler(w, r, s.store)
+	}
+}
+
+// createSettingsHandler creates a handler that uses the server's store
+func (s *Server) createSettingsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.SettingsHandler(w, r, s.store)
+	}
+}
+
+// createCreateAPITokenHandler creates a handler that uses the server's store
+func (s *Server) createCreateAPITokenHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.CreateAPITokenHandler(w, r, s.store)
+	}
+}
+
+// createRevokeAPITokenHandler creates a handler that uses the server's store
+func (s *Server) createRevokeAPITokenHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RevokeAPITokenHandler(w, r, s.store)
+	}
+}
+
+// createMediaHandler creates a handler that uses the server's poster cache
+func (s *Server) createMediaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.MediaHandler(w, r, s.posters, s.store)
+	}
 }
 
 // createHomeHandler creates a handler that uses the server's configuration
@@ -65,14 +238,339 @@ func (s *Server) createHomeHandler() http.HandlerFunc {
 // createMovieBoardHandler creates a handler that uses the server's configuration
 func (s *Server) createMovieBoardHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		handlers.MovieBoardHandlerWithConfig(w, r, s.config)
+		handlers.MovieBoardHandlerWithConfig(w, r, s.config, s.store, s.store)
+	}
+}
+
+// createAddMovieHandler creates a handler that uses the server's store
+func (s *Server) createAddMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.AddMovieHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createEditMovieHandler creates a handler that uses the server's store
+func (s *Server) createEditMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.EditMovieHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createDeleteMovieHandler creates a handler that uses the server's store
+func (s *Server) createDeleteMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.DeleteMovieHandler(w, r, s.store)
+	}
+}
+
+// createRandomMovieHandler creates a handler that uses the server's store
+func (s *Server) createRandomMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RandomMovieHandler(w, r, s.config, s.store, s.store, s.store)
+	}
+}
+
+// createWatchMovieHandler creates a handler that uses the server's store
+func (s *Server) createWatchMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WatchMovieHandler(w, r, s.store)
+	}
+}
+
+// createWatchedMoviesHandler creates a handler that uses the server's store
+func (s *Server) createWatchedMoviesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WatchedMoviesHandler(w, r, s.store)
+	}
+}
+
+// createUnwatchMovieHandler creates a handler that uses the server's store
+func (s *Server) createUnwatchMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.UnwatchMovieHandler(w, r, s.store)
 	}
 }
 
 // createTVShowBoardHandler creates a handler that uses the server's configuration
 func (s *Server) createTVShowBoardHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		handlers.TVShowBoardHandlerWithConfig(w, r, s.config)
+		handlers.TVShowBoardHandlerWithConfig(w, r, s.config, s.store, s.store)
+	}
+}
+
+// createAddTVShowHandler creates a handler that uses the server's store
+func (s *Server) createAddTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.AddTVShowHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createEditTVShowHandler creates a handler that uses the server's store
+func (s *Server) createEditTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.EditTVShowHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createDeleteTVShowHandler creates a handler that uses the server's store
+func (s *Server) createDeleteTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.DeleteTVShowHandler(w, r, s.store)
+	}
+}
+
+// createRandomTVShowHandler creates a handler that uses the server's store
+func (s *Server) createRandomTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RandomTVShowHandler(w, r, s.config, s.store, s.store, s.store)
+	}
+}
+
+// createWatchTVShowHandler creates a handler that uses the server's store
+func (s *Server) createWatchTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WatchTVShowHandler(w, r, s.store)
+	}
+}
+
+// createWatchedTVShowsHandler creates a handler that uses the server's store
+func (s *Server) createWatchedTVShowsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WatchedTVShowsHandler(w, r, s.store)
+	}
+}
+
+// createUnwatchTVShowHandler creates a handler that uses the server's store
+func (s *Server) createUnwatchTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.UnwatchTVShowHandler(w, r, s.store)
+	}
+}
+
+// createEpisodesHandler creates a handler that uses the server's store
+func (s *Server) createEpisodesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.EpisodesHandler(w, r, s.store, s.store)
+	}
+}
+
+// createEpisodeWatchedHandler creates a handler that uses the server's store
+func (s *Server) createEpisodeWatchedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.EpisodeWatchedHandler(w, r, s.store, s.store)
+	}
+}
+
+// createWatchNextEpisodeHandler creates a handler that uses the server's store
+func (s *Server) createWatchNextEpisodeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WatchNextEpisodeHandler(w, r, s.store, s.store)
+	}
+}
+
+// createAddSeasonHandler creates a handler that uses the server's store
+func (s *Server) createAddSeasonHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.AddSeasonHandler(w, r, s.store, s.store)
+	}
+}
+
+// createDeleteSeasonHandler creates a handler that uses the server's store
+func (s *Server) createDeleteSeasonHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.DeleteSeasonHandler(w, r, s.store, s.store)
+	}
+}
+
+// createRestoreMovieHandler creates a handler that uses the server's store
+func (s *Server) createRestoreMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RestoreMovieHandler(w, r, s.store, s.store)
+	}
+}
+
+// createRestoreTVShowHandler creates a handler that uses the server's store
+func (s *Server) createRestoreTVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RestoreTVShowHandler(w, r, s.store, s.store)
+	}
+}
+
+// createTrashHandler creates a handler that uses the server's configuration
+func (s *Server) createTrashHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.TrashHandlerWithConfig(w, r, s.config, s.store)
+	}
+}
+
+// createRestoreTrashHandler creates a handler that uses the server's store
+func (s *Server) createRestoreTrashHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.RestoreTrashHandler(w, r, s.store)
+	}
+}
+
+// createPurgeTrashHandler creates a handler that uses the server's store and
+// expires the posters of purged items
+func (s *Server) createPurgeTrashHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.PurgeTrashHandler(w, r, s.store)
+
+		go s.removeOrphanedPosters()
+	}
+}
+
+// createHistoryHandler creates a handler that uses the server's store
+func (s *Server) createHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.HistoryHandler(w, r, s.store)
+	}
+}
+
+// createExportHandler creates a handler that uses the server's store
+func (s *Server) createExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.ExportHandler(w, r, s.store, s.store)
+	}
+}
+
+// createImportHandler creates a handler that uses the server's store
+func (s *Server) createImportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.ImportHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createImportPreviewHandler creates a handler that uses the server's store
+func (s *Server) createImportPreviewHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.ImportPreviewHandler(w, r, s.store, s.store)
+	}
+}
+
+// createExternalImportHandler creates a handler that uses the server's store
+func (s *Server) createExternalImportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.ExternalImportHandler(w, r, s.store, s.store)
+	}
+}
+
+// createWantedHandler creates a handler that uses the server's store for one board
+func (s *Server) createWantedHandler(itemType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.WantedHandler(w, r, s.store, itemType)
+	}
+}
+
+// createHouseholdHandler creates a handler that uses the server's store
+func (s *Server) createHouseholdHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.HouseholdHandler(w, r, s.store)
+	}
+}
+
+// createAddProfileHandler creates a handler that uses the server's store
+func (s *Server) createAddProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.AddProfileHandler(w, r, s.store)
+	}
+}
+
+// createDeleteProfileHandler creates a handler that uses the server's store
+func (s *Server) createDeleteProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.DeleteProfileHandler(w, r, s.store)
+	}
+}
+
+// createVotesHandler creates a handler that uses the server's store
+func (s *Server) createVotesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.VotesHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createSetVoteHandler creates a handler that uses the server's store
+func (s *Server) createSetVoteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.SetVoteHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createDuplicatesHandler creates a handler that uses the server's store
+func (s *Server) createDuplicatesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.DuplicatesHandler(w, r, s.store)
+	}
+}
+
+// createMergeDuplicatesHandler creates a handler that uses the server's store
+func (s *Server) createMergeDuplicatesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.MergeDuplicatesHandler(w, r, s.store)
+	}
+}
+
+// createMetadataSearchHandler creates a handler that uses the server's metadata provider
+func (s *Server) createMetadataSearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.MetadataSearchHandler(w, r, s.lookup)
+	}
+}
+
+// createMetadataDetailsHandler creates a handler that uses the server's metadata provider
+func (s *Server) createMetadataDetailsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.MetadataDetailsHandler(w, r, s.lookup)
+	}
+}
+
+// createSearchHandler creates a handler that uses the server's store
+func (s *Server) createSearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.SearchHandler(w, r, s.store, s.store)
+	}
+}
+
+// createAPIDocHandler creates a handler that uses the server's configuration
+func (s *Server) createAPIDocHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APIDocHandler(w, r, s.config.Static.Dir)
+	}
+}
+
+// createAPIMoviesHandler creates a handler that uses the server's store
+func (s *Server) createAPIMoviesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APIMoviesHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createAPIMovieHandler creates a handler that uses the server's store
+func (s *Server) createAPIMovieHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APIMovieHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createAPITVShowsHandler creates a handler that uses the server's store
+func (s *Server) createAPITVShowsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APITVShowsHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createAPITVShowHandler creates a handler that uses the server's store
+func (s *Server) createAPITVShowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APITVShowHandler(w, r, s.store, s.store, s.store)
+	}
+}
+
+// createAPISearchHandler creates a handler that uses the server's store
+func (s *Server) createAPISearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlers.APISearchHandler(w, r, s.store, s.store)
 	}
 }
 
@@ -91,6 +589,233 @@ func (s *Server) createFortuneHandler() http.HandlerFunc {
 	}
 }
 
+// purgeExpiredTrash permanently removes items that have been in the trash
+// longer than the configured retention period
+func (s *Server) purgeExpiredTrash() {
+	retention := s.config.Trash.RetentionDays
+
+	if retention < 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -retention)
+	purged, err := s.store.PurgeTrashBefore(context.Background(), cutoff)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to purge expired trash", err)
+
+		return
+	}
+
+	if purged > 0 {
+		logger.Info("Purged %d items trashed more than %d days ago", purged, retention)
+		s.removeOrphanedPosters()
+	}
+}
+
+// runTrashPurger purges expired trash at startup and then every trashPurgeInterval
+func (s *Server) runTrashPurger() {
+	s.purgeExpiredTrash()
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.purgeExpiredTrash()
+	}
+}
+
+// backupIfDue takes a snapshot when the newest one is older than interval
+// and prunes snapshots past the retention count
+func (s *Server) backupIfDue(store database.BackupStore, interval time.Duration) {
+	dir := s.config.Database.BackupDir
+	snapshots, err := database.ListSnapshots(dir, s.config.Database.DBName)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to list backups", err)
+
+		return
+	}
+
+	if len(snapshots) > 0 && time.Since(snapshots[0].CreatedAt) < interval {
+		return
+	}
+
+	path, err := store.Backup(context.Background(), dir)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to back up database", err)
+
+		return
+	}
+
+	logger.Info("Database backed up to %s", path)
+
+	removed, err := database.PruneSnapshots(dir, s.config.Database.DBName, s.config.Database.BackupRetention)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to prune old backups", err)
+
+		return
+	}
+
+	if removed > 0 {
+		logger.Info("Removed %d old backups", removed)
+	}
+}
+
+// runBackups checks at startup and then every backupCheckInterval whether a
+// snapshot is due, so restarts do not reset the backup schedule
+func (s *Server) runBackups() {
+	store, ok := s.store.(database.BackupStore)
+
+	if !ok || s.config.Database.BackupIntervalHours < 0 {
+		return
+	}
+
+	interval := time.Duration(s.config.Database.BackupIntervalHours) * time.Hour
+
+	s.backupIfDue(store, interval)
+
+	ticker := time.NewTicker(backupCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.backupIfDue(store, interval)
+	}
+}
+
+// syncPosters caches every poster on the boards and removes orphaned ones
+func (s *Server) syncPosters() {
+	urls, err := s.store.GetPosterURLs(context.Background())
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to list poster URLs", err)
+
+		return
+	}
+
+	result, err := s.posters.Sync(context.Background(), urls)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to sync poster cache", err)
+	}
+
+	if result.Fetched > 0 || result.Removed > 0 {
+		logger.Info("Poster cache: %d cached, %d failed, %d orphaned files removed", result.Fetched, result.Failed, result.Removed)
+	}
+}
+
+// removeOrphanedPosters deletes cached posters no item uses any more, such as
+// those of purged items. Trashed items keep theirs until purged.
+func (s *Server) removeOrphanedPosters() {
+	urls, err := s.store.GetPosterURLs(context.Background())
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to list poster URLs", err)
+
+		return
+	}
+
+	removed, err := s.posters.RemoveOrphans(urls)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to remove orphaned posters", err)
+
+		return
+	}
+
+	if removed > 0 {
+		logger.Info("Removed %d orphaned poster files", removed)
+	}
+}
+
+// runPosterCache syncs the poster cache at startup and then every posterSyncInterval
+func (s *Server) runPosterCache() {
+	s.syncPosters()
+
+	ticker := time.NewTicker(posterSyncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.syncPosters()
+	}
+}
+
+// enrichMetadata fills in missing fields from the metadata provider on a batch
+// of items not looked up recently
+func (s *Server) enrichMetadata() {
+	enricher := metadata.Enricher{Provider: s.provider, Store: s.store}
+	result, err := enricher.Run(context.Background(), time.Now().Add(-enrichRecheckAfter), enrichBatchSize)
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to enrich metadata", err)
+	}
+
+	if result.Updated > 0 {
+		logger.Info("Filled in metadata for %d of %d items from %s", result.Updated, result.Checked, s.provider.Name())
+	}
+}
+
+// runMetadataEnricher enriches items at startup and then every configured
+// interval while a metadata provider is set up
+func (s *Server) runMetadataEnricher() {
+	if s.provider == nil || s.config.Metadata.EnrichIntervalHours < 0 {
+		return
+	}
+
+	s.enrichMetadata()
+
+	ticker := time.NewTicker(time.Duration(s.config.Metadata.EnrichIntervalHours) * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.enrichMetadata()
+	}
+}
+
+// deleteExpiredSessions removes sessions past their expiry
+func (s *Server) deleteExpiredSessions() {
+	removed, err := s.store.DeleteExpiredSessions(context.Background())
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to delete expired sessions", err)
+
+		return
+	}
+
+	if removed > 0 {
+		logger.Debug("Deleted %d expired sessions", removed)
+	}
+}
+
+// runSessionCleanup deletes expired sessions at startup and then every sessionCleanupInterval
+func (s *Server) runSessionCleanup() {
+	s.deleteExpiredSessions()
+
+	ticker := time.NewTicker(sessionCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.deleteExpiredSessions()
+	}
+}
+
+// warnIfOpen logs when no accounts exist, since anyone who can reach the server can then change everything
+func (s *Server) warnIfOpen() {
+	count, err := s.store.CountUsers(context.Background())
+
+	if err != nil {
+		logger.ErrorWithErr("Failed to count users", err)
+
+		return
+	}
+
+	if count == 0 {
+		logger.Warn("No user accounts exist, so sign-in is off; create an admin with 'homenet user add <username> admin'")
+	}
+}
+
 // StartServer initializes and starts the HTTP server
 func StartServer(port string) error {
 	// Load configuration
@@ -100,18 +825,32 @@ func StartServer(port string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if err := database.CheckBackupDir(cfg.Database.DataDir, cfg.Database.BackupDir); err != nil {
+		return err
+	}
+
 	// Initialize database with configuration
-	if err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName); err != nil {
+	store, err := database.InitDB(cfg.Database.DataDir, cfg.Database.DBName)
+
+	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// Create server instance and set up routes
-	server := New(cfg)
+	defer store.Close()
+
+	// Create server instance
+	server := New(cfg, store)
+
+	go server.runTrashPurger()
+	go server.runBackups()
+	go server.runMetadataEnricher()
+	go server.runPosterCache()
+	go server.runSessionCleanup()
 
-	server.SetupRoutes()
+	server.warnIfOpen()
 
 	// Start server
 	logger.Info("Server starting on http://%s:%s", cfg.Server.Host, port)
 
-	return http.ListenAndServe(server.addr, nil)
+	return http.ListenAndServe(server.addr, server.Handler())
 }
@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// datasetColumns are the columns of IMDb's title.basics.tsv, in order
+var datasetColumns = []string{"tconst", "titleType", "primaryTitle", "originalTitle", "isAdult", "startYear", "endYear", "runtimeMinutes", "genres"}
+
+// datasetNull is how the dataset writes a missing value
+const datasetNull = `\N`
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadIMDbDataset streams the movies and series out of IMDb's
+// title.basics.tsv, gzipped as published or already decompressed. Episodes,
+// games and adult titles are skipped. A malformed line ends the sequence with
+// an error naming it.
+func ReadIMDbDataset(r io.Reader) iter.Seq2[database.IMDbTitle, error] {
+	return func(yield func(database.IMDbTitle, error) bool) {
+		lines, err := datasetLines(r)
+
+		if err != nil {
+			yield(database.IMDbTitle{}, err)
+
+			return
+		}
+
+		header, err := lines.ReadString('\n')
+
+		if err != nil && header == "" {
+			yield(database.IMDbTitle{}, fmt.Errorf("failed to read dataset header: %w", err))
+
+			return
+		}
+
+		if strings.Join(datasetColumns, "\t") != strings.TrimRight(header, "\r\n") {
+			yield(database.IMDbTitle{}, errors.New("not a title.basics dataset: unexpected header"))
+
+			return
+		}
+
+		for number := 2; ; number++ {
+			line, err := lines.ReadString('\n')
+
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				title, ok, parseErr := parseDatasetLine(line)
+
+				if parseErr != nil {
+					yield(database.IMDbTitle{}, fmt.Errorf("line %d: %w", number, parseErr))
+
+					return
+				}
+
+				if ok && !yield(title, nil) {
+					return
+				}
+			}
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(database.IMDbTitle{}, fmt.Errorf("failed to read dataset: %w", err))
+
+				return
+			}
+		}
+	}
+}
+
+// datasetLines returns a line reader over the dataset, decompressing it when
+// it starts with the gzip magic number
+func datasetLines(r io.Reader) (*bufio.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(gzipMagic))
+
+	if !bytes.Equal(magic, gzipMagic) {
+		return buffered, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip dataset: %w", err)
+	}
+
+	return bufio.NewReader(gz), nil
+}
+
+// parseDatasetLine reads one title; ok is false for titles that are skipped
+func parseDatasetLine(line string) (title database.IMDbTitle, ok bool, err error) {
+	fields := strings.Split(line, "\t")
+
+	if len(fields) != len(datasetColumns) {
+		return title, false, fmt.Errorf("expected %d columns, found %d", len(datasetColumns), len(fields))
+	}
+
+	itemType, known := imdbTitleTypes[strings.ToLower(fields[1])]
+
+	if !known || fields[4] == "1" {
+		return title, false, nil
+	}
+
+	if database.IMDbID(fields[0]) != fields[0] {
+		return title, false, fmt.Errorf("invalid IMDb ID %q", fields[0])
+	}
+
+	title = database.IMDbTitle{
+		ID:       fields[0],
+		ItemType: itemType,
+		Title:    fields[2],
+	}
+
+	for _, number := range []struct {
+		value string
+		dest  *int
+	}{
+		{fields[5], &title.Year},
+		{fields[6], &title.EndYear},
+		{fields[7], &title.Runtime},
+	} {
+		if number.value == datasetNull {
+			continue
+		}
+
+		if *number.dest, err = strconv.Atoi(number.value); err != nil {
+			return title, false, fmt.Errorf("invalid number %q for %s", number.value, fields[0])
+		}
+	}
+
+	if fields[8] != datasetNull {
+		title.Genres = splitList(fields[8])
+	}
+
+	return title, true, nil
+}
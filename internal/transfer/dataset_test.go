@@ -0,0 +1,95 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// datasetHeader is title.basics.tsv's header line
+const datasetHeader = "tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n"
+
+// readDataset collects every title ReadIMDbDataset yields before it stops
+func readDataset(data []byte) ([]database.IMDbTitle, error) {
+	var titles []database.IMDbTitle
+
+	for title, err := range ReadIMDbDataset(bytes.NewReader(data)) {
+		if err != nil {
+			return titles, err
+		}
+
+		titles = append(titles, title)
+	}
+
+	return titles, nil
+}
+
+// gzipped compresses data as IMDb publishes its datasets
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestReadIMDbDataset(t *testing.T) {
+	dataset := datasetHeader +
+		"tt0113277\tmovie\tHeat\tHeat\t0\t1995\t\\N\t170\tCrime,Drama\n" +
+		"tt0411008\ttvSeries\tLost\tLost\t0\t2004\t2010\t44\tAdventure,Drama\r\n" +
+		"tt0636289\ttvEpisode\tPilot: Part 1\tPilot: Part 1\t0\t2004\t\\N\t42\tDrama\n" +
+		"tt0000009\tmovie\tAn Adult Title\tAn Adult Title\t1\t2001\t\\N\t80\tAdult\n" +
+		"tt0000010\tvideoGame\tA Game\tA Game\t0\t2010\t\\N\t\\N\tAction\n" +
+		"\n" +
+		"tt0000011\ttvMiniSeries\tUnknown\tUnknown\t0\t\\N\t\\N\t\\N\t\\N"
+
+	want := []database.IMDbTitle{
+		{ID: "tt0113277", ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime", "Drama"}},
+		{ID: "tt0411008", ItemType: database.ItemTypeTVShow, Title: "Lost", Year: 2004, EndYear: 2010, Runtime: 44, Genres: []string{"Adventure", "Drama"}},
+		{ID: "tt0000011", ItemType: database.ItemTypeTVShow, Title: "Unknown"},
+	}
+
+	for name, data := range map[string][]byte{"plain": []byte(dataset), "gzip": gzipped(t, dataset)} {
+		if got, err := readDataset(data); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ReadIMDbDataset = %+v, %v; want %+v", name, got, err, want)
+		}
+	}
+
+	heatLine := "tt0113277\tmovie\tHeat\tHeat\t0\t1995\t\\N\t170\tCrime\n"
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantCount int    // titles yielded before the error
+		wantErr   string // a substring of the error
+	}{
+		{"empty file", nil, 0, "header"},
+		{"another header", []byte("Const,Title,Title Type\n" + heatLine), 0, "unexpected header"},
+		{"a bad gzip stream", []byte{0x1f, 0x8b, 0}, 0, "gzip"},
+		{"too few columns", []byte(datasetHeader + heatLine + "tt0000001\tmovie\n"), 1, "line 3: expected 9 columns, found 2"},
+		{"a bad year", []byte(datasetHeader + heatLine + heatLine + "tt0000001\tmovie\tX\tX\t0\t19x5\t\\N\t\\N\t\\N\n"), 2, `line 4: invalid number "19x5"`},
+		{"a bad ID", []byte(datasetHeader + "0113277\tmovie\tHeat\tHeat\t0\t1995\t\\N\t170\tCrime\n"), 0, `line 2: invalid IMDb ID "0113277"`},
+		{"a bad line in gzip", gzipped(t, datasetHeader+heatLine+"\n"+"tt0000001\n"), 1, "line 4: expected 9 columns"},
+	}
+
+	for _, tt := range tests {
+		got, err := readDataset(tt.data)
+
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) || len(got) != tt.wantCount {
+			t.Errorf("%s: ReadIMDbDataset read %d titles, error %v; want %d and an error containing %q", tt.name, len(got), err, tt.wantCount, tt.wantErr)
+		}
+	}
+}
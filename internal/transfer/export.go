@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// movieCSVHeader is the column order of movie CSV exports
+var movieCSVHeader = []string{"id", "title", "year", "runtime", "tags", "availability", "notes", "imdb_link", "poster_url", "director", "cast", "watched"}
+
+// tvShowCSVHeader is the column order of TV show CSV exports
+var tvShowCSVHeader = []string{"id", "title", "year", "tags", "availability", "notes", "imdb_link", "poster_url", "director", "cast", "active_season", "watched", "next_episode", "watched_episodes", "total_episodes"}
+
+// CollectMovies returns every movie on the watchlist and in the watched archive, by ID
+func CollectMovies(ctx context.Context, store database.MovieStore) ([]database.Movie, error) {
+	movies, err := store.GetAllMovies(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := store.GetWatchedMovies(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, movie := range watched {
+		movies = append(movies, movie.Movie)
+	}
+
+	sort.Slice(movies, func(i, j int) bool {
+		return movies[i].ID < movies[j].ID
+	})
+
+	return movies, nil
+}
+
+// CollectTVShows returns every TV show on the watchlist and in the watched archive, by ID
+func CollectTVShows(ctx context.Context, store database.TVShowStore) ([]database.TVShow, error) {
+	tvShows, err := store.GetAllTVShows(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := store.GetWatchedTVShows(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tvShow := range watched {
+		tvShows = append(tvShows, tvShow.TVShow)
+	}
+
+	sort.Slice(tvShows, func(i, j int) bool {
+		return tvShows[i].ID < tvShows[j].ID
+	})
+
+	return tvShows, nil
+}
+
+// ExportMovies writes movies as CSV or JSON
+func ExportMovies(w io.Writer, format string, movies []database.Movie) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, movies)
+	case FormatCSV:
+		records := make([][]string, 0, len(movies))
+
+		for _, movie := range movies {
+			records = append(records, []string{
+				strconv.Itoa(movie.ID),
+				movie.Title,
+				numberField(movie.Year),
+				numberField(movie.Runtime),
+				strings.Join(movie.Tags, ", "),
+				movie.Availability.String(),
+				movie.Notes,
+				movie.IMDBLink,
+				movie.PosterURL,
+				movie.Director,
+				strings.Join(movie.Cast, ", "),
+				strconv.FormatBool(movie.Watched),
+			})
+		}
+
+		return writeCSV(w, movieCSVHeader, records)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ExportTVShows writes TV shows as CSV or JSON
+func ExportTVShows(w io.Writer, format string, tvShows []database.TVShow) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, tvShows)
+	case FormatCSV:
+		records := make([][]string, 0, len(tvShows))
+
+		for _, tvShow := range tvShows {
+			progress := tvShow.Progress
+			next := ""
+
+			if progress.Season > 0 {
+				next = fmt.Sprintf("S%02dE%02d", progress.Season, progress.Episode)
+			}
+
+			records = append(records, []string{
+				strconv.Itoa(tvShow.ID),
+				tvShow.Title,
+				numberField(tvShow.Year),
+				strings.Join(tvShow.Tags, ", "),
+				tvShow.Availability.String(),
+				tvShow.Notes,
+				tvShow.IMDBLink,
+				tvShow.PosterURL,
+				tvShow.Director,
+				strings.Join(tvShow.Cast, ", "),
+				strconv.FormatBool(tvShow.ActiveSeason),
+				strconv.FormatBool(tvShow.Watched),
+				next,
+				strconv.Itoa(progress.WatchedEpisodes),
+				strconv.Itoa(progress.TotalEpisodes),
+			})
+		}
+
+		return writeCSV(w, tvShowCSVHeader, records)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// numberField leaves unknown years and runtimes empty rather than writing 0
+func numberField(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(n)
+}
+
+// writeJSON writes an indented JSON array
+func writeJSON(w io.Writer, items interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(items); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
+
+// writeCSV writes a header row followed by the records
+func writeCSV(w io.Writer, header []string, records [][]string) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// imdbTitleTypes maps IMDb's Title Type column, lower-cased with spaces
+// removed, onto board item types; episodes, games and the like are skipped
+var imdbTitleTypes = map[string]string{
+	"movie":        database.ItemTypeMovie,
+	"tvmovie":      database.ItemTypeMovie,
+	"short":        database.ItemTypeMovie,
+	"tvshort":      database.ItemTypeMovie,
+	"video":        database.ItemTypeMovie,
+	"tvspecial":    database.ItemTypeMovie,
+	"tvseries":     database.ItemTypeTVShow,
+	"tvminiseries": database.ItemTypeTVShow,
+}
+
+// readIMDbList reads an IMDb list, watchlist or ratings CSV. They share the
+// Const, Title, Title Type, Year and Genres columns; a ratings export adds
+// Your Rating (1-10) and Date Rated, and its titles are imported as watched.
+func readIMDbList(r io.Reader) ([]Entry, error) {
+	records, err := readCSVRecords(r, "const")
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(records))
+
+	for _, record := range records {
+		entry := Entry{Row: record.number, Err: record.err}
+
+		if record.err == nil {
+			entry.Title = record.values["title"]
+			entry.IMDBLink = database.IMDbLink(record.values["const"])
+			entry.Tags = splitList(record.values["genres"])
+			entry.Notes = record.values["description"]
+			entry.Err = readIMDbFields(&entry, record.values)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// readIMDbFields parses the title type, year, runtime, rating and rating date of one row
+func readIMDbFields(entry *Entry, values map[string]string) error {
+	titleType := strings.ToLower(strings.ReplaceAll(values["title type"], " ", ""))
+	itemType, ok := imdbTitleTypes[titleType]
+
+	if !ok {
+		return fmt.Errorf("%w: IMDb title type %q", errUnsupportedTitle, values["title type"])
+	}
+
+	entry.ItemType = itemType
+
+	if entry.IMDBLink == "" {
+		return fmt.Errorf("invalid IMDb ID %q", values["const"])
+	}
+
+	if year := values["year"]; year != "" {
+		parsed, err := strconv.Atoi(year)
+
+		if err != nil {
+			return fmt.Errorf("invalid year %q", year)
+		}
+
+		entry.Year = parsed
+	}
+
+	if runtime := values["runtime (mins)"]; runtime != "" && itemType == database.ItemTypeMovie {
+		parsed, err := strconv.Atoi(runtime)
+
+		if err != nil {
+			return fmt.Errorf("invalid runtime %q", runtime)
+		}
+
+		entry.Runtime = parsed
+	}
+
+	rating := values["your rating"]
+
+	if rating == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(rating, 64)
+
+	if err != nil {
+		return fmt.Errorf("invalid rating %q", rating)
+	}
+
+	entry.Watched = true
+	entry.Rating = starRating(parsed, 10)
+
+	if date := values["date rated"]; date != "" {
+		watchedAt, err := parseSourceDate(date)
+
+		if err != nil {
+			return err
+		}
+
+		entry.WatchedAt = watchedAt
+	}
+
+	return nil
+}
@@ -0,0 +1,530 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// importFields are the columns and JSON keys an import reads; anything else,
+// such as id or episode progress, is export-only and ignored
+var importFields = []string{"title", "year", "runtime", "tags", "availability", "notes", "imdb_link", "poster_url", "director", "cast", "active_season", "watched"}
+
+// item holds the importable fields shared by movies and TV shows
+type item struct {
+	Title        string                    `json:"title"`
+	Year         int                       `json:"year"`
+	Runtime      int                       `json:"runtime"` // movies only
+	Tags         []string                  `json:"tags"`
+	Availability database.AvailabilityList `json:"availability"`
+	Notes        string                    `json:"notes"`
+	IMDBLink     string                    `json:"imdb_link"`
+	ActiveSeason bool                      `json:"active_season"`
+	Watched      bool                      `json:"watched"`
+	database.Details
+}
+
+// row is one parsed import row; present lists the fields the row supplied,
+// so an upsert keeps the existing value of every field the file leaves out
+type row struct {
+	number  int
+	item    item
+	present map[string]bool
+	err     error
+}
+
+// over returns base with the row's supplied fields written over it
+func (r row) over(base item) item {
+	for field := range r.present {
+		switch field {
+		case "title":
+			base.Title = r.item.Title
+		case "year":
+			base.Year = r.item.Year
+		case "runtime":
+			base.Runtime = r.item.Runtime
+		case "tags":
+			base.Tags = r.item.Tags
+		case "availability":
+			base.Availability = r.item.Availability
+		case "notes":
+			base.Notes = r.item.Notes
+		case "imdb_link":
+			base.IMDBLink = r.item.IMDBLink
+		case "poster_url":
+			base.PosterURL = r.item.PosterURL
+		case "director":
+			base.Director = r.item.Director
+		case "cast":
+			base.Cast = r.item.Cast
+		case "active_season":
+			base.ActiveSeason = r.item.ActiveSeason
+		case "watched":
+			base.Watched = r.item.Watched
+		}
+	}
+
+	return base
+}
+
+// ImportMovies reads movies as CSV or JSON and saves each row with AddMovie,
+// or with UpdateMovie when mode is ModeUpsert and a movie with the row's IMDb
+// ID is already on the board. A row that only matches a movie by title and
+// year is reported as a conflict and left out, since it may be another film.
+func ImportMovies(ctx context.Context, store database.MovieStore, duplicates database.DuplicateStore, r io.Reader, format, mode string) (*Report, error) {
+	rows, err := readRows(r, format, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+
+	for _, row := range rows {
+		if row.err != nil {
+			report.fail(row.number, row.item.Title, row.err)
+
+			continue
+		}
+
+		existing, similar, err := findMovie(ctx, store, duplicates, movieFromItem(row.over(item{})))
+
+		if err != nil {
+			report.fail(row.number, row.item.Title, err)
+
+			continue
+		}
+
+		switch {
+		case existing == nil && similar != nil:
+			report.conflict(row.number, row.item.Title, similar.Title, similar.Year)
+		case existing == nil:
+			movie := movieFromItem(row.over(item{}))
+			id, err := store.AddMovie(ctx, movie)
+
+			if err == nil {
+				err = setMovieWatched(ctx, store, id, false, movie.Watched)
+			}
+
+			if err != nil {
+				report.fail(row.number, row.item.Title, err)
+
+				continue
+			}
+
+			report.Added++
+		case mode == ModeSkip:
+			report.Skipped++
+		default:
+			movie := movieFromItem(row.over(itemFromMovie(*existing)))
+			movie.ID = existing.ID
+
+			err := store.UpdateMovie(ctx, movie)
+
+			if err == nil {
+				err = setMovieWatched(ctx, store, movie.ID, existing.Watched, movie.Watched)
+			}
+
+			if err != nil {
+				report.fail(row.number, row.item.Title, err)
+
+				continue
+			}
+
+			report.Updated++
+		}
+	}
+
+	logger.Info("Imported movies: %d added, %d updated, %d skipped, %d conflicts, %d failed", report.Added, report.Updated, report.Skipped, len(report.Conflicts), len(report.Errors))
+
+	return report, nil
+}
+
+// ImportTVShows reads TV shows as CSV or JSON and saves each row with AddTVShow,
+// or with UpdateTVShow when mode is ModeUpsert and a show with the row's IMDb
+// ID is already on the board; title and year matches are conflicts, as for movies
+func ImportTVShows(ctx context.Context, store database.TVShowStore, duplicates database.DuplicateStore, r io.Reader, format, mode string) (*Report, error) {
+	rows, err := readRows(r, format, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+
+	for _, row := range rows {
+		if row.err != nil {
+			report.fail(row.number, row.item.Title, row.err)
+
+			continue
+		}
+
+		existing, similar, err := findTVShow(ctx, store, duplicates, tvShowFromItem(row.over(item{})))
+
+		if err != nil {
+			report.fail(row.number, row.item.Title, err)
+
+			continue
+		}
+
+		switch {
+		case existing == nil && similar != nil:
+			report.conflict(row.number, row.item.Title, similar.Title, similar.Year)
+		case existing == nil:
+			tvShow := tvShowFromItem(row.over(item{}))
+			id, err := store.AddTVShow(ctx, tvShow)
+
+			if err == nil {
+				err = setTVShowWatched(ctx, store, id, false, tvShow.Watched)
+			}
+
+			if err != nil {
+				report.fail(row.number, row.item.Title, err)
+
+				continue
+			}
+
+			report.Added++
+		case mode == ModeSkip:
+			report.Skipped++
+		default:
+			tvShow := tvShowFromItem(row.over(itemFromTVShow(*existing)))
+			tvShow.ID = existing.ID
+
+			err := store.UpdateTVShow(ctx, tvShow)
+
+			if err == nil {
+				err = setTVShowWatched(ctx, store, tvShow.ID, existing.Watched, tvShow.Watched)
+			}
+
+			if err != nil {
+				report.fail(row.number, row.item.Title, err)
+
+				continue
+			}
+
+			report.Updated++
+		}
+	}
+
+	logger.Info("Imported TV shows: %d added, %d updated, %d skipped, %d conflicts, %d failed", report.Added, report.Updated, report.Skipped, len(report.Conflicts), len(report.Errors))
+
+	return report, nil
+}
+
+// findMovie returns the movie already on the board with an imported movie's
+// IMDb ID, or when there is none, the first likely title and year duplicate
+// as similar; both are nil when nothing matches
+func findMovie(ctx context.Context, store database.MovieStore, duplicates database.DuplicateStore, movie database.Movie) (existing, similar *database.Movie, err error) {
+	existing, err = store.GetMovieByIMDbID(ctx, database.IMDbID(movie.IMDBLink))
+
+	if err != nil || existing != nil {
+		return existing, nil, err
+	}
+
+	matches, err := duplicates.FindMovieDuplicates(ctx, movie)
+
+	if err != nil || len(matches) == 0 {
+		return nil, nil, err
+	}
+
+	return nil, &matches[0], nil
+}
+
+// findTVShow is findMovie for TV shows
+func findTVShow(ctx context.Context, store database.TVShowStore, duplicates database.DuplicateStore, tvShow database.TVShow) (existing, similar *database.TVShow, err error) {
+	existing, err = store.GetTVShowByIMDbID(ctx, database.IMDbID(tvShow.IMDBLink))
+
+	if err != nil || existing != nil {
+		return existing, nil, err
+	}
+
+	matches, err := duplicates.FindTVShowDuplicates(ctx, tvShow)
+
+	if err != nil || len(matches) == 0 {
+		return nil, nil, err
+	}
+
+	return nil, &matches[0], nil
+}
+
+// setMovieWatched moves an imported movie to the watched archive or back to
+// the watchlist when the file's watched column differs from the board;
+// AddMovie and UpdateMovie leave the watched flag alone. A newly watched
+// movie gets an undated viewing recorded at import time.
+func setMovieWatched(ctx context.Context, store database.MovieStore, id int, was, watched bool) error {
+	switch {
+	case watched == was:
+		return nil
+	case watched:
+		return store.MarkMovieWatched(ctx, database.WatchEvent{ItemID: id})
+	default:
+		return store.ReturnMovieToWatchlist(ctx, id)
+	}
+}
+
+// setTVShowWatched is setMovieWatched for TV shows
+func setTVShowWatched(ctx context.Context, store database.TVShowStore, id int, was, watched bool) error {
+	switch {
+	case watched == was:
+		return nil
+	case watched:
+		return store.MarkTVShowWatched(ctx, database.WatchEvent{ItemID: id})
+	default:
+		return store.ReturnTVShowToWatchlist(ctx, id)
+	}
+}
+
+// itemFromMovie copies a movie's importable fields
+func itemFromMovie(movie database.Movie) item {
+	return item{Title: movie.Title, Year: movie.Year, Runtime: movie.Runtime, Tags: movie.Tags, Availability: movie.Availability, Notes: movie.Notes, IMDBLink: movie.IMDBLink, Watched: movie.Watched, Details: movie.Details}
+}
+
+// movieFromItem builds a movie from imported fields
+func movieFromItem(i item) database.Movie {
+	return database.Movie{Title: i.Title, Year: i.Year, Runtime: i.Runtime, Tags: i.Tags, Availability: i.Availability, Notes: i.Notes, IMDBLink: i.IMDBLink, Watched: i.Watched, Details: i.Details}
+}
+
+// itemFromTVShow copies a TV show's importable fields
+func itemFromTVShow(tvShow database.TVShow) item {
+	return item{Title: tvShow.Title, Year: tvShow.Year, Tags: tvShow.Tags, Availability: tvShow.Availability, Notes: tvShow.Notes, IMDBLink: tvShow.IMDBLink, ActiveSeason: tvShow.ActiveSeason, Watched: tvShow.Watched, Details: tvShow.Details}
+}
+
+// tvShowFromItem builds a TV show from imported fields
+func tvShowFromItem(i item) database.TVShow {
+	return database.TVShow{Title: i.Title, Year: i.Year, Tags: i.Tags, Availability: i.Availability, Notes: i.Notes, IMDBLink: i.IMDBLink, ActiveSeason: i.ActiveSeason, Watched: i.Watched, Details: i.Details}
+}
+
+// readRows parses an import file; errors in a single row are kept on the row
+// so the rest of the file still imports
+func readRows(r io.Reader, format, mode string) ([]row, error) {
+	if !ValidMode(mode) {
+		return nil, fmt.Errorf("unsupported import mode: %s", mode)
+	}
+
+	switch format {
+	case FormatCSV:
+		return readCSVRows(r)
+	case FormatJSON:
+		return readJSONRows(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+// readCSVRows parses a CSV file whose header names the columns; rows are
+// numbered by the line they start on, so the first data row is 2
+func readCSVRows(r io.Reader) ([]row, error) {
+	records, err := readCSVRecords(r, "title")
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, 0, len(records))
+
+	for _, record := range records {
+		if record.err != nil {
+			rows = append(rows, row{number: record.number, err: record.err})
+
+			continue
+		}
+
+		values := make(map[string]string)
+
+		for _, field := range importFields {
+			if value, ok := record.values[field]; ok {
+				values[field] = value
+			}
+		}
+
+		rows = append(rows, csvRow(record.number, values))
+	}
+
+	return rows, nil
+}
+
+// csvRecord is one CSV data line keyed by lower-cased column name
+type csvRecord struct {
+	number int
+	values map[string]string
+	err    error
+}
+
+// readCSVRecords reads a CSV file with a header row that must include the
+// required column; a malformed line is kept as a record with err set so the
+// rest of the file can still be used
+func readCSVRecords(r io.Reader, required string) ([]csvRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	if _, ok := columns[required]; !ok {
+		return nil, fmt.Errorf("CSV header has no %s column", required)
+	}
+
+	var records []csvRecord
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			records = append(records, csvRecord{number: parseErr.StartLine, err: parseErr.Err})
+
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		values := make(map[string]string)
+
+		for name, i := range columns {
+			if i < len(record) {
+				values[name] = strings.TrimSpace(record[i])
+			}
+		}
+
+		number, _ := reader.FieldPos(0)
+
+		records = append(records, csvRecord{number: number, values: values})
+	}
+
+	return records, nil
+}
+
+// csvRow converts one CSV record's text fields into a row
+func csvRow(number int, values map[string]string) row {
+	parsed := row{number: number, present: make(map[string]bool)}
+
+	for field, value := range values {
+		parsed.present[field] = true
+
+		switch field {
+		case "title":
+			parsed.item.Title = value
+		case "year":
+			if value == "" {
+				continue
+			}
+
+			year, err := strconv.Atoi(value)
+
+			if err != nil {
+				parsed.err = fmt.Errorf("invalid year %q", value)
+			}
+
+			parsed.item.Year = year
+		case "runtime":
+			if value == "" {
+				continue
+			}
+
+			runtime, err := strconv.Atoi(value)
+
+			if err != nil {
+				parsed.err = fmt.Errorf("invalid runtime %q", value)
+			}
+
+			parsed.item.Runtime = runtime
+		case "tags":
+			parsed.item.Tags = []string{value}
+		case "availability":
+			availability, err := database.ParseAvailability(value)
+
+			if err != nil {
+				parsed.err = err
+			}
+
+			parsed.item.Availability = availability
+		case "notes":
+			parsed.item.Notes = value
+		case "imdb_link":
+			parsed.item.IMDBLink = value
+		case "poster_url":
+			parsed.item.PosterURL = value
+		case "director":
+			parsed.item.Director = value
+		case "cast":
+			parsed.item.Cast = splitList(value)
+		case "active_season":
+			active, err := strconv.ParseBool(strings.ToLower(value))
+
+			if value != "" && err != nil {
+				parsed.err = fmt.Errorf("invalid active_season %q", value)
+			}
+
+			parsed.item.ActiveSeason = active
+		case "watched":
+			watched, err := strconv.ParseBool(strings.ToLower(value))
+
+			if value != "" && err != nil {
+				parsed.err = fmt.Errorf("invalid watched %q", value)
+			}
+
+			parsed.item.Watched = watched
+		}
+	}
+
+	return parsed
+}
+
+// readJSONRows parses a JSON array of objects using the export's keys; row
+// numbers are positions in the array, starting at 1
+func readJSONRows(r io.Reader) ([]row, error) {
+	var objects []json.RawMessage
+
+	if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return nil, fmt.Errorf("failed to read JSON array: %w", err)
+	}
+
+	rows := make([]row, 0, len(objects))
+
+	for i, object := range objects {
+		parsed := row{number: i + 1, present: make(map[string]bool)}
+
+		var keys map[string]json.RawMessage
+
+		if err := json.Unmarshal(object, &keys); err != nil {
+			parsed.err = fmt.Errorf("not a JSON object: %w", err)
+		} else if err := json.Unmarshal(object, &parsed.item); err != nil {
+			parsed.err = fmt.Errorf("invalid field: %w", err)
+		}
+
+		for _, field := range importFields {
+			if _, ok := keys[field]; ok {
+				parsed.present[field] = true
+			}
+		}
+
+		rows = append(rows, parsed)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// readLetterboxd reads a Letterboxd export CSV. Every file shares the Date,
+// Name, Year and Letterboxd URI columns; diary.csv adds Watched Date and Tags
+// and ratings.csv adds a 0.5-5 Rating. Letterboxd only lists films and its
+// exports carry no IMDb IDs, so entries are matched by title and year.
+func readLetterboxd(r io.Reader, watched bool) ([]Entry, error) {
+	records, err := readCSVRecords(r, "name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(records))
+
+	for _, record := range records {
+		entry := Entry{Row: record.number, ItemType: database.ItemTypeMovie, Err: record.err}
+
+		if record.err == nil {
+			entry.Title = record.values["name"]
+			entry.Tags = splitList(record.values["tags"])
+			entry.Watched = watched
+			entry.Err = readLetterboxdFields(&entry, record.values)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// readLetterboxdFields parses the year, rating and watch date of one row
+func readLetterboxdFields(entry *Entry, values map[string]string) error {
+	if year := values["year"]; year != "" {
+		parsed, err := strconv.Atoi(year)
+
+		if err != nil {
+			return fmt.Errorf("invalid year %q", year)
+		}
+
+		entry.Year = parsed
+	}
+
+	if rating := values["rating"]; rating != "" {
+		parsed, err := strconv.ParseFloat(rating, 64)
+
+		if err != nil {
+			return fmt.Errorf("invalid rating %q", rating)
+		}
+
+		entry.Rating = starRating(parsed, 5)
+	}
+
+	if !entry.Watched {
+		return nil
+	}
+
+	date := values["watched date"]
+
+	if date == "" {
+		date = values["date"]
+	}
+
+	if date != "" {
+		watchedAt, err := parseSourceDate(date)
+
+		if err != nil {
+			return err
+		}
+
+		entry.WatchedAt = watchedAt
+	}
+
+	return nil
+}
@@ -0,0 +1,406 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pwnderpants/homenet/internal/database"
+	"github.com/pwnderpants/homenet/internal/logger"
+)
+
+// What applying a plan does with each entry
+const (
+	ActionAdd   = "add"   // a new title on the board
+	ActionMerge = "merge" // fills in an existing title or marks it watched
+	ActionSkip  = "skip"  // nothing to do, or the row can't be imported
+)
+
+// PlanItem is an entry together with what importing it does. Details say
+// what a merge changes or why the entry is skipped; Failure is set when
+// applying the item went wrong.
+type PlanItem struct {
+	Entry
+	Action  string
+	Details []string
+	Failure string
+	movie   *database.Movie
+	tvShow  *database.TVShow
+}
+
+// Plan is a dry run of an external import; Apply carries it out
+type Plan struct {
+	Source  string
+	Watcher string
+	Items   []PlanItem
+}
+
+// Count returns how many items have action
+func (p *Plan) Count(action string) int {
+	count := 0
+
+	for _, item := range p.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// titleKey normalises a title for matching: case and spacing are ignored
+func titleKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// entryKey identifies an entry within one file, by IMDb ID when it has one
+func entryKey(entry Entry) string {
+	if id := database.IMDbID(entry.IMDBLink); id != "" {
+		return entry.ItemType + "|" + id
+	}
+
+	return fmt.Sprintf("%s|%s|%d", entry.ItemType, titleKey(entry.Title), entry.Year)
+}
+
+// sameYear reports whether two years can be the same title; an unknown year matches any
+func sameYear(a, b int) bool {
+	return a == 0 || b == 0 || a == b
+}
+
+// fold combines a repeated entry into the first one seen, so a title that is
+// both on a watchlist and rated in the same backup is imported once
+func fold(first, repeat Entry) Entry {
+	if first.Year == 0 {
+		first.Year = repeat.Year
+	}
+
+	if first.IMDBLink == "" {
+		first.IMDBLink = repeat.IMDBLink
+	}
+
+	if first.Notes == "" {
+		first.Notes = repeat.Notes
+	}
+
+	first.Tags = append(first.Tags, missingTags(first.Tags, repeat.Tags)...)
+
+	if repeat.Watched {
+		first.Watched = true
+
+		if repeat.WatchedAt.After(first.WatchedAt) {
+			first.WatchedAt = repeat.WatchedAt
+		}
+
+		if repeat.Rating > 0 {
+			first.Rating = repeat.Rating
+		}
+	}
+
+	return first
+}
+
+// missingTags returns the tags in add that are not already in has, ignoring case
+func missingTags(has, add []string) []string {
+	seen := make(map[string]bool)
+
+	for _, tag := range has {
+		seen[strings.ToLower(tag)] = true
+	}
+
+	var missing []string
+
+	for _, tag := range add {
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			missing = append(missing, tag)
+		}
+	}
+
+	return missing
+}
+
+// BuildPlan works out what importing entries would do without saving
+// anything. An entry merges into a movie or TV show already on either list,
+// watched or not, with the same IMDb ID, or failing that the same title and year.
+func BuildPlan(ctx context.Context, movies database.MovieStore, tvShows database.TVShowStore, source, watcher string, entries []Entry) (*Plan, error) {
+	existingMovies, err := CollectMovies(ctx, movies)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movies: %w", err)
+	}
+
+	existingTVShows, err := CollectTVShows(ctx, tvShows)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TV shows: %w", err)
+	}
+
+	if watcher = strings.TrimSpace(watcher); watcher == "" {
+		watcher = sourceServices[source]
+	}
+
+	plan := &Plan{Source: source, Watcher: watcher}
+	seen := make(map[string]int)
+
+	for _, entry := range entries {
+		entry.Title = strings.TrimSpace(entry.Title)
+		item := PlanItem{Entry: entry}
+
+		switch {
+		case entry.Err != nil:
+			item.Action = ActionSkip
+			item.Details = []string{entry.Err.Error()}
+		case entry.Title == "":
+			item.Action = ActionSkip
+			item.Details = []string{"missing title"}
+		default:
+			key := entryKey(entry)
+
+			if first, ok := seen[key]; ok {
+				plan.Items[first].Entry = fold(plan.Items[first].Entry, entry)
+				item.Action = ActionSkip
+				item.Details = []string{fmt.Sprintf("same title as row %d", plan.Items[first].Row)}
+			} else {
+				seen[key] = len(plan.Items)
+			}
+		}
+
+		plan.Items = append(plan.Items, item)
+	}
+
+	// Repeats are folded in first so each title is matched with everything the file says about it
+	for i := range plan.Items {
+		item := &plan.Items[i]
+
+		if item.Action == ActionSkip {
+			continue
+		}
+
+		if item.ItemType == database.ItemTypeMovie {
+			item.movie = matchMovie(existingMovies, item.Entry)
+		} else {
+			item.tvShow = matchTVShow(existingTVShows, item.Entry)
+		}
+
+		planItem(item)
+	}
+
+	return plan, nil
+}
+
+// matchMovie finds the movie an entry refers to, or nil
+func matchMovie(movies []database.Movie, entry Entry) *database.Movie {
+	id := database.IMDbID(entry.IMDBLink)
+
+	for i, movie := range movies {
+		if id != "" && database.IMDbID(movie.IMDBLink) == id {
+			return &movies[i]
+		}
+	}
+
+	for i, movie := range movies {
+		if titleKey(movie.Title) == titleKey(entry.Title) && sameYear(movie.Year, entry.Year) {
+			return &movies[i]
+		}
+	}
+
+	return nil
+}
+
+// matchTVShow finds the TV show an entry refers to, or nil
+func matchTVShow(tvShows []database.TVShow, entry Entry) *database.TVShow {
+	id := database.IMDbID(entry.IMDBLink)
+
+	for i, tvShow := range tvShows {
+		if id != "" && database.IMDbID(tvShow.IMDBLink) == id {
+			return &tvShows[i]
+		}
+	}
+
+	for i, tvShow := range tvShows {
+		if titleKey(tvShow.Title) == titleKey(entry.Title) && sameYear(tvShow.Year, entry.Year) {
+			return &tvShows[i]
+		}
+	}
+
+	return nil
+}
+
+// planItem sets the action and details of an entry from the title it matched
+func planItem(item *PlanItem) {
+	var year int
+	var link string
+	var tags []string
+	var watched bool
+
+	switch {
+	case item.movie != nil:
+		year, link, tags, watched = item.movie.Year, item.movie.IMDBLink, item.movie.Tags, item.movie.Watched
+	case item.tvShow != nil:
+		year, link, tags, watched = item.tvShow.Year, item.tvShow.IMDBLink, item.tvShow.Tags, item.tvShow.Watched
+	default:
+		item.Action = ActionAdd
+
+		if item.Watched {
+			item.Details = []string{"marks watched"}
+		}
+
+		return
+	}
+
+	if year == 0 && item.Year != 0 {
+		item.Details = append(item.Details, fmt.Sprintf("adds year %d", item.Year))
+	}
+
+	if link == "" && item.IMDBLink != "" {
+		item.Details = append(item.Details, "adds IMDb link")
+	}
+
+	if missing := missingTags(tags, item.Tags); len(missing) > 0 {
+		item.Details = append(item.Details, "adds tags "+strings.Join(missing, ", "))
+	}
+
+	if item.Watched && !watched {
+		item.Details = append(item.Details, "marks watched")
+	}
+
+	if len(item.Details) == 0 {
+		item.Action = ActionSkip
+		item.Details = []string{"already on the board"}
+
+		return
+	}
+
+	item.Action = ActionMerge
+}
+
+// Apply carries out a plan and reports it like a native import: merges count
+// as updates. Each item that could not be saved also gets a Failure.
+func Apply(ctx context.Context, movies database.MovieStore, tvShows database.TVShowStore, plan *Plan) *Report {
+	report := &Report{}
+
+	for i := range plan.Items {
+		item := &plan.Items[i]
+
+		if item.Action == ActionSkip {
+			report.Skipped++
+
+			continue
+		}
+
+		var err error
+
+		if item.ItemType == database.ItemTypeMovie {
+			err = applyMovie(ctx, movies, plan.Watcher, item)
+		} else {
+			err = applyTVShow(ctx, tvShows, plan.Watcher, item)
+		}
+
+		switch {
+		case err != nil:
+			item.Failure = err.Error()
+			report.fail(item.Row, item.Label(), err)
+		case item.Action == ActionAdd:
+			report.Added++
+		default:
+			report.Updated++
+		}
+	}
+
+	logger.Info("Imported %s: %d added, %d merged, %d skipped, %d failed", SourceLabel(plan.Source), report.Added, report.Updated, report.Skipped, len(report.Errors))
+
+	return report
+}
+
+// watchEvent builds the viewing recorded for a watched entry
+func watchEvent(itemID int, watcher string, entry Entry) database.WatchEvent {
+	return database.WatchEvent{ItemID: itemID, Watcher: watcher, WatchedAt: entry.WatchedAt, Rating: entry.Rating}
+}
+
+// applyMovie adds or merges one movie and records its viewing
+func applyMovie(ctx context.Context, store database.MovieStore, watcher string, item *PlanItem) error {
+	if item.movie == nil {
+		id, err := store.AddMovie(ctx, database.Movie{Title: item.Title, Year: item.Year, Runtime: item.Runtime, Tags: item.Tags, Notes: item.Notes, IMDBLink: item.IMDBLink})
+
+		if err != nil {
+			return err
+		}
+
+		if item.Watched {
+			return store.MarkMovieWatched(ctx, watchEvent(id, watcher, item.Entry))
+		}
+
+		return nil
+	}
+
+	movie := *item.movie
+	updated := movie
+
+	if updated.Year == 0 {
+		updated.Year = item.Year
+	}
+
+	if updated.Runtime == 0 {
+		updated.Runtime = item.Runtime
+	}
+
+	if updated.IMDBLink == "" {
+		updated.IMDBLink = item.IMDBLink
+	}
+
+	updated.Tags = append(append([]string{}, movie.Tags...), missingTags(movie.Tags, item.Tags)...)
+
+	if updated.Year != movie.Year || updated.Runtime != movie.Runtime || updated.IMDBLink != movie.IMDBLink || len(updated.Tags) != len(movie.Tags) {
+		if err := store.UpdateMovie(ctx, updated); err != nil {
+			return err
+		}
+	}
+
+	if item.Watched && !movie.Watched {
+		return store.MarkMovieWatched(ctx, watchEvent(movie.ID, watcher, item.Entry))
+	}
+
+	return nil
+}
+
+// applyTVShow adds or merges one TV show and records its viewing
+func applyTVShow(ctx context.Context, store database.TVShowStore, watcher string, item *PlanItem) error {
+	if item.tvShow == nil {
+		id, err := store.AddTVShow(ctx, database.TVShow{Title: item.Title, Year: item.Year, Tags: item.Tags, Notes: item.Notes, IMDBLink: item.IMDBLink})
+
+		if err != nil {
+			return err
+		}
+
+		if item.Watched {
+			return store.MarkTVShowWatched(ctx, watchEvent(id, watcher, item.Entry))
+		}
+
+		return nil
+	}
+
+	tvShow := *item.tvShow
+	updated := tvShow
+
+	if updated.Year == 0 {
+		updated.Year = item.Year
+	}
+
+	if updated.IMDBLink == "" {
+		updated.IMDBLink = item.IMDBLink
+	}
+
+	updated.Tags = append(append([]string{}, tvShow.Tags...), missingTags(tvShow.Tags, item.Tags)...)
+
+	if updated.Year != tvShow.Year || updated.IMDBLink != tvShow.IMDBLink || len(updated.Tags) != len(tvShow.Tags) {
+		if err := store.UpdateTVShow(ctx, updated); err != nil {
+			return err
+		}
+	}
+
+	if item.Watched && !tvShow.Watched {
+		return store.MarkTVShowWatched(ctx, watchEvent(tvShow.ID, watcher, item.Entry))
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// External services whose export files can be imported
+const (
+	SourceLetterboxdWatchlist = "letterboxd-watchlist" // Letterboxd watchlist.csv
+	SourceLetterboxdWatched   = "letterboxd-watched"   // Letterboxd watched.csv, diary.csv or ratings.csv
+	SourceIMDb                = "imdb"                 // IMDb list, watchlist or ratings CSV
+	SourceTrakt               = "trakt"                // Trakt JSON backup
+)
+
+// Sources lists the external sources in the order the import page offers them
+var Sources = []string{SourceLetterboxdWatchlist, SourceLetterboxdWatched, SourceIMDb, SourceTrakt}
+
+// sourceLabels are readable names for each source
+var sourceLabels = map[string]string{
+	SourceLetterboxdWatchlist: "Letterboxd watchlist",
+	SourceLetterboxdWatched:   "Letterboxd watched",
+	SourceIMDb:                "IMDb",
+	SourceTrakt:               "Trakt",
+}
+
+// sourceServices name the service behind each source; imported viewings are
+// credited to it when no watcher is given
+var sourceServices = map[string]string{
+	SourceLetterboxdWatchlist: "Letterboxd",
+	SourceLetterboxdWatched:   "Letterboxd",
+	SourceIMDb:                "IMDb",
+	SourceTrakt:               "Trakt",
+}
+
+// ValidSource reports whether source is a supported external source
+func ValidSource(source string) bool {
+	_, ok := sourceLabels[source]
+
+	return ok
+}
+
+// SourceLabel returns a readable name for source
+func SourceLabel(source string) string {
+	if label, ok := sourceLabels[source]; ok {
+		return label
+	}
+
+	return source
+}
+
+// errUnsupportedTitle marks rows for things the boards don't hold, such as episodes
+var errUnsupportedTitle = errors.New("not a movie or TV show")
+
+// Entry is one title read from an external export, mapped onto the fields
+// movies and TV shows share. Rows that could not be read keep Err.
+type Entry struct {
+	Row       int
+	ItemType  string
+	Title     string
+	Year      int
+	Runtime   int // minutes, movies only; 0 when the source had none
+	IMDBLink  string
+	Tags      []string
+	Notes     string
+	Watched   bool
+	WatchedAt time.Time
+	Rating    int // 1-5, or 0 when the source had no rating
+	Err       error
+}
+
+// Label returns the entry's title with its year, when known
+func (e Entry) Label() string {
+	if e.Year == 0 {
+		return e.Title
+	}
+
+	return fmt.Sprintf("%s (%d)", e.Title, e.Year)
+}
+
+// ParseSource reads an external export file into entries
+func ParseSource(source string, r io.Reader) ([]Entry, error) {
+	switch source {
+	case SourceLetterboxdWatchlist:
+		return readLetterboxd(r, false)
+	case SourceLetterboxdWatched:
+		return readLetterboxd(r, true)
+	case SourceIMDb:
+		return readIMDbList(r)
+	case SourceTrakt:
+		return readTrakt(r)
+	default:
+		return nil, fmt.Errorf("unsupported import source: %s", source)
+	}
+}
+
+// starRating scales a rating out of outOf onto the boards' 1-5 stars; 0 stays unrated
+func starRating(rating, outOf float64) int {
+	if rating <= 0 {
+		return 0
+	}
+
+	stars := int(math.Round(rating * 5 / outOf))
+
+	return max(1, min(5, stars))
+}
+
+// parseSourceDate reads a date in any of the layouts the sources use
+func parseSourceDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, database.AvailabilityDateLayout} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// splitList splits a comma-separated list such as genres, dropping blanks
+func splitList(value string) []string {
+	var values []string
+
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+
+	return values
+}
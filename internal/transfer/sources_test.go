@@ -0,0 +1,259 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// date is midnight local time on a day, as sources without a time of day parse
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestParseSource(t *testing.T) {
+	heat := database.IMDbLink("tt0113277")
+	lost := database.IMDbLink("tt0411008")
+
+	tests := []struct {
+		name    string
+		source  string
+		input   string
+		want    []Entry
+		failed  []int // rows expected to carry an error
+		wantErr bool
+	}{
+		{
+			name:   "Letterboxd watchlist",
+			source: SourceLetterboxdWatchlist,
+			input:  "Date,Name,Year,Letterboxd URI\n2024-01-02,Heat,1995,https://boxd.it/1\n2024-01-03,Untitled,,https://boxd.it/2\n",
+			want: []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995},
+				{Row: 3, ItemType: database.ItemTypeMovie, Title: "Untitled"},
+			},
+		},
+		{
+			name:   "Letterboxd diary",
+			source: SourceLetterboxdWatched,
+			input:  "Date,Name,Year,Letterboxd URI,Rating,Rewatch,Tags,Watched Date\n2024-02-01,Heat,1995,https://boxd.it/1,4.5,,\"crime, rewatch\",2024-01-30\n",
+			want: []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, Tags: []string{"crime", "rewatch"}, Watched: true, WatchedAt: date(2024, 1, 30), Rating: 5},
+			},
+		},
+		{
+			name:   "Letterboxd ratings",
+			source: SourceLetterboxdWatched,
+			input:  "Date,Name,Year,Letterboxd URI,Rating\n2024-02-01,Heat,1995,https://boxd.it/1,0.5\n2024-02-02,Ronin,199x,https://boxd.it/2,3\n",
+			want: []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, Watched: true, WatchedAt: date(2024, 2, 1), Rating: 1},
+				{Row: 3, ItemType: database.ItemTypeMovie, Title: "Ronin", Watched: true},
+			},
+			failed: []int{3},
+		},
+		{name: "Letterboxd without names", source: SourceLetterboxdWatchlist, input: "Date,Title\n", wantErr: true},
+		{
+			name:   "IMDb watchlist",
+			source: SourceIMDb,
+			input: "Position,Const,Created,Modified,Description,Title,URL,Title Type,IMDb Rating,Runtime (mins),Year,Genres\n" +
+				"1,tt0113277,,,heist,Heat,,Movie,8.3,170,1995,\"Crime, Drama\"\n" +
+				"2,tt0411008,,,,Lost,,TV Series,8.3,44,2004,Drama\n" +
+				"3,tt0000001,,,,Pilot,,TV Episode,,,2004,\n" +
+				"4,nonsense,,,,Broken,,Movie,,,,\n",
+			want: []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, Runtime: 170, IMDBLink: heat, Tags: []string{"Crime", "Drama"}, Notes: "heist"},
+				{Row: 3, ItemType: database.ItemTypeTVShow, Title: "Lost", Year: 2004, IMDBLink: lost, Tags: []string{"Drama"}},
+				{Row: 4, Title: "Pilot", IMDBLink: database.IMDbLink("tt0000001")},
+				{Row: 5, ItemType: database.ItemTypeMovie, Title: "Broken"},
+			},
+			failed: []int{4, 5},
+		},
+		{
+			name:   "IMDb ratings",
+			source: SourceIMDb,
+			input:  "Const,Your Rating,Date Rated,Title,Title Type,Year\ntt0113277,7,2023-05-06,Heat,movie,1995\n",
+			want: []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, IMDBLink: heat, Watched: true, WatchedAt: date(2023, 5, 6), Rating: 4},
+			},
+		},
+		{
+			name:   "Trakt watchlist",
+			source: SourceTrakt,
+			input: `[{"type":"movie","listed_at":"2024-01-01T00:00:00.000Z","movie":{"title":"Heat","year":1995,"ids":{"imdb":"tt0113277"}}},
+				{"type":"episode","episode":{"title":"Pilot"},"show":{"title":"Lost"}},
+				{"type":"person"}]`,
+			want: []Entry{
+				{Row: 1, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, IMDBLink: heat},
+				{Row: 2},
+				{Row: 3},
+			},
+			failed: []int{2, 3},
+		},
+		{
+			name:   "Trakt backup of several lists",
+			source: SourceTrakt,
+			input: `{"watched_shows":[{"plays":3,"show":{"title":"Lost","year":2004,"genres":["drama"],"ids":{"imdb":"tt0411008"}}}],
+				"ratings_movies":[{"rating":10,"rated_at":"2024-03-04T20:00:00Z","movie":{"title":"Heat","year":1995}}],
+				"user":{"username":"sam"}}`,
+			want: []Entry{
+				{Row: 1, ItemType: database.ItemTypeMovie, Title: "Heat", Year: 1995, Watched: true, WatchedAt: time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC), Rating: 5},
+				{Row: 2, ItemType: database.ItemTypeTVShow, Title: "Lost", Year: 2004, IMDBLink: lost, Tags: []string{"drama"}, Watched: true},
+			},
+		},
+		{name: "Trakt not JSON", source: SourceTrakt, input: "title,year", wantErr: true},
+		{name: "unknown source", source: "netflix", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		entries, err := ParseSource(tt.source, strings.NewReader(tt.input))
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ParseSource = %+v, want an error", tt.name, entries)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: ParseSource: %v", tt.name, err)
+		}
+
+		var failed []int
+
+		for i := range entries {
+			if entries[i].Err != nil {
+				failed = append(failed, entries[i].Row)
+			}
+		}
+
+		if !reflect.DeepEqual(failed, tt.failed) {
+			t.Errorf("%s: failed rows = %v, want %v", tt.name, failed, tt.failed)
+		}
+
+		if got, want := comparableEntries(entries), comparableEntries(tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ParseSource =\n%+v\nwant\n%+v", tt.name, got, want)
+		}
+	}
+}
+
+// comparableEntries drops errors, which are checked by row, and writes watch
+// times in UTC so entries parsed in different locations compare equal
+func comparableEntries(entries []Entry) []Entry {
+	result := make([]Entry, 0, len(entries))
+
+	for _, entry := range entries {
+		entry.Err = nil
+
+		if !entry.WatchedAt.IsZero() {
+			entry.WatchedAt = entry.WatchedAt.UTC()
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+func TestBuildPlanAndApply(t *testing.T) {
+	for name, store := range database.TestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			heat, err := store.AddMovie(ctx, database.Movie{Title: "Heat", Year: 1995, Tags: []string{"Crime"}})
+
+			if err != nil {
+				t.Fatalf("AddMovie: %v", err)
+			}
+
+			lost, err := store.AddTVShow(ctx, database.TVShow{Title: "Lost", Year: 2004})
+
+			if err != nil {
+				t.Fatalf("AddTVShow: %v", err)
+			}
+
+			if err := store.MarkTVShowWatched(ctx, database.WatchEvent{ItemID: lost}); err != nil {
+				t.Fatalf("MarkTVShowWatched: %v", err)
+			}
+
+			entries := []Entry{
+				{Row: 2, ItemType: database.ItemTypeMovie, Title: "HEAT", Year: 1995, IMDBLink: database.IMDbLink("tt0113277"), Tags: []string{"crime", "Drama"}},
+				{Row: 3, ItemType: database.ItemTypeMovie, Title: " heat ", IMDBLink: "tt0113277", Watched: true, Rating: 4},
+				{Row: 4, ItemType: database.ItemTypeMovie, Title: "Ronin", Year: 1998, Watched: true},
+				{Row: 5, ItemType: database.ItemTypeTVShow, Title: "Lost", Year: 2004, Watched: true},
+				{Row: 6, Title: "Pilot", Err: errors.New("not a movie or TV show")},
+				{Row: 7, ItemType: database.ItemTypeMovie, Title: "  "},
+			}
+
+			plan, err := BuildPlan(ctx, store, store, SourceLetterboxdWatched, "", entries)
+
+			if err != nil {
+				t.Fatalf("BuildPlan: %v", err)
+			}
+
+			wantActions := []string{ActionMerge, ActionSkip, ActionAdd, ActionSkip, ActionSkip, ActionSkip}
+
+			for i, item := range plan.Items {
+				if item.Action != wantActions[i] {
+					t.Errorf("row %d: action %q (%v), want %q", item.Row, item.Action, item.Details, wantActions[i])
+				}
+			}
+
+			if plan.Watcher != "Letterboxd" {
+				t.Errorf("Watcher = %q, want the source's service", plan.Watcher)
+			}
+
+			report := Apply(ctx, store, store, plan)
+
+			if report.Added != 1 || report.Updated != 1 || report.Skipped != 4 || len(report.Errors) != 0 {
+				t.Fatalf("Apply = %+v; items %+v", report, plan.Items)
+			}
+
+			watched, err := store.GetWatchedMovies(ctx)
+
+			if err != nil {
+				t.Fatalf("GetWatchedMovies: %v", err)
+			}
+
+			titles := make(map[string]database.Movie)
+
+			for _, movie := range watched {
+				titles[movie.Title] = movie.Movie
+			}
+
+			merged, ok := titles["Heat"]
+
+			if !ok || merged.ID != heat || database.IMDbID(merged.IMDBLink) != "tt0113277" || len(merged.Tags) != 2 {
+				t.Errorf("Heat after the merge = %+v, want it watched with its IMDb link and both tags", merged)
+			}
+
+			if _, ok := titles["Ronin"]; !ok {
+				t.Errorf("watched movies = %+v, want Ronin added as watched", watched)
+			}
+		})
+	}
+}
+
+func TestStarRating(t *testing.T) {
+	tests := []struct {
+		rating, outOf float64
+		want          int
+	}{
+		{0, 10, 0},
+		{1, 10, 1},
+		{5, 10, 3},
+		{10, 10, 5},
+		{0.5, 5, 1},
+		{3.5, 5, 4},
+		{12, 10, 5},
+	}
+
+	for _, tt := range tests {
+		if got := starRating(tt.rating, tt.outOf); got != tt.want {
+			t.Errorf("starRating(%v, %v) = %d, want %d", tt.rating, tt.outOf, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// traktTitle is the movie or show object inside a Trakt list item
+type traktTitle struct {
+	Title  string   `json:"title"`
+	Year   int      `json:"year"`
+	Genres []string `json:"genres"`
+	IDs    struct {
+		IMDb string `json:"imdb"`
+	} `json:"ids"`
+}
+
+// traktItem is one item of a Trakt backup list. Watchlists set listed_at,
+// watched lists set plays and last_watched_at, history sets watched_at and
+// ratings set rating (1-10) and rated_at.
+type traktItem struct {
+	Type          string      `json:"type"`
+	Movie         *traktTitle `json:"movie"`
+	Show          *traktTitle `json:"show"`
+	Episode       interface{} `json:"episode"`
+	Season        interface{} `json:"season"`
+	Plays         int         `json:"plays"`
+	LastWatchedAt string      `json:"last_watched_at"`
+	WatchedAt     string      `json:"watched_at"`
+	RatedAt       string      `json:"rated_at"`
+	Rating        float64     `json:"rating"`
+}
+
+// readTrakt reads a Trakt JSON backup: either a single list such as
+// watchlist.json or watched_movies.json, or an object holding several lists
+// by name. Rows are numbered across the lists, taken in name order.
+func readTrakt(r io.Reader) ([]Entry, error) {
+	var document json.RawMessage
+
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	var lists [][]json.RawMessage
+
+	if list, err := traktList(document); err == nil {
+		lists = append(lists, list)
+	} else {
+		var named map[string]json.RawMessage
+
+		if err := json.Unmarshal(document, &named); err != nil {
+			return nil, fmt.Errorf("expected a Trakt list or an object of lists")
+		}
+
+		names := make([]string, 0, len(named))
+
+		for name := range named {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			// Profile data and other non-list values are not titles
+			if list, err := traktList(named[name]); err == nil {
+				lists = append(lists, list)
+			}
+		}
+	}
+
+	var entries []Entry
+
+	for _, list := range lists {
+		for _, raw := range list {
+			entries = append(entries, traktEntry(len(entries)+1, raw))
+		}
+	}
+
+	return entries, nil
+}
+
+// traktList decodes a JSON array of list items
+func traktList(data json.RawMessage) ([]json.RawMessage, error) {
+	var list []json.RawMessage
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// traktEntry maps one Trakt list item onto an entry
+func traktEntry(number int, raw json.RawMessage) Entry {
+	entry := Entry{Row: number}
+
+	var item traktItem
+
+	if err := json.Unmarshal(raw, &item); err != nil {
+		entry.Err = fmt.Errorf("invalid Trakt item: %w", err)
+
+		return entry
+	}
+
+	var title *traktTitle
+
+	switch {
+	case item.Episode != nil || item.Season != nil:
+		entry.Err = fmt.Errorf("%w: Trakt %s", errUnsupportedTitle, item.Type)
+
+		return entry
+	case item.Movie != nil:
+		entry.ItemType = database.ItemTypeMovie
+		title = item.Movie
+	case item.Show != nil:
+		entry.ItemType = database.ItemTypeTVShow
+		title = item.Show
+	default:
+		entry.Err = fmt.Errorf("%w: Trakt item has no movie or show", errUnsupportedTitle)
+
+		return entry
+	}
+
+	entry.Title = title.Title
+	entry.Year = title.Year
+	entry.IMDBLink = database.IMDbLink(title.IDs.IMDb)
+	entry.Tags = title.Genres
+	entry.Rating = starRating(item.Rating, 10)
+
+	for _, date := range []string{item.WatchedAt, item.LastWatchedAt, item.RatedAt} {
+		if date == "" {
+			continue
+		}
+
+		watchedAt, err := parseSourceDate(date)
+
+		if err != nil {
+			entry.Err = err
+
+			return entry
+		}
+
+		entry.Watched = true
+		entry.WatchedAt = watchedAt
+
+		break
+	}
+
+	if item.Plays > 0 {
+		entry.Watched = true
+	}
+
+	return entry
+}
@@ -0,0 +1,72 @@
+package transfer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Formats that boards can be exported to and imported from
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Import modes for rows whose IMDb ID matches an item already on the board
+const (
+	ModeUpsert = "upsert" // update the existing item with the row's fields
+	ModeSkip   = "skip"   // leave the existing item alone
+)
+
+// ValidFormat reports whether format is a supported export and import format
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON
+}
+
+// ValidMode reports whether mode is a supported import mode
+func ValidMode(mode string) bool {
+	return mode == ModeUpsert || mode == ModeSkip
+}
+
+// FormatFromName guesses the format from a file name's extension, or returns ""
+func FormatFromName(name string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+
+	if !ValidFormat(format) {
+		return ""
+	}
+
+	return format
+}
+
+// RowError is an import row that could not be saved
+type RowError struct {
+	Row     int
+	Title   string
+	Message string
+}
+
+// Report summarises an import. Conflicts are rows left out because they
+// match an item on the board by title and year but not by IMDb ID.
+type Report struct {
+	Added     int
+	Updated   int
+	Skipped   int
+	Conflicts []RowError
+	Errors    []RowError
+}
+
+// fail records a row that could not be saved
+func (r *Report) fail(row int, title string, err error) {
+	r.Errors = append(r.Errors, RowError{Row: row, Title: title, Message: err.Error()})
+}
+
+// conflict records a row left out because it resembles an item on the board
+func (r *Report) conflict(row int, title, similarTitle string, similarYear int) {
+	if similarYear != 0 {
+		similarTitle = fmt.Sprintf("%s (%d)", similarTitle, similarYear)
+	}
+
+	message := fmt.Sprintf("looks like %s on the board; give both the same IMDb link to update it", similarTitle)
+	r.Conflicts = append(r.Conflicts, RowError{Row: row, Title: title, Message: message})
+}
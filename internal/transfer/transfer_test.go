@@ -0,0 +1,241 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pwnderpants/homenet/internal/database"
+)
+
+// comparableMovies drops what an import cannot carry over, the IDs, and puts
+// tags in order so movies from different stores can be compared
+func comparableMovies(movies []database.Movie) []database.Movie {
+	var result []database.Movie
+
+	for _, movie := range movies {
+		movie.ID = 0
+		movie.Tags = slices.Sorted(slices.Values(movie.Tags))
+		result = append(result, movie)
+	}
+
+	return result
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	movies := []database.Movie{
+		{
+			Title: "Heat", Year: 1995, Runtime: 170, Tags: []string{"Crime", "Drama"},
+			Availability: database.AvailabilityList{{Service: "Netflix", Until: "2099-01-01"}},
+			Notes:        "with \"quotes\", commas\nand lines", IMDBLink: "https://www.imdb.com/title/tt0113277/",
+			Details: database.Details{Director: "Michael Mann", Cast: []string{"Al Pacino", "Robert De Niro"}},
+		},
+		{Title: "Plain", Watched: true},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		for name, store := range database.TestStores(t) {
+			t.Run(format+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				source := database.NewMemoryStore()
+
+				for _, movie := range movies {
+					id, err := source.AddMovie(ctx, movie)
+
+					if err != nil {
+						t.Fatalf("AddMovie: %v", err)
+					}
+
+					if err := setMovieWatched(ctx, source, id, false, movie.Watched); err != nil {
+						t.Fatalf("setMovieWatched: %v", err)
+					}
+				}
+
+				exported, err := CollectMovies(ctx, source)
+
+				if err != nil {
+					t.Fatalf("CollectMovies: %v", err)
+				}
+
+				var buf bytes.Buffer
+
+				if err := ExportMovies(&buf, format, exported); err != nil {
+					t.Fatalf("ExportMovies: %v", err)
+				}
+
+				report, err := ImportMovies(ctx, store, store, &buf, format, ModeUpsert)
+
+				if err != nil {
+					t.Fatalf("ImportMovies: %v", err)
+				}
+
+				if report.Added != len(movies) || len(report.Errors) != 0 {
+					t.Fatalf("ImportMovies report = %+v, want %d added", report, len(movies))
+				}
+
+				imported, err := CollectMovies(ctx, store)
+
+				if err != nil {
+					t.Fatalf("CollectMovies: %v", err)
+				}
+
+				if got, want := comparableMovies(imported), comparableMovies(exported); !reflect.DeepEqual(got, want) {
+					t.Errorf("imported movies = %+v\nwant %+v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestImportMatchesExistingMovies(t *testing.T) {
+	header := "title,year,notes,imdb_link,watched\n"
+
+	tests := []struct {
+		name      string
+		csv       string
+		mode      string
+		want      Report
+		conflicts int
+		notes     string // Heat's notes afterwards
+		watched   bool   // whether Heat is watched afterwards
+	}{
+		{"by IMDb ID", "Heat (1995),1995,by id,tt0113277,\n", ModeUpsert, Report{Updated: 1}, 0, "by id", false},
+		{"by title and year", "heat,1995,by title,,\n", ModeUpsert, Report{}, 1, "original", false},
+		{"another IMDb ID is another title", "Heat,1995,other id,tt0068699,\n", ModeUpsert, Report{Added: 1}, 0, "original", false},
+		{"skip", "Heat,1995,skipped,tt0113277,\n", ModeSkip, Report{Skipped: 1}, 0, "original", false},
+		{"skip by title", "Heat,1995,skipped,,\n", ModeSkip, Report{}, 1, "original", false},
+		{"other year", "Heat,1972,remake,,\n", ModeUpsert, Report{Added: 1}, 0, "original", false},
+		{"watched", "Heat,1995,,tt0113277,true\n", ModeUpsert, Report{Updated: 1}, 0, "", true},
+		{"watched column left empty", "Heat,1995,,tt0113277,\n", ModeUpsert, Report{Updated: 1}, 0, "", false},
+		{"new and watched", "Ronin,1998,,,1\n", ModeUpsert, Report{Added: 1}, 0, "original", false},
+	}
+
+	for _, tt := range tests {
+		for name, store := range database.TestStores(t) {
+			ctx := context.Background()
+			heat, err := store.AddMovie(ctx, database.Movie{Title: "Heat", Year: 1995, Notes: "original", IMDBLink: "tt0113277"})
+
+			if err != nil {
+				t.Fatalf("AddMovie: %v", err)
+			}
+
+			report, err := ImportMovies(ctx, store, store, strings.NewReader(header+tt.csv), FormatCSV, tt.mode)
+
+			if err != nil {
+				t.Fatalf("%s/%s: ImportMovies: %v", tt.name, name, err)
+			}
+
+			got := *report
+			got.Errors, got.Conflicts = nil, nil
+
+			if !reflect.DeepEqual(got, tt.want) || len(report.Conflicts) != tt.conflicts {
+				t.Errorf("%s/%s: report = %+v, want %+v and %d conflicts", tt.name, name, report, tt.want, tt.conflicts)
+			}
+
+			movies, err := CollectMovies(ctx, store)
+
+			if err != nil {
+				t.Fatalf("CollectMovies: %v", err)
+			}
+
+			for _, movie := range movies {
+				if movie.ID == heat && (movie.Notes != tt.notes || movie.Watched != tt.watched) {
+					t.Errorf("%s/%s: Heat has notes %q, watched %v; want %q, %v", tt.name, name, movie.Notes, movie.Watched, tt.notes, tt.watched)
+				}
+			}
+		}
+	}
+}
+
+func TestImportReturnsWatchedToWatchlist(t *testing.T) {
+	for name, store := range database.TestStores(t) {
+		ctx := context.Background()
+		id, err := store.AddTVShow(ctx, database.TVShow{Title: "Lost", Year: 2004, IMDBLink: "tt0411008"})
+
+		if err != nil {
+			t.Fatalf("AddTVShow: %v", err)
+		}
+
+		if err := store.MarkTVShowWatched(ctx, database.WatchEvent{ItemID: id}); err != nil {
+			t.Fatalf("MarkTVShowWatched: %v", err)
+		}
+
+		report, err := ImportTVShows(ctx, store, store, strings.NewReader(`[{"title":"Lost","year":2004,"imdb_link":"tt0411008","watched":false}]`), FormatJSON, ModeUpsert)
+
+		if err != nil || report.Updated != 1 {
+			t.Fatalf("%s: ImportTVShows = %+v, %v; want 1 updated", name, report, err)
+		}
+
+		tvShows, err := store.GetAllTVShows(ctx)
+
+		if err != nil || len(tvShows) != 1 || tvShows[0].ID != id || tvShows[0].Watched {
+			t.Errorf("%s: watchlist = %+v, %v; want Lost back on it", name, tvShows, err)
+		}
+	}
+}
+
+func TestImportRowErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		wantErr bool
+		rows    []int // rows reported as failed
+	}{
+		{"bad year", FormatCSV, "title,year\nHeat,199x\nRonin,1998\n", false, []int{2}},
+		{"bad runtime", FormatCSV, "title,runtime\nHeat,long\n", false, []int{2}},
+		{"bad watched", FormatCSV, "title,watched\nHeat,maybe\n", false, []int{2}},
+		{"bad availability", FormatCSV, "title,availability\nHeat,Netflix|someday|\n", false, []int{2}},
+		{"no title", FormatCSV, "title,year\n,1995\n", false, []int{2}},
+		{"no title column", FormatCSV, "name,year\nHeat,1995\n", true, nil},
+		{"JSON wrong type", FormatJSON, `[{"title":"Heat","year":"1995"},{"title":"Ronin"}]`, false, []int{1}},
+		{"JSON not an object", FormatJSON, `["Heat"]`, false, []int{1}},
+		{"JSON not an array", FormatJSON, `{"title":"Heat"}`, true, nil},
+		{"unknown format", "xml", "<movies/>", true, nil},
+	}
+
+	for _, tt := range tests {
+		report, err := ImportMovies(context.Background(), database.NewMemoryStore(), database.NewMemoryStore(), strings.NewReader(tt.input), tt.format, ModeUpsert)
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportMovies = %+v, want an error", tt.name, report)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: ImportMovies: %v", tt.name, err)
+		}
+
+		var rows []int
+
+		for _, rowErr := range report.Errors {
+			rows = append(rows, rowErr.Row)
+		}
+
+		if !reflect.DeepEqual(rows, tt.rows) {
+			t.Errorf("%s: failed rows = %v (%+v), want %v", tt.name, rows, report.Errors, tt.rows)
+		}
+	}
+}
+
+func TestFormatFromName(t *testing.T) {
+	tests := map[string]string{
+		"movies.csv":       FormatCSV,
+		"Movies.JSON":      FormatJSON,
+		"backup.tar.gz":    "",
+		"no-extension":     "",
+		"dir.csv/list.txt": "",
+	}
+
+	for name, want := range tests {
+		if got := FormatFromName(name); got != want {
+			t.Errorf("FormatFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -97,6 +97,7 @@ function handleRequest(message) {
         method: 'POST',
         headers: {
             'Content-Type': 'application/x-www-form-urlencoded',
+            ...CSRFUtils.headers(),
         },
         body: formData,
         signal: abortController.signal
@@ -13,34 +13,52 @@ function closeEditModal() {
     ModalUtils.closeEditModal();
 }
 
-function deleteMovie(button) {
-    DeleteUtils.deleteEntity(button, 'movie');
-}
-
 function pickRandomMovie() {
-    RandomUtils.pickRandomEntity('movie');
+    RandomUtils.pickRandomEntity('movie', '/movie-board/random');
 }
 
 function closeRandomMovieModal() {
     RandomUtils.closeRandomEntityModal('movie');
 }
 
+function openWatchModal(button) {
+    WatchUtils.openWatchModal(button, 'movie');
+}
+
+function closeWatchModal() {
+    WatchUtils.closeWatchModal();
+}
+
+function openVotesModal() {
+    VoteUtils.openVotesModal();
+}
+
+function closeVotesModal() {
+    VoteUtils.closeVotesModal();
+}
+
 // Initialize the movie board interface
 document.addEventListener('DOMContentLoaded', function() {
     Logger.info('Movie board interface initializing...');
     
     // Set up modal click outside to close
     ModalUtils.setupModalClickOutside('edit-modal', closeEditModal);
+    ModalUtils.setupModalClickOutside('watch-modal', closeWatchModal);
+    ModalUtils.setupModalClickOutside('votes-modal', closeVotesModal);
     
     // Make sure functions are available globally
-    window.deleteMovie = deleteMovie;
     window.openEditModal = openEditModal;
     window.closeEditModal = closeEditModal;
+    window.openWatchModal = openWatchModal;
+    window.closeWatchModal = closeWatchModal;
+    window.openVotesModal = openVotesModal;
+    window.closeVotesModal = closeVotesModal;
     window.pickRandomMovie = pickRandomMovie;
     window.closeRandomMovieModal = closeRandomMovieModal;
     
-    // Set up event delegation
-    EventUtils.setupDeleteEventDelegation('movie');
+    // Set up the undo toast, duplicate warnings and event delegation
+    ToastUtils.setupAutoDismiss();
+    DuplicateUtils.setupWarningSwap();
     EventUtils.setupRandomModalClickOutside('movie');
     
     Logger.info('Movie board interface initialized successfully');
@@ -13,8 +13,42 @@ function closeEditModal() {
     ModalUtils.closeEditModal();
 }
 
-function deleteTVShow(button) {
-    DeleteUtils.deleteEntity(button, 'tvshow');
+function openWatchModal(button) {
+    WatchUtils.openWatchModal(button, 'tvshow');
+}
+
+function closeWatchModal() {
+    WatchUtils.closeWatchModal();
+}
+
+function pickRandomTVShow() {
+    RandomUtils.pickRandomEntity('tvshow', '/tv-shows-board/random');
+}
+
+function closeRandomTVShowModal() {
+    RandomUtils.closeRandomEntityModal('tvshow');
+}
+
+function openEpisodesModal() {
+    Logger.debug('Opening episodes modal');
+    const modal = document.getElementById('episodes-modal');
+    modal.classList.remove('hidden');
+    modal.classList.add('animate-fade-in');
+}
+
+function closeEpisodesModal() {
+    Logger.debug('Closing episodes modal');
+    const modal = document.getElementById('episodes-modal');
+    modal.classList.add('hidden');
+    modal.classList.remove('animate-fade-in');
+}
+
+function openVotesModal() {
+    VoteUtils.openVotesModal();
+}
+
+function closeVotesModal() {
+    VoteUtils.closeVotesModal();
 }
 
 // Initialize the TV Shows board interface
@@ -23,14 +57,26 @@ document.addEventListener('DOMContentLoaded', function() {
     
     // Set up modal click outside to close
     ModalUtils.setupModalClickOutside('edit-modal', closeEditModal);
+    ModalUtils.setupModalClickOutside('watch-modal', closeWatchModal);
+    ModalUtils.setupModalClickOutside('votes-modal', closeVotesModal);
+    ModalUtils.setupModalClickOutside('episodes-modal', closeEpisodesModal);
     
     // Make sure functions are available globally
-    window.deleteTVShow = deleteTVShow;
     window.openEditModal = openEditModal;
     window.closeEditModal = closeEditModal;
+    window.openWatchModal = openWatchModal;
+    window.closeWatchModal = closeWatchModal;
+    window.openVotesModal = openVotesModal;
+    window.closeVotesModal = closeVotesModal;
+    window.openEpisodesModal = openEpisodesModal;
+    window.closeEpisodesModal = closeEpisodesModal;
+    window.pickRandomTVShow = pickRandomTVShow;
+    window.closeRandomTVShowModal = closeRandomTVShowModal;
     
-    // Set up event delegation
-    EventUtils.setupDeleteEventDelegation('tvshow');
+    // Set up the undo toast, duplicate warnings and event delegation
+    ToastUtils.setupAutoDismiss();
+    DuplicateUtils.setupWarningSwap();
+    EventUtils.setupRandomModalClickOutside('tvshow');
     
     Logger.info('TV Shows board interface initialized successfully');
 });
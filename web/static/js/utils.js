@@ -64,7 +64,7 @@ const ModalUtils = {
         Logger.info(`Opening edit modal for ${entityType}:`, entityTitle, 'ID:', entityId);
         
         const modal = document.getElementById('edit-modal');
-        const fields = ['year', 'genre', 'streaming', 'notes', 'imdb'];
+        const fields = ['year', 'runtime', 'notes', 'imdb', 'poster', 'director', 'cast'];
         
         // Populate common form fields
         document.getElementById(`edit-${entityType}-id`).value = entityId;
@@ -78,15 +78,23 @@ const ModalUtils = {
             }
         });
         
+        // Select the item's tags in the multi-select and clear the new tags input
+        const tags = (button.getAttribute(`data-${entityType}-tags`) || '').split(',');
+        Array.from(document.getElementById('edit-tags').options).forEach(option => {
+            option.selected = tags.includes(option.value);
+        });
+        document.getElementById('edit-new-tags').value = '';
+        
+        // Rebuild the availability rows from the item's windows
+        AvailabilityUtils.setRows('edit-availability', button.getAttribute(`data-${entityType}-availability`));
+        
         // Handle entity-specific fields
-        if (entityType === 'movie') {
-            const movieAvailableNow = button.getAttribute('data-movie-available-now');
-            document.getElementById('edit-available-now').checked = movieAvailableNow === 'true';
-        } else if (entityType === 'tvshow') {
+        if (entityType === 'tvshow') {
             const tvshowActiveSeason = button.getAttribute('data-tvshow-active-season');
             document.getElementById('edit-active-season').checked = tvshowActiveSeason === 'true';
         }
         
+        DuplicateUtils.clear('edit-duplicate-warning');
         Logger.debug('Edit form populated with data');
         
         // Show modal
@@ -142,88 +150,220 @@ const FormUtils = {
             // Clear form fields
             if (formElement) {
                 formElement.reset();
+                AvailabilityUtils.setRows('availability', '');
+                DuplicateUtils.clear('add-duplicate-warning');
+                MetadataUtils.clearSuggestions();
                 Logger.debug('Form fields cleared');
             }
         }
     }
 };
 
-// Delete utilities
-const DeleteUtils = {
-    deleteEntity(button, entityType) {
-        const entityId = button.getAttribute(`data-${entityType}-id`);
-        const entityTitle = button.closest('.bg-gray-700').querySelector('h4').textContent;
+// Streaming availability row utilities
+const AvailabilityUtils = {
+    addRow(containerId, service = '', from = '', until = '') {
+        const template = document.getElementById('availability-template');
+        const container = document.getElementById(containerId);
+        const row = template.content.firstElementChild.cloneNode(true);
+        const select = row.querySelector('select[name="service"]');
         
-        Logger.warn(`Delete ${entityType} requested:`, entityTitle, 'ID:', entityId);
+        // Keep services that are no longer configured selectable
+        if (service && !Array.from(select.options).some(option => option.value === service)) {
+            select.add(new Option(service, service));
+        }
         
-        if (confirm(`Are you sure you want to delete this ${entityType}?`)) {
-            Logger.info('Delete confirmed, sending delete request');
-            
-            fetch(`/${entityType}-board/delete/${entityId}`, {
-                method: 'DELETE',
-            })
+        select.value = service;
+        row.querySelector('input[name="available_from"]').value = from;
+        row.querySelector('input[name="available_until"]').value = until;
+        container.appendChild(row);
+    },
+    
+    removeRow(button) {
+        button.closest('.availability-row').remove();
+    },
+    
+    // Replace the rows with windows encoded as "service|from|until;..."
+    setRows(containerId, encoded) {
+        document.getElementById(containerId).innerHTML = '';
+        
+        (encoded || '').split(';').filter(Boolean).forEach(entry => {
+            const [service, from, until] = entry.split('|');
+            this.addRow(containerId, service, from, until);
+        });
+    }
+};
+
+// Board filter bar utilities
+const FilterUtils = {
+    // Leave unset filters out of the request so the pushed URL stays short
+    dropEmptyParameters(event) {
+        const parameters = event.detail.parameters;
+        Object.keys(parameters).forEach(name => {
+            if (parameters[name] === '') {
+                delete parameters[name];
+            }
+        });
+    }
+};
+
+// CSRF utilities
+const CSRFUtils = {
+    // The token the page was rendered with, for requests made outside HTMX
+    headers() {
+        try {
+            return JSON.parse(document.body.getAttribute('hx-headers') || '{}');
+        } catch (error) {
+            Logger.error('Failed to read CSRF token:', error.message);
+            return {};
+        }
+    },
+    
+    // The server answers a refused change with 403 and a notice to append to the page
+    setupErrorSwap() {
+        document.body.addEventListener('htmx:beforeSwap', (e) => {
+            if (e.detail.xhr.status === 403 && e.detail.xhr.getResponseHeader('X-CSRF-Error')) {
+                Logger.warn('Change refused by the CSRF check, showing reload notice');
+                document.getElementById('csrf-error')?.remove();
+                e.detail.shouldSwap = true;
+                e.detail.isError = false;
+            }
+        });
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => CSRFUtils.setupErrorSwap());
+
+// Duplicate warning utilities
+const DuplicateUtils = {
+    // The server answers a likely duplicate with 409 and a warning to show in the form
+    setupWarningSwap() {
+        document.body.addEventListener('htmx:beforeSwap', (e) => {
+            if (e.detail.xhr.status === 409) {
+                Logger.info('Likely duplicate, showing warning');
+                e.detail.shouldSwap = true;
+                e.detail.isError = false;
+            }
+        });
+    },
+    
+    clear(containerId) {
+        const container = document.getElementById(containerId);
+        if (container) {
+            container.innerHTML = '';
+        }
+    },
+    
+    // Leave the add form and edit the existing entry instead
+    openExisting(button, entityType) {
+        const addForm = document.getElementById(`add-${entityType}-form`);
+        if (addForm && !addForm.classList.contains('hidden')) {
+            FormUtils.toggleAddForm(entityType);
+        }
+        ModalUtils.openEditModal(button, entityType);
+    }
+};
+
+// Metadata lookup utilities
+const MetadataUtils = {
+    // Fill the add form from the provider match the user chose under the title
+    applyMatch(button) {
+        const type = button.getAttribute('data-type');
+        const id = button.getAttribute('data-id');
+        const form = button.closest('form');
+        Logger.info(`Looking up ${type} metadata:`, id);
+        
+        fetch(`/metadata/details?type=${encodeURIComponent(type)}&id=${encodeURIComponent(id)}`)
             .then(response => {
-                if (response.ok) {
-                    Logger.info(`${entityType} deleted successfully from server`);
-                    
-                    // Remove the entity card from DOM
-                    const entityCard = button.closest('.bg-gray-700');
-                    if (entityCard) {
-                        entityCard.remove();
-                        Logger.debug(`${entityType} card removed from DOM`);
-                    }
-                    
-                    // Update entity count
-                    const countElement = document.getElementById(`${entityType}-count`);
-                    const currentCount = parseInt(countElement.textContent.match(/\d+/)[0]);
-                    const newCount = currentCount - 1;
-                    
-                    if (newCount === 0) {
-                        Logger.info(`No ${entityType}s remaining, showing empty state`);
-                        // Show "no entities" message
-                        const listElement = document.getElementById(`${entityType}-list`);
-                        listElement.innerHTML = `
-                            <div class="text-center py-8">
-                                <svg class="w-16 h-16 text-gray-600 mx-auto mb-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                                    <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4V2a1 1 0 011-1h8a1 1 0 011 1v2m-9 0h10m-10 0a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V6a2 2 0 00-2-2"></path>
-                                </svg>
-                                <p class="text-gray-400">No ${entityType}s added yet. Add your first ${entityType} above!</p>
-                            </div>
-                        `;
-                        countElement.textContent = `0 ${entityType}s in your list`;
-                    } else {
-                        countElement.textContent = `${newCount} ${entityType}s in your list`;
-                        Logger.debug(`${entityType} count updated to:`, newCount);
-                    }
-                    
-                } else {
-                    Logger.error(`Failed to delete ${entityType} from server, status:`, response.status);
-                    alert(`Failed to delete ${entityType}`);
+                if (!response.ok) {
+                    throw new Error(`status ${response.status}`);
                 }
+                return response.json();
+            })
+            .then(details => {
+                this.setField(form, 'title', details.title);
+                this.setField(form, 'year', details.year || '');
+                this.setField(form, 'runtime', details.runtime || '');
+                this.setField(form, 'imdb_link', details.imdb_id ? `https://www.imdb.com/title/${details.imdb_id}/` : '');
+                this.setField(form, 'poster_url', details.poster_url);
+                this.setField(form, 'director', details.director);
+                this.setField(form, 'cast', (details.cast || []).join(', '));
+                this.setTags(form, details.genres || []);
+                this.clearSuggestions();
+                Logger.debug('Add form filled from metadata');
             })
             .catch(error => {
-                Logger.error('Delete request failed:', error.message);
-                alert(`Failed to delete ${entityType}: ` + error.message);
+                Logger.error('Failed to look up metadata:', error.message);
             });
-        } else {
-            Logger.info('Delete cancelled by user');
+    },
+    
+    // Set a form field by name, skipping fields the form does not have
+    setField(form, name, value) {
+        const element = form.elements.namedItem(name);
+        if (element && value !== undefined) {
+            element.value = value;
+        }
+    },
+    
+    // Select genres that are already tags and type the rest as new tags
+    setTags(form, genres) {
+        const select = form.elements.namedItem('tags');
+        const known = Array.from(select.options).map(option => option.value.toLowerCase());
+        Array.from(select.options).forEach(option => {
+            option.selected = genres.some(genre => genre.toLowerCase() === option.value.toLowerCase());
+        });
+        this.setField(form, 'new_tags', genres.filter(genre => !known.includes(genre.toLowerCase())).join(', '));
+    },
+    
+    clearSuggestions() {
+        const container = document.getElementById('title-suggestions');
+        if (container) {
+            container.innerHTML = '';
+        }
+    }
+};
+
+// Toast utilities
+const ToastUtils = {
+    dismissAfterMs: 10000,
+    timer: null,
+    
+    // Clear the undo toast, e.g. once Undo has been clicked
+    dismiss() {
+        clearTimeout(this.timer);
+        const toast = document.getElementById('toast');
+        if (toast) {
+            toast.innerHTML = '';
         }
+    },
+    
+    // Auto-dismiss the toast a few seconds after the server swaps one in
+    setupAutoDismiss() {
+        document.body.addEventListener('htmx:oobAfterSwap', (e) => {
+            if (e.detail.target && e.detail.target.id === 'toast') {
+                Logger.debug('Undo toast shown');
+                clearTimeout(this.timer);
+                this.timer = setTimeout(() => this.dismiss(), this.dismissAfterMs);
+            }
+        });
     }
 };
 
 // Random entity utilities
 const RandomUtils = {
-    pickRandomEntity(entityType) {
+    pickRandomEntity(entityType, url) {
         Logger.info(`Requesting random ${entityType}`);
         
-        fetch(`/${entityType}-board/random`)
+        fetch(url, { method: 'POST', headers: CSRFUtils.headers() })
             .then(response => {
                 Logger.debug(`Random ${entityType} response received, status:`, response.status);
                 return response.text();
             })
             .then(html => {
                 Logger.debug(`Random ${entityType} HTML received, length:`, html.length);
-                document.getElementById(`random-${entityType}-modal-content`).innerHTML = html;
+                const content = document.getElementById(`random-${entityType}-modal-content`);
+                content.innerHTML = html;
+                // Let HTMX wire up the picker's filter form and reroll button
+                htmx.process(content);
                 document.getElementById(`random-${entityType}-modal`).classList.remove('hidden');
                 document.getElementById(`random-${entityType}-modal`).classList.add('animate-fade-in');
                 Logger.info(`Random ${entityType} modal displayed`);
@@ -243,17 +383,56 @@ const RandomUtils = {
     }
 };
 
-// Event delegation utilities
-const EventUtils = {
-    setupDeleteEventDelegation(entityType) {
-        document.addEventListener('click', function(e) {
-            if (e.target.closest(`.delete-${entityType}-btn`)) {
-                const button = e.target.closest(`.delete-${entityType}-btn`);
-                DeleteUtils.deleteEntity(button, entityType);
-            }
-        });
+// Watch history utilities
+const WatchUtils = {
+    openWatchModal(button, entityType) {
+        const entityId = button.getAttribute(`data-${entityType}-id`);
+        const entityTitle = button.getAttribute(`data-${entityType}-title`);
+        Logger.info(`Opening watch modal for ${entityType}:`, entityTitle, 'ID:', entityId);
+        
+        const modal = document.getElementById('watch-modal');
+        const form = document.getElementById(`watch-${entityType}-form`);
+        
+        // Keep the last watcher name so repeat entries are quicker
+        const watcher = document.getElementById('watch-watcher').value;
+        form.reset();
+        document.getElementById('watch-watcher').value = watcher;
+        
+        document.getElementById(`watch-${entityType}-id`).value = entityId;
+        document.getElementById('watch-title').textContent = entityTitle;
+        document.getElementById('watch-watched-at').value = new Date().toLocaleDateString('en-CA');
+        
+        modal.classList.remove('hidden');
+        modal.classList.add('animate-fade-in');
+    },
+    
+    closeWatchModal() {
+        Logger.debug('Closing watch modal');
+        const modal = document.getElementById('watch-modal');
+        modal.classList.add('hidden');
+        modal.classList.remove('animate-fade-in');
+    }
+};
+
+// Household vote utilities; the votes modal's content is loaded by HTMX
+const VoteUtils = {
+    openVotesModal() {
+        Logger.debug('Opening votes modal');
+        const modal = document.getElementById('votes-modal');
+        modal.classList.remove('hidden');
+        modal.classList.add('animate-fade-in');
     },
     
+    closeVotesModal() {
+        Logger.debug('Closing votes modal');
+        const modal = document.getElementById('votes-modal');
+        modal.classList.add('hidden');
+        modal.classList.remove('animate-fade-in');
+    }
+};
+
+// Event delegation utilities
+const EventUtils = {
     setupRandomModalClickOutside(entityType) {
         const modal = document.getElementById(`random-${entityType}-modal`);
         if (modal) {
@@ -282,7 +461,14 @@ function initializeLogLevel(storageKey, defaultLevel = 'INFO') {
 window.Logger = Logger;
 window.ModalUtils = ModalUtils;
 window.FormUtils = FormUtils;
-window.DeleteUtils = DeleteUtils;
+window.AvailabilityUtils = AvailabilityUtils;
+window.FilterUtils = FilterUtils;
+window.CSRFUtils = CSRFUtils;
+window.DuplicateUtils = DuplicateUtils;
+window.MetadataUtils = MetadataUtils;
+window.ToastUtils = ToastUtils;
 window.RandomUtils = RandomUtils;
+window.WatchUtils = WatchUtils;
+window.VoteUtils = VoteUtils;
 window.EventUtils = EventUtils;
 window.initializeLogLevel = initializeLogLevel; 
\ No newline at end of file